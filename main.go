@@ -15,6 +15,7 @@ import (
 	_ "net/http/pprof"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/zxh326/kite/pkg/auth"
 	"github.com/zxh326/kite/pkg/common"
 	"github.com/zxh326/kite/pkg/handlers"
@@ -66,6 +67,9 @@ func setupAPIRouter(r *gin.Engine, k8sClient *kube.K8sClient, promClient *promet
 			"status": "ok",
 		})
 	})
+	if common.EnableMetrics {
+		r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	}
 
 	// Auth routes (no auth required)
 	authHandler := auth.NewAuthHandler()
@@ -100,6 +104,7 @@ func setupAPIRouter(r *gin.Engine, k8sClient *kube.K8sClient, promClient *promet
 
 		nodeTerminalHandler := handlers.NewNodeTerminalHandler(k8sClient)
 		api.GET("/node-terminal/:nodeName/ws", nodeTerminalHandler.HandleNodeTerminalWebSocket)
+		api.GET("/nodes/_all/:name/terminal", nodeTerminalHandler.HandleNodeTerminalWebSocket)
 
 		searchHandler := handlers.NewSearchHandler(k8sClient)
 		api.GET("/search", searchHandler.GlobalSearch)
@@ -2,6 +2,9 @@ package common
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/zxh326/kite/pkg/utils"
 	"k8s.io/klog/v2"
@@ -21,9 +24,42 @@ var (
 	OAuthProviders  = ""
 	OAuthAllowUsers = ""
 	EnableAnalytics = false
+	// EnableMetrics serves the Prometheus metrics of kite on /metrics. The
+	// endpoint has no authentication, so it is off unless opted in.
+	EnableMetrics = false
 
 	NodeTerminalImage = "busybox:latest"
 
+	// NodeTerminalIdleTimeout closes node terminal sessions without input
+	NodeTerminalIdleTimeout = 30 * time.Minute
+
+	// NodeHelperPodMaxAge is how long finished node operation pods are kept
+	NodeHelperPodMaxAge = time.Hour
+
+	// RestartBySelectorMax caps how many deployments a label selector
+	// restart may match
+	RestartBySelectorMax = 200
+
+	// PodHistoryMaxPods caps how many pods the history recorder keeps, the
+	// least recently updated are evicted first. 0 disables the recorder.
+	PodHistoryMaxPods = 5000
+	// PodHistoryMaxAge is how long the history of deleted pods is kept
+	PodHistoryMaxAge = 24 * time.Hour
+	// PodHistoryMaxEntries caps each recorded history list per pod
+	PodHistoryMaxEntries = 50
+	// PodHistoryCacheTTL is how long batch pod history responses are
+	// cached. 0 disables the cache.
+	PodHistoryCacheTTL = 15 * time.Second
+
+	// SidecarContainerNames are the container names pod history treats as
+	// sidecars rather than the application
+	SidecarContainerNames = []string{"istio-proxy", "istio-init", "linkerd-proxy", "linkerd-init", "envoy", "vault-agent", "vault-agent-init", "cloud-sql-proxy"}
+
+	// ProtectedNamespaces are refused by destructive operations unless the
+	// request confirms it and the user is in ProtectedNamespaceUsers
+	ProtectedNamespaces     = []string{"kube-system", "kube-node-lease", "kube-public"}
+	ProtectedNamespaceUsers []string
+
 	WebhookUsername = "kite-webhook"
 	WebhookPassword = "kite-webhook-password"
 
@@ -71,10 +107,79 @@ func LoadEnvs() {
 		EnableAnalytics = true
 	}
 
+	if metrics := os.Getenv("ENABLE_METRICS"); metrics == "true" {
+		EnableMetrics = true
+	}
+
 	if nodeTerminalImage := os.Getenv("NODE_TERMINAL_IMAGE"); nodeTerminalImage != "" {
 		NodeTerminalImage = nodeTerminalImage
 	}
 
+	if idleTimeout := os.Getenv("NODE_TERMINAL_IDLE_TIMEOUT"); idleTimeout != "" {
+		if d, err := time.ParseDuration(idleTimeout); err == nil && d > 0 {
+			NodeTerminalIdleTimeout = d
+		} else {
+			klog.Warningf("Invalid NODE_TERMINAL_IDLE_TIMEOUT %q, using %s", idleTimeout, NodeTerminalIdleTimeout)
+		}
+	}
+
+	if maxAge := os.Getenv("NODE_HELPER_POD_MAX_AGE"); maxAge != "" {
+		if d, err := time.ParseDuration(maxAge); err == nil && d > 0 {
+			NodeHelperPodMaxAge = d
+		} else {
+			klog.Warningf("Invalid NODE_HELPER_POD_MAX_AGE %q, using %s", maxAge, NodeHelperPodMaxAge)
+		}
+	}
+
+	if selectorMax := os.Getenv("RESTART_BY_SELECTOR_MAX"); selectorMax != "" {
+		if n, err := strconv.Atoi(selectorMax); err == nil && n > 0 {
+			RestartBySelectorMax = n
+		} else {
+			klog.Warningf("Invalid RESTART_BY_SELECTOR_MAX %q, using %d", selectorMax, RestartBySelectorMax)
+		}
+	}
+
+	if maxPods := os.Getenv("POD_HISTORY_MAX_PODS"); maxPods != "" {
+		if n, err := strconv.Atoi(maxPods); err == nil && n >= 0 {
+			PodHistoryMaxPods = n
+		} else {
+			klog.Warningf("Invalid POD_HISTORY_MAX_PODS %q, using %d", maxPods, PodHistoryMaxPods)
+		}
+	}
+	if maxAge := os.Getenv("POD_HISTORY_MAX_AGE"); maxAge != "" {
+		if d, err := time.ParseDuration(maxAge); err == nil && d > 0 {
+			PodHistoryMaxAge = d
+		} else {
+			klog.Warningf("Invalid POD_HISTORY_MAX_AGE %q, using %s", maxAge, PodHistoryMaxAge)
+		}
+	}
+	if maxEntries := os.Getenv("POD_HISTORY_MAX_ENTRIES"); maxEntries != "" {
+		if n, err := strconv.Atoi(maxEntries); err == nil && n > 0 {
+			PodHistoryMaxEntries = n
+		} else {
+			klog.Warningf("Invalid POD_HISTORY_MAX_ENTRIES %q, using %d", maxEntries, PodHistoryMaxEntries)
+		}
+	}
+
+	if cacheTTL := os.Getenv("POD_HISTORY_CACHE_TTL"); cacheTTL != "" {
+		if d, err := time.ParseDuration(cacheTTL); err == nil && d >= 0 {
+			PodHistoryCacheTTL = d
+		} else {
+			klog.Warningf("Invalid POD_HISTORY_CACHE_TTL %q, using %s", cacheTTL, PodHistoryCacheTTL)
+		}
+	}
+
+	if sidecars := os.Getenv("SIDECAR_CONTAINER_NAMES"); sidecars != "" {
+		SidecarContainerNames = splitList(sidecars)
+	}
+
+	if namespaces, ok := os.LookupEnv("PROTECTED_NAMESPACES"); ok {
+		ProtectedNamespaces = splitList(namespaces)
+	}
+	if users := os.Getenv("PROTECTED_NAMESPACE_USERS"); users != "" {
+		ProtectedNamespaceUsers = splitList(users)
+	}
+
 	if webhookUsername := os.Getenv("WEBHOOK_USERNAME"); webhookUsername != "" {
 		WebhookUsername = webhookUsername
 	}
@@ -87,3 +192,14 @@ func LoadEnvs() {
 		Readonly = true
 	}
 }
+
+// splitList splits a comma-separated env var, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,27 @@
+package common
+
+import (
+	"fmt"
+	"slices"
+)
+
+// IsProtectedNamespace reports whether namespace is in PROTECTED_NAMESPACES
+func IsProtectedNamespace(namespace string) bool {
+	return slices.Contains(ProtectedNamespaces, namespace)
+}
+
+// CheckProtectedNamespace returns an error when a destructive operation
+// targets a protected namespace. It is allowed when the request confirmed it
+// and user is in PROTECTED_NAMESPACE_USERS.
+func CheckProtectedNamespace(namespace, user string, confirmed bool) error {
+	if !IsProtectedNamespace(namespace) {
+		return nil
+	}
+	if !confirmed {
+		return fmt.Errorf("namespace %s is protected, set confirmProtected to proceed", namespace)
+	}
+	if !slices.Contains(ProtectedNamespaceUsers, user) {
+		return fmt.Errorf("namespace %s is protected and user %s is not allowed to modify it", namespace, user)
+	}
+	return nil
+}
@@ -29,9 +29,15 @@ func NewNodeTerminalHandler(client *kube.K8sClient) *NodeTerminalHandler {
 	}
 }
 
-// HandleNodeTerminalWebSocket handles WebSocket connections for node terminal access
+// HandleNodeTerminalWebSocket handles WebSocket connections for node terminal access.
+// Every connection gets its own agent pod, which is deleted when the socket
+// closes or the session has been idle for common.NodeTerminalIdleTimeout.
 func (h *NodeTerminalHandler) HandleNodeTerminalWebSocket(c *gin.Context) {
 	nodeName := c.Param("nodeName")
+	if nodeName == "" {
+		// Also served as /nodes/_all/:name/terminal
+		nodeName = c.Param("name")
+	}
 	if nodeName == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Node name is required"})
 		return
@@ -77,6 +83,7 @@ func (h *NodeTerminalHandler) HandleNodeTerminalWebSocket(c *gin.Context) {
 		}
 
 		session := kube.NewTerminalSession(h.k8sClient, conn, "kube-system", nodeAgentName, common.NodeTerminalPodName)
+		session.SetIdleTimeout(common.NodeTerminalIdleTimeout)
 		if err := session.Start(ctx, "attach"); err != nil {
 			klog.Errorf("Terminal session error: %v", err)
 		}
@@ -92,7 +99,8 @@ func (h *NodeTerminalHandler) createNodeAgent(ctx context.Context, nodeName stri
 			Name:      podName,
 			Namespace: "kube-system",
 			Labels: map[string]string{
-				"app": podName,
+				"app":  podName,
+				"type": "node-terminal",
 			},
 		},
 		Spec: corev1.PodSpec{
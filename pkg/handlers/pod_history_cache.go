@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/singleflight"
+)
+
+// podHistoryCacheBuildTimeout bounds a batch build shared by several
+// requests, it no longer follows any one request's context
+const podHistoryCacheBuildTimeout = time.Minute
+
+var podHistoryCacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "kite_pod_history_cache_requests_total",
+	Help: "Batch pod history requests served by the response cache, by result (hit or miss)",
+}, []string{"result"})
+
+func init() {
+	prometheus.MustRegister(podHistoryCacheRequests)
+}
+
+type podHistoryCacheEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+// podHistoryCache keeps serialized batch history responses for a short TTL.
+// Concurrent requests for the same key share one build.
+type podHistoryCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]podHistoryCacheEntry
+	group   singleflight.Group
+}
+
+func newPodHistoryCache(ttl time.Duration) *podHistoryCache {
+	return &podHistoryCache{
+		ttl:     ttl,
+		entries: make(map[string]podHistoryCacheEntry),
+	}
+}
+
+type podHistoryCacheResult struct {
+	status int
+	data   []byte
+}
+
+// get returns the cached response for key or builds it. Only successful
+// responses are cached, hit is set when no build was needed.
+func (c *podHistoryCache) get(key string, build func() (int, gin.H)) (status int, data []byte, hit bool) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		podHistoryCacheRequests.WithLabelValues("hit").Inc()
+		return http.StatusOK, entry.data, true
+	}
+
+	podHistoryCacheRequests.WithLabelValues("miss").Inc()
+	value, _, _ := c.group.Do(key, func() (interface{}, error) {
+		status, body := build()
+		data, err := json.Marshal(body)
+		if err != nil {
+			data, _ = json.Marshal(gin.H{"error": "Failed to encode pod history: " + err.Error()})
+			return podHistoryCacheResult{status: http.StatusInternalServerError, data: data}, nil
+		}
+		if status == http.StatusOK {
+			c.set(key, data)
+		}
+		return podHistoryCacheResult{status: status, data: data}, nil
+	})
+	result := value.(podHistoryCacheResult)
+	return result.status, result.data, false
+}
+
+// set stores a response and drops the expired ones
+func (c *podHistoryCache) set(key string, data []byte) {
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = podHistoryCacheEntry{data: data, expires: now.Add(c.ttl)}
+}
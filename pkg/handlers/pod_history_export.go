@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// podHistoryExportColumns is the header of CSV exports
+var podHistoryExportColumns = []string{"pod", "type", "timestamp", "reason", "message", "node"}
+
+// podHistoryExportRow is one line of a CSV export
+type podHistoryExportRow struct {
+	pod     string
+	kind    string
+	time    time.Time
+	reason  string
+	message string
+	node    string
+}
+
+// ExportPodHistory downloads the full history of a Pod as an attachment.
+// ?format=json returns the PodNodeHistory, ?format=csv flattens node
+// history, restarts, phase transitions and events into one time-ordered
+// table. All node history entries are kept unless ?historyLimit= is set,
+// the other parameters work as for GetPodHistory. Timestamps are RFC3339 UTC.
+func (h *PodHistoryHandler) ExportPodHistory(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if !validExportFormat(format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+		return
+	}
+
+	status, body := h.podHistoryResponse(c, maxNodeHistoryLimit)
+	if status != http.StatusOK {
+		c.JSON(status, body)
+		return
+	}
+	history := body.(*PodNodeHistory)
+	name := fmt.Sprintf("%s_%s_history", history.Namespace, history.PodName)
+	writePodHistoryExport(c, format, name, []*PodNodeHistory{history}, true)
+}
+
+// exportPodsHistoryBatch serves GetPodsHistoryBatch with ?format=, the
+// histories of the page are written to one file. Exports skip the cache.
+func (h *PodHistoryHandler) exportPodsHistoryBatch(c *gin.Context) {
+	format := c.Query("format")
+	if !validExportFormat(format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+		return
+	}
+
+	status, body := h.buildPodsHistoryBatch(c.Request.Context(), c)
+	if status != http.StatusOK {
+		c.JSON(status, body)
+		return
+	}
+	histories, _ := body["histories"].([]PodNodeHistory)
+	exported := make([]*PodNodeHistory, 0, len(histories))
+	for i := range histories {
+		exported = append(exported, &histories[i])
+	}
+	name := fmt.Sprintf("%s_pods_history", c.Param("namespace"))
+	writePodHistoryExport(c, format, name, exported, false)
+}
+
+func validExportFormat(format string) bool {
+	return format == "json" || format == "csv"
+}
+
+// writePodHistoryExport writes histories as an attachment named
+// <name>_<timestamp>.<format>. With single the JSON export is the one
+// history object instead of an array.
+func writePodHistoryExport(c *gin.Context, format, name string, histories []*PodNodeHistory, single bool) {
+	filename := fmt.Sprintf("%s_%s.%s", name, time.Now().UTC().Format("20060102T150405Z"), format)
+
+	var (
+		data        []byte
+		contentType string
+		err         error
+	)
+	switch format {
+	case "csv":
+		contentType = "text/csv; charset=utf-8"
+		data, err = podHistoryCSV(histories)
+	default:
+		contentType = "application/json; charset=utf-8"
+		for _, history := range histories {
+			podHistoryToUTC(history)
+		}
+		if single && len(histories) == 1 {
+			data, err = json.MarshalIndent(histories[0], "", "  ")
+		} else {
+			data, err = json.MarshalIndent(histories, "", "  ")
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to export pod history: %v", err)})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// podHistoryCSV flattens histories into CSV rows ordered by time
+func podHistoryCSV(histories []*PodNodeHistory) ([]byte, error) {
+	var rows []podHistoryExportRow
+	for _, history := range histories {
+		rows = append(rows, podHistoryExportRows(history)...)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].time.Before(rows[j].time)
+	})
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(podHistoryExportColumns); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		record := []string{row.pod, row.kind, formatExportTime(row.time), row.reason, row.message, row.node}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// podHistoryExportRows returns the node, restart, phase and event rows of
+// one history. Recorded events are only used when the pod's events are gone.
+func podHistoryExportRows(history *PodNodeHistory) []podHistoryExportRow {
+	pod := history.PodName
+	var rows []podHistoryExportRow
+
+	for _, entry := range history.NodeHistory {
+		rows = append(rows, podHistoryExportRow{
+			pod:     pod,
+			kind:    "node",
+			time:    entry.StartTime,
+			reason:  entry.Reason,
+			message: entry.Phase,
+			node:    entry.NodeName,
+		})
+	}
+	for _, restart := range workloadRestarts(history) {
+		message := restart.Message
+		if restart.ExitCode != nil {
+			message = fmt.Sprintf("container %s exited with code %d", restart.ContainerName, *restart.ExitCode)
+			if restart.Message != "" {
+				message += ": " + restart.Message
+			}
+		} else if message == "" {
+			message = "container " + restart.ContainerName
+		}
+		rows = append(rows, podHistoryExportRow{
+			pod:     pod,
+			kind:    "restart",
+			time:    restart.Time,
+			reason:  restart.Reason,
+			message: message,
+			node:    nodeAt(history.NodeHistory, restart.Time),
+		})
+	}
+	for _, transition := range history.PhaseTransitions {
+		rows = append(rows, podHistoryExportRow{
+			pod:     pod,
+			kind:    "phase",
+			time:    transition.Time,
+			reason:  transition.Reason,
+			message: transition.Phase,
+			node:    nodeAt(history.NodeHistory, transition.Time),
+		})
+	}
+	for _, event := range history.Events {
+		rows = append(rows, podHistoryExportRow{
+			pod:     pod,
+			kind:    "event",
+			time:    eventTime(event),
+			reason:  event.Reason,
+			message: event.Message,
+			node:    event.Source.Host,
+		})
+	}
+	if len(history.Events) == 0 {
+		for _, event := range history.RecordedEvents {
+			rows = append(rows, podHistoryExportRow{
+				pod:     pod,
+				kind:    "event",
+				time:    event.LastTime,
+				reason:  event.Reason,
+				message: event.Message,
+				node:    nodeAt(history.NodeHistory, event.LastTime),
+			})
+		}
+	}
+	return rows
+}
+
+// nodeAt returns the node the pod was on at t, or "" when unknown
+func nodeAt(nodeHistory []NodeHistoryEntry, t time.Time) string {
+	for _, entry := range nodeHistory {
+		if t.Before(entry.StartTime) {
+			continue
+		}
+		if entry.EndTime == nil || !t.After(*entry.EndTime) {
+			return entry.NodeName
+		}
+	}
+	return ""
+}
+
+func formatExportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// podHistoryToUTC converts the timestamps of history to UTC so that exports
+// do not depend on the server's time zone. metav1.Time is always encoded in
+// UTC. Slices are copied as recorded histories may share them.
+func podHistoryToUTC(history *PodNodeHistory) {
+	utc := func(t *time.Time) *time.Time {
+		if t == nil {
+			return nil
+		}
+		u := t.UTC()
+		return &u
+	}
+
+	history.DeletedAt = utc(history.DeletedAt)
+	if history.Eviction != nil {
+		eviction := *history.Eviction
+		eviction.Time = utc(eviction.Time)
+		history.Eviction = &eviction
+	}
+
+	nodeHistory := make([]NodeHistoryEntry, len(history.NodeHistory))
+	for i, entry := range history.NodeHistory {
+		entry.StartTime = entry.StartTime.UTC()
+		entry.EndTime = utc(entry.EndTime)
+		nodeHistory[i] = entry
+	}
+	history.NodeHistory = nodeHistory
+
+	restartHistory := make([]RestartHistoryEntry, len(history.RestartHistory))
+	for i, entry := range history.RestartHistory {
+		entry.LastRestartTime = utc(entry.LastRestartTime)
+		states := make([]ContainerRestartInfo, len(entry.ContainerStates))
+		for j, state := range entry.ContainerStates {
+			state.LastRestartTime = utc(state.LastRestartTime)
+			states[j] = state
+		}
+		entry.ContainerStates = states
+		restartHistory[i] = entry
+	}
+	history.RestartHistory = restartHistory
+
+	timeline := make([]RestartTimelineEntry, len(history.RestartTimeline))
+	for i, entry := range history.RestartTimeline {
+		entry.Time = entry.Time.UTC()
+		timeline[i] = entry
+	}
+	history.RestartTimeline = timeline
+
+	if history.PhaseTransitions != nil {
+		transitions := make([]PhaseTransition, len(history.PhaseTransitions))
+		for i, transition := range history.PhaseTransitions {
+			transition.Time = transition.Time.UTC()
+			transitions[i] = transition
+		}
+		history.PhaseTransitions = transitions
+	}
+	if history.Terminations != nil {
+		terminations := make([]ContainerTermination, len(history.Terminations))
+		for i, termination := range history.Terminations {
+			termination.StartedAt = termination.StartedAt.UTC()
+			termination.FinishedAt = termination.FinishedAt.UTC()
+			terminations[i] = termination
+		}
+		history.Terminations = terminations
+	}
+	if history.RecordedEvents != nil {
+		events := make([]PodHistoryEvent, len(history.RecordedEvents))
+		for i, event := range history.RecordedEvents {
+			event.FirstTime = event.FirstTime.UTC()
+			event.LastTime = event.LastTime.UTC()
+			events[i] = event
+		}
+		history.RecordedEvents = events
+	}
+}
@@ -3,15 +3,22 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
+	"slices"
 	"sort"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/common"
+	"github.com/zxh326/kite/pkg/utils"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
 )
@@ -19,13 +26,51 @@ import (
 // PodHistoryHandler handles Pod history tracking
 type PodHistoryHandler struct {
 	client kubernetes.Interface
+	// store holds the recorded history, nil when the recorder is disabled
+	store *podHistoryStore
+	// batchCache holds recent batch responses, nil when caching is disabled
+	batchCache *podHistoryCache
 }
 
-// NewPodHistoryHandler creates a new Pod history handler
+// NewPodHistoryHandler creates a new Pod history handler and starts the
+// history recorder unless POD_HISTORY_MAX_PODS is 0
 func NewPodHistoryHandler(client kubernetes.Interface) *PodHistoryHandler {
-	return &PodHistoryHandler{
+	h := &PodHistoryHandler{
 		client: client,
 	}
+	if common.PodHistoryCacheTTL > 0 {
+		h.batchCache = newPodHistoryCache(common.PodHistoryCacheTTL)
+	}
+	if common.PodHistoryMaxPods > 0 {
+		store := newPodHistoryStore(common.PodHistoryMaxPods, common.PodHistoryMaxEntries, common.PodHistoryMaxAge)
+		recorder, err := newPodHistoryRecorder(client, store)
+		if err != nil {
+			klog.Warningf("Pod history recorder disabled: %v", err)
+		} else {
+			h.store = store
+			go recorder.Run(context.Background())
+		}
+	}
+	return h
+}
+
+// podDisruptionReasons are the event and pod status reasons of pods that
+// were moved off their node by eviction or preemption
+var podDisruptionReasons = map[string]bool{
+	"Evicted":              true,
+	"Preempted":            true,
+	"NodeNotReady":         true,
+	"TaintManagerEviction": true,
+}
+
+// disruptionConditionReasons maps the reasons of the DisruptionTarget pod
+// condition to the matching event reasons
+var disruptionConditionReasons = map[string]string{
+	"TerminationByKubelet":      "Evicted",
+	"PreemptionByScheduler":     "Preempted",
+	"PreemptionByKubeScheduler": "Preempted",
+	"DeletionByTaintManager":    "TaintManagerEviction",
+	"EvictionByEvictionAPI":     "Evicted",
 }
 
 // PodNodeHistory represents the node history of a Pod
@@ -34,9 +79,37 @@ type PodNodeHistory struct {
 	Namespace      string              `json:"namespace"`
 	CurrentNode    string              `json:"currentNode"`
 	NodeHistory    []NodeHistoryEntry  `json:"nodeHistory"`
+	// NodeHistoryTruncated is set when entries beyond ?historyLimit= were left out
+	NodeHistoryTruncated bool `json:"nodeHistoryTruncated"`
 	RestartHistory []RestartHistoryEntry `json:"restartHistory"`
+	// RestartTimeline has one entry per observed termination or restart
+	// event of each container, RestartHistory is the aggregate of it
+	RestartTimeline []RestartTimelineEntry `json:"restartTimeline"`
 	Events         []corev1.Event      `json:"events"`
 	Status         PodStatusInfo       `json:"status"`
+	// Eviction is set when the pod was evicted or preempted
+	Eviction *PodEvictionInfo `json:"eviction,omitempty"`
+	// CurrentlyOnNode is set when histories are filtered by node, false for
+	// pods that ran on the node before
+	CurrentlyOnNode *bool `json:"currentlyOnNode,omitempty"`
+	// Timings breaks down the pod's startup, unset for deleted pods
+	Timings *PodTimings `json:"timings,omitempty"`
+
+	// Recorded by the history recorder, kept after the pod and its events
+	// are gone
+	Deleted          bool                   `json:"deleted,omitempty"`
+	DeletedAt        *time.Time             `json:"deletedAt,omitempty"`
+	PhaseTransitions []PhaseTransition      `json:"phaseTransitions,omitempty"`
+	Terminations     []ContainerTermination `json:"terminations,omitempty"`
+	RecordedEvents   []PodHistoryEvent      `json:"recordedEvents,omitempty"`
+}
+
+// PodEvictionInfo explains why a pod was evicted or preempted
+type PodEvictionInfo struct {
+	Reason   string     `json:"reason"`
+	Message  string     `json:"message"`
+	NodeName string     `json:"nodeName,omitempty"`
+	Time     *time.Time `json:"time,omitempty"`
 }
 
 // NodeHistoryEntry represents a single node history entry
@@ -46,6 +119,8 @@ type NodeHistoryEntry struct {
 	EndTime   *time.Time `json:"endTime,omitempty"`
 	Reason    string    `json:"reason"`
 	Phase     string    `json:"phase"`
+	// DurationSeconds is how long the pod stayed, set once EndTime is
+	DurationSeconds *int64 `json:"durationSeconds,omitempty"`
 }
 
 // RestartHistoryEntry represents a single restart history entry
@@ -55,23 +130,54 @@ type RestartHistoryEntry struct {
 	Reason         string                     `json:"reason"`
 	ExitCode       *int32                     `json:"exitCode,omitempty"`
 	Message        string                     `json:"message"`
+	// OOMKillCount is how many containers were last OOM killed
+	OOMKillCount   int32                      `json:"oomKillCount"`
 	ContainerStates []ContainerRestartInfo    `json:"containerStates"`
 	Events         []corev1.Event            `json:"events"`
 }
 
+// RestartTimelineEntry is one termination or restart event of a container
+type RestartTimelineEntry struct {
+	ContainerName string `json:"containerName"`
+	ContainerType string `json:"containerType"`
+	// Role is app or sidecar
+	Role string    `json:"role"`
+	Time time.Time `json:"time"`
+	// Source is lastTerminationState, state or event
+	Source    string `json:"source"`
+	ExitCode  *int32 `json:"exitCode,omitempty"`
+	Reason    string `json:"reason"`
+	Message   string `json:"message,omitempty"`
+	OOMKilled bool   `json:"oomKilled,omitempty"`
+	// Count is how often a restart event was repeated
+	Count int32 `json:"count,omitempty"`
+}
+
 // ContainerRestartInfo represents container-specific restart information
 type ContainerRestartInfo struct {
 	ContainerName string     `json:"containerName"`
+	// ContainerType is init, regular or ephemeral
+	ContainerType string     `json:"containerType"`
 	RestartCount  int32      `json:"restartCount"`
 	LastRestartTime *time.Time `json:"lastRestartTime,omitempty"`
 	ExitCode      *int32     `json:"exitCode,omitempty"`
 	Reason        string     `json:"reason"`
 	Message       string     `json:"message"`
+	OOMKilled     bool       `json:"oomKilled,omitempty"`
+	// MemoryLimit is the container's memory limit, set when it was OOM killed
+	MemoryLimit   string     `json:"memoryLimit,omitempty"`
+	// PreviousLogs is the tail of the previous container's logs, set with
+	// ?includeLogs=true. PreviousLogsError explains why it is missing.
+	PreviousLogs          string `json:"previousLogs,omitempty"`
+	PreviousLogsTruncated bool   `json:"previousLogsTruncated,omitempty"`
+	PreviousLogsError     string `json:"previousLogsError,omitempty"`
 }
 
 // PodStatusInfo represents enhanced Pod status information
 type PodStatusInfo struct {
 	Phase             string                 `json:"phase"`
+	Reason            string                 `json:"reason,omitempty"`
+	Message           string                 `json:"message,omitempty"`
 	Conditions        []corev1.PodCondition  `json:"conditions"`
 	ContainerStatuses []corev1.ContainerStatus `json:"containerStatuses"`
 	IsReady           bool                   `json:"isReady"`
@@ -81,32 +187,176 @@ type PodStatusInfo struct {
 	StartTime         *metav1.Time           `json:"startTime,omitempty"`
 }
 
-// GetPodHistory retrieves the complete history for a specific Pod
+const (
+	// defaultPreviousLogTailLines is how many lines of the previous
+	// container's logs are attached with ?includeLogs=true
+	defaultPreviousLogTailLines = 50
+	// maxPreviousLogTailLines caps ?logTailLines=
+	maxPreviousLogTailLines = 1000
+	// maxPreviousLogBytes caps the logs attached per container
+	maxPreviousLogBytes = 64 << 10
+
+	// defaultNodeHistoryLimit is how many node history entries are returned
+	// unless ?historyLimit= asks otherwise
+	defaultNodeHistoryLimit = 5
+	// maxNodeHistoryLimit caps ?historyLimit=
+	maxNodeHistoryLimit = 200
+	// maxHistorySinceHours caps ?sinceHours=
+	maxHistorySinceHours = 30 * 24
+)
+
+// podHistoryOptions shape the node history of a pod
+type podHistoryOptions struct {
+	// historyLimit is how many node history entries are kept, newest first
+	historyLimit int
+	// since drops events and node history older than it, unless zero
+	since time.Time
+}
+
+// parsePodHistoryOptions reads ?historyLimit=, defaulting to defaultLimit,
+// and ?sinceHours=. Values above the server-side maximums are capped.
+func parsePodHistoryOptions(c *gin.Context, defaultLimit int) (podHistoryOptions, error) {
+	opts := podHistoryOptions{historyLimit: defaultLimit}
+	if value := c.Query("historyLimit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return opts, fmt.Errorf("invalid historyLimit parameter")
+		}
+		opts.historyLimit = min(n, maxNodeHistoryLimit)
+	}
+	if value := c.Query("sinceHours"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return opts, fmt.Errorf("invalid sinceHours parameter")
+		}
+		opts.since = time.Now().Add(-time.Duration(min(n, maxHistorySinceHours)) * time.Hour)
+	}
+	return opts, nil
+}
+
+// PodHistoryError reports a pod whose history could not be built
+type PodHistoryError struct {
+	PodName string `json:"podName"`
+	Error   string `json:"error"`
+}
+
+// GetPodHistory retrieves the complete history for a specific Pod. With
+// ?includeDeleted=true the recorded history of a pod that no longer exists is
+// returned instead of a 404. ?type=Warning only returns events of that type.
+// ?includeLogs=true attaches the last ?logTailLines= lines of the previous
+// container's logs to each restarted container. ?historyLimit= and
+// ?sinceHours= control how many node history entries are returned and how
+// far back events are considered.
 func (h *PodHistoryHandler) GetPodHistory(c *gin.Context) {
+	status, body := h.podHistoryResponse(c, defaultNodeHistoryLimit)
+	c.JSON(status, body)
+}
+
+// podHistoryResponse builds the response of GetPodHistory, keeping
+// defaultHistoryLimit node history entries unless ?historyLimit= is set
+func (h *PodHistoryHandler) podHistoryResponse(c *gin.Context, defaultHistoryLimit int) (int, any) {
 	namespace := c.Param("namespace")
 	podName := c.Param("name")
 
 	if namespace == "" || podName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "namespace and pod name are required"})
-		return
+		return http.StatusBadRequest, gin.H{"error": "namespace and pod name are required"}
+	}
+	eventType := c.Query("type")
+	if !validEventType(eventType) {
+		return http.StatusBadRequest, gin.H{"error": "type must be Normal or Warning"}
+	}
+	includeLogs := c.Query("includeLogs") == "true"
+	logTailLines := int64(defaultPreviousLogTailLines)
+	if value := c.Query("logTailLines"); value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n <= 0 {
+			return http.StatusBadRequest, gin.H{"error": "invalid logTailLines parameter"}
+		}
+		logTailLines = min(n, maxPreviousLogTailLines)
+	}
+	opts, err := parsePodHistoryOptions(c, defaultHistoryLimit)
+	if err != nil {
+		return http.StatusBadRequest, gin.H{"error": err.Error()}
 	}
 
-	history, err := h.buildPodHistory(c.Request.Context(), namespace, podName)
+	history, err := h.buildPodHistory(c.Request.Context(), namespace, podName, opts)
+	if errors.IsNotFound(err) {
+		if c.Query("includeDeleted") == "true" {
+			if recorded, ok := h.recordedPodHistory(namespace, podName, opts); ok {
+				filterHistoryEvents(recorded, eventType)
+				return http.StatusOK, recorded
+			}
+		}
+		return http.StatusNotFound, gin.H{"error": "Pod not found"}
+	}
 	if err != nil {
 		klog.Errorf("Failed to build pod history for %s/%s: %v", namespace, podName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get pod history: %v", err)})
-		return
+		return http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get pod history: %v", err)}
 	}
 
-	c.JSON(http.StatusOK, history)
+	if includeLogs {
+		h.attachPreviousLogs(c.Request.Context(), history, logTailLines)
+	}
+	filterHistoryEvents(history, eventType)
+	return http.StatusOK, history
 }
 
-// GetPodsHistoryBatch retrieves history for multiple Pods in a namespace
+// GetPodsHistoryBatch retrieves history for multiple Pods in a namespace.
+// The namespace's events are listed once and the histories are built by a
+// bounded worker pool. Pods that fail are skipped and listed under errors.
+// Pages are fetched with ?continue= set to the continueToken of the previous
+// response, deleted pods are only added to the first page.
+// ?ownerUID= limits the result to the pods of one controller and
+// ?includeDeleted=true adds the recorded history of deleted pods.
+// ?nodeName= limits the result to the pods on a node, the first page also
+// gets the recorded pods that ran on the node before.
+// ?type=Warning only returns events of that type, ?historyLimit= and
+// ?sinceHours= work as for GetPodHistory. Responses are cached for
+// POD_HISTORY_CACHE_TTL, ?noCache=true skips the cache. ?format=json|csv
+// downloads the histories as one file, see ExportPodHistory.
 func (h *PodHistoryHandler) GetPodsHistoryBatch(c *gin.Context) {
+	if c.Query("format") != "" {
+		h.exportPodsHistoryBatch(c)
+		return
+	}
+	if h.batchCache == nil || c.Query("noCache") == "true" {
+		status, body := h.buildPodsHistoryBatch(c.Request.Context(), c)
+		c.Header("X-Kite-Cache", "miss")
+		c.JSON(status, body)
+		return
+	}
+
+	query := c.Request.URL.Query()
+	query.Del("noCache")
+	key := c.Param("namespace") + "?" + query.Encode()
+	status, data, hit := h.batchCache.get(key, func() (int, gin.H) {
+		// Requests sharing this build must not fail because the first
+		// client went away
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), podHistoryCacheBuildTimeout)
+		defer cancel()
+		return h.buildPodsHistoryBatch(ctx, c)
+	})
+	if hit {
+		c.Header("X-Kite-Cache", "hit")
+	} else {
+		c.Header("X-Kite-Cache", "miss")
+	}
+	c.Data(status, "application/json; charset=utf-8", data)
+}
+
+// buildPodsHistoryBatch builds the response of GetPodsHistoryBatch
+func (h *PodHistoryHandler) buildPodsHistoryBatch(ctx context.Context, c *gin.Context) (int, gin.H) {
 	namespace := c.Param("namespace")
 	if namespace == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "namespace is required"})
-		return
+		return http.StatusBadRequest, gin.H{"error": "namespace is required"}
+	}
+	eventType := c.Query("type")
+	if !validEventType(eventType) {
+		return http.StatusBadRequest, gin.H{"error": "type must be Normal or Warning"}
+	}
+	opts, err := parsePodHistoryOptions(c, defaultNodeHistoryLimit)
+	if err != nil {
+		return http.StatusBadRequest, gin.H{"error": err.Error()}
 	}
 
 	// Get query parameters
@@ -118,35 +368,149 @@ func (h *PodHistoryHandler) GetPodsHistoryBatch(c *gin.Context) {
 			limit = l
 		}
 	}
+	ownerUID := types.UID(c.Query("ownerUID"))
+	selector, err := labels.Parse(labelSelector)
+	if err != nil {
+		return http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid labelSelector: %v", err)}
+	}
 
-	pods, err := h.client.CoreV1().Pods(namespace).List(c.Request.Context(), metav1.ListOptions{
+	nodeName := c.Query("nodeName")
+	var fieldSelector string
+	if nodeName != "" {
+		fieldSelector = fields.OneTermEqualSelector("spec.nodeName", nodeName).String()
+	}
+
+	continueToken := c.Query("continue")
+	pods, err := h.client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
 		LabelSelector: labelSelector,
+		FieldSelector: fieldSelector,
 		Limit:         int64(limit),
+		Continue:      continueToken,
 	})
+	if errors.IsResourceExpired(err) {
+		return http.StatusGone, gin.H{"error": "The continue token has expired, restart pagination from the first page"}
+	}
 	if err != nil {
 		klog.Errorf("Failed to list pods in namespace %s: %v", namespace, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to list pods: %v", err)})
-		return
+		return http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to list pods: %v", err)}
 	}
 
-	histories := make([]PodNodeHistory, 0, len(pods.Items))
-	for _, pod := range pods.Items {
-		history, err := h.buildPodHistory(c.Request.Context(), namespace, pod.Name)
-		if err != nil {
-			klog.Errorf("Failed to build history for pod %s/%s: %v", namespace, pod.Name, err)
+	// Pods without a listed object are fetched by name, they ran on nodeName
+	// before and have moved since
+	type target struct {
+		name string
+		pod  *corev1.Pod
+	}
+	live := make(map[string]bool, len(pods.Items))
+	targets := make([]target, 0, len(pods.Items))
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		live[pod.Name] = true
+		if ownerUID != "" {
+			if owner := metav1.GetControllerOf(pod); owner == nil || owner.UID != ownerUID {
+				continue
+			}
+		}
+		targets = append(targets, target{name: pod.Name, pod: pod})
+	}
+
+	includeDeleted := c.Query("includeDeleted") == "true"
+	var records []podHistoryRecord
+	if (includeDeleted || nodeName != "") && continueToken == "" && h.store != nil {
+		for _, record := range h.store.list(namespace, ownerUID) {
+			if live[record.Name] || !selector.Matches(labels.Set(record.Labels)) {
+				continue
+			}
+			if nodeName != "" && !ranOnNodeBefore(record, nodeName) {
+				continue
+			}
+			records = append(records, record)
+		}
+	}
+	if nodeName != "" {
+		for _, record := range records {
+			if !record.Deleted {
+				targets = append(targets, target{name: record.Name})
+			}
+		}
+	}
+
+	eventsByPod, err := h.getNamespacePodEvents(ctx, namespace)
+	if err != nil {
+		klog.Warningf("Failed to get events in namespace %s: %v", namespace, err)
+		eventsByPod = map[string][]corev1.Event{} // Continue without events
+	}
+
+	type result struct {
+		history *PodNodeHistory
+		err     error
+	}
+	results := utils.RunBatch(ctx, len(targets), utils.DefaultBatchConcurrency,
+		func(ctx context.Context, i int) result {
+			if err := ctx.Err(); err != nil {
+				return result{err: err}
+			}
+			pod := targets[i].pod
+			if pod == nil {
+				var err error
+				pod, err = h.client.CoreV1().Pods(namespace).Get(ctx, targets[i].name, metav1.GetOptions{})
+				if err != nil {
+					return result{err: fmt.Errorf("failed to get pod: %w", err)}
+				}
+			}
+			events := eventsSince(eventsByPod[pod.Name], opts.since)
+			history := h.buildPodHistoryFromPod(pod, events, opts)
+			if nodeName != "" {
+				onNode := pod.Spec.NodeName == nodeName
+				history.CurrentlyOnNode = &onNode
+			}
+			return result{history: history}
+		},
+		func(i int, err error) result {
+			return result{err: err}
+		})
+
+	histories := make([]PodNodeHistory, 0, len(targets))
+	historyErrors := []PodHistoryError{}
+	for i, r := range results {
+		if r.err != nil {
+			klog.Errorf("Failed to build history for pod %s/%s: %v", namespace, targets[i].name, r.err)
+			historyErrors = append(historyErrors, PodHistoryError{PodName: targets[i].name, Error: r.err.Error()})
 			continue // Skip this pod but continue with others
 		}
-		histories = append(histories, *history)
+		histories = append(histories, *r.history)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"histories": histories,
-		"total":     len(histories),
-	})
+	if includeDeleted {
+		for _, record := range records {
+			if !record.Deleted {
+				continue
+			}
+			history := recordedHistory(record, opts)
+			if nodeName != "" {
+				history.CurrentlyOnNode = new(bool)
+			}
+			histories = append(histories, *history)
+		}
+	}
+
+	for i := range histories {
+		filterHistoryEvents(&histories[i], eventType)
+	}
+
+	return http.StatusOK, gin.H{
+		"histories":          histories,
+		"total":              len(histories),
+		"errors":             historyErrors,
+		"continueToken":      pods.Continue,
+		"remainingItemCount": pods.RemainingItemCount,
+		"labelSelector":      labelSelector,
+		"limit":              limit,
+	}
 }
 
 // buildPodHistory constructs the complete history for a Pod
-func (h *PodHistoryHandler) buildPodHistory(ctx context.Context, namespace, podName string) (*PodNodeHistory, error) {
+func (h *PodHistoryHandler) buildPodHistory(ctx context.Context, namespace, podName string, opts podHistoryOptions) (*PodNodeHistory, error) {
 	// Get current Pod
 	pod, err := h.client.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
 	if err != nil {
@@ -160,43 +524,282 @@ func (h *PodHistoryHandler) buildPodHistory(ctx context.Context, namespace, podN
 		events = []corev1.Event{} // Continue without events
 	}
 
+	return h.buildPodHistoryFromPod(pod, eventsSince(events, opts.since), opts), nil
+}
+
+// buildPodHistoryFromPod constructs the history of a Pod from the pod and its
+// events, sorted newest first
+func (h *PodHistoryHandler) buildPodHistoryFromPod(pod *corev1.Pod, events []corev1.Event, opts podHistoryOptions) *PodNodeHistory {
 	// Build node history from events
 	nodeHistory := h.buildNodeHistoryFromEvents(events, pod)
 
 	// Build restart history
 	restartHistory := h.buildRestartHistory(pod, events)
+	restartTimeline := buildRestartTimeline(pod, events)
 
 	// Build status info
 	status := h.buildPodStatusInfo(pod)
 
 	history := &PodNodeHistory{
-		PodName:        podName,
-		Namespace:      namespace,
-		CurrentNode:    pod.Spec.NodeName,
-		NodeHistory:    nodeHistory,
-		RestartHistory: restartHistory,
-		Events:         events,
-		Status:         status,
+		PodName:         pod.Name,
+		Namespace:       pod.Namespace,
+		CurrentNode:     pod.Spec.NodeName,
+		NodeHistory:     nodeHistory,
+		RestartHistory:  restartHistory,
+		RestartTimeline: restartTimeline,
+		Events:          events,
+		Status:          status,
+		Eviction:        buildEvictionInfo(pod, events),
+		Timings:         buildPodTimings(pod, events),
+	}
+	if h.store != nil {
+		if record, ok := h.store.get(pod.Namespace, pod.Name); ok {
+			mergeRecordedHistory(history, record)
+		}
 	}
+	limitNodeHistory(history, opts)
 
-	return history, nil
+	return history
+}
+
+// eventsSince drops the events last seen before since, unless it is zero
+func eventsSince(events []corev1.Event, since time.Time) []corev1.Event {
+	if since.IsZero() {
+		if events == nil {
+			return []corev1.Event{}
+		}
+		return events
+	}
+	filtered := make([]corev1.Event, 0, len(events))
+	for _, event := range events {
+		if !eventTime(event).Before(since) {
+			filtered = append(filtered, event)
+		}
+	}
+	return filtered
+}
+
+// limitNodeHistory drops the node history entries that ended before
+// opts.since and keeps the newest opts.historyLimit of the rest, reporting
+// whether any were cut
+func limitNodeHistory(history *PodNodeHistory, opts podHistoryOptions) {
+	if !opts.since.IsZero() {
+		kept := history.NodeHistory[:0]
+		for _, entry := range history.NodeHistory {
+			if entry.EndTime == nil || !entry.EndTime.Before(opts.since) {
+				kept = append(kept, entry)
+			}
+		}
+		history.NodeHistory = kept
+	}
+	if len(history.NodeHistory) > opts.historyLimit {
+		history.NodeHistory = history.NodeHistory[:opts.historyLimit]
+		history.NodeHistoryTruncated = true
+	}
+}
+
+// ranOnNodeBefore reports whether a recorded pod was on a node it has
+// since left
+func ranOnNodeBefore(record podHistoryRecord, nodeName string) bool {
+	for _, entry := range record.NodeHistory {
+		if entry.NodeName == nodeName && entry.EndTime != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// recordedPodHistory returns the recorded history of a pod that no longer
+// exists
+func (h *PodHistoryHandler) recordedPodHistory(namespace, podName string, opts podHistoryOptions) (*PodNodeHistory, bool) {
+	if h.store == nil {
+		return nil, false
+	}
+	record, ok := h.store.get(namespace, podName)
+	if !ok {
+		return nil, false
+	}
+	return recordedHistory(record, opts), true
+}
+
+// recordedHistory builds the history of a deleted pod from its record alone
+func recordedHistory(record podHistoryRecord, opts podHistoryOptions) *PodNodeHistory {
+	history := &PodNodeHistory{
+		PodName:         record.Name,
+		Namespace:       record.Namespace,
+		NodeHistory:     []NodeHistoryEntry{},
+		RestartHistory:  []RestartHistoryEntry{},
+		RestartTimeline: []RestartTimelineEntry{},
+		Events:          []corev1.Event{},
+		Deleted:         record.Deleted,
+		DeletedAt:       record.DeletedAt,
+	}
+	if n := len(record.PhaseTransitions); n > 0 {
+		history.Status.Phase = record.PhaseTransitions[n-1].Phase
+	}
+	mergeRecordedHistory(history, record)
+	limitNodeHistory(history, opts)
+	return history
+}
+
+// mergeRecordedHistory adds the recorded node assignments, phase
+// transitions, terminations and events to a history, newest first. Recorded
+// node assignments replace the ones reconstructed from events for the same
+// node.
+func mergeRecordedHistory(history *PodNodeHistory, record podHistoryRecord) {
+	recordedNodes := make(map[string]bool, len(record.NodeHistory))
+	for _, entry := range record.NodeHistory {
+		recordedNodes[entry.NodeName] = true
+	}
+	nodeHistory := append([]NodeHistoryEntry{}, record.NodeHistory...)
+	for _, entry := range history.NodeHistory {
+		if !recordedNodes[entry.NodeName] {
+			nodeHistory = append(nodeHistory, entry)
+		} else if podDisruptionReasons[entry.Reason] {
+			// Keep the eviction reason found in the live events
+			nodeHistory = markNodeDisrupted(nodeHistory, entry.NodeName, entry.Reason, entry.StartTime, entry.Phase)
+		}
+	}
+	closeNodeHistory(nodeHistory)
+	sort.Slice(nodeHistory, func(i, j int) bool {
+		return nodeHistory[i].StartTime.After(nodeHistory[j].StartTime)
+	})
+	history.NodeHistory = nodeHistory
+
+	history.PhaseTransitions = record.PhaseTransitions
+	sort.SliceStable(history.PhaseTransitions, func(i, j int) bool {
+		return history.PhaseTransitions[i].Time.After(history.PhaseTransitions[j].Time)
+	})
+	history.Terminations = record.Terminations
+	sort.SliceStable(history.Terminations, func(i, j int) bool {
+		return history.Terminations[i].FinishedAt.After(history.Terminations[j].FinishedAt)
+	})
+	history.RecordedEvents = record.Events
+	sort.SliceStable(history.RecordedEvents, func(i, j int) bool {
+		return history.RecordedEvents[i].LastTime.After(history.RecordedEvents[j].LastTime)
+	})
 }
 
 // getPodEvents retrieves all events related to a specific Pod
 func (h *PodHistoryHandler) getPodEvents(ctx context.Context, namespace, podName string) ([]corev1.Event, error) {
-	events, err := h.client.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
-		FieldSelector: fields.OneTermEqualSelector("involvedObject.name", podName).String(),
+	return h.listPodEvents(ctx, namespace, podName)
+}
+
+// getNamespacePodEvents lists the pod events of a namespace once and groups
+// them by pod name, each sorted newest first
+func (h *PodHistoryHandler) getNamespacePodEvents(ctx context.Context, namespace string) (map[string][]corev1.Event, error) {
+	events, err := h.listPodEvents(ctx, namespace, "")
+	if err != nil {
+		return nil, err
+	}
+
+	eventsByPod := make(map[string][]corev1.Event)
+	for _, event := range events {
+		eventsByPod[event.InvolvedObject.Name] = append(eventsByPod[event.InvolvedObject.Name], event)
+	}
+	return eventsByPod, nil
+}
+
+// listPodEvents lists the events of a pod, or of all pods in the namespace
+// when podName is empty, from both the core and the events.k8s.io API.
+// Repeats are merged by mergePodEvents and the result is newest first.
+func (h *PodHistoryHandler) listPodEvents(ctx context.Context, namespace, podName string) ([]corev1.Event, error) {
+	coreFields := fields.Set{"involvedObject.kind": "Pod"}
+	seriesFields := fields.Set{"regarding.kind": "Pod"}
+	if podName != "" {
+		coreFields["involvedObject.name"] = podName
+		seriesFields["regarding.name"] = podName
+	}
+
+	coreList, err := h.client.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.SelectorFromSet(coreFields).String(),
 	})
 	if err != nil {
 		return nil, err
 	}
+	events := coreList.Items
 
-	// Sort events by time (newest first)
-	sort.Slice(events.Items, func(i, j int) bool {
-		return events.Items[i].CreationTimestamp.After(events.Items[j].CreationTimestamp.Time)
+	seriesList, err := h.client.EventsV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.SelectorFromSet(seriesFields).String(),
 	})
+	if err != nil {
+		// The core events are still worth returning on their own
+		klog.Warningf("Failed to list events.k8s.io events in namespace %s: %v", namespace, err)
+		return mergePodEvents(events), nil
+	}
+	byUID := make(map[types.UID]int, len(events))
+	for i := range events {
+		byUID[events[i].UID] = i
+	}
+	for i := range seriesList.Items {
+		event := &seriesList.Items[i]
+		if index, ok := byUID[event.UID]; ok {
+			// The same event served by both APIs, only events.k8s.io has
+			// the series
+			if event.Series != nil && events[index].Series == nil {
+				events[index].Series = &corev1.EventSeries{
+					Count:            event.Series.Count,
+					LastObservedTime: event.Series.LastObservedTime,
+				}
+			}
+			continue
+		}
+		events = append(events, utils.CoreEventFromEventsV1(event))
+	}
+	return mergePodEvents(events), nil
+}
 
-	return events.Items, nil
+// mergePodEvents merges the events of a pod with the same reason, message
+// and reporting controller into one whose count covers all of them, and
+// sorts the result newest first
+func mergePodEvents(events []corev1.Event) []corev1.Event {
+	type eventKey struct {
+		pod        types.UID
+		name       string
+		reason     string
+		message    string
+		controller string
+	}
+
+	merged := make([]corev1.Event, 0, len(events))
+	index := make(map[eventKey]int, len(events))
+	for _, event := range events {
+		controller := event.ReportingController
+		if controller == "" {
+			controller = event.Source.Component
+		}
+		key := eventKey{event.InvolvedObject.UID, event.InvolvedObject.Name, event.Reason, event.Message, controller}
+		event.Count = podEventCount(event)
+		if event.Series != nil {
+			event.Series = event.Series.DeepCopy()
+		}
+
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(merged)
+			merged = append(merged, event)
+			continue
+		}
+		existing := &merged[i]
+		count := existing.Count + event.Count
+		first := podEventFirstTime(*existing)
+		if eventFirst := podEventFirstTime(event); eventFirst.Before(first) {
+			first = eventFirst
+		}
+		if eventTime(event).After(eventTime(*existing)) {
+			*existing = event
+		}
+		existing.Count = count
+		existing.FirstTimestamp = metav1.NewTime(first)
+		if existing.Series != nil {
+			existing.Series.Count = count
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return eventTime(merged[i]).After(eventTime(merged[j]))
+	})
+	return merged
 }
 
 // buildNodeHistoryFromEvents constructs node history from events
@@ -208,7 +811,7 @@ func (h *PodHistoryHandler) buildNodeHistoryFromEvents(events []corev1.Event, po
 	if pod.Spec.NodeName != "" {
 		current := &NodeHistoryEntry{
 			NodeName:  pod.Spec.NodeName,
-			StartTime: pod.CreationTimestamp.Time,
+			StartTime: podScheduledTime(pod),
 			Reason:    "Scheduled",
 			Phase:     string(pod.Status.Phase),
 		}
@@ -245,16 +848,203 @@ func (h *PodHistoryHandler) buildNodeHistoryFromEvents(events []corev1.Event, po
 		}
 	}
 
-	// Sort by start time (newest first) and limit to last 5
+	// Mark the nodes pods were evicted or preempted from, events are newest
+	// first so the latest reason wins
+	for i := len(events) - 1; i >= 0; i-- {
+		event := events[i]
+		if !podDisruptionReasons[event.Reason] {
+			continue
+		}
+		node := event.Source.Host
+		if node == "" {
+			node = pod.Spec.NodeName
+		}
+		nodeHistory = markNodeDisrupted(nodeHistory, node, event.Reason, eventTime(event), string(pod.Status.Phase))
+	}
+	if podDisruptionReasons[pod.Status.Reason] && pod.Spec.NodeName != "" {
+		nodeHistory = markNodeDisrupted(nodeHistory, pod.Spec.NodeName, pod.Status.Reason, pod.CreationTimestamp.Time, string(pod.Status.Phase))
+	}
+
+	closeNodeHistory(nodeHistory)
+	if n := len(nodeHistory); n > 0 && pod.DeletionTimestamp != nil && nodeHistory[n-1].EndTime == nil {
+		nodeHistory[n-1].EndTime = &pod.DeletionTimestamp.Time
+		closeNodeHistory(nodeHistory)
+	}
+
+	// Sort by start time (newest first), limitNodeHistory cuts it down later
 	sort.Slice(nodeHistory, func(i, j int) bool {
 		return nodeHistory[i].StartTime.After(nodeHistory[j].StartTime)
 	})
 
-	if len(nodeHistory) > 5 {
-		nodeHistory = nodeHistory[:5]
+	return nodeHistory
+}
+
+// podScheduledTime returns when a pod was bound to its node, falling back to
+// its creation time
+func podScheduledTime(pod *corev1.Pod) time.Time {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionTrue && !condition.LastTransitionTime.IsZero() {
+			return condition.LastTransitionTime.Time
+		}
 	}
+	return pod.CreationTimestamp.Time
+}
 
-	return nodeHistory
+// closeNodeHistory sorts node history oldest first and ends every entry
+// but the last when the next one starts. A FailedScheduling entry ends when
+// the pod is eventually scheduled. Closed entries get their duration.
+func closeNodeHistory(nodeHistory []NodeHistoryEntry) {
+	sort.SliceStable(nodeHistory, func(i, j int) bool {
+		return nodeHistory[i].StartTime.Before(nodeHistory[j].StartTime)
+	})
+	for i := range nodeHistory {
+		entry := &nodeHistory[i]
+		if entry.EndTime == nil {
+			for _, next := range nodeHistory[i+1:] {
+				if entry.NodeName != "none" || next.NodeName != "none" {
+					end := next.StartTime
+					entry.EndTime = &end
+					break
+				}
+			}
+		}
+		if entry.EndTime != nil {
+			duration := int64(entry.EndTime.Sub(entry.StartTime).Seconds())
+			entry.DurationSeconds = &duration
+		}
+	}
+}
+
+// markNodeDisrupted sets the reason of a node's entry to an eviction or
+// preemption reason, adding an entry if the node isn't in the history yet
+func markNodeDisrupted(nodeHistory []NodeHistoryEntry, node, reason string, at time.Time, phase string) []NodeHistoryEntry {
+	if node == "" {
+		return nodeHistory
+	}
+	for i := range nodeHistory {
+		if nodeHistory[i].NodeName == node {
+			nodeHistory[i].Reason = reason
+			return nodeHistory
+		}
+	}
+	return append(nodeHistory, NodeHistoryEntry{
+		NodeName:  node,
+		StartTime: at,
+		Reason:    reason,
+		Phase:     phase,
+	})
+}
+
+// buildEvictionInfo explains why a pod was evicted or preempted, using the
+// pod status, its DisruptionTarget condition and the eviction events in that
+// order. It returns nil for pods that weren't disrupted.
+func buildEvictionInfo(pod *corev1.Pod, events []corev1.Event) *PodEvictionInfo {
+	var info *PodEvictionInfo
+	if podDisruptionReasons[pod.Status.Reason] {
+		info = &PodEvictionInfo{Reason: pod.Status.Reason, Message: pod.Status.Message, NodeName: pod.Spec.NodeName}
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type != corev1.DisruptionTarget || condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		if info == nil {
+			reason := disruptionConditionReasons[condition.Reason]
+			if reason == "" {
+				reason = condition.Reason
+			}
+			info = &PodEvictionInfo{Reason: reason, Message: condition.Message, NodeName: pod.Spec.NodeName}
+		}
+		if info.Message == "" {
+			info.Message = condition.Message
+		}
+		at := condition.LastTransitionTime.Time
+		info.Time = &at
+	}
+
+	// Events are newest first
+	for _, event := range events {
+		if !podDisruptionReasons[event.Reason] || (info != nil && info.Reason != event.Reason) {
+			continue
+		}
+		if info == nil {
+			info = &PodEvictionInfo{Reason: event.Reason, NodeName: event.Source.Host}
+			if info.NodeName == "" {
+				info.NodeName = pod.Spec.NodeName
+			}
+		}
+		if info.Message == "" {
+			info.Message = event.Message
+		}
+		if info.Time == nil {
+			at := eventTime(event)
+			info.Time = &at
+		}
+		break
+	}
+	return info
+}
+
+// eventTime returns when an event last happened
+func eventTime(event corev1.Event) time.Time {
+	if event.Series != nil && !event.Series.LastObservedTime.IsZero() {
+		return event.Series.LastObservedTime.Time
+	}
+	if !event.LastTimestamp.IsZero() {
+		return event.LastTimestamp.Time
+	}
+	if !event.EventTime.IsZero() {
+		return event.EventTime.Time
+	}
+	return event.CreationTimestamp.Time
+}
+
+// podEventFirstTime returns when an event was first observed
+func podEventFirstTime(event corev1.Event) time.Time {
+	if !event.FirstTimestamp.IsZero() {
+		return event.FirstTimestamp.Time
+	}
+	if !event.EventTime.IsZero() {
+		return event.EventTime.Time
+	}
+	return event.CreationTimestamp.Time
+}
+
+// podEventCount returns how often an event occurred, taking the series
+// count of events.k8s.io events into account
+func podEventCount(event corev1.Event) int32 {
+	if event.Series != nil && event.Series.Count > event.Count {
+		return event.Series.Count
+	}
+	return max(event.Count, 1)
+}
+
+// validEventType reports whether a ?type= filter names an event type, an
+// empty filter is valid too
+func validEventType(eventType string) bool {
+	return eventType == "" || eventType == corev1.EventTypeNormal || eventType == corev1.EventTypeWarning
+}
+
+// filterHistoryEvents keeps the events of one type, e.g. Warning, in a
+// history. The node and restart history are built from all events before.
+func filterHistoryEvents(history *PodNodeHistory, eventType string) {
+	if eventType == "" {
+		return
+	}
+	events := make([]corev1.Event, 0, len(history.Events))
+	for _, event := range history.Events {
+		if event.Type == eventType {
+			events = append(events, event)
+		}
+	}
+	history.Events = events
+
+	var recorded []PodHistoryEvent
+	for _, event := range history.RecordedEvents {
+		if event.Type == eventType {
+			recorded = append(recorded, event)
+		}
+	}
+	history.RecordedEvents = recorded
 }
 
 // buildRestartHistory constructs restart history from Pod status and events
@@ -262,12 +1052,16 @@ func (h *PodHistoryHandler) buildRestartHistory(pod *corev1.Pod, events []corev1
 	var restartHistory []RestartHistoryEntry
 
 	// Get container restart information
-	containerRestarts := make([]ContainerRestartInfo, 0, len(pod.Status.ContainerStatuses))
+	statuses := podContainerStatuses(pod)
+	containerRestarts := make([]ContainerRestartInfo, 0, len(statuses))
 	totalRestarts := int32(0)
+	oomKills := int32(0)
 
-	for _, containerStatus := range pod.Status.ContainerStatuses {
+	for _, typed := range statuses {
+		containerStatus := typed.status
 		restartInfo := ContainerRestartInfo{
 			ContainerName: containerStatus.Name,
+			ContainerType: typed.containerType,
 			RestartCount:  containerStatus.RestartCount,
 		}
 
@@ -277,13 +1071,18 @@ func (h *PodHistoryHandler) buildRestartHistory(pod *corev1.Pod, events []corev1
 			restartInfo.Reason = containerStatus.LastTerminationState.Terminated.Reason
 			restartInfo.Message = containerStatus.LastTerminationState.Terminated.Message
 		}
+		if isOOMKilled(containerStatus.LastTerminationState.Terminated) || isOOMKilled(containerStatus.State.Terminated) {
+			restartInfo.OOMKilled = true
+			restartInfo.MemoryLimit = containerMemoryLimit(pod, containerStatus)
+			oomKills++
+		}
 
 		containerRestarts = append(containerRestarts, restartInfo)
 		totalRestarts += containerStatus.RestartCount
 	}
 
 	// Create restart history entries
-	if totalRestarts > 0 {
+	if totalRestarts > 0 || oomKills > 0 {
 		// Get related restart events
 		restartEvents := h.getRestartEvents(events)
 
@@ -291,6 +1090,7 @@ func (h *PodHistoryHandler) buildRestartHistory(pod *corev1.Pod, events []corev1
 			RestartCount:    totalRestarts,
 			ContainerStates: containerRestarts,
 			Events:          restartEvents,
+			OOMKillCount:    oomKills,
 		}
 
 		// Find most recent restart time
@@ -313,15 +1113,172 @@ func (h *PodHistoryHandler) buildRestartHistory(pod *corev1.Pod, events []corev1
 	return restartHistory
 }
 
+// buildRestartTimeline lists the terminations of every container, from its
+// last termination state and its current terminated state, along with the
+// BackOff and Killing events of the container, newest first
+func buildRestartTimeline(pod *corev1.Pod, events []corev1.Event) []RestartTimelineEntry {
+	timeline := []RestartTimelineEntry{}
+	containerTypes := make(map[string]string)
+	for _, typed := range podContainerStatuses(pod) {
+		status := typed.status
+		containerTypes[status.Name] = typed.containerType
+		role := containerRole(pod, status.Name)
+		for _, state := range []struct {
+			source     string
+			terminated *corev1.ContainerStateTerminated
+		}{
+			{"lastTerminationState", status.LastTerminationState.Terminated},
+			{"state", status.State.Terminated},
+		} {
+			if state.terminated == nil {
+				continue
+			}
+			exitCode := state.terminated.ExitCode
+			timeline = append(timeline, RestartTimelineEntry{
+				ContainerName: status.Name,
+				ContainerType: typed.containerType,
+				Role:          role,
+				Time:          state.terminated.FinishedAt.Time,
+				Source:        state.source,
+				ExitCode:      &exitCode,
+				Reason:        state.terminated.Reason,
+				Message:       state.terminated.Message,
+				OOMKilled:     isOOMKilled(state.terminated),
+			})
+		}
+	}
+
+	for _, event := range events {
+		if event.Reason != "BackOff" && event.Reason != "Killing" {
+			continue
+		}
+		container := utils.EventContainerName(&event)
+		if container == "" {
+			continue
+		}
+		containerType := containerTypes[container]
+		if containerType == "" {
+			containerType = "regular"
+		}
+		timeline = append(timeline, RestartTimelineEntry{
+			ContainerName: container,
+			ContainerType: containerType,
+			Role:          containerRole(pod, container),
+			Time:          eventTime(event),
+			Source:        "event",
+			Reason:        event.Reason,
+			Message:       event.Message,
+			Count:         podEventCount(event),
+		})
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Time.After(timeline[j].Time)
+	})
+	return timeline
+}
+
+// containerRole tells the application containers of a pod from its
+// sidecars. The container named by the kubectl default-container annotation
+// is the app, native sidecars and containers named in
+// common.SidecarContainerNames are sidecars, everything else is app.
+func containerRole(pod *corev1.Pod, name string) string {
+	if pod.Annotations["kubectl.kubernetes.io/default-container"] == name {
+		return "app"
+	}
+	for _, container := range pod.Spec.InitContainers {
+		if container.Name == name && container.RestartPolicy != nil && *container.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			return "sidecar"
+		}
+	}
+	if slices.Contains(common.SidecarContainerNames, name) {
+		return "sidecar"
+	}
+	return "app"
+}
+
+// attachPreviousLogs adds the tail of the previous container's logs to every
+// restarted container. A container whose logs can't be read gets a note
+// instead, the other containers are not affected.
+func (h *PodHistoryHandler) attachPreviousLogs(ctx context.Context, history *PodNodeHistory, tailLines int64) {
+	var containers []*ContainerRestartInfo
+	for i := range history.RestartHistory {
+		for j := range history.RestartHistory[i].ContainerStates {
+			if history.RestartHistory[i].ContainerStates[j].RestartCount > 0 {
+				containers = append(containers, &history.RestartHistory[i].ContainerStates[j])
+			}
+		}
+	}
+
+	utils.RunBatch(ctx, len(containers), utils.DefaultBatchConcurrency,
+		func(ctx context.Context, i int) struct{} {
+			container := containers[i]
+			logs, truncated, err := h.readPreviousLogs(ctx, history.Namespace, history.PodName, container.ContainerName, tailLines)
+			if err != nil {
+				container.PreviousLogsError = previousLogsNote(err)
+				return struct{}{}
+			}
+			container.PreviousLogs = logs
+			container.PreviousLogsTruncated = truncated
+			return struct{}{}
+		},
+		func(i int, err error) struct{} {
+			containers[i].PreviousLogsError = previousLogsNote(err)
+			return struct{}{}
+		})
+}
+
+// readPreviousLogs returns the last tailLines lines of the previous instance
+// of a container, capped at maxPreviousLogBytes
+func (h *PodHistoryHandler) readPreviousLogs(ctx context.Context, namespace, podName, container string, tailLines int64) (string, bool, error) {
+	limitBytes := int64(maxPreviousLogBytes + 1)
+	stream, err := h.client.CoreV1().Pods(namespace).GetLogs(podName, &corev1.PodLogOptions{
+		Container:  container,
+		Previous:   true,
+		TailLines:  &tailLines,
+		LimitBytes: &limitBytes,
+	}).Stream(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	defer stream.Close()
+
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		return "", false, err
+	}
+	if len(data) > maxPreviousLogBytes {
+		return string(data[:maxPreviousLogBytes]), true, nil
+	}
+	return string(data), false, nil
+}
+
+// previousLogsNote explains why the previous container's logs are missing
+func previousLogsNote(err error) string {
+	if errors.IsNotFound(err) || errors.IsBadRequest(err) {
+		return fmt.Sprintf("Logs of the previous container are no longer available, the container may have been removed or its node is gone: %v", err)
+	}
+	return fmt.Sprintf("Failed to read logs of the previous container: %v", err)
+}
+
 // buildPodStatusInfo constructs enhanced status information
 func (h *PodHistoryHandler) buildPodStatusInfo(pod *corev1.Pod) PodStatusInfo {
 	status := PodStatusInfo{
 		Phase:             string(pod.Status.Phase),
+		Reason:            pod.Status.Reason,
+		Message:           pod.Status.Message,
 		Conditions:        pod.Status.Conditions,
 		ContainerStatuses: pod.Status.ContainerStatuses,
 		QOSClass:          string(pod.Status.QOSClass),
 		StartTime:         pod.Status.StartTime,
 	}
+	// Evicted pods may have no conditions or container statuses left
+	if status.Conditions == nil {
+		status.Conditions = []corev1.PodCondition{}
+	}
+	if status.ContainerStatuses == nil {
+		status.ContainerStatuses = []corev1.ContainerStatus{}
+	}
 
 	// Check if Pod is ready
 	status.IsReady = h.isPodReady(pod)
@@ -354,7 +1311,8 @@ func (h *PodHistoryHandler) getRestartEvents(events []corev1.Event) []corev1.Eve
 	var restartEvents []corev1.Event
 	for _, event := range events {
 		if event.Reason == "BackOff" || event.Reason == "Killing" || 
-		   event.Reason == "Unhealthy" || event.Reason == "FailedPostStartHook" {
+		   event.Reason == "Unhealthy" || event.Reason == "FailedPostStartHook" ||
+		   podDisruptionReasons[event.Reason] {
 			restartEvents = append(restartEvents, event)
 		}
 	}
@@ -373,9 +1331,57 @@ func (h *PodHistoryHandler) isPodReady(pod *corev1.Pod) bool {
 func (h *PodHistoryHandler) getPodErrorInfo(pod *corev1.Pod) (bool, string) {
 	// Check phase
 	if pod.Status.Phase == corev1.PodFailed {
+		if pod.Status.Message == "" && pod.Status.Reason != "" {
+			return true, fmt.Sprintf("Pod failed: %s", pod.Status.Reason)
+		}
 		return true, pod.Status.Message
 	}
 
+	// OOM kills first, a container in CrashLoopBackOff after one should be
+	// reported as OOM killed rather than as crashing
+	for _, containerStatus := range append(append([]corev1.ContainerStatus(nil), pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...) {
+		oomKilled := isOOMKilled(containerStatus.State.Terminated)
+		if waiting := containerStatus.State.Waiting; waiting != nil && waiting.Reason == "CrashLoopBackOff" {
+			oomKilled = oomKilled || isOOMKilled(containerStatus.LastTerminationState.Terminated)
+		}
+		if oomKilled {
+			if limit := containerMemoryLimit(pod, containerStatus); limit != "" {
+				return true, fmt.Sprintf("Container %s was OOMKilled at %s memory limit", containerStatus.Name, limit)
+			}
+			return true, fmt.Sprintf("Container %s was OOMKilled", containerStatus.Name)
+		}
+	}
+
+	// Check init containers, while one fails the regular containers only
+	// wait in PodInitializing
+	initDone := 0
+	sidecars := make(map[string]bool)
+	for _, container := range pod.Spec.InitContainers {
+		if container.RestartPolicy != nil && *container.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			sidecars[container.Name] = true
+		}
+	}
+	for _, containerStatus := range pod.Status.InitContainerStatuses {
+		if terminated := containerStatus.State.Terminated; terminated != nil && terminated.ExitCode == 0 {
+			initDone++
+		} else if sidecars[containerStatus.Name] && containerStatus.Started != nil && *containerStatus.Started {
+			// Sidecars keep running, they are done once started
+			initDone++
+		}
+	}
+	for _, containerStatus := range pod.Status.InitContainerStatuses {
+		progress := fmt.Sprintf("%d/%d", initDone, len(pod.Spec.InitContainers))
+		if waiting := containerStatus.State.Waiting; waiting != nil {
+			if waiting.Reason == "ImagePullBackOff" || waiting.Reason == "ErrImagePull" ||
+				waiting.Reason == "CrashLoopBackOff" || waiting.Reason == "CreateContainerConfigError" {
+				return true, fmt.Sprintf("Init container %s failed at Init:%s: Init:%s - %s", containerStatus.Name, progress, waiting.Reason, waiting.Message)
+			}
+		}
+		if terminated := containerStatus.State.Terminated; terminated != nil && terminated.ExitCode != 0 {
+			return true, fmt.Sprintf("Init container %s failed at Init:%s: Init:Error - exited with code %d: %s", containerStatus.Name, progress, terminated.ExitCode, terminated.Message)
+		}
+	}
+
 	// Check container statuses
 	for _, containerStatus := range pod.Status.ContainerStatuses {
 		if containerStatus.State.Waiting != nil {
@@ -412,8 +1418,66 @@ func (h *PodHistoryHandler) getPodErrorInfo(pod *corev1.Pod) (bool, string) {
 	return false, ""
 }
 
+// typedContainerStatus is a container status along with the kind of
+// container it belongs to
+type typedContainerStatus struct {
+	containerType string
+	status        corev1.ContainerStatus
+}
+
+// podContainerStatuses returns the statuses of the init, regular and
+// ephemeral containers of a pod in that order
+func podContainerStatuses(pod *corev1.Pod) []typedContainerStatus {
+	statuses := make([]typedContainerStatus, 0, len(pod.Status.InitContainerStatuses)+len(pod.Status.ContainerStatuses)+len(pod.Status.EphemeralContainerStatuses))
+	for _, status := range pod.Status.InitContainerStatuses {
+		statuses = append(statuses, typedContainerStatus{containerType: "init", status: status})
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		statuses = append(statuses, typedContainerStatus{containerType: "regular", status: status})
+	}
+	for _, status := range pod.Status.EphemeralContainerStatuses {
+		statuses = append(statuses, typedContainerStatus{containerType: "ephemeral", status: status})
+	}
+	return statuses
+}
+
+// isOOMKilled reports whether a container was killed for exceeding its memory
+// limit. Besides the OOMKilled reason, exit code 137 (SIGKILL) without a
+// reason is taken as an OOM kill since some runtimes don't set one.
+func isOOMKilled(terminated *corev1.ContainerStateTerminated) bool {
+	if terminated == nil {
+		return false
+	}
+	return terminated.Reason == "OOMKilled" || (terminated.ExitCode == 128+9 && terminated.Reason == "")
+}
+
+// containerMemoryLimit returns the memory limit of a container, preferring
+// the limit the kubelet reports over the one in the pod spec since it
+// reflects in-place resizes
+func containerMemoryLimit(pod *corev1.Pod, status corev1.ContainerStatus) string {
+	if status.Resources != nil {
+		if limit, ok := status.Resources.Limits[corev1.ResourceMemory]; ok {
+			return limit.String()
+		}
+	}
+	for _, containers := range [][]corev1.Container{pod.Spec.Containers, pod.Spec.InitContainers} {
+		for _, container := range containers {
+			if container.Name != status.Name {
+				continue
+			}
+			if limit, ok := container.Resources.Limits[corev1.ResourceMemory]; ok {
+				return limit.String()
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
 // RegisterRoutes registers the Pod history routes
 func (h *PodHistoryHandler) RegisterRoutes(router *gin.RouterGroup) {
 	router.GET("/pods/:namespace/:name/history", h.GetPodHistory)
+	router.GET("/pods/:namespace/:name/history/export", h.ExportPodHistory)
 	router.GET("/pods/:namespace/history", h.GetPodsHistoryBatch)
+	router.GET("/workloads/:namespace/:kind/:name/pod-history", h.GetWorkloadPodHistory)
 }
\ No newline at end of file
@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestPodContainerStatuses(t *testing.T) {
+	pod := &corev1.Pod{Status: corev1.PodStatus{
+		EphemeralContainerStatuses: []corev1.ContainerStatus{{Name: "debugger"}},
+		ContainerStatuses:          []corev1.ContainerStatus{{Name: "app"}, {Name: "proxy"}},
+		InitContainerStatuses:      []corev1.ContainerStatus{{Name: "migrate"}},
+	}}
+
+	want := []struct{ name, containerType string }{
+		{"migrate", "init"},
+		{"app", "regular"},
+		{"proxy", "regular"},
+		{"debugger", "ephemeral"},
+	}
+	statuses := podContainerStatuses(pod)
+	if len(statuses) != len(want) {
+		t.Fatalf("got %d statuses, want %d", len(statuses), len(want))
+	}
+	for i, status := range statuses {
+		if status.status.Name != want[i].name || status.containerType != want[i].containerType {
+			t.Errorf("statuses[%d] = %s (%s), want %s (%s)", i, status.status.Name, status.containerType, want[i].name, want[i].containerType)
+		}
+	}
+}
+
+func TestBuildRestartHistoryContainerTypes(t *testing.T) {
+	pod := &corev1.Pod{Status: corev1.PodStatus{
+		InitContainerStatuses: []corev1.ContainerStatus{{
+			Name:                 "migrate",
+			RestartCount:         2,
+			LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Reason: "Error"}},
+		}},
+		ContainerStatuses:          []corev1.ContainerStatus{{Name: "app"}},
+		EphemeralContainerStatuses: []corev1.ContainerStatus{{Name: "debugger", RestartCount: 1}},
+	}}
+
+	history := (&PodHistoryHandler{}).buildRestartHistory(pod, nil)
+	if len(history) != 1 {
+		t.Fatalf("got %d restart entries, want 1", len(history))
+	}
+	entry := history[0]
+	if entry.RestartCount != 3 {
+		t.Errorf("restartCount = %d, want the init and ephemeral restarts, 3", entry.RestartCount)
+	}
+	types := map[string]string{}
+	for _, state := range entry.ContainerStates {
+		types[state.ContainerName] = state.ContainerType
+	}
+	if types["migrate"] != "init" || types["app"] != "regular" || types["debugger"] != "ephemeral" {
+		t.Errorf("container types = %v", types)
+	}
+	if entry.Reason != "Error" || entry.ExitCode == nil || *entry.ExitCode != 1 {
+		t.Errorf("entry = %+v, want the init container's termination", entry)
+	}
+}
+
+func TestGetPodErrorInfoInitContainers(t *testing.T) {
+	waiting := func(name, reason string) corev1.ContainerStatus {
+		return corev1.ContainerStatus{Name: name, State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason, Message: "back-off"}}}
+	}
+	terminated := func(name string, exitCode int32) corev1.ContainerStatus {
+		return corev1.ContainerStatus{Name: name, State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: exitCode, Message: "failed"}}}
+	}
+	sidecar := corev1.ContainerStatus{Name: "proxy", Started: ptr.To(true), State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}
+
+	tests := []struct {
+		name     string
+		statuses []corev1.ContainerStatus
+		hasError bool
+		message  string
+	}{
+		{name: "init containers done", statuses: []corev1.ContainerStatus{terminated("migrate", 0), sidecar, terminated("seed", 0)}},
+		{
+			name:     "init container crash looping",
+			statuses: []corev1.ContainerStatus{terminated("migrate", 0), waiting("seed", "CrashLoopBackOff")},
+			hasError: true,
+			message:  "Init container seed failed at Init:1/3: Init:CrashLoopBackOff - back-off",
+		},
+		{
+			name:     "started sidecar counts as done",
+			statuses: []corev1.ContainerStatus{terminated("migrate", 0), sidecar, terminated("seed", 2)},
+			hasError: true,
+			message:  "Init container seed failed at Init:2/3: Init:Error - exited with code 2: failed",
+		},
+		{
+			name:     "init container image pull",
+			statuses: []corev1.ContainerStatus{waiting("migrate", "ImagePullBackOff")},
+			hasError: true,
+			message:  "Init container migrate failed at Init:0/3: Init:ImagePullBackOff",
+		},
+		{
+			name: "OOM kill of an init container",
+			statuses: []corev1.ContainerStatus{{
+				Name:  "migrate",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled"}},
+			}},
+			hasError: true,
+			message:  "Container migrate was OOMKilled",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				Spec: corev1.PodSpec{InitContainers: []corev1.Container{
+					{Name: "migrate"},
+					{Name: "proxy", RestartPolicy: ptr.To(corev1.ContainerRestartPolicyAlways)},
+					{Name: "seed"},
+				}},
+				Status: corev1.PodStatus{Phase: corev1.PodPending, InitContainerStatuses: tt.statuses},
+			}
+			hasError, message := (&PodHistoryHandler{}).getPodErrorInfo(pod)
+			if hasError != tt.hasError || !strings.HasPrefix(message, tt.message) {
+				t.Errorf("getPodErrorInfo() = %v, %q, want %v, %q", hasError, message, tt.hasError, tt.message)
+			}
+		})
+	}
+}
+
+func TestPodScheduledTime(t *testing.T) {
+	created := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	scheduled := created.Add(30 * time.Second)
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(created)}}
+	if got := podScheduledTime(pod); !got.Equal(created) {
+		t.Errorf("podScheduledTime() without a PodScheduled condition = %s, want the creation time", got)
+	}
+
+	pod.Status.Conditions = []corev1.PodCondition{
+		{Type: corev1.PodReady, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(scheduled.Add(time.Minute))},
+		{Type: corev1.PodScheduled, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(scheduled)},
+	}
+	if got := podScheduledTime(pod); !got.Equal(scheduled) {
+		t.Errorf("podScheduledTime() = %s, want %s", got, scheduled)
+	}
+
+	pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodScheduled, Status: corev1.ConditionFalse, LastTransitionTime: metav1.NewTime(scheduled)}}
+	if got := podScheduledTime(pod); !got.Equal(created) {
+		t.Errorf("podScheduledTime() while unschedulable = %s, want the creation time", got)
+	}
+}
+
+func TestCloseNodeHistory(t *testing.T) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+
+	// Out of order, as built from events
+	history := []NodeHistoryEntry{
+		{NodeName: "node-b", StartTime: at(30), Reason: "Scheduled"},
+		{NodeName: "none", StartTime: at(0), Reason: "FailedScheduling"},
+		{NodeName: "node-a", StartTime: at(10), Reason: "Scheduled"},
+		{NodeName: "none", StartTime: at(5), Reason: "FailedScheduling"},
+	}
+	closeNodeHistory(history)
+
+	want := []struct {
+		node     string
+		start    time.Time
+		end      *time.Time
+		duration int64
+	}{
+		// Consecutive FailedScheduling entries end when the pod is scheduled
+		{"none", at(0), ptr.To(at(10)), 600},
+		{"none", at(5), ptr.To(at(10)), 300},
+		{"node-a", at(10), ptr.To(at(30)), 1200},
+		{"node-b", at(30), nil, 0},
+	}
+	for i, w := range want {
+		entry := history[i]
+		if entry.NodeName != w.node || !entry.StartTime.Equal(w.start) {
+			t.Errorf("history[%d] = %s at %s, want %s at %s", i, entry.NodeName, entry.StartTime, w.node, w.start)
+			continue
+		}
+		if w.end == nil {
+			if entry.EndTime != nil || entry.DurationSeconds != nil {
+				t.Errorf("history[%d] ended at %v, want it open", i, entry.EndTime)
+			}
+			continue
+		}
+		if entry.EndTime == nil || !entry.EndTime.Equal(*w.end) {
+			t.Errorf("history[%d] end = %v, want %s", i, entry.EndTime, *w.end)
+		}
+		if entry.DurationSeconds == nil || *entry.DurationSeconds != w.duration {
+			t.Errorf("history[%d] duration = %v, want %d", i, entry.DurationSeconds, w.duration)
+		}
+	}
+}
+
+func TestBuildNodeHistoryEndsAtDeletion(t *testing.T) {
+	created := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	deleted := metav1.NewTime(created.Add(90 * time.Second))
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(created), DeletionTimestamp: &deleted},
+		Spec:       corev1.PodSpec{NodeName: "node-a"},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+
+	history := (&PodHistoryHandler{}).buildNodeHistoryFromEvents(nil, pod)
+	if len(history) != 1 {
+		t.Fatalf("got %d entries, want 1", len(history))
+	}
+	entry := history[0]
+	if entry.EndTime == nil || !entry.EndTime.Equal(deleted.Time) {
+		t.Errorf("end = %v, want the deletion time %s", entry.EndTime, deleted.Time)
+	}
+	if entry.DurationSeconds == nil || *entry.DurationSeconds != 90 {
+		t.Errorf("duration = %v, want 90", entry.DurationSeconds)
+	}
+}
+
+func TestBuildNodeHistoryEvictedAndRescheduled(t *testing.T) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+	scheduled := func(node string, minutes int) corev1.Event {
+		return corev1.Event{
+			ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(at(minutes))},
+			Reason:     "Scheduled",
+			Message:    "Successfully assigned apps/web to " + node,
+		}
+	}
+	evicted := func(node string, minutes int) corev1.Event {
+		return corev1.Event{
+			ObjectMeta:    metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(at(minutes))},
+			Reason:        "Evicted",
+			Message:       "The node was low on resource: memory.",
+			Source:        corev1.EventSource{Host: node},
+			LastTimestamp: metav1.NewTime(at(minutes)),
+		}
+	}
+	// Scheduled on node-a, evicted, rescheduled on node-b, evicted again and
+	// rescheduled on node-c where it runs now. Events are newest first.
+	events := []corev1.Event{
+		scheduled("node-c", 22),
+		evicted("node-b", 20),
+		scheduled("node-b", 12),
+		evicted("node-a", 10),
+		scheduled("node-a", 0),
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(at(0))},
+		Spec:       corev1.PodSpec{NodeName: "node-c"},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodScheduled, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(at(22))}},
+		},
+	}
+
+	history := (&PodHistoryHandler{}).buildNodeHistoryFromEvents(events, pod)
+	want := []struct {
+		node   string
+		start  time.Time
+		end    *time.Time
+		reason string
+	}{
+		{"node-c", at(22), nil, "Scheduled"},
+		{"node-b", at(12), ptr.To(at(22)), "Evicted"},
+		{"node-a", at(0), ptr.To(at(12)), "Evicted"},
+	}
+	if len(history) != len(want) {
+		t.Fatalf("got %d entries %+v, want %d", len(history), history, len(want))
+	}
+	for i, w := range want {
+		entry := history[i]
+		if entry.NodeName != w.node || !entry.StartTime.Equal(w.start) || entry.Reason != w.reason {
+			t.Errorf("history[%d] = %s at %s (%s), want %s at %s (%s)", i, entry.NodeName, entry.StartTime, entry.Reason, w.node, w.start, w.reason)
+		}
+		switch {
+		case w.end == nil && entry.EndTime != nil:
+			t.Errorf("history[%d] ended at %s, want it open", i, entry.EndTime)
+		case w.end != nil && (entry.EndTime == nil || !entry.EndTime.Equal(*w.end)):
+			t.Errorf("history[%d] end = %v, want %s", i, entry.EndTime, *w.end)
+		case w.end != nil && (entry.DurationSeconds == nil || *entry.DurationSeconds != int64(w.end.Sub(w.start).Seconds())):
+			t.Errorf("history[%d] duration = %v, want %s", i, entry.DurationSeconds, w.end.Sub(w.start))
+		}
+	}
+}
@@ -0,0 +1,591 @@
+package handlers
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// podHistoryExpireInterval is how often records past the retention age are
+// dropped from the store
+const podHistoryExpireInterval = time.Minute
+
+var (
+	podHistoryEvictions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kite_pod_history_evictions_total",
+		Help: "Pods dropped from the pod history recorder, by reason (lru or age)",
+	}, []string{"reason"})
+	podHistoryRecords = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "kite_pod_history_records",
+		Help: "Pods currently held by the pod history recorder",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(podHistoryEvictions, podHistoryRecords)
+}
+
+// recordedEventReasons are the Normal events worth keeping after a pod is
+// gone, Warning events are always recorded
+var recordedEventReasons = map[string]bool{
+	"Scheduled":            true,
+	"Killing":              true,
+	"Preempted":            true,
+	"TaintManagerEviction": true,
+}
+
+// PhaseTransition is a recorded change of a pod's phase
+type PhaseTransition struct {
+	Phase  string    `json:"phase"`
+	Reason string    `json:"reason,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// ContainerTermination is a recorded container exit
+type ContainerTermination struct {
+	ContainerName string    `json:"containerName"`
+	RestartCount  int32     `json:"restartCount"`
+	ExitCode      int32     `json:"exitCode"`
+	Reason        string    `json:"reason"`
+	Message       string    `json:"message,omitempty"`
+	OOMKilled     bool      `json:"oomKilled,omitempty"`
+	StartedAt     time.Time `json:"startedAt"`
+	FinishedAt    time.Time `json:"finishedAt"`
+}
+
+// PodHistoryEvent is a recorded pod event, kept after the event itself expires
+type PodHistoryEvent struct {
+	UID       types.UID `json:"-"`
+	Type      string    `json:"type"`
+	Reason    string    `json:"reason"`
+	Message   string    `json:"message"`
+	Count     int32     `json:"count"`
+	FirstTime time.Time `json:"firstTime"`
+	LastTime  time.Time `json:"lastTime"`
+}
+
+// podHistoryRecord is what the recorder remembers about a pod name. A pod
+// recreated under the same name, e.g. by a StatefulSet, keeps appending to
+// the same record. The lists are in chronological order.
+type podHistoryRecord struct {
+	Namespace string
+	Name      string
+	UID       types.UID
+	OwnerUID  types.UID
+	Labels    map[string]string
+	Deleted   bool
+	DeletedAt *time.Time
+	UpdatedAt time.Time
+
+	NodeHistory      []NodeHistoryEntry
+	PhaseTransitions []PhaseTransition
+	Terminations     []ContainerTermination
+	Events           []PodHistoryEvent
+}
+
+func (r *podHistoryRecord) clone() podHistoryRecord {
+	out := *r
+	out.Labels = make(map[string]string, len(r.Labels))
+	for k, v := range r.Labels {
+		out.Labels[k] = v
+	}
+	out.NodeHistory = append([]NodeHistoryEntry(nil), r.NodeHistory...)
+	out.PhaseTransitions = append([]PhaseTransition(nil), r.PhaseTransitions...)
+	out.Terminations = append([]ContainerTermination(nil), r.Terminations...)
+	out.Events = append([]PodHistoryEvent(nil), r.Events...)
+	return out
+}
+
+// trim keeps the newest maxEntries items of every list
+func (r *podHistoryRecord) trim(maxEntries int) {
+	if n := len(r.NodeHistory); n > maxEntries {
+		r.NodeHistory = append([]NodeHistoryEntry(nil), r.NodeHistory[n-maxEntries:]...)
+	}
+	if n := len(r.PhaseTransitions); n > maxEntries {
+		r.PhaseTransitions = append([]PhaseTransition(nil), r.PhaseTransitions[n-maxEntries:]...)
+	}
+	if n := len(r.Terminations); n > maxEntries {
+		r.Terminations = append([]ContainerTermination(nil), r.Terminations[n-maxEntries:]...)
+	}
+	if n := len(r.Events); n > maxEntries {
+		r.Events = append([]PodHistoryEvent(nil), r.Events[n-maxEntries:]...)
+	}
+}
+
+// expireEntries drops entries that happened before cutoff, keeping the
+// latest node assignment and phase so a live pod never loses its current
+// state
+func (r *podHistoryRecord) expireEntries(cutoff time.Time) {
+	if n := len(r.NodeHistory); n > 1 {
+		kept := r.NodeHistory[:0]
+		for i, entry := range r.NodeHistory {
+			if i == n-1 || entry.EndTime == nil || entry.EndTime.After(cutoff) {
+				kept = append(kept, entry)
+			}
+		}
+		r.NodeHistory = kept
+	}
+	if n := len(r.PhaseTransitions); n > 1 {
+		kept := r.PhaseTransitions[:0]
+		for i, transition := range r.PhaseTransitions {
+			if i == n-1 || transition.Time.After(cutoff) {
+				kept = append(kept, transition)
+			}
+		}
+		r.PhaseTransitions = kept
+	}
+	terminations := r.Terminations[:0]
+	for _, termination := range r.Terminations {
+		if termination.FinishedAt.After(cutoff) {
+			terminations = append(terminations, termination)
+		}
+	}
+	r.Terminations = terminations
+	events := r.Events[:0]
+	for _, event := range r.Events {
+		if event.LastTime.After(cutoff) {
+			events = append(events, event)
+		}
+	}
+	r.Events = events
+}
+
+// podHistoryStore keeps pod history records in memory. The number of pods is
+// capped and the least recently updated pod is evicted when the cap is hit,
+// every list of a record is a ring buffer of at most maxEntries items.
+type podHistoryStore struct {
+	mu         sync.Mutex
+	maxPods    int
+	maxEntries int
+	maxAge     time.Duration
+	// lru holds *podHistoryRecord, most recently updated first
+	lru     *list.List
+	records map[string]*list.Element
+	byOwner map[types.UID]map[string]struct{}
+}
+
+func newPodHistoryStore(maxPods, maxEntries int, maxAge time.Duration) *podHistoryStore {
+	return &podHistoryStore{
+		maxPods:    maxPods,
+		maxEntries: maxEntries,
+		maxAge:     maxAge,
+		lru:        list.New(),
+		records:    make(map[string]*list.Element),
+		byOwner:    make(map[types.UID]map[string]struct{}),
+	}
+}
+
+func podHistoryKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// update applies fn to the record of a pod, creating it if needed
+func (s *podHistoryStore) update(namespace, name string, fn func(record *podHistoryRecord)) {
+	key := podHistoryKey(namespace, name)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.records[key]
+	if ok {
+		s.lru.MoveToFront(elem)
+	} else {
+		elem = s.lru.PushFront(&podHistoryRecord{Namespace: namespace, Name: name})
+		s.records[key] = elem
+	}
+	record := elem.Value.(*podHistoryRecord)
+	previousOwner := record.OwnerUID
+	fn(record)
+	record.UpdatedAt = time.Now()
+	record.trim(s.maxEntries)
+	if record.OwnerUID != previousOwner {
+		s.unindexOwner(previousOwner, key)
+		s.indexOwner(record.OwnerUID, key)
+	}
+
+	for s.lru.Len() > s.maxPods {
+		s.remove(s.lru.Back())
+		podHistoryEvictions.WithLabelValues("lru").Inc()
+	}
+	podHistoryRecords.Set(float64(s.lru.Len()))
+}
+
+// get returns a copy of the record of a pod
+func (s *podHistoryStore) get(namespace, name string) (podHistoryRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.records[podHistoryKey(namespace, name)]
+	if !ok {
+		return podHistoryRecord{}, false
+	}
+	return elem.Value.(*podHistoryRecord).clone(), true
+}
+
+// list returns copies of the records in a namespace, or of the pods owned by
+// ownerUID when it is set
+func (s *podHistoryStore) list(namespace string, ownerUID types.UID) []podHistoryRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var records []podHistoryRecord
+	if ownerUID != "" {
+		for key := range s.byOwner[ownerUID] {
+			record := s.records[key].Value.(*podHistoryRecord)
+			if record.Namespace == namespace {
+				records = append(records, record.clone())
+			}
+		}
+		return records
+	}
+	for elem := s.lru.Front(); elem != nil; elem = elem.Next() {
+		record := elem.Value.(*podHistoryRecord)
+		if record.Namespace == namespace {
+			records = append(records, record.clone())
+		}
+	}
+	return records
+}
+
+// expire drops deleted pods and entries older than the retention age
+func (s *podHistoryStore) expire(now time.Time) {
+	cutoff := now.Add(-s.maxAge)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for elem := s.lru.Back(); elem != nil; {
+		prev := elem.Prev()
+		record := elem.Value.(*podHistoryRecord)
+		if record.Deleted && record.DeletedAt != nil && record.DeletedAt.Before(cutoff) {
+			s.remove(elem)
+			podHistoryEvictions.WithLabelValues("age").Inc()
+		} else {
+			record.expireEntries(cutoff)
+		}
+		elem = prev
+	}
+	podHistoryRecords.Set(float64(s.lru.Len()))
+}
+
+func (s *podHistoryStore) remove(elem *list.Element) {
+	record := s.lru.Remove(elem).(*podHistoryRecord)
+	key := podHistoryKey(record.Namespace, record.Name)
+	delete(s.records, key)
+	s.unindexOwner(record.OwnerUID, key)
+}
+
+func (s *podHistoryStore) indexOwner(ownerUID types.UID, key string) {
+	if ownerUID == "" {
+		return
+	}
+	if s.byOwner[ownerUID] == nil {
+		s.byOwner[ownerUID] = make(map[string]struct{})
+	}
+	s.byOwner[ownerUID][key] = struct{}{}
+}
+
+func (s *podHistoryStore) unindexOwner(ownerUID types.UID, key string) {
+	if ownerUID == "" {
+		return
+	}
+	delete(s.byOwner[ownerUID], key)
+	if len(s.byOwner[ownerUID]) == 0 {
+		delete(s.byOwner, ownerUID)
+	}
+}
+
+// podHistoryRecorder feeds the store from shared informers on pods and pod
+// events
+type podHistoryRecorder struct {
+	store        *podHistoryStore
+	podFactory   informers.SharedInformerFactory
+	eventFactory informers.SharedInformerFactory
+	synced       []cache.InformerSynced
+}
+
+func newPodHistoryRecorder(client kubernetes.Interface, store *podHistoryStore) (*podHistoryRecorder, error) {
+	r := &podHistoryRecorder{
+		store:      store,
+		podFactory: informers.NewSharedInformerFactory(client, 0),
+		eventFactory: informers.NewSharedInformerFactoryWithOptions(client, 0,
+			informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+				options.FieldSelector = fields.OneTermEqualSelector("involvedObject.kind", "Pod").String()
+			})),
+	}
+
+	podInformer := r.podFactory.Core().V1().Pods().Informer()
+	if err := podInformer.SetTransform(trimPodForHistory); err != nil {
+		return nil, fmt.Errorf("failed to set pod transform: %w", err)
+	}
+	if _, err := podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    r.onPod,
+		UpdateFunc: func(_, obj interface{}) { r.onPod(obj) },
+		DeleteFunc: r.onPodDelete,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to add pod event handler: %w", err)
+	}
+
+	eventInformer := r.eventFactory.Core().V1().Events().Informer()
+	if err := eventInformer.SetTransform(trimEventForHistory); err != nil {
+		return nil, fmt.Errorf("failed to set event transform: %w", err)
+	}
+	if _, err := eventInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    r.onEvent,
+		UpdateFunc: func(_, obj interface{}) { r.onEvent(obj) },
+	}); err != nil {
+		return nil, fmt.Errorf("failed to add event handler: %w", err)
+	}
+
+	r.synced = []cache.InformerSynced{podInformer.HasSynced, eventInformer.HasSynced}
+	return r, nil
+}
+
+// Run starts the informers and expires old records until ctx is done
+func (r *podHistoryRecorder) Run(ctx context.Context) {
+	r.podFactory.Start(ctx.Done())
+	r.eventFactory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), r.synced...) {
+		klog.Warning("Failed to wait for pod history recorder sync")
+		return
+	}
+	klog.Info("Pod history recorder started")
+
+	ticker := time.NewTicker(podHistoryExpireInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			r.store.expire(now)
+		}
+	}
+}
+
+// trimPodForHistory keeps only the fields the recorder reads so the informer
+// cache stays small
+func trimPodForHistory(obj interface{}) (interface{}, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return obj, nil
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              pod.Name,
+			Namespace:         pod.Namespace,
+			UID:               pod.UID,
+			ResourceVersion:   pod.ResourceVersion,
+			Labels:            pod.Labels,
+			OwnerReferences:   pod.OwnerReferences,
+			CreationTimestamp: pod.CreationTimestamp,
+			DeletionTimestamp: pod.DeletionTimestamp,
+		},
+		Spec: corev1.PodSpec{NodeName: pod.Spec.NodeName},
+		Status: corev1.PodStatus{
+			Phase:                 pod.Status.Phase,
+			Reason:                pod.Status.Reason,
+			Conditions:            pod.Status.Conditions,
+			StartTime:             pod.Status.StartTime,
+			InitContainerStatuses: pod.Status.InitContainerStatuses,
+			ContainerStatuses:     pod.Status.ContainerStatuses,
+		},
+	}, nil
+}
+
+// trimEventForHistory drops the managed fields of cached events
+func trimEventForHistory(obj interface{}) (interface{}, error) {
+	if event, ok := obj.(*corev1.Event); ok {
+		event.ManagedFields = nil
+	}
+	return obj, nil
+}
+
+func (r *podHistoryRecorder) onPod(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+	r.store.update(pod.Namespace, pod.Name, func(record *podHistoryRecord) {
+		if record.UID != pod.UID {
+			// A new pod under a known name, close what the old one left open
+			closeNodeEntry(record, time.Now())
+			record.UID = pod.UID
+			record.Deleted = false
+			record.DeletedAt = nil
+		}
+		record.OwnerUID = ""
+		if owner := metav1.GetControllerOf(pod); owner != nil {
+			record.OwnerUID = owner.UID
+		}
+		record.Labels = pod.Labels
+		recordNodeAssignment(record, pod)
+		recordPhase(record, pod)
+		recordTerminations(record, pod)
+	})
+}
+
+func (r *podHistoryRecorder) onPodDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+	r.store.update(pod.Namespace, pod.Name, func(record *podHistoryRecord) {
+		if record.UID != "" && record.UID != pod.UID {
+			return
+		}
+		record.UID = pod.UID
+		recordTerminations(record, pod)
+		now := time.Now()
+		// The node entry ends when the pod was asked to go away
+		end := now
+		if pod.DeletionTimestamp != nil {
+			end = pod.DeletionTimestamp.Time
+		}
+		closeNodeEntry(record, end)
+		record.Deleted = true
+		record.DeletedAt = &now
+	})
+}
+
+func (r *podHistoryRecorder) onEvent(obj interface{}) {
+	event, ok := obj.(*corev1.Event)
+	if !ok || event.InvolvedObject.Kind != "Pod" {
+		return
+	}
+	if event.Type != corev1.EventTypeWarning && !recordedEventReasons[event.Reason] {
+		return
+	}
+
+	recorded := PodHistoryEvent{
+		UID:       event.UID,
+		Type:      event.Type,
+		Reason:    event.Reason,
+		Message:   event.Message,
+		Count:     max(event.Count, 1),
+		FirstTime: event.FirstTimestamp.Time,
+		LastTime:  event.LastTimestamp.Time,
+	}
+	if recorded.LastTime.IsZero() {
+		recorded.LastTime = event.EventTime.Time
+	}
+	if recorded.LastTime.IsZero() {
+		recorded.LastTime = event.CreationTimestamp.Time
+	}
+	if recorded.FirstTime.IsZero() {
+		recorded.FirstTime = recorded.LastTime
+	}
+
+	r.store.update(event.InvolvedObject.Namespace, event.InvolvedObject.Name, func(record *podHistoryRecord) {
+		for i := range record.Events {
+			if record.Events[i].UID == recorded.UID {
+				record.Events[i] = recorded
+				return
+			}
+		}
+		record.Events = append(record.Events, recorded)
+	})
+}
+
+// closeNodeEntry sets the end time of the open node assignment, if any
+func closeNodeEntry(record *podHistoryRecord, at time.Time) {
+	if n := len(record.NodeHistory); n > 0 && record.NodeHistory[n-1].EndTime == nil {
+		record.NodeHistory[n-1].EndTime = &at
+	}
+}
+
+func recordNodeAssignment(record *podHistoryRecord, pod *corev1.Pod) {
+	if pod.Spec.NodeName == "" {
+		return
+	}
+	if n := len(record.NodeHistory); n > 0 {
+		last := &record.NodeHistory[n-1]
+		if last.EndTime == nil && last.NodeName == pod.Spec.NodeName {
+			last.Phase = string(pod.Status.Phase)
+			if podDisruptionReasons[pod.Status.Reason] {
+				last.Reason = pod.Status.Reason
+			}
+			return
+		}
+	}
+
+	startTime := podScheduledTime(pod)
+	closeNodeEntry(record, startTime)
+	record.NodeHistory = append(record.NodeHistory, NodeHistoryEntry{
+		NodeName:  pod.Spec.NodeName,
+		StartTime: startTime,
+		Reason:    "Scheduled",
+		Phase:     string(pod.Status.Phase),
+	})
+}
+
+func recordPhase(record *podHistoryRecord, pod *corev1.Pod) {
+	phase := string(pod.Status.Phase)
+	if phase == "" {
+		return
+	}
+	n := len(record.PhaseTransitions)
+	if n > 0 && record.PhaseTransitions[n-1].Phase == phase {
+		return
+	}
+
+	at := time.Now()
+	if n == 0 {
+		// The first phase seen may predate the recorder, e.g. on startup
+		at = pod.CreationTimestamp.Time
+		if pod.Status.Phase != corev1.PodPending && pod.Status.StartTime != nil {
+			at = pod.Status.StartTime.Time
+		}
+	}
+	record.PhaseTransitions = append(record.PhaseTransitions, PhaseTransition{
+		Phase:  phase,
+		Reason: pod.Status.Reason,
+		Time:   at,
+	})
+}
+
+func recordTerminations(record *podHistoryRecord, pod *corev1.Pod) {
+	statuses := append(append([]corev1.ContainerStatus(nil), pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		for _, terminated := range []*corev1.ContainerStateTerminated{status.LastTerminationState.Terminated, status.State.Terminated} {
+			if terminated == nil || hasTermination(record, status.Name, terminated) {
+				continue
+			}
+			record.Terminations = append(record.Terminations, ContainerTermination{
+				ContainerName: status.Name,
+				RestartCount:  status.RestartCount,
+				ExitCode:      terminated.ExitCode,
+				Reason:        terminated.Reason,
+				Message:       terminated.Message,
+				OOMKilled:     isOOMKilled(terminated),
+				StartedAt:     terminated.StartedAt.Time,
+				FinishedAt:    terminated.FinishedAt.Time,
+			})
+		}
+	}
+}
+
+func hasTermination(record *podHistoryRecord, container string, terminated *corev1.ContainerStateTerminated) bool {
+	for i := len(record.Terminations) - 1; i >= 0; i-- {
+		t := record.Terminations[i]
+		if t.ContainerName == container && t.StartedAt.Equal(terminated.StartedAt.Time) && t.FinishedAt.Equal(terminated.FinishedAt.Time) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/zxh326/kite/pkg/utils"
+)
+
+// PodTimings breaks down how long a pod took to start. A field is null when
+// the condition or events it is computed from are missing.
+type PodTimings struct {
+	// SchedulingLatencySeconds is from creation until the pod was scheduled
+	SchedulingLatencySeconds *float64 `json:"schedulingLatencySeconds"`
+	// ImagePullSeconds is the first image pull of each container, from its
+	// Pulling to its Pulled event. Images already present are left out.
+	ImagePullSeconds map[string]float64 `json:"imagePullSeconds"`
+	// InitDurationSeconds is from scheduling until the init containers were
+	// done, null for pods without init containers
+	InitDurationSeconds *float64 `json:"initDurationSeconds"`
+	// TimeToReadySeconds is from creation until the pod last became Ready
+	TimeToReadySeconds *float64 `json:"timeToReadySeconds"`
+	// ClockSkewDetected is set when a duration came out negative, it was
+	// clamped to zero
+	ClockSkewDetected bool `json:"clockSkewDetected"`
+}
+
+// buildPodTimings computes the startup timings of a pod from its conditions
+// and events
+func buildPodTimings(pod *corev1.Pod, events []corev1.Event) *PodTimings {
+	timings := &PodTimings{}
+	created := pod.CreationTimestamp.Time
+
+	scheduled, ok := podConditionTime(pod, corev1.PodScheduled)
+	if !ok {
+		scheduled, ok = firstEventTime(events, "Scheduled")
+	}
+	if ok {
+		timings.SchedulingLatencySeconds = timings.duration(created, scheduled)
+		if len(pod.Spec.InitContainers) > 0 {
+			if initialized, ok := podConditionTime(pod, corev1.PodInitialized); ok {
+				timings.InitDurationSeconds = timings.duration(scheduled, initialized)
+			}
+		}
+	}
+	if ready, ok := podConditionTime(pod, corev1.PodReady); ok {
+		timings.TimeToReadySeconds = timings.duration(created, ready)
+	}
+
+	pulling := make(map[string]time.Time)
+	pulled := make(map[string]time.Time)
+	for _, event := range events {
+		container := utils.EventContainerName(&event)
+		if container == "" {
+			continue
+		}
+		var first map[string]time.Time
+		switch event.Reason {
+		case "Pulling":
+			first = pulling
+		case "Pulled":
+			first = pulled
+		default:
+			continue
+		}
+		at := podEventFirstTime(event)
+		if at.IsZero() {
+			continue
+		}
+		if seen, ok := first[container]; !ok || at.Before(seen) {
+			first[container] = at
+		}
+	}
+	for container, start := range pulling {
+		end, ok := pulled[container]
+		if !ok {
+			continue
+		}
+		if timings.ImagePullSeconds == nil {
+			timings.ImagePullSeconds = make(map[string]float64)
+		}
+		timings.ImagePullSeconds[container] = *timings.duration(start, end)
+	}
+
+	return timings
+}
+
+// duration returns the seconds from start to end, negative durations are
+// clamped to zero and flagged as clock skew
+func (t *PodTimings) duration(start, end time.Time) *float64 {
+	seconds := end.Sub(start).Seconds()
+	if seconds < 0 {
+		t.ClockSkewDetected = true
+		seconds = 0
+	}
+	return &seconds
+}
+
+// podConditionTime returns when the condition last became true
+func podConditionTime(pod *corev1.Pod, conditionType corev1.PodConditionType) (time.Time, bool) {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == conditionType && condition.Status == corev1.ConditionTrue && !condition.LastTransitionTime.IsZero() {
+			return condition.LastTransitionTime.Time, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// firstEventTime returns when an event with reason was first seen
+func firstEventTime(events []corev1.Event, reason string) (time.Time, bool) {
+	var first time.Time
+	for _, event := range events {
+		if event.Reason != reason {
+			continue
+		}
+		if at := podEventFirstTime(event); !at.IsZero() && (first.IsZero() || at.Before(first)) {
+			first = at
+		}
+	}
+	return first, !first.IsZero()
+}
@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+// maxWorkloadTimelineEntries caps the merged node assignments and restarts
+// of a workload
+const maxWorkloadTimelineEntries = 500
+
+// WorkloadPodHistory is the pod churn of a workload across its current pods
+// and the recorded ones that are gone
+type WorkloadPodHistory struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Revisions are the ReplicaSets of a Deployment or the
+	// ControllerRevisions of a StatefulSet or DaemonSet, to line restarts up
+	// with rollouts
+	Revisions       []WorkloadRevision       `json:"revisions"`
+	Pods            []WorkloadPodSummary     `json:"pods"`
+	NodeAssignments []WorkloadNodeAssignment `json:"nodeAssignments"`
+	Restarts        []WorkloadRestart        `json:"restarts"`
+	TotalRestarts   int32                    `json:"totalRestarts"`
+	// Truncated is set when node assignments or restarts were cut at
+	// maxWorkloadTimelineEntries
+	Truncated bool `json:"truncated"`
+}
+
+// WorkloadRevision is one rollout of a workload
+type WorkloadRevision struct {
+	Name      string    `json:"name"`
+	Revision  int64     `json:"revision"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// WorkloadPodSummary sums up the history of one pod of a workload
+type WorkloadPodSummary struct {
+	PodName         string     `json:"podName"`
+	CurrentNode     string     `json:"currentNode,omitempty"`
+	Phase           string     `json:"phase"`
+	Deleted         bool       `json:"deleted"`
+	RestartCount    int32      `json:"restartCount"`
+	OOMKillCount    int32      `json:"oomKillCount"`
+	NodeCount       int        `json:"nodeCount"`
+	LastRestartTime *time.Time `json:"lastRestartTime,omitempty"`
+}
+
+// WorkloadNodeAssignment is a node history entry of one of a workload's pods
+type WorkloadNodeAssignment struct {
+	PodName string `json:"podName"`
+	NodeHistoryEntry
+}
+
+// WorkloadRestart is a restart timeline entry of one of a workload's pods
+type WorkloadRestart struct {
+	PodName string `json:"podName"`
+	RestartTimelineEntry
+}
+
+// GetWorkloadPodHistory returns the merged pod history of a Deployment,
+// StatefulSet or DaemonSet. Current pods are found by the workload's
+// selector and ownership, pods that are gone come from the history recorder.
+// ?historyLimit= and ?sinceHours= apply to each pod as for GetPodHistory.
+func (h *PodHistoryHandler) GetWorkloadPodHistory(c *gin.Context) {
+	namespace := c.Param("namespace")
+	kind := strings.ToLower(c.Param("kind"))
+	name := c.Param("name")
+	if kind != "deployment" && kind != "statefulset" && kind != "daemonset" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be deployment, statefulset or daemonset"})
+		return
+	}
+	opts, err := parsePodHistoryOptions(c, defaultNodeHistoryLimit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	selector, owners, revisions, err := h.resolveWorkload(ctx, namespace, kind, name)
+	if errors.IsNotFound(err) {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("%s not found", kind)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get %s: %v", kind, err)})
+		return
+	}
+
+	pods, err := h.client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to list pods: %v", err)})
+		return
+	}
+	eventsByPod, err := h.getNamespacePodEvents(ctx, namespace)
+	if err != nil {
+		klog.Warningf("Failed to get events in namespace %s: %v", namespace, err)
+		eventsByPod = map[string][]corev1.Event{} // Continue without events
+	}
+
+	var histories []*PodNodeHistory
+	live := make(map[string]bool, len(pods.Items))
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if owner := metav1.GetControllerOf(pod); owner == nil || !owners[owner.UID] {
+			continue
+		}
+		live[pod.Name] = true
+		histories = append(histories, h.buildPodHistoryFromPod(pod, eventsSince(eventsByPod[pod.Name], opts.since), opts))
+	}
+	if h.store != nil {
+		for ownerUID := range owners {
+			for _, record := range h.store.list(namespace, ownerUID) {
+				if !live[record.Name] && record.Deleted {
+					histories = append(histories, recordedHistory(record, opts))
+				}
+			}
+		}
+	}
+
+	result := WorkloadPodHistory{
+		Kind:            kind,
+		Name:            name,
+		Namespace:       namespace,
+		Revisions:       revisions,
+		Pods:            make([]WorkloadPodSummary, 0, len(histories)),
+		NodeAssignments: []WorkloadNodeAssignment{},
+		Restarts:        []WorkloadRestart{},
+	}
+	for _, history := range histories {
+		summary := WorkloadPodSummary{
+			PodName:     history.PodName,
+			CurrentNode: history.CurrentNode,
+			Phase:       history.Status.Phase,
+			Deleted:     history.Deleted,
+		}
+		nodes := make(map[string]bool)
+		for _, entry := range history.NodeHistory {
+			if entry.NodeName != "none" {
+				nodes[entry.NodeName] = true
+			}
+			result.NodeAssignments = append(result.NodeAssignments, WorkloadNodeAssignment{PodName: history.PodName, NodeHistoryEntry: entry})
+		}
+		summary.NodeCount = len(nodes)
+		for _, entry := range history.RestartHistory {
+			summary.RestartCount += entry.RestartCount
+			summary.OOMKillCount += entry.OOMKillCount
+			summary.LastRestartTime = entry.LastRestartTime
+		}
+		for _, entry := range workloadRestarts(history) {
+			result.Restarts = append(result.Restarts, WorkloadRestart{PodName: history.PodName, RestartTimelineEntry: entry})
+			if history.Deleted {
+				// Deleted pods have no restart history, count what was recorded
+				if entry.OOMKilled {
+					summary.OOMKillCount++
+				}
+				if summary.LastRestartTime == nil || entry.Time.After(*summary.LastRestartTime) {
+					at := entry.Time
+					summary.LastRestartTime = &at
+				}
+			}
+		}
+		if history.Deleted {
+			for _, termination := range history.Terminations {
+				summary.RestartCount = max(summary.RestartCount, termination.RestartCount)
+			}
+		}
+		result.TotalRestarts += summary.RestartCount
+		result.Pods = append(result.Pods, summary)
+	}
+
+	sort.SliceStable(result.Pods, func(i, j int) bool {
+		return result.Pods[i].PodName < result.Pods[j].PodName
+	})
+	sort.SliceStable(result.NodeAssignments, func(i, j int) bool {
+		return result.NodeAssignments[i].StartTime.After(result.NodeAssignments[j].StartTime)
+	})
+	sort.SliceStable(result.Restarts, func(i, j int) bool {
+		return result.Restarts[i].Time.After(result.Restarts[j].Time)
+	})
+	if len(result.NodeAssignments) > maxWorkloadTimelineEntries {
+		result.NodeAssignments = result.NodeAssignments[:maxWorkloadTimelineEntries]
+		result.Truncated = true
+	}
+	if len(result.Restarts) > maxWorkloadTimelineEntries {
+		result.Restarts = result.Restarts[:maxWorkloadTimelineEntries]
+		result.Truncated = true
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// resolveWorkload returns the pod selector of a workload, the UIDs that own
+// its pods and its revisions. Pods of a Deployment are owned by its
+// ReplicaSets, the others own their pods directly.
+func (h *PodHistoryHandler) resolveWorkload(ctx context.Context, namespace, kind, name string) (labels.Selector, map[types.UID]bool, []WorkloadRevision, error) {
+	apps := h.client.AppsV1()
+	var (
+		uid         types.UID
+		podSelector *metav1.LabelSelector
+	)
+	switch kind {
+	case "deployment":
+		deployment, err := apps.Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid selector: %w", err)
+		}
+		replicaSets, err := apps.ReplicaSets(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		owners := make(map[types.UID]bool)
+		revisions := []WorkloadRevision{}
+		for _, rs := range replicaSets.Items {
+			if owner := metav1.GetControllerOf(&rs); owner == nil || owner.UID != deployment.UID {
+				continue
+			}
+			owners[rs.UID] = true
+			revision, _ := strconv.ParseInt(rs.Annotations["deployment.kubernetes.io/revision"], 10, 64)
+			revisions = append(revisions, WorkloadRevision{Name: rs.Name, Revision: revision, CreatedAt: rs.CreationTimestamp.Time})
+		}
+		sortWorkloadRevisions(revisions)
+		return selector, owners, revisions, nil
+	case "statefulset":
+		statefulSet, err := apps.StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		uid, podSelector = statefulSet.UID, statefulSet.Spec.Selector
+	case "daemonset":
+		daemonSet, err := apps.DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		uid, podSelector = daemonSet.UID, daemonSet.Spec.Selector
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(podSelector)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid selector: %w", err)
+	}
+	controllerRevisions, err := apps.ControllerRevisions(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	revisions := []WorkloadRevision{}
+	for _, revision := range controllerRevisions.Items {
+		if owner := metav1.GetControllerOf(&revision); owner != nil && owner.UID == uid {
+			revisions = append(revisions, WorkloadRevision{Name: revision.Name, Revision: revision.Revision, CreatedAt: revision.CreationTimestamp.Time})
+		}
+	}
+	sortWorkloadRevisions(revisions)
+	return selector, map[types.UID]bool{uid: true}, revisions, nil
+}
+
+func sortWorkloadRevisions(revisions []WorkloadRevision) {
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision > revisions[j].Revision
+	})
+}
+
+// workloadRestarts returns the restart timeline of a pod, completed with the
+// recorded terminations the live pod status no longer shows
+func workloadRestarts(history *PodNodeHistory) []RestartTimelineEntry {
+	entries := append([]RestartTimelineEntry{}, history.RestartTimeline...)
+	type termination struct {
+		container  string
+		finishedAt int64
+	}
+	seen := make(map[termination]bool, len(entries))
+	for _, entry := range entries {
+		seen[termination{entry.ContainerName, entry.Time.UnixNano()}] = true
+	}
+	for _, recorded := range history.Terminations {
+		if seen[termination{recorded.ContainerName, recorded.FinishedAt.UnixNano()}] {
+			continue
+		}
+		exitCode := recorded.ExitCode
+		entries = append(entries, RestartTimelineEntry{
+			ContainerName: recorded.ContainerName,
+			Time:          recorded.FinishedAt,
+			Source:        "recorded",
+			ExitCode:      &exitCode,
+			Reason:        recorded.Reason,
+			Message:       recorded.Message,
+			OOMKilled:     recorded.OOMKilled,
+		})
+	}
+	return entries
+}
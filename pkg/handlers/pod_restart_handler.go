@@ -4,22 +4,133 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/common"
+	"github.com/zxh326/kite/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
 )
 
 type PodRestartHandler struct {
 	client kubernetes.Interface
+
+	evictionCheck     sync.Once
+	evictionAvailable bool
+
+	jobs *restartJobStore
+}
+
+// PDBBlockInfo describes a PodDisruptionBudget that blocked an eviction
+type PDBBlockInfo struct {
+	Name               string `json:"name"`
+	DisruptionsAllowed int32  `json:"disruptionsAllowed"`
+	CurrentHealthy     int32  `json:"currentHealthy"`
+	DesiredHealthy     int32  `json:"desiredHealthy"`
+}
+
+// restartOptions controls how a pod is restarted
+type restartOptions struct {
+	// evict uses the Eviction API instead of deleting the pod
+	evict bool
+	// force deletes pods that have no controller and allows a zero grace
+	// period
+	force bool
+	// gracePeriodSeconds is nil for the pod's own grace period
+	gracePeriodSeconds *int64
+	// propagationPolicy is empty for the default, Foreground for deletions
+	propagationPolicy metav1.DeletionPropagation
+	// strict refuses pods whose restart has a safety warning, see
+	// restartWarning
+	strict bool
+	// dryRun only lets the API server validate the delete or eviction
+	dryRun bool
+}
+
+// newRestartOptions validates the grace period and propagation policy of a
+// restart request
+func newRestartOptions(evict, force bool, gracePeriodSeconds *int64, propagationPolicy string) (restartOptions, error) {
+	opts := restartOptions{
+		evict:              evict,
+		force:              force,
+		gracePeriodSeconds: gracePeriodSeconds,
+		propagationPolicy:  metav1.DeletionPropagation(propagationPolicy),
+	}
+	if gracePeriodSeconds != nil {
+		if *gracePeriodSeconds < 0 {
+			return opts, fmt.Errorf("gracePeriodSeconds must not be negative")
+		}
+		if *gracePeriodSeconds == 0 && !force {
+			return opts, fmt.Errorf("gracePeriodSeconds 0 skips graceful shutdown and requires force=true")
+		}
+	}
+	switch opts.propagationPolicy {
+	case "", metav1.DeletePropagationForeground, metav1.DeletePropagationBackground, metav1.DeletePropagationOrphan:
+	default:
+		return opts, fmt.Errorf("propagationPolicy must be Foreground, Background or Orphan")
+	}
+	if opts.propagationPolicy == "" && !evict {
+		opts.propagationPolicy = metav1.DeletePropagationForeground
+	}
+	return opts, nil
+}
+
+// deleteOptions returns the options of the delete or eviction
+func (o restartOptions) deleteOptions() *metav1.DeleteOptions {
+	options := &metav1.DeleteOptions{GracePeriodSeconds: o.gracePeriodSeconds}
+	if o.dryRun {
+		options.DryRun = []string{metav1.DryRunAll}
+	}
+	if o.propagationPolicy != "" {
+		propagationPolicy := o.propagationPolicy
+		options.PropagationPolicy = &propagationPolicy
+	}
+	return options
+}
+
+// errRestartRefused is returned for pods that can't be restarted by deleting
+// them
+type errRestartRefused struct {
+	reason string
+}
+
+func (e *errRestartRefused) Error() string {
+	return e.reason
+}
+
+// errRestartSkipped is returned for pods that don't need to be restarted,
+// e.g. because they are already terminating
+type errRestartSkipped struct {
+	reason string
+}
+
+func (e *errRestartSkipped) Error() string {
+	return e.reason
+}
+
+// errEvictionBlocked is returned when a PodDisruptionBudget blocks an eviction
+type errEvictionBlocked struct {
+	err       error
+	blockedBy []PDBBlockInfo
+}
+
+func (e *errEvictionBlocked) Error() string {
+	return fmt.Sprintf("eviction blocked by PodDisruptionBudget: %v", e.err)
 }
 
 func NewPodRestartHandler(client kubernetes.Interface) *PodRestartHandler {
 	return &PodRestartHandler{
 		client: client,
+		jobs:   newRestartJobStore(),
 	}
 }
 
@@ -27,17 +138,59 @@ func NewPodRestartHandler(client kubernetes.Interface) *PodRestartHandler {
 func (h *PodRestartHandler) RegisterRoutes(r *gin.RouterGroup) {
 	r.POST("/pods/:namespace/:name/restart", h.RestartPod)
 	r.POST("/pods/batch/restart", h.RestartPodsBatch)
+	r.GET("/pods/batch/restart/:jobID", h.GetRestartJob)
+	r.DELETE("/pods/batch/restart/:jobID", h.CancelRestartJob)
+	r.POST("/pods/restart-by-owner", h.RestartPodsByOwner)
 }
 
-// RestartPod deletes a pod to trigger restart by controller
+// RestartPod deletes a pod to trigger restart by controller. With
+// ?evict=true the pod is evicted instead so that PodDisruptionBudgets are
+// respected, a blocked eviction returns 429 with the blocking budgets.
+// Pods without a controller are refused unless ?force=true, mirror pods are
+// always refused. Pods in protected namespaces need ?confirmProtected=true. ?gracePeriodSeconds= and ?propagationPolicy= are passed
+// on, a zero grace period also needs ?force=true. With ?wait=true the
+// response is sent once the replacement pod created by the pod's
+// ReplicaSet, StatefulSet or DaemonSet is Ready, or with 504 after
+// ?timeoutSeconds= (default 120) with the last state of the replacement.
+// The response carries a warning when the restart may hurt availability,
+// ?strict=true refuses such restarts. ?dryRun=true only validates the
+// restart.
 func (h *PodRestartHandler) RestartPod(c *gin.Context) {
 	namespace := c.Param("namespace")
 	podName := c.Param("name")
+	wait := c.Query("wait") == "true"
 
 	if namespace == "" || podName == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "namespace and pod name are required"})
 		return
 	}
+	var gracePeriodSeconds *int64
+	if value := c.Query("gracePeriodSeconds"); value != "" {
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid gracePeriodSeconds parameter"})
+			return
+		}
+		gracePeriodSeconds = &seconds
+	}
+	opts, err := newRestartOptions(c.Query("evict") == "true", c.Query("force") == "true",
+		gracePeriodSeconds, c.Query("propagationPolicy"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	opts.strict = c.Query("strict") == "true"
+	opts.dryRun = c.Query("dryRun") == "true"
+	if err := common.CheckProtectedNamespace(namespace, utils.ActingUser(c), c.Query("confirmProtected") == "true"); err != nil {
+		klog.Warningf("Refused to restart pod %s/%s: %v", namespace, podName, err)
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "namespace": namespace})
+		return
+	}
+	waitTimeout, err := restartWaitTimeout(c.Query("timeoutSeconds"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -52,41 +205,141 @@ func (h *PodRestartHandler) RestartPod(c *gin.Context) {
 		return
 	}
 
-	// Check if pod is managed by a controller (has ownerReferences)
-	if len(pod.OwnerReferences) == 0 {
-		klog.Warningf("Pod %s/%s has no owner references, deleting directly", namespace, podName)
+	if !opts.evict {
+		h.logEvictionHint(ctx)
+	}
+	warning := h.restartWarning(ctx, pod)
+	if warning != "" && opts.strict {
+		klog.Warningf("Refused to restart pod %s/%s in strict mode: %s", namespace, podName, warning)
+		c.JSON(http.StatusConflict, gin.H{"error": "Restart refused: " + warning, "warning": warning})
+		return
+	}
+	requestedAt := time.Now()
+	err = h.restart(ctx, pod, opts)
+	skipped, isSkipped := err.(*errRestartSkipped)
+	if isSkipped {
+		klog.Infof("Skipped restart of pod %s/%s: %v", namespace, podName, skipped)
+		err = nil
+	}
+	if refused, ok := err.(*errRestartRefused); ok {
+		klog.Warningf("Refused to restart pod %s/%s: %v", namespace, podName, refused)
+		c.JSON(http.StatusConflict, gin.H{"error": refused.Error()})
+		return
+	}
+	if blocked, ok := err.(*errEvictionBlocked); ok {
+		klog.Warningf("Restart of pod %s/%s blocked: %v", namespace, podName, err)
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":     fmt.Sprintf("Failed to restart pod: %v", err),
+			"pod":       podName,
+			"namespace": namespace,
+			"blockedBy": blocked.blockedBy,
+		})
+		return
 	}
-
-	// Delete the pod to trigger restart
-	deletePolicy := metav1.DeletePropagationForeground
-	err = h.client.CoreV1().Pods(namespace).Delete(ctx, podName, metav1.DeleteOptions{
-		PropagationPolicy: &deletePolicy,
-	})
-
 	if err != nil {
-		klog.Errorf("Failed to delete pod %s/%s for restart: %v", namespace, podName, err)
+		klog.Errorf("Failed to restart pod %s/%s: %v", namespace, podName, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to restart pod: %v", err)})
 		return
 	}
 
-	klog.Infof("Successfully triggered restart for pod %s/%s", namespace, podName)
-	c.JSON(http.StatusOK, gin.H{
+	if !isSkipped {
+		klog.Infof("Successfully triggered restart for pod %s/%s", namespace, podName)
+	}
+	response := gin.H{
 		"message": fmt.Sprintf("Pod %s restart triggered successfully", podName),
 		"pod":     podName,
 		"namespace": namespace,
+		"method":    restartMethod(opts.evict),
 		"timestamp": time.Now().Format(time.RFC3339),
-	})
+		"gracePeriodSeconds": opts.gracePeriodSeconds,
+		"propagationPolicy":  opts.propagationPolicy,
+		"dryRun":             opts.dryRun,
+	}
+	if warning != "" {
+		response["warning"] = warning
+	}
+	if opts.dryRun {
+		response["message"] = fmt.Sprintf("Pod %s restart validated (dry run)", podName)
+	}
+	if isSkipped {
+		response["message"] = fmt.Sprintf("Pod %s is %s", podName, skipped.reason)
+		response["skipped"] = true
+		response["reason"] = skipped.reason
+	}
+	if !wait || opts.dryRun {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	owner := podController(pod)
+	if owner == nil {
+		response["warning"] = "Pod is not managed by a ReplicaSet, StatefulSet or DaemonSet, no replacement to wait for"
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	selector, err := h.ownerSelector(ctx, namespace, owner)
+	if err != nil {
+		klog.Errorf("Failed to get %s %s/%s: %v", owner.Kind, namespace, owner.Name, err)
+		response["error"] = fmt.Sprintf("Failed to get %s %s: %v", owner.Kind, owner.Name, err)
+		c.JSON(http.StatusInternalServerError, response)
+		return
+	}
+	deletedAt := h.deletionTime(ctx, pod, requestedAt)
+	replacement, err := h.waitForReplacement(c.Request.Context(), pod, owner, selector, deletedAt, waitTimeout)
+	response["replacement"] = replacement
+	if err != nil {
+		klog.Warningf("Restart of pod %s/%s: %v", namespace, podName, err)
+		response["error"] = err.Error()
+		c.JSON(http.StatusGatewayTimeout, response)
+		return
+	}
+	response["message"] = fmt.Sprintf("Pod %s restarted, replacement %s is ready", podName, replacement.Name)
+	c.JSON(http.StatusOK, response)
 }
 
+// defaultRestartConcurrency is how many pods a batch restarts at the same
+// time unless the request asks otherwise
+const defaultRestartConcurrency = 15
+
 // BatchRestartRequest represents the request body for batch pod restart
 type BatchRestartRequest struct {
 	Pods []PodIdentifier `json:"pods" binding:"required"`
+	// MaxConcurrency bounds how many pods are restarted at the same time,
+	// defaultRestartConcurrency unless set, capped at utils.MaxBatchConcurrency
+	MaxConcurrency int `json:"maxConcurrency,omitempty" binding:"min=0"`
+	// Evict restarts the pods through the Eviction API so that
+	// PodDisruptionBudgets are respected, pods are deleted by default
+	Evict bool `json:"evict,omitempty"`
+	// Force deletes pods without a controller, they are not recreated. It
+	// is also required for a zero GracePeriodSeconds.
+	Force bool `json:"force,omitempty"`
+	// GracePeriodSeconds overrides the pods' termination grace period
+	GracePeriodSeconds *int64 `json:"gracePeriodSeconds,omitempty"`
+	// PropagationPolicy is Foreground, Background or Orphan
+	PropagationPolicy string `json:"propagationPolicy,omitempty"`
+	// MaxUnavailablePerOwner bounds how many pods of the same Deployment,
+	// StatefulSet or DaemonSet are restarted at the same time, each waits for
+	// its replacement to be Ready. Defaults to defaultMaxUnavailablePerOwner,
+	// the batch never restarts more than MaxConcurrency pods at once.
+	MaxUnavailablePerOwner int `json:"maxUnavailablePerOwner,omitempty" binding:"min=0"`
+	// Strict refuses pods whose restart has a safety warning
+	Strict bool `json:"strict,omitempty"`
+	// DryRun only validates the restarts, nothing is deleted
+	DryRun bool `json:"dryRun,omitempty"`
+	// Async runs the batch as a background job and returns its ID, the job
+	// is polled with GET /pods/batch/restart/:jobID
+	Async bool `json:"async,omitempty"`
+	// ConfirmProtected allows pods in protected namespaces to be restarted
+	// by users in PROTECTED_NAMESPACE_USERS, see common.CheckProtectedNamespace
+	ConfirmProtected bool `json:"confirmProtected,omitempty"`
 }
 
 // PodIdentifier represents a pod to be restarted
 type PodIdentifier struct {
 	Namespace string `json:"namespace" binding:"required"`
 	Name      string `json:"name" binding:"required"`
+	// Force overrides BatchRestartRequest.Force for this pod
+	Force *bool `json:"force,omitempty"`
 }
 
 // RestartResult represents the result of restarting a single pod
@@ -95,9 +348,56 @@ type RestartResult struct {
 	Name      string `json:"name"`
 	Success   bool   `json:"success"`
 	Error     string `json:"error,omitempty"`
+	// Warning explains why the restart may hurt availability
+	Warning string `json:"warning,omitempty"`
+	// Skipped is set for pods that were not restarted, either refused in a
+	// protected namespace or, with Success and Reason, already terminating
+	Skipped bool   `json:"skipped,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+	// StatusCode is 429 when the eviction was blocked by BlockedBy
+	StatusCode int            `json:"statusCode,omitempty"`
+	BlockedBy  []PDBBlockInfo `json:"blockedBy,omitempty"`
+	// The effective delete options, a nil grace period is the pod's own
+	GracePeriodSeconds *int64                     `json:"gracePeriodSeconds,omitempty"`
+	PropagationPolicy  metav1.DeletionPropagation `json:"propagationPolicy,omitempty"`
+	// Owner is the workload the pod was grouped by, Order its position in
+	// the group's restart sequence
+	Owner string `json:"owner,omitempty"`
+	Order int    `json:"order,omitempty"`
+	// Replacement is set when the restart waited for the replacement pod,
+	// WaitError when it didn't become Ready
+	Replacement *ReplacementPod `json:"replacement,omitempty"`
+	WaitError   string          `json:"waitError,omitempty"`
+	// ReplacementObserved is set for pods with a controller, false when no
+	// replacement pod showed up in time. StatefulSet replacements have the
+	// same name and a new UID.
+	ReplacementObserved *bool        `json:"replacementObserved,omitempty"`
+	NewPodName          string       `json:"newPodName,omitempty"`
+	NewPodNode          string       `json:"newPodNode,omitempty"`
+	NewPodUID           types.UID    `json:"newPodUID,omitempty"`
+	NewPodCreatedAt     *metav1.Time `json:"newPodCreatedAt,omitempty"`
 }
 
-// RestartPodsBatch restarts multiple pods concurrently
+// setReplacement records the replacement of the restarted pod, nil when none
+// was observed
+func (r *RestartResult) setReplacement(replacement *ReplacementPod) {
+	observed := replacement != nil
+	r.ReplacementObserved = &observed
+	if replacement == nil {
+		return
+	}
+	r.NewPodName = replacement.Name
+	r.NewPodNode = replacement.Node
+	r.NewPodUID = replacement.UID
+	r.NewPodCreatedAt = &replacement.CreatedAt
+}
+
+// RestartPodsBatch restarts multiple pods concurrently. Pods in protected
+// namespaces are skipped unless the request confirms them, the other pods
+// are still restarted. Pods are grouped by their owning workload, the
+// groups are restarted in parallel and the pods of a group are staggered,
+// see restartGroups. With async the batch runs in the background, see
+// GetRestartJob.
 func (h *PodRestartHandler) RestartPodsBatch(c *gin.Context) {
 	var req BatchRestartRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -109,99 +409,298 @@ func (h *PodRestartHandler) RestartPodsBatch(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "No pods specified for restart"})
 		return
 	}
+	batchOpts, err := newRestartOptions(req.Evict, req.Force, req.GracePeriodSeconds, req.PropagationPolicy)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	batchOpts.strict = req.Strict
+	batchOpts.dryRun = req.DryRun
 
 	klog.Infof("Starting batch restart for %d pods", len(req.Pods))
+	if !req.Evict {
+		h.logEvictionHint(c.Request.Context())
+	}
+
+	concurrency := restartConcurrency(req.MaxConcurrency)
+	perOwner := defaultMaxUnavailablePerOwner
+	if req.MaxUnavailablePerOwner > 0 {
+		perOwner = req.MaxUnavailablePerOwner
+	}
 
-	// Use a context with timeout for all operations
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	user := utils.ActingUser(c)
+
+	if req.Async {
+		job, err := h.jobs.start(len(req.Pods))
+		if err != nil {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
+		go func() {
+			results, groups := h.runBatchRestart(job.ctx, &req, batchOpts, user, concurrency, perOwner, job)
+			job.finish(results, groups)
+		}()
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": fmt.Sprintf("Batch restart of %d pods queued", len(req.Pods)),
+			"jobID":   job.ID,
+			"total":   len(req.Pods),
+		})
+		return
+	}
+
+	results, groups := h.runBatchRestart(c.Request.Context(), &req, batchOpts, user, concurrency, perOwner, nil)
+	status, response := batchRestartResponse(results, groups)
+	response["dryRun"] = req.DryRun
+	c.JSON(status, response)
+}
+
+// restartConcurrency returns how many pods a batch restarts at the same
+// time, defaultRestartConcurrency unless requested, capped at
+// utils.MaxBatchConcurrency
+func restartConcurrency(requested int) int {
+	if requested <= 0 {
+		return defaultRestartConcurrency
+	}
+	return utils.BatchConcurrency(requested)
+}
+
+// runBatchRestart looks up the pods of a batch and restarts them grouped by
+// owner. progress, if not nil, is told about every pod. Once ctx is done no
+// further pods are restarted.
+func (h *PodRestartHandler) runBatchRestart(ctx context.Context, req *BatchRestartRequest, batchOpts restartOptions, user string, concurrency, perOwner int, progress restartProgress) ([]RestartResult, []RestartGroup) {
+	// Use a context with timeout to look up the pods and their owners
+	lookupCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
 	defer cancel()
 
-	// Channel to collect results
-	resultChan := make(chan RestartResult, len(req.Pods))
-	var wg sync.WaitGroup
-
-	// Process each pod restart concurrently
-	for _, pod := range req.Pods {
-		wg.Add(1)
-		go func(pod PodIdentifier) {
-			defer wg.Done()
-			result := h.restartSinglePod(ctx, pod.Namespace, pod.Name)
-			resultChan <- result
-		}(pod)
-	}
-
-	// Wait for all goroutines to complete
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
-
-	// Collect results
-	var results []RestartResult
-	var successCount, failureCount int
-
-	for result := range resultChan {
-		results = append(results, result)
-		if result.Success {
+	targets := utils.RunBatch(lookupCtx, len(req.Pods), concurrency,
+		func(ctx context.Context, i int) restartTarget {
+			namespace, podName := req.Pods[i].Namespace, req.Pods[i].Name
+			if err := common.CheckProtectedNamespace(namespace, user, req.ConfirmProtected); err != nil {
+				klog.Warningf("Skipped restart of pod %s/%s: %v", namespace, podName, err)
+				return restartTarget{result: &RestartResult{
+					Namespace: namespace,
+					Name:      podName,
+					Error:     err.Error(),
+					Skipped:   true,
+				}}
+			}
+
+			// Check if the pod exists
+			pod, err := h.client.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+			if err != nil {
+				klog.Errorf("Failed to get pod %s/%s: %v", namespace, podName, err)
+				return restartTarget{result: &RestartResult{
+					Namespace: namespace,
+					Name:      podName,
+					Error:     fmt.Sprintf("Pod not found: %v", err),
+				}}
+			}
+
+			opts := batchOpts
+			if req.Pods[i].Force != nil {
+				opts.force = *req.Pods[i].Force
+			}
+			return restartTarget{pod: pod, owner: h.podOwner(ctx, pod), opts: opts}
+		}, func(i int, err error) restartTarget {
+			return restartTarget{result: &RestartResult{
+				Namespace: req.Pods[i].Namespace,
+				Name:      req.Pods[i].Name,
+				Error:     fmt.Sprintf("Not processed: %v", err),
+			}}
+		})
+	if progress != nil {
+		for i, target := range targets {
+			if target.result != nil {
+				progress.restartFinished(i, *target.result)
+			}
+		}
+	}
+
+	// Process the groups with bounded concurrency
+	groups, members := groupRestartTargets(targets)
+	restartCtx, cancelRestart := context.WithTimeout(ctx, 2*time.Minute+staggerTimeout(members, perOwner))
+	defer cancelRestart()
+	h.restartGroups(restartCtx, targets, groups, members, concurrency, perOwner, progress)
+
+	results := make([]RestartResult, len(targets))
+	for i, target := range targets {
+		results[i] = *target.result
+	}
+	return results, groups
+}
+
+// batchRestartResponse summarizes the results of a batch restart, the
+// status is 206 when a pod failed or was refused. Pods that were already
+// terminating are counted as skipped but don't make the batch partial.
+func batchRestartResponse(results []RestartResult, groups []RestartGroup) (int, gin.H) {
+	var successCount, failureCount, skippedCount int
+	partial := false
+	for _, result := range results {
+		switch {
+		case result.Skipped:
+			skippedCount++
+			partial = partial || !result.Success
+		case result.Success:
 			successCount++
-		} else {
+		default:
 			failureCount++
+			partial = true
 		}
 	}
 
-	klog.Infof("Batch restart completed: %d successful, %d failed", successCount, failureCount)
+	klog.Infof("Batch restart completed: %d successful, %d failed, %d skipped", successCount, failureCount, skippedCount)
 
 	// Return response
 	response := gin.H{
-		"message":      fmt.Sprintf("Batch restart completed: %d successful, %d failed", successCount, failureCount),
-		"total":        len(req.Pods),
+		"message":      fmt.Sprintf("Batch restart completed: %d successful, %d failed, %d skipped", successCount, failureCount, skippedCount),
+		"total":        len(results),
 		"successful":   successCount,
 		"failed":       failureCount,
+		"skipped":      skippedCount,
 		"results":      results,
+		"groups":       groups,
 		"timestamp":    time.Now().Format(time.RFC3339),
 	}
 
-	if failureCount > 0 {
-		c.JSON(http.StatusPartialContent, response)
-	} else {
-		c.JSON(http.StatusOK, response)
+	if partial {
+		return http.StatusPartialContent, response
 	}
+	return http.StatusOK, response
 }
 
-// restartSinglePod restarts a single pod and returns the result
-func (h *PodRestartHandler) restartSinglePod(ctx context.Context, namespace, podName string) RestartResult {
+// restartResolvedPod restarts a single pod and returns the result
+func (h *PodRestartHandler) restartResolvedPod(ctx context.Context, pod *corev1.Pod, opts restartOptions) RestartResult {
+	namespace, podName := pod.Namespace, pod.Name
 	result := RestartResult{
-		Namespace: namespace,
-		Name:      podName,
-		Success:   false,
+		Namespace:          namespace,
+		Name:               podName,
+		Success:            false,
+		GracePeriodSeconds: opts.gracePeriodSeconds,
+		PropagationPolicy:  opts.propagationPolicy,
+		Warning:            h.restartWarning(ctx, pod),
+	}
+	if result.Warning != "" && opts.strict {
+		result.Error = "Restart refused: " + result.Warning
+		klog.Warningf("Refused to restart pod %s/%s in strict mode: %s", namespace, podName, result.Warning)
+		return result
 	}
 
-	// Check if the pod exists
-	pod, err := h.client.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
-	if err != nil {
-		result.Error = fmt.Sprintf("Pod not found: %v", err)
-		klog.Errorf("Failed to get pod %s/%s: %v", namespace, podName, err)
+	if err := h.restart(ctx, pod, opts); err != nil {
+		if skipped, ok := err.(*errRestartSkipped); ok {
+			result.Success = true
+			result.Skipped = true
+			result.Reason = skipped.reason
+			klog.Infof("Skipped restart of pod %s/%s: %v", namespace, podName, skipped)
+			return result
+		}
+		if refused, ok := err.(*errRestartRefused); ok {
+			result.Error = refused.Error()
+			klog.Warningf("Refused to restart pod %s/%s: %v", namespace, podName, refused)
+			return result
+		}
+		result.Error = fmt.Sprintf("Failed to restart pod: %v", err)
+		if blocked, ok := err.(*errEvictionBlocked); ok {
+			result.StatusCode = http.StatusTooManyRequests
+			result.BlockedBy = blocked.blockedBy
+		}
+		klog.Errorf("Failed to restart pod %s/%s: %v", namespace, podName, err)
 		return result
 	}
 
-	// Check if pod is managed by a controller
-	if len(pod.OwnerReferences) == 0 {
-		klog.Warningf("Pod %s/%s has no owner references, deleting directly", namespace, podName)
+	result.Success = true
+	klog.Infof("Successfully triggered restart for pod %s/%s", namespace, podName)
+	return result
+}
+
+// restart deletes or, with evict, evicts a pod. Pods that would not come
+// back return an *errRestartRefused, a blocked eviction returns an
+// *errEvictionBlocked. Terminating pods return an *errRestartSkipped unless
+// force and a zero grace period were given to kill a stuck pod.
+func (h *PodRestartHandler) restart(ctx context.Context, pod *corev1.Pod, opts restartOptions) error {
+	if pod.DeletionTimestamp != nil {
+		if !opts.force || opts.gracePeriodSeconds == nil || *opts.gracePeriodSeconds != 0 {
+			return &errRestartSkipped{reason: "already terminating"}
+		}
+		klog.Warningf("Pod %s/%s is already terminating, forcing immediate deletion", pod.Namespace, pod.Name)
+	}
+
+	// Deleting a mirror pod does nothing, the kubelet recreates it
+	if _, ok := pod.Annotations[corev1.MirrorPodAnnotationKey]; ok {
+		return &errRestartRefused{reason: "pod is a static pod; restart it on its node"}
+	}
+	if metav1.GetControllerOf(pod) == nil {
+		if !opts.force {
+			return &errRestartRefused{reason: "pod has no controller; use force=true to delete permanently"}
+		}
+		klog.Warningf("Pod %s/%s has no controller, deleting it permanently", pod.Namespace, pod.Name)
+	}
+
+	if !opts.evict {
+		// Delete the pod to trigger restart
+		return h.client.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, *opts.deleteOptions())
 	}
 
-	// Delete the pod to trigger restart
-	deletePolicy := metav1.DeletePropagationForeground
-	err = h.client.CoreV1().Pods(namespace).Delete(ctx, podName, metav1.DeleteOptions{
-		PropagationPolicy: &deletePolicy,
+	err := h.client.PolicyV1().Evictions(pod.Namespace).Evict(ctx, &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+		},
+		DeleteOptions: opts.deleteOptions(),
 	})
+	if errors.IsTooManyRequests(err) {
+		return &errEvictionBlocked{err: err, blockedBy: h.blockingPDBs(ctx, pod)}
+	}
+	return err
+}
 
+// blockingPDBs returns the PodDisruptionBudgets selecting pod. Errors are
+// logged, the eviction error is reported either way.
+func (h *PodRestartHandler) blockingPDBs(ctx context.Context, pod *corev1.Pod) []PDBBlockInfo {
+	pdbs, err := h.client.PolicyV1().PodDisruptionBudgets(pod.Namespace).List(ctx, metav1.ListOptions{})
 	if err != nil {
-		result.Error = fmt.Sprintf("Failed to restart pod: %v", err)
-		klog.Errorf("Failed to delete pod %s/%s for restart: %v", namespace, podName, err)
-		return result
+		klog.Warningf("Failed to list PodDisruptionBudgets in %s: %v", pod.Namespace, err)
+		return nil
+	}
+	var blockedBy []PDBBlockInfo
+	for _, pdb := range pdbs.Items {
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil || selector.Empty() || !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		blockedBy = append(blockedBy, PDBBlockInfo{
+			Name:               pdb.Name,
+			DisruptionsAllowed: pdb.Status.DisruptionsAllowed,
+			CurrentHealthy:     pdb.Status.CurrentHealthy,
+			DesiredHealthy:     pdb.Status.DesiredHealthy,
+		})
 	}
+	return blockedBy
+}
 
-	result.Success = true
-	klog.Infof("Successfully triggered restart for pod %s/%s", namespace, podName)
-	return result
-}
\ No newline at end of file
+// logEvictionHint logs that deleting pods bypasses PodDisruptionBudgets when
+// the cluster serves the Eviction API
+func (h *PodRestartHandler) logEvictionHint(ctx context.Context) {
+	h.evictionCheck.Do(func() {
+		resources, err := h.client.Discovery().ServerResourcesForGroupVersion("v1")
+		if err != nil {
+			klog.V(2).Infof("Failed to discover the Eviction API: %v", err)
+			return
+		}
+		for _, resource := range resources.APIResources {
+			if resource.Name == "pods/eviction" {
+				h.evictionAvailable = true
+				break
+			}
+		}
+	})
+	if h.evictionAvailable {
+		klog.Infof("Restarting pods by deletion is deprecated as it bypasses PodDisruptionBudgets, pass evict=true to use the Eviction API")
+	}
+}
+
+func restartMethod(evict bool) string {
+	if evict {
+		return "evict"
+	}
+	return "delete"
+}
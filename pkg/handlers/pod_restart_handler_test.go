@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zxh326/kite/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+func TestRestartConcurrency(t *testing.T) {
+	tests := map[int]int{
+		0:                              defaultRestartConcurrency,
+		-3:                             defaultRestartConcurrency,
+		1:                              1,
+		30:                             30,
+		utils.MaxBatchConcurrency + 10: utils.MaxBatchConcurrency,
+	}
+	for requested, want := range tests {
+		if got := restartConcurrency(requested); got != want {
+			t.Errorf("restartConcurrency(%d) = %d, want %d", requested, got, want)
+		}
+	}
+}
+
+func TestBatchRestartResponseStatus(t *testing.T) {
+	terminating := RestartResult{Name: "terminating", Success: true, Skipped: true, Reason: "already terminating"}
+	tests := []struct {
+		name    string
+		results []RestartResult
+		want    int
+	}{
+		{name: "all restarted", results: []RestartResult{{Name: "a", Success: true}}, want: http.StatusOK},
+		{name: "already terminating", results: []RestartResult{{Name: "a", Success: true}, terminating}, want: http.StatusOK},
+		{name: "failed", results: []RestartResult{{Name: "a", Success: true}, {Name: "b", Error: "boom"}}, want: http.StatusPartialContent},
+		{name: "refused", results: []RestartResult{{Name: "a", Skipped: true, Error: "protected namespace"}}, want: http.StatusPartialContent},
+		{name: "blocked eviction", results: []RestartResult{{Name: "a", Error: "blocked", StatusCode: http.StatusTooManyRequests}}, want: http.StatusPartialContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := batchRestartResponse(tt.results, nil); got != tt.want {
+				t.Errorf("batchRestartResponse() status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// deleteGate holds pod deletes until they are released and records how many
+// were in flight at the same time. The fake clientset runs its reactors under
+// a lock, so the deletes are held before they reach it.
+type deleteGate struct {
+	inFlight, peak, total atomic.Int32
+	started               chan struct{}
+	release               chan struct{}
+}
+
+func newDeleteGate(n int) *deleteGate {
+	return &deleteGate{started: make(chan struct{}, n), release: make(chan struct{})}
+}
+
+func (g *deleteGate) hold() {
+	current := g.inFlight.Add(1)
+	for {
+		seen := g.peak.Load()
+		if current <= seen || g.peak.CompareAndSwap(seen, current) {
+			break
+		}
+	}
+	g.total.Add(1)
+	g.started <- struct{}{}
+	<-g.release
+	g.inFlight.Add(-1)
+}
+
+func (g *deleteGate) waitStarted(t *testing.T) {
+	t.Helper()
+	select {
+	case <-g.started:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("timed out waiting for a delete, %d started", g.total.Load())
+	}
+}
+
+type gatedClientset struct {
+	*fake.Clientset
+	gate *deleteGate
+}
+
+func (c *gatedClientset) CoreV1() corev1client.CoreV1Interface {
+	return &gatedCoreV1{CoreV1Interface: c.Clientset.CoreV1(), gate: c.gate}
+}
+
+type gatedCoreV1 struct {
+	corev1client.CoreV1Interface
+	gate *deleteGate
+}
+
+func (c *gatedCoreV1) Pods(namespace string) corev1client.PodInterface {
+	return &gatedPods{PodInterface: c.CoreV1Interface.Pods(namespace), gate: c.gate}
+}
+
+type gatedPods struct {
+	corev1client.PodInterface
+	gate *deleteGate
+}
+
+func (p *gatedPods) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	p.gate.hold()
+	return p.PodInterface.Delete(ctx, name, opts)
+}
+
+// gatedRestartHandler returns a restart handler over n bare pods, the
+// request forces their deletion
+func gatedRestartHandler(t *testing.T, n int) (*PodRestartHandler, *deleteGate, *BatchRestartRequest, restartOptions) {
+	t.Helper()
+	var objects []runtime.Object
+	req := &BatchRestartRequest{}
+	for i := range n {
+		name := fmt.Sprintf("worker-%d", i)
+		objects = append(objects, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: name}})
+		req.Pods = append(req.Pods, PodIdentifier{Namespace: "apps", Name: name})
+	}
+	opts, err := newRestartOptions(false, true, nil, "")
+	if err != nil {
+		t.Fatalf("newRestartOptions: %v", err)
+	}
+	gate := newDeleteGate(n)
+	return NewPodRestartHandler(&gatedClientset{Clientset: fake.NewSimpleClientset(objects...), gate: gate}), gate, req, opts
+}
+
+func TestRunBatchRestartConcurrencyCeiling(t *testing.T) {
+	const n, ceiling = 12, 3
+	handler, gate, req, opts := gatedRestartHandler(t, n)
+
+	done := make(chan []RestartResult)
+	go func() {
+		results, _ := handler.runBatchRestart(context.Background(), req, opts, "", ceiling, defaultMaxUnavailablePerOwner, nil)
+		done <- results
+	}()
+	// Release a delete each time the ceiling is reached, then the rest
+	for i := range n {
+		gate.waitStarted(t)
+		if i >= ceiling-1 {
+			gate.release <- struct{}{}
+		}
+	}
+	for range ceiling - 1 {
+		gate.release <- struct{}{}
+	}
+	results := <-done
+
+	if peak := gate.peak.Load(); peak != ceiling {
+		t.Errorf("%d deletes in flight at the same time, want %d", peak, ceiling)
+	}
+	for _, result := range results {
+		if !result.Success {
+			t.Errorf("pod %s: %s", result.Name, result.Error)
+		}
+	}
+}
+
+func TestRunBatchRestartCancelled(t *testing.T) {
+	const n, ceiling = 10, 2
+	handler, gate, req, opts := gatedRestartHandler(t, n)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan []RestartResult)
+	go func() {
+		results, _ := handler.runBatchRestart(ctx, req, opts, "", ceiling, defaultMaxUnavailablePerOwner, nil)
+		done <- results
+	}()
+	for range ceiling {
+		gate.waitStarted(t)
+	}
+	cancel()
+	for range ceiling {
+		gate.release <- struct{}{}
+	}
+	results := <-done
+
+	if total := gate.total.Load(); total != ceiling {
+		t.Errorf("%d pods deleted after the batch was cancelled, want only the %d in flight", total, ceiling)
+	}
+	var restarted, notProcessed int
+	for _, result := range results {
+		switch {
+		case result.Success:
+			restarted++
+		case strings.HasPrefix(result.Error, "Not processed"):
+			notProcessed++
+		default:
+			t.Errorf("pod %s: %s", result.Name, result.Error)
+		}
+	}
+	if restarted != ceiling || notProcessed != n-ceiling {
+		t.Errorf("%d restarted and %d not processed, want %d and %d", restarted, notProcessed, ceiling, n-ceiling)
+	}
+}
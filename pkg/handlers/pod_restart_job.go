@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/utils"
+)
+
+const (
+	// maxRunningRestartJobs caps how many async batch restarts run at the
+	// same time, further requests are rejected with 429
+	maxRunningRestartJobs = 5
+	// restartJobTTL is how long finished restart jobs are kept for polling
+	restartJobTTL = 30 * time.Minute
+)
+
+// Restart job phases
+const (
+	RestartJobRunning   = "running"
+	RestartJobDone      = "done"
+	RestartJobCancelled = "cancelled"
+)
+
+// Progress of each pod of a restart job
+const (
+	restartQueued = iota
+	restartRunning
+	restartDone
+)
+
+// restartProgress is told when the restart of a pod of a batch starts and
+// when its result is final
+type restartProgress interface {
+	restartStarted(i int)
+	restartFinished(i int, result RestartResult)
+}
+
+// restartJob is an async batch restart
+type restartJob struct {
+	ID        string
+	ctx       context.Context
+	cancel    context.CancelFunc
+	createdAt time.Time
+
+	mu         sync.Mutex
+	phase      string
+	state      []int
+	results    []*RestartResult
+	groups     []RestartGroup
+	finishedAt *time.Time
+}
+
+// RestartJobStatus is a snapshot of an async batch restart. Results only
+// holds the pods that are done.
+type RestartJobStatus struct {
+	ID         string          `json:"id"`
+	Phase      string          `json:"phase"`
+	Total      int             `json:"total"`
+	Queued     int             `json:"queued"`
+	Running    int             `json:"running"`
+	Done       int             `json:"done"`
+	Successful int             `json:"successful"`
+	Failed     int             `json:"failed"`
+	Skipped    int             `json:"skipped"`
+	Results    []RestartResult `json:"results"`
+	Groups     []RestartGroup  `json:"groups,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	FinishedAt *time.Time      `json:"finishedAt,omitempty"`
+}
+
+func (j *restartJob) restartStarted(i int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.state[i] = restartRunning
+}
+
+func (j *restartJob) restartFinished(i int, result RestartResult) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.state[i] = restartDone
+	j.results[i] = &result
+}
+
+// finish records the final results once the batch has returned
+func (j *restartJob) finish(results []RestartResult, groups []RestartGroup) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for i := range results {
+		j.state[i] = restartDone
+		j.results[i] = &results[i]
+	}
+	j.groups = groups
+	j.phase = RestartJobDone
+	if j.ctx.Err() != nil {
+		j.phase = RestartJobCancelled
+	}
+	now := time.Now()
+	j.finishedAt = &now
+	j.cancel()
+}
+
+// Status returns a consistent snapshot of the job
+func (j *restartJob) Status() RestartJobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	status := RestartJobStatus{
+		ID:         j.ID,
+		Phase:      j.phase,
+		Total:      len(j.state),
+		Results:    []RestartResult{},
+		Groups:     j.groups,
+		CreatedAt:  j.createdAt,
+		FinishedAt: j.finishedAt,
+	}
+	for i, state := range j.state {
+		switch state {
+		case restartQueued:
+			status.Queued++
+		case restartRunning:
+			status.Running++
+		case restartDone:
+			status.Done++
+			result := *j.results[i]
+			status.Results = append(status.Results, result)
+			switch {
+			case result.Skipped:
+				status.Skipped++
+			case result.Success:
+				status.Successful++
+			default:
+				status.Failed++
+			}
+		}
+	}
+	return status
+}
+
+// restartJobStore keeps async batch restarts in memory. Finished jobs are
+// dropped after restartJobTTL.
+type restartJobStore struct {
+	mu   sync.Mutex
+	jobs map[string]*restartJob
+}
+
+func newRestartJobStore() *restartJobStore {
+	return &restartJobStore{jobs: make(map[string]*restartJob)}
+}
+
+// start registers a job for n pods unless maxRunningRestartJobs are
+// running. The job's context doesn't derive from the request that started
+// it.
+func (s *restartJobStore) start(n int) (*restartJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireLocked()
+
+	running := 0
+	for _, job := range s.jobs {
+		job.mu.Lock()
+		if job.finishedAt == nil {
+			running++
+		}
+		job.mu.Unlock()
+	}
+	if running >= maxRunningRestartJobs {
+		return nil, fmt.Errorf("%d batch restart jobs are already running, try again later", running)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &restartJob{
+		ID:        "restart-" + utils.RandomString(8),
+		ctx:       ctx,
+		cancel:    cancel,
+		createdAt: time.Now(),
+		phase:     RestartJobRunning,
+		state:     make([]int, n),
+		results:   make([]*RestartResult, n),
+	}
+	s.jobs[job.ID] = job
+	return job, nil
+}
+
+func (s *restartJobStore) get(id string) (*restartJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireLocked()
+	job, ok := s.jobs[id]
+	return job, ok
+}
+
+func (s *restartJobStore) expireLocked() {
+	for id, job := range s.jobs {
+		job.mu.Lock()
+		expired := job.finishedAt != nil && time.Since(*job.finishedAt) > restartJobTTL
+		job.mu.Unlock()
+		if expired {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// GetRestartJob returns the progress and the results so far of an async
+// batch restart
+func (h *PodRestartHandler) GetRestartJob(c *gin.Context) {
+	job, ok := h.jobs.get(c.Param("jobID"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Restart job not found"})
+		return
+	}
+	c.JSON(http.StatusOK, job.Status())
+}
+
+// CancelRestartJob stops an async batch restart, pods that were not started
+// yet are not restarted
+func (h *PodRestartHandler) CancelRestartJob(c *gin.Context) {
+	job, ok := h.jobs.get(c.Param("jobID"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Restart job not found"})
+		return
+	}
+	job.cancel()
+	c.JSON(http.StatusOK, job.Status())
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/common"
+	"github.com/zxh326/kite/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+// restartOwnerKinds are the kinds RestartPodsByOwner can resolve
+var restartOwnerKinds = []string{"Deployment", "ReplicaSet", "StatefulSet", "DaemonSet"}
+
+// RestartByOwnerRequest represents the request body for restarting the pods
+// of a controller
+type RestartByOwnerRequest struct {
+	Namespace string `json:"namespace" binding:"required"`
+	Kind      string `json:"kind" binding:"required"`
+	Name      string `json:"name" binding:"required"`
+	// MaxUnavailable bounds how many pods are restarted at the same time,
+	// defaults to defaultMaxUnavailablePerOwner, capped at
+	// utils.MaxBatchConcurrency
+	MaxUnavailable int  `json:"maxUnavailable,omitempty" binding:"min=0"`
+	Evict          bool `json:"evict,omitempty"`
+	// ConfirmProtected works as for BatchRestartRequest
+	ConfirmProtected bool `json:"confirmProtected,omitempty"`
+}
+
+// RestartPodsByOwner restarts all pods of a Deployment, ReplicaSet,
+// StatefulSet or DaemonSet without rolling out a new revision. Pods are
+// matched by their controller's UID rather than by labels, a Deployment's
+// pods are those of its current ReplicaSet. The pods are staggered as in
+// RestartPodsBatch.
+func (h *PodRestartHandler) RestartPodsByOwner(c *gin.Context) {
+	var req RestartByOwnerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request body: %v", err)})
+		return
+	}
+	if err := common.CheckProtectedNamespace(req.Namespace, utils.ActingUser(c), req.ConfirmProtected); err != nil {
+		klog.Warningf("Refused to restart pods of %s %s/%s: %v", req.Kind, req.Namespace, req.Name, err)
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "namespace": req.Namespace})
+		return
+	}
+	opts, err := newRestartOptions(req.Evict, false, nil, "")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	perOwner := defaultMaxUnavailablePerOwner
+	if req.MaxUnavailable > 0 {
+		perOwner = utils.BatchConcurrency(req.MaxUnavailable)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	ownerUID, err := h.resolveRestartOwner(ctx, req.Namespace, req.Kind, req.Name)
+	if err != nil {
+		switch {
+		case errors.IsNotFound(err):
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("%s not found: %v", req.Kind, err)})
+		case errors.IsBadRequest(err):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "supportedKinds": restartOwnerKinds})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to resolve %s: %v", req.Kind, err)})
+		}
+		return
+	}
+
+	podList, err := h.client.CoreV1().Pods(req.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to list pods: %v", err)})
+		return
+	}
+	owner := req.Kind + "/" + req.Name
+	var targets []restartTarget
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if controller := metav1.GetControllerOf(pod); controller == nil || controller.UID != ownerUID {
+			continue
+		}
+		targets = append(targets, restartTarget{pod: pod, owner: owner, opts: opts})
+	}
+	if len(targets) == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"message": fmt.Sprintf("%s %s has no pods to restart", req.Kind, req.Name),
+			"total":   0,
+			"results": []RestartResult{},
+		})
+		return
+	}
+
+	klog.Infof("Restarting %d pods of %s %s/%s", len(targets), req.Kind, req.Namespace, req.Name)
+	if !req.Evict {
+		h.logEvictionHint(ctx)
+	}
+
+	groups, members := groupRestartTargets(targets)
+	restartCtx, cancelRestart := context.WithTimeout(c.Request.Context(), 2*time.Minute+staggerTimeout(members, perOwner))
+	defer cancelRestart()
+	// All pods share one owner, perOwner bounds the whole restart
+	h.restartGroups(restartCtx, targets, groups, members, perOwner, perOwner, nil)
+
+	results := make([]RestartResult, len(targets))
+	for i, target := range targets {
+		results[i] = *target.result
+	}
+	status, response := batchRestartResponse(results, groups)
+	c.JSON(status, response)
+}
+
+// resolveRestartOwner returns the UID that the controller references of the
+// pods to restart point to. For a Deployment that is its current ReplicaSet,
+// the one with the Deployment's revision.
+func (h *PodRestartHandler) resolveRestartOwner(ctx context.Context, namespace, kind, name string) (types.UID, error) {
+	switch strings.ToLower(kind) {
+	case "deployment":
+		deployment, err := h.client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		revision := deployment.Annotations["deployment.kubernetes.io/revision"]
+		replicaSets, err := h.client.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return "", err
+		}
+		for _, rs := range replicaSets.Items {
+			if controller := metav1.GetControllerOf(&rs); controller == nil || controller.UID != deployment.UID {
+				continue
+			}
+			if revision != "" && rs.Annotations["deployment.kubernetes.io/revision"] == revision {
+				return rs.UID, nil
+			}
+		}
+		return "", errors.NewNotFound(corev1.Resource("replicasets"), fmt.Sprintf("current ReplicaSet of deployment %s", name))
+	case "replicaset":
+		rs, err := h.client.AppsV1().ReplicaSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		return rs.UID, nil
+	case "statefulset":
+		sts, err := h.client.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		return sts.UID, nil
+	case "daemonset":
+		ds, err := h.client.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		return ds.UID, nil
+	}
+	return "", errors.NewBadRequest(fmt.Sprintf("unsupported kind %s, must be one of %s", kind, strings.Join(restartOwnerKinds, ", ")))
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// restartWarning explains why restarting a pod may hurt availability: its
+// workload has a single replica or unavailable replicas already, or a
+// PodDisruptionBudget allows no disruption. ReplicaSets are followed to
+// their Deployment. It returns "" when nothing was found.
+func (h *PodRestartHandler) restartWarning(ctx context.Context, pod *corev1.Pod) string {
+	if pod.DeletionTimestamp != nil {
+		return ""
+	}
+
+	var warnings []string
+	if workload, err := h.podWorkload(ctx, pod); err != nil {
+		klog.V(2).Infof("Failed to get the workload of pod %s/%s: %v", pod.Namespace, pod.Name, err)
+	} else if workload != nil {
+		if workload.replicas != nil && *workload.replicas <= 1 {
+			warnings = append(warnings, fmt.Sprintf("%s %s has %d replica, restarting its pod causes downtime", workload.kind, workload.name, *workload.replicas))
+		}
+		if workload.unavailable > 0 {
+			warnings = append(warnings, fmt.Sprintf("%s %s already has %d unavailable replicas", workload.kind, workload.name, workload.unavailable))
+		}
+	}
+	for _, pdb := range h.blockingPDBs(ctx, pod) {
+		if pdb.DisruptionsAllowed < 1 {
+			warnings = append(warnings, fmt.Sprintf("PodDisruptionBudget %s allows no disruption (%d/%d healthy)", pdb.Name, pdb.CurrentHealthy, pdb.DesiredHealthy))
+		}
+	}
+	return strings.Join(warnings, "; ")
+}
+
+// podWorkloadStatus is the availability of the workload owning a pod
+type podWorkloadStatus struct {
+	kind string
+	name string
+	// replicas is nil for DaemonSets, which run one pod per node
+	replicas    *int32
+	unavailable int32
+}
+
+// podWorkload returns the availability of the workload owning a pod, nil for
+// pods without a known controller
+func (h *PodRestartHandler) podWorkload(ctx context.Context, pod *corev1.Pod) (*podWorkloadStatus, error) {
+	controller := metav1.GetControllerOf(pod)
+	if controller == nil {
+		return nil, nil
+	}
+	apps := h.client.AppsV1()
+	switch controller.Kind {
+	case "ReplicaSet":
+		rs, err := apps.ReplicaSets(pod.Namespace).Get(ctx, controller.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		if owner := metav1.GetControllerOf(rs); owner != nil && owner.Kind == "Deployment" {
+			deployment, err := apps.Deployments(pod.Namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+			replicas := replicasOrDefault(deployment.Spec.Replicas)
+			return &podWorkloadStatus{"Deployment", deployment.Name, &replicas, deployment.Status.UnavailableReplicas}, nil
+		}
+		replicas := replicasOrDefault(rs.Spec.Replicas)
+		return &podWorkloadStatus{"ReplicaSet", rs.Name, &replicas, max(replicas-rs.Status.AvailableReplicas, 0)}, nil
+	case "StatefulSet":
+		sts, err := apps.StatefulSets(pod.Namespace).Get(ctx, controller.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		replicas := replicasOrDefault(sts.Spec.Replicas)
+		return &podWorkloadStatus{"StatefulSet", sts.Name, &replicas, max(replicas-sts.Status.AvailableReplicas, 0)}, nil
+	case "DaemonSet":
+		ds, err := apps.DaemonSets(pod.Namespace).Get(ctx, controller.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &podWorkloadStatus{kind: "DaemonSet", name: ds.Name, unavailable: ds.Status.NumberUnavailable}, nil
+	}
+	return nil, nil
+}
+
+// replicasOrDefault dereferences spec.replicas, which defaults to 1
+func replicasOrDefault(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/zxh326/kite/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// defaultMaxUnavailablePerOwner is how many pods of the same owner a batch
+// restarts at the same time unless the request asks otherwise
+const defaultMaxUnavailablePerOwner = 1
+
+// RestartGroup is the pods of one owner in a batch restart, in the order
+// they were restarted
+type RestartGroup struct {
+	Namespace string   `json:"namespace"`
+	Owner     string   `json:"owner"`
+	Pods      []string `json:"pods"`
+}
+
+// restartTarget is a pod of a batch restart, result is set once it is done
+type restartTarget struct {
+	pod    *corev1.Pod
+	owner  string
+	opts   restartOptions
+	result *RestartResult
+}
+
+// podOwner returns the workload owning a pod as Kind/name, following a
+// ReplicaSet to its Deployment. Pods without a controller return "".
+func (h *PodRestartHandler) podOwner(ctx context.Context, pod *corev1.Pod) string {
+	controller := metav1.GetControllerOf(pod)
+	if controller == nil {
+		return ""
+	}
+	if controller.Kind == "ReplicaSet" {
+		rs, err := h.client.AppsV1().ReplicaSets(pod.Namespace).Get(ctx, controller.Name, metav1.GetOptions{})
+		if err != nil {
+			klog.V(2).Infof("Failed to get ReplicaSet %s/%s: %v", pod.Namespace, controller.Name, err)
+		} else if owner := metav1.GetControllerOf(rs); owner != nil {
+			return owner.Kind + "/" + owner.Name
+		}
+	}
+	return controller.Kind + "/" + controller.Name
+}
+
+// groupRestartTargets groups the pending targets by namespace and owner in
+// request order. Pods without a controller are a group of their own.
+func groupRestartTargets(targets []restartTarget) ([]RestartGroup, [][]int) {
+	groups := []RestartGroup{}
+	var members [][]int
+	index := make(map[string]int)
+	for i, target := range targets {
+		if target.result != nil {
+			continue
+		}
+		owner := target.owner
+		if owner == "" {
+			owner = "Pod/" + target.pod.Name
+		}
+		key := target.pod.Namespace + "/" + owner
+		g, ok := index[key]
+		if !ok {
+			g = len(groups)
+			index[key] = g
+			groups = append(groups, RestartGroup{Namespace: target.pod.Namespace, Owner: owner, Pods: []string{}})
+			members = append(members, nil)
+		}
+		members[g] = append(members[g], i)
+	}
+	return groups, members
+}
+
+// staggerTimeout returns how long restarting the groups may take when each
+// restart waits for its replacement before the next pod of the group and the
+// last replacements are looked up
+func staggerTimeout(members [][]int, perOwner int) time.Duration {
+	steps := 0
+	for _, group := range members {
+		steps = max(steps, (len(group)-1+perOwner-1)/perOwner)
+	}
+	return time.Duration(steps)*defaultRestartWaitTimeout + replacementObserveTimeout
+}
+
+// restartGroups restarts the groups in parallel, at most concurrency at a
+// time. Within a group at most perOwner pods are restarted at the same time
+// and each restart waits for the replacement pod to be Ready before the next
+// pod is started. When a replacement doesn't become Ready the rest of the
+// group is left alone. Across all groups at most concurrency pods are
+// restarted at the same time.
+func (h *PodRestartHandler) restartGroups(ctx context.Context, targets []restartTarget, groups []RestartGroup, members [][]int, concurrency, perOwner int, progress restartProgress) {
+	slots := make(chan struct{}, concurrency)
+	notProcessed := func(g, i int, err error) struct{} {
+		targets[i].result = &RestartResult{
+			Namespace: targets[i].pod.Namespace,
+			Name:      targets[i].pod.Name,
+			Error:     fmt.Sprintf("Not processed: %v", err),
+			Owner:     groups[g].Owner,
+		}
+		if progress != nil {
+			progress.restartFinished(i, *targets[i].result)
+		}
+		return struct{}{}
+	}
+
+	utils.RunBatch(ctx, len(groups), concurrency, func(ctx context.Context, g int) struct{} {
+		group := members[g]
+		ctx, cancel := context.WithCancelCause(ctx)
+		defer cancel(nil)
+
+		var order atomic.Int32
+		utils.RunBatch(ctx, len(group), perOwner, func(ctx context.Context, m int) struct{} {
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+			}
+			if ctx.Err() != nil {
+				return notProcessed(g, group[m], context.Cause(ctx))
+			}
+			target := &targets[group[m]]
+			if progress != nil {
+				progress.restartStarted(group[m])
+				defer func() { progress.restartFinished(group[m], *target.result) }()
+			}
+			requestedAt := time.Now()
+			result := h.restartResolvedPod(ctx, target.pod, target.opts)
+			result.Owner = groups[g].Owner
+			result.Order = int(order.Add(1))
+			target.result = &result
+
+			controller := metav1.GetControllerOf(target.pod)
+			if !result.Success || controller == nil || target.opts.dryRun {
+				return struct{}{}
+			}
+			// Every pod but the last of a group waits for its replacement
+			// to be Ready, the others only look it up briefly
+			owner := podController(target.pod)
+			if owner != nil && m < len(group)-1 {
+				err := h.waitForRestart(ctx, target.pod, owner, requestedAt, &result)
+				result.setReplacement(result.Replacement)
+				if err != nil {
+					cancel(fmt.Errorf("replacement of pod %s is not ready: %w", target.pod.Name, err))
+				}
+				return struct{}{}
+			}
+			if owner != nil || controller.Kind == "Job" {
+				deletedAt := h.deletionTime(ctx, target.pod, requestedAt)
+				result.setReplacement(h.observeReplacement(ctx, target.pod, controller, deletedAt))
+			}
+			return struct{}{}
+		}, func(m int, err error) struct{} {
+			return notProcessed(g, group[m], context.Cause(ctx))
+		})
+		return struct{}{}
+	}, func(g int, err error) struct{} {
+		for _, i := range members[g] {
+			notProcessed(g, i, err)
+		}
+		return struct{}{}
+	})
+
+	// List the pods of each group in restart order
+	for g, group := range members {
+		ordered := append([]int{}, group...)
+		sort.SliceStable(ordered, func(a, b int) bool {
+			return restartOrder(targets[ordered[a]].result) < restartOrder(targets[ordered[b]].result)
+		})
+		for _, i := range ordered {
+			if targets[i].result.Order > 0 {
+				groups[g].Pods = append(groups[g].Pods, targets[i].pod.Name)
+			}
+		}
+	}
+}
+
+// waitForRestart waits for the replacement of a restarted pod and records
+// it in result
+func (h *PodRestartHandler) waitForRestart(ctx context.Context, pod *corev1.Pod, owner *metav1.OwnerReference, requestedAt time.Time, result *RestartResult) error {
+	selector, err := h.ownerSelector(ctx, pod.Namespace, owner)
+	if err != nil {
+		result.WaitError = fmt.Sprintf("Failed to get %s %s: %v", owner.Kind, owner.Name, err)
+		return err
+	}
+	deletedAt := h.deletionTime(ctx, pod, requestedAt)
+	replacement, err := h.waitForReplacement(ctx, pod, owner, selector, deletedAt, defaultRestartWaitTimeout)
+	result.Replacement = replacement
+	if err != nil {
+		result.WaitError = err.Error()
+		klog.Warningf("Batch restart of pod %s/%s: %v", pod.Namespace, pod.Name, err)
+	}
+	return err
+}
+
+// restartOrder sorts pods that were never started last
+func restartOrder(result *RestartResult) int {
+	if result == nil || result.Order == 0 {
+		return int(^uint(0) >> 1)
+	}
+	return result.Order
+}
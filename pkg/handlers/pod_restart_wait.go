@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/zxh326/kite/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// defaultRestartWaitTimeout is how long ?wait=true waits for the
+	// replacement pod unless ?timeoutSeconds= is set
+	defaultRestartWaitTimeout = 2 * time.Minute
+	// maxRestartWaitTimeout caps ?timeoutSeconds=
+	maxRestartWaitTimeout = 10 * time.Minute
+	// replacementObserveTimeout bounds how long batch restarts look for the
+	// replacement of a pod they don't wait for
+	replacementObserveTimeout = 15 * time.Second
+)
+
+// ReplacementPod is the pod a controller created for a restarted pod
+type ReplacementPod struct {
+	Name      string          `json:"name"`
+	UID       types.UID       `json:"uid"`
+	Node      string          `json:"node"`
+	Phase     corev1.PodPhase `json:"phase"`
+	Ready     bool            `json:"ready"`
+	CreatedAt metav1.Time     `json:"createdAt"`
+	// TimeToReadySeconds is from the deletion of the old pod until the
+	// replacement became Ready
+	TimeToReadySeconds *float64 `json:"timeToReadySeconds,omitempty"`
+	Message            string   `json:"message,omitempty"`
+}
+
+// podController returns the controller of a pod if it is a ReplicaSet,
+// StatefulSet or DaemonSet
+func podController(pod *corev1.Pod) *metav1.OwnerReference {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return nil
+	}
+	switch owner.Kind {
+	case "ReplicaSet", "StatefulSet", "DaemonSet":
+		return owner
+	}
+	return nil
+}
+
+// deletionTime returns when the pod was deleted according to the API
+// server, falling back to requestedAt when the pod is already gone
+func (h *PodRestartHandler) deletionTime(ctx context.Context, pod *corev1.Pod, requestedAt time.Time) time.Time {
+	current, err := h.client.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+	if err != nil || current.UID != pod.UID || current.DeletionTimestamp == nil {
+		return requestedAt
+	}
+	// The deletion timestamp is set to the end of the grace period
+	deletedAt := current.DeletionTimestamp.Time
+	if current.DeletionGracePeriodSeconds != nil {
+		deletedAt = deletedAt.Add(-time.Duration(*current.DeletionGracePeriodSeconds) * time.Second)
+	}
+	return deletedAt
+}
+
+// ownerSelector returns the pod selector of a pod's controller
+func (h *PodRestartHandler) ownerSelector(ctx context.Context, namespace string, owner *metav1.OwnerReference) (labels.Selector, error) {
+	var selector *metav1.LabelSelector
+	switch owner.Kind {
+	case "ReplicaSet":
+		rs, err := h.client.AppsV1().ReplicaSets(namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		selector = rs.Spec.Selector
+	case "StatefulSet":
+		sts, err := h.client.AppsV1().StatefulSets(namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		selector = sts.Spec.Selector
+	case "DaemonSet":
+		ds, err := h.client.AppsV1().DaemonSets(namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		selector = ds.Spec.Selector
+	case "Job":
+		job, err := h.client.BatchV1().Jobs(namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		selector = job.Spec.Selector
+	default:
+		return nil, fmt.Errorf("unsupported owner kind %s", owner.Kind)
+	}
+	return metav1.LabelSelectorAsSelector(selector)
+}
+
+// waitForReplacement watches the pods of the old pod's controller, matched by
+// selector, until one created after deletedAt is Ready. On timeout the last
+// observed replacement, if any, is returned along with the error.
+func (h *PodRestartHandler) waitForReplacement(ctx context.Context, old *corev1.Pod, owner *metav1.OwnerReference, selector labels.Selector, deletedAt time.Time, timeout time.Duration) (*ReplacementPod, error) {
+	ready, last, err := h.watchReplacement(ctx, old, owner, selector, deletedAt, timeout, utils.IsPodReady)
+	if ready != nil {
+		return replacementPod(ready, deletedAt), nil
+	}
+	if err != nil {
+		return replacementPod(last, deletedAt), err
+	}
+	if last == nil {
+		return nil, fmt.Errorf("no replacement pod was created within %s", timeout)
+	}
+	return replacementPod(last, deletedAt), fmt.Errorf("replacement pod %s not ready within %s", last.Name, timeout)
+}
+
+// observeReplacement briefly watches for the replacement of a restarted pod,
+// preferring one that is already scheduled. It returns nil when no
+// replacement showed up in time.
+func (h *PodRestartHandler) observeReplacement(ctx context.Context, old *corev1.Pod, owner *metav1.OwnerReference, deletedAt time.Time) *ReplacementPod {
+	selector, err := h.ownerSelector(ctx, old.Namespace, owner)
+	if err != nil {
+		klog.V(2).Infof("Failed to get %s %s/%s: %v", owner.Kind, old.Namespace, owner.Name, err)
+		return nil
+	}
+	scheduled, last, err := h.watchReplacement(ctx, old, owner, selector, deletedAt, replacementObserveTimeout, func(pod *corev1.Pod) bool {
+		return pod.Spec.NodeName != ""
+	})
+	if err != nil {
+		klog.V(2).Infof("Failed to watch the replacement of pod %s/%s: %v", old.Namespace, old.Name, err)
+	}
+	if scheduled != nil {
+		return replacementPod(scheduled, deletedAt)
+	}
+	return replacementPod(last, deletedAt)
+}
+
+// watchReplacement watches for a pod of owner created after deletedAt for
+// which done returns true. StatefulSet pods come back under the same name
+// and are told apart by UID. last is the latest replacement seen, also when
+// none was done before the timeout.
+func (h *PodRestartHandler) watchReplacement(ctx context.Context, old *corev1.Pod, owner *metav1.OwnerReference, selector labels.Selector, deletedAt time.Time, timeout time.Duration, done func(*corev1.Pod) bool) (match, last *corev1.Pod, err error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	opts := metav1.ListOptions{LabelSelector: selector.String()}
+	if owner.Kind == "StatefulSet" {
+		opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", old.Name).String()
+	}
+	// Creation timestamps only have second precision
+	createdAfter := deletedAt.Truncate(time.Second)
+
+	pods := h.client.CoreV1().Pods(old.Namespace)
+	for {
+		// Without a resourceVersion the watch starts with the current state
+		watcher, err := pods.Watch(ctx, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, last, nil
+			}
+			return nil, last, fmt.Errorf("failed to watch pods: %w", err)
+		}
+
+		found := func() *corev1.Pod {
+			defer watcher.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case event, ok := <-watcher.ResultChan():
+					if !ok {
+						// The watch expired, start a new one
+						return nil
+					}
+					if event.Type == watch.Error {
+						// Start a new watch
+						return nil
+					}
+					if event.Type == watch.Deleted {
+						continue
+					}
+					pod, ok := event.Object.(*corev1.Pod)
+					if !ok || pod.UID == old.UID || pod.CreationTimestamp.Time.Before(createdAfter) {
+						continue
+					}
+					if controller := metav1.GetControllerOf(pod); controller == nil || controller.UID != owner.UID {
+						continue
+					}
+					last = pod
+					if done(pod) {
+						return pod
+					}
+				}
+			}
+		}()
+		if found != nil {
+			return found, found, nil
+		}
+		if ctx.Err() != nil {
+			return nil, last, nil
+		}
+	}
+}
+
+func replacementPod(pod *corev1.Pod, deletedAt time.Time) *ReplacementPod {
+	if pod == nil {
+		return nil
+	}
+	replacement := &ReplacementPod{
+		Name:      pod.Name,
+		UID:       pod.UID,
+		Node:      pod.Spec.NodeName,
+		Phase:     pod.Status.Phase,
+		Ready:     utils.IsPodReady(pod),
+		CreatedAt: pod.CreationTimestamp,
+		Message:   utils.GetPodErrorMessage(pod),
+	}
+	if readyAt, ok := podConditionTime(pod, corev1.PodReady); ok && replacement.Ready {
+		seconds := max(readyAt.Sub(deletedAt).Seconds(), 0)
+		replacement.TimeToReadySeconds = &seconds
+	}
+	return replacement
+}
+
+// restartWaitTimeout parses ?timeoutSeconds= for ?wait=true
+func restartWaitTimeout(value string) (time.Duration, error) {
+	if value == "" {
+		return defaultRestartWaitTimeout, nil
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 {
+		return 0, fmt.Errorf("invalid timeoutSeconds parameter")
+	}
+	return min(time.Duration(seconds)*time.Second, maxRestartWaitTimeout), nil
+}
@@ -0,0 +1,51 @@
+package resources
+
+import (
+	"github.com/gin-gonic/gin"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// exportedMetadataFields are the server-populated metadata fields dropped
+// when a custom resource is exported for re-applying elsewhere
+var exportedMetadataFields = []string{
+	"managedFields",
+	"resourceVersion",
+	"uid",
+	"creationTimestamp",
+	"generation",
+	"selfLink",
+}
+
+// ExportCR returns a custom resource stripped of server-populated fields so
+// that it can be applied to another cluster or namespace as is
+func (h *CRHandler) ExportCR(c *gin.Context) {
+	crd, cr, ok := h.getCRFromRequest(c)
+	if !ok {
+		return
+	}
+	writeCRObject(c, exportCR(crd, cr))
+}
+
+// exportCR returns a copy of cr without status, server-populated metadata and
+// the last-applied-configuration annotation
+func exportCR(crd *apiextensionsv1.CustomResourceDefinition, cr *unstructured.Unstructured) *unstructured.Unstructured {
+	exported := cr.DeepCopy()
+	for _, field := range exportedMetadataFields {
+		unstructured.RemoveNestedField(exported.Object, "metadata", field)
+	}
+	unstructured.RemoveNestedField(exported.Object, "status")
+
+	if annotations := exported.GetAnnotations(); annotations != nil {
+		delete(annotations, "kubectl.kubernetes.io/last-applied-configuration")
+		if len(annotations) == 0 {
+			annotations = nil
+		}
+		exported.SetAnnotations(annotations)
+	}
+
+	if crd.Spec.Scope == apiextensionsv1.ClusterScoped {
+		exported.SetNamespace("")
+	}
+	return exported
+}
@@ -0,0 +1,116 @@
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/utils"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// GetCRFinalizers returns the finalizers of a custom resource together with
+// its deletionTimestamp, to help with resources stuck in Terminating
+func (h *CRHandler) GetCRFinalizers(c *gin.Context) {
+	_, cr, ok := h.getCRFromRequest(c)
+	if !ok {
+		return
+	}
+
+	finalizers := cr.GetFinalizers()
+	if finalizers == nil {
+		finalizers = []string{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"finalizers":        finalizers,
+		"deletionTimestamp": cr.GetDeletionTimestamp(),
+		"terminating":       cr.GetDeletionTimestamp() != nil,
+	})
+}
+
+// RemoveCRFinalizer removes a single finalizer from a custom resource.
+// Removing finalizers skips whatever cleanup the owning controller would have
+// done, so the caller has to pass ?confirm=true, and objects that are not
+// being deleted additionally require ?force=true.
+func (h *CRHandler) RemoveCRFinalizer(c *gin.Context) {
+	// The finalizer is a catch-all parameter because finalizer names usually
+	// contain a slash
+	finalizer := strings.TrimPrefix(c.Param("finalizer"), "/")
+	if finalizer == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "finalizer name is required"})
+		return
+	}
+	if c.Query("confirm") != "true" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "removing a finalizer skips the controller's cleanup, pass confirm=true to proceed"})
+		return
+	}
+
+	_, cr, ok := h.getCRFromRequest(c)
+	if !ok {
+		return
+	}
+	if cr.GetDeletionTimestamp() == nil && c.Query("force") != "true" {
+		c.JSON(http.StatusConflict, gin.H{"error": "custom resource is not being deleted, pass force=true to remove the finalizer anyway"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	key := client.ObjectKeyFromObject(cr)
+	errNotPresent := fmt.Errorf("finalizer %q is not set on the custom resource", finalizer)
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := h.K8sClient.Client.Get(ctx, key, cr); err != nil {
+			return err
+		}
+		index := -1
+		for i, f := range cr.GetFinalizers() {
+			if f == finalizer {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return errNotPresent
+		}
+
+		// Pinning the resourceVersion turns a concurrent change of the
+		// finalizer list into a conflict instead of removing the wrong entry
+		patch, err := json.Marshal([]map[string]interface{}{
+			{"op": "remove", "path": fmt.Sprintf("/metadata/finalizers/%d", index)},
+			{"op": "replace", "path": "/metadata/resourceVersion", "value": cr.GetResourceVersion()},
+		})
+		if err != nil {
+			return err
+		}
+		return h.K8sClient.Client.Patch(ctx, cr, client.RawPatch(types.JSONPatchType, patch))
+	})
+	if err == errNotPresent {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.IsNotFound(err) {
+		// The object went away once its last finalizer was removed
+		err = nil
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove finalizer: " + err.Error()})
+		return
+	}
+
+	klog.Warningf("User %s removed finalizer %q from %s %s", utils.ActingUser(c), finalizer, cr.GetKind(), key)
+
+	finalizers := cr.GetFinalizers()
+	if finalizers == nil {
+		finalizers = []string{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":    fmt.Sprintf("Finalizer %s removed", finalizer),
+		"finalizers": finalizers,
+	})
+}
@@ -2,7 +2,13 @@ package resources
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,9 +20,14 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
 )
 
 // CRHandler handles API operations for Custom Resources based on CRD name
@@ -29,13 +40,26 @@ func NewCRHandler(client *kube.K8sClient) *CRHandler {
 	return &CRHandler{K8sClient: client}
 }
 
-// getCRDByName retrieves the CRD definition by name
+// getCRDByName retrieves the CRD definition from the CRD cache by its full
+// name, falling back to its plural, singular, kind or short names
 func (h *CRHandler) getCRDByName(ctx context.Context, crdName string) (*apiextensionsv1.CustomResourceDefinition, error) {
-	var crd apiextensionsv1.CustomResourceDefinition
-	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Name: crdName}, &crd); err != nil {
-		return nil, err
+	return h.K8sClient.CRDCache.Resolve(ctx, crdName)
+}
+
+// writeCRDLookupError writes the response for a failed CRD lookup
+func writeCRDLookupError(c *gin.Context, err error) {
+	if ambiguous, ok := err.(*kube.AmbiguousCRDError); ok {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      ambiguous.Error(),
+			"candidates": ambiguous.Candidates,
+		})
+		return
+	}
+	if errors.IsNotFound(err) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "CustomResourceDefinition not found"})
+		return
 	}
-	return &crd, nil
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 }
 
 // getGVRFromCRD extracts GroupVersionResource from CRD
@@ -56,6 +80,157 @@ func (h *CRHandler) getGVRFromCRD(crd *apiextensionsv1.CustomResourceDefinition)
 	}
 }
 
+// getCRFromRequest resolves the CRD named by the :crd parameter and fetches
+// the custom resource named by :namespace and :name. On failure the error
+// response has already been written and ok is false.
+func (h *CRHandler) getCRFromRequest(c *gin.Context) (crd *apiextensionsv1.CustomResourceDefinition, cr *unstructured.Unstructured, ok bool) {
+	crdName := c.Param("crd")
+	name := c.Param("name")
+	namespace := c.Param("namespace")
+	ctx := c.Request.Context()
+
+	if crdName == "" || name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "CRD name and resource name are required"})
+		return nil, nil, false
+	}
+
+	crd, err := h.getCRDByName(ctx, crdName)
+	if err != nil {
+		writeCRDLookupError(c, err)
+		return nil, nil, false
+	}
+
+	cr = &unstructured.Unstructured{}
+	gvr := h.getGVRFromCRD(crd)
+	cr.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   gvr.Group,
+		Version: gvr.Version,
+		Kind:    crd.Spec.Names.Kind,
+	})
+
+	namespacedName := types.NamespacedName{Name: name}
+	if crd.Spec.Scope == apiextensionsv1.NamespaceScoped {
+		if namespace == "" || namespace == "_all" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "namespace is required for namespaced custom resources"})
+			return nil, nil, false
+		}
+		namespacedName.Namespace = namespace
+	}
+
+	if err := h.K8sClient.Client.Get(ctx, namespacedName, cr); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Custom resource not found"})
+			return nil, nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, nil, false
+	}
+	return crd, cr, true
+}
+
+// isDryRun reports whether the request asked for a server-side dry run
+func isDryRun(c *gin.Context) bool {
+	return c.Query("dryRun") == "true"
+}
+
+// parsePropagationPolicy reads ?propagationPolicy=, defaulting to Foreground
+func parsePropagationPolicy(c *gin.Context) (metav1.DeletionPropagation, error) {
+	policy := metav1.DeletionPropagation(c.DefaultQuery("propagationPolicy", string(metav1.DeletePropagationForeground)))
+	switch policy {
+	case metav1.DeletePropagationForeground, metav1.DeletePropagationBackground, metav1.DeletePropagationOrphan:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid propagationPolicy %q, must be one of Foreground, Background or Orphan", policy)
+}
+
+// writeCRMutationError maps an apiserver error from a create, update or delete
+// onto the HTTP response. Client errors such as validation failures (422),
+// conflicts and admission webhook rejections keep the apiserver's status code
+// and details so the editor can highlight the offending fields, anything
+// else is a 500.
+func writeCRMutationError(c *gin.Context, err error, dryRun bool) {
+	code := http.StatusInternalServerError
+	resp := gin.H{"error": err.Error()}
+	if status, ok := err.(errors.APIStatus); ok {
+		s := status.Status()
+		if s.Code >= http.StatusBadRequest && s.Code < http.StatusInternalServerError {
+			code = int(s.Code)
+			resp["details"] = s.Details
+		}
+		if errors.IsConflict(err) {
+			resp["hint"] = "The resource was modified since it was loaded, refresh it and reapply your changes"
+		}
+	}
+	if dryRun {
+		resp["dryRun"] = true
+	}
+	c.JSON(code, resp)
+}
+
+// yamlErrorPosition extracts the line and, when present, the column from a
+// YAML decoder error
+var yamlErrorPosition = regexp.MustCompile(`line (\d+)(?:, column (\d+))?`)
+
+// isYAMLMediaType reports whether mediaType is one of the YAML media types
+func isYAMLMediaType(mediaType string) bool {
+	switch strings.ToLower(strings.TrimSpace(mediaType)) {
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return true
+	}
+	return false
+}
+
+// acceptsYAML reports whether the Accept header asks for YAML
+func acceptsYAML(c *gin.Context) bool {
+	for _, part := range strings.Split(c.GetHeader("Accept"), ",") {
+		mediaType, _, _ := strings.Cut(part, ";")
+		if isYAMLMediaType(mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// bindCRBody decodes the request body into obj. JSON is the default and YAML
+// is used when the Content-Type asks for it. On failure the 400 response is
+// written and false is returned.
+func bindCRBody(c *gin.Context, obj *unstructured.Unstructured) bool {
+	if !isYAMLMediaType(c.ContentType()) {
+		if err := c.ShouldBindJSON(obj); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return false
+		}
+		return true
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body: " + err.Error()})
+		return false
+	}
+
+	jsonBody, err := yaml.YAMLToJSON(body)
+	if err != nil {
+		resp := gin.H{"error": "Invalid YAML: " + err.Error()}
+		if m := yamlErrorPosition.FindStringSubmatch(err.Error()); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			resp["line"] = line
+			if m[2] != "" {
+				column, _ := strconv.Atoi(m[2])
+				resp["column"] = column
+			}
+		}
+		c.JSON(http.StatusBadRequest, resp)
+		return false
+	}
+
+	if err := obj.UnmarshalJSON(jsonBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid object: " + err.Error()})
+		return false
+	}
+	return true
+}
+
 func (h *CRHandler) List(c *gin.Context) {
 	crdName := c.Param("crd")
 	if crdName == "" {
@@ -68,14 +243,49 @@ func (h *CRHandler) List(c *gin.Context) {
 	// Get the CRD definition
 	crd, err := h.getCRDByName(ctx, crdName)
 	if err != nil {
-		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "CustomResourceDefinition not found"})
+		writeCRDLookupError(c, err)
+		return
+	}
+
+	var limit int64
+	if c.Query("limit") != "" {
+		limit, err = strconv.ParseInt(c.Query("limit"), 10, 64)
+		if err != nil || limit < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
 			return
 		}
+	}
+
+	namespace := c.Param("namespace")
+	crList, err := h.listCustomResources(ctx, crd, namespace)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	// Filter before truncating so that limit applies to the matches
+	allNamespaces := crd.Spec.Scope == apiextensionsv1.NamespaceScoped && (namespace == "" || namespace == "_all")
+	if search := c.Query("search"); search != "" {
+		crList.Items = filterCRsBySearch(crList.Items, search, allNamespaces)
+	}
+	matched := len(crList.Items)
+	if limit > 0 && int64(matched) > limit {
+		crList.Items = crList.Items[:limit]
+	}
+	c.Header("X-Total-Count", strconv.Itoa(matched))
+	crList.Object["matchedCount"] = matched
+
+	if c.Query("format") == "table" {
+		c.JSON(http.StatusOK, buildCRTable(crd, h.getGVRFromCRD(crd).Version, crList, c.Query("wide") == "true"))
+		return
+	}
+
+	c.JSON(http.StatusOK, crList)
+}
+
+// listCustomResources lists the custom resources of a CRD, restricted to
+// namespace for namespaced CRDs unless it is empty or _all
+func (h *CRHandler) listCustomResources(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition, namespace string) (*unstructured.UnstructuredList, error) {
 	// Create GVR from CRD
 	gvr := h.getGVRFromCRD(crd)
 
@@ -91,18 +301,44 @@ func (h *CRHandler) List(c *gin.Context) {
 
 	// Handle namespace parameter for namespaced resources
 	if crd.Spec.Scope == apiextensionsv1.NamespaceScoped {
-		namespace := c.Param("namespace")
 		if namespace != "" && namespace != "_all" {
 			opts.Namespace = namespace
 		}
 	}
 
 	if err := h.K8sClient.Client.List(ctx, crList, opts); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		return nil, err
 	}
+	return crList, nil
+}
 
-	c.JSON(http.StatusOK, crList)
+// filterCRsBySearch keeps the custom resources whose name contains search,
+// case-insensitively. When listing across namespaces the namespace is matched
+// as well, and a search of the form "ns/name" matches the namespace and name
+// separately.
+func filterCRsBySearch(items []unstructured.Unstructured, search string, allNamespaces bool) []unstructured.Unstructured {
+	search = strings.ToLower(search)
+	nsSearch, nameSearch, split := strings.Cut(search, "/")
+
+	filtered := make([]unstructured.Unstructured, 0, len(items))
+	for _, item := range items {
+		name := strings.ToLower(item.GetName())
+		namespace := strings.ToLower(item.GetNamespace())
+
+		var match bool
+		switch {
+		case split:
+			match = strings.Contains(namespace, nsSearch) && strings.Contains(name, nameSearch)
+		case allNamespaces:
+			match = strings.Contains(name, search) || strings.Contains(namespace, search)
+		default:
+			match = strings.Contains(name, search)
+		}
+		if match {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
 }
 
 func (h *CRHandler) Get(c *gin.Context) {
@@ -119,11 +355,7 @@ func (h *CRHandler) Get(c *gin.Context) {
 	// Get the CRD definition
 	crd, err := h.getCRDByName(ctx, crdName)
 	if err != nil {
-		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "CustomResourceDefinition not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeCRDLookupError(c, err)
 		return
 	}
 
@@ -165,6 +397,25 @@ func (h *CRHandler) Get(c *gin.Context) {
 		return
 	}
 
+	if c.Query("export") == "true" {
+		cr = exportCR(crd, cr)
+	}
+	writeCRObject(c, cr)
+}
+
+// writeCRObject writes a custom resource as YAML when the Accept header asks
+// for it and as JSON otherwise
+func writeCRObject(c *gin.Context, cr *unstructured.Unstructured) {
+	if acceptsYAML(c) {
+		out, err := yaml.Marshal(cr.Object)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode YAML: " + err.Error()})
+			return
+		}
+		c.Data(http.StatusOK, "application/yaml", out)
+		return
+	}
+
 	c.JSON(http.StatusOK, cr)
 }
 
@@ -180,11 +431,7 @@ func (h *CRHandler) Create(c *gin.Context) {
 	// Get the CRD definition
 	crd, err := h.getCRDByName(ctx, crdName)
 	if err != nil {
-		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "CustomResourceDefinition not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeCRDLookupError(c, err)
 		return
 	}
 
@@ -193,8 +440,7 @@ func (h *CRHandler) Create(c *gin.Context) {
 
 	// Parse the request body into unstructured object
 	var cr unstructured.Unstructured
-	if err := c.ShouldBindJSON(&cr); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindCRBody(c, &cr) {
 		return
 	}
 
@@ -219,8 +465,19 @@ func (h *CRHandler) Create(c *gin.Context) {
 		cr.SetNamespace(namespace)
 	}
 
-	if err := h.K8sClient.Client.Create(ctx, &cr); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	dryRun := isDryRun(c)
+	var createOpts []client.CreateOption
+	if dryRun {
+		createOpts = append(createOpts, client.DryRunAll)
+	}
+
+	if err := h.K8sClient.Client.Create(ctx, &cr, createOpts...); err != nil {
+		writeCRMutationError(c, err, dryRun)
+		return
+	}
+
+	if dryRun {
+		c.JSON(http.StatusOK, gin.H{"dryRun": true, "object": cr})
 		return
 	}
 
@@ -241,11 +498,7 @@ func (h *CRHandler) Update(c *gin.Context) {
 	// Get the CRD definition
 	crd, err := h.getCRDByName(ctx, crdName)
 	if err != nil {
-		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "CustomResourceDefinition not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeCRDLookupError(c, err)
 		return
 	}
 
@@ -287,23 +540,52 @@ func (h *CRHandler) Update(c *gin.Context) {
 
 	// Parse the request body into unstructured object
 	var updatedCR unstructured.Unstructured
-	if err := c.ShouldBindJSON(&updatedCR); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindCRBody(c, &updatedCR) {
 		return
 	}
 
+	// A resourceVersion sent by the caller means they edited a specific
+	// revision, so a conflict is meaningful and must not be retried
+	callerVersion := updatedCR.GetResourceVersion()
+
 	// Preserve important metadata
 	updatedCR.SetGroupVersionKind(existingCR.GroupVersionKind())
 	updatedCR.SetName(name)
-	updatedCR.SetResourceVersion(existingCR.GetResourceVersion())
 	updatedCR.SetUID(existingCR.GetUID())
 
 	if crd.Spec.Scope == apiextensionsv1.NamespaceScoped {
 		updatedCR.SetNamespace(existingCR.GetNamespace())
 	}
 
-	if err := h.K8sClient.Client.Update(ctx, &updatedCR); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	dryRun := isDryRun(c)
+	var updateOpts []client.UpdateOption
+	if dryRun {
+		updateOpts = append(updateOpts, client.DryRunAll)
+	}
+
+	if callerVersion != "" {
+		err = h.K8sClient.Client.Update(ctx, &updatedCR, updateOpts...)
+	} else {
+		updatedCR.SetResourceVersion(existingCR.GetResourceVersion())
+		attempt := 0
+		err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			if attempt > 0 {
+				if err := h.K8sClient.Client.Get(ctx, namespacedName, existingCR); err != nil {
+					return err
+				}
+				updatedCR.SetResourceVersion(existingCR.GetResourceVersion())
+			}
+			attempt++
+			return h.K8sClient.Client.Update(ctx, &updatedCR, updateOpts...)
+		})
+	}
+	if err != nil {
+		writeCRMutationError(c, err, dryRun)
+		return
+	}
+
+	if dryRun {
+		c.JSON(http.StatusOK, gin.H{"dryRun": true, "object": updatedCR})
 		return
 	}
 
@@ -324,11 +606,7 @@ func (h *CRHandler) Delete(c *gin.Context) {
 	// Get the CRD definition
 	crd, err := h.getCRDByName(ctx, crdName)
 	if err != nil {
-		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "CustomResourceDefinition not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeCRDLookupError(c, err)
 		return
 	}
 
@@ -361,6 +639,12 @@ func (h *CRHandler) Delete(c *gin.Context) {
 	}
 	cr.SetName(name)
 
+	propagationPolicy, err := parsePropagationPolicy(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// First check if the resource exists
 	if err := h.K8sClient.Client.Get(ctx, namespacedName, cr); err != nil {
 		if errors.IsNotFound(err) {
@@ -371,207 +655,127 @@ func (h *CRHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	// Delete the custom resource
-	if err := h.K8sClient.Client.Delete(ctx, cr, &client.DeleteOptions{
-		PropagationPolicy: &[]metav1.DeletionPropagation{metav1.DeletePropagationForeground}[0],
-	}); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+	deleteOpts := &client.DeleteOptions{
+		PropagationPolicy: &propagationPolicy,
+	}
+	dryRun := isDryRun(c)
+	if dryRun {
+		deleteOpts.DryRun = []string{metav1.DryRunAll}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Custom resource deleted successfully"})
-}
-
-// GetCRRelatedResources lists resources related to a custom resource
-// such as pods, services, etc.
-func (h *CRHandler) GetCRRelatedResources(c *gin.Context) {
-	crdName := c.Param("crd")
-	name := c.Param("name")
-	namespace := c.Param("namespace")
-	ctx := c.Request.Context()
-
-	if crdName == "" || name == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "CRD name and resource name are required"})
+	// Delete the custom resource
+	if err := h.K8sClient.Client.Delete(ctx, cr, deleteOpts); err != nil {
+		writeCRMutationError(c, err, dryRun)
 		return
 	}
 
-	// Get the CRD definition
-	crd, err := h.getCRDByName(ctx, crdName)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "CustomResourceDefinition not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if dryRun {
+		c.JSON(http.StatusOK, gin.H{"message": "Custom resource would be deleted (dry run)", "dryRun": true, "propagationPolicy": propagationPolicy, "object": cr})
 		return
 	}
 
-	// Get the custom resource to access its labels
-	cr := &unstructured.Unstructured{}
-	gvr := h.getGVRFromCRD(crd)
-	cr.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   gvr.Group,
-		Version: gvr.Version,
-		Kind:    crd.Spec.Names.Kind,
-	})
+	c.JSON(http.StatusOK, gin.H{"message": "Custom resource deleted successfully", "propagationPolicy": propagationPolicy})
+}
 
-	var namespacedName types.NamespacedName
-	if crd.Spec.Scope == apiextensionsv1.NamespaceScoped {
-		if namespace == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "namespace is required for namespaced custom resources"})
+// defaultCRTemplatePath is probed for a pod template when the caller doesn't
+// name one, it matches the layout of most workload-like custom resources
+const defaultCRTemplatePath = "spec.template.metadata.annotations"
+
+// RestartCR restarts a custom resource by setting the restart annotation on
+// its pod template, so that the operator rolls the pods. The template
+// annotations path can be passed as templatePath in the body and defaults to
+// spec.template.metadata.annotations when spec.template exists. When no
+// template path takes the annotation the CR's own metadata is annotated
+// instead, which some operators watch as well.
+func (h *CRHandler) RestartCR(c *gin.Context) {
+	var restartRequest struct {
+		TemplatePath string `json:"templatePath"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&restartRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
 			return
 		}
-		namespacedName = types.NamespacedName{Namespace: namespace, Name: name}
-	} else {
-		namespacedName = types.NamespacedName{Name: name}
 	}
 
-	if err := h.K8sClient.Client.Get(ctx, namespacedName, cr); err != nil {
-		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Custom resource not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	_, cr, ok := h.getCRFromRequest(c)
+	if !ok {
 		return
 	}
+	ctx := c.Request.Context()
+	restartedAt := time.Now().Format(time.RFC3339)
 
-	relatedResources := gin.H{}
-
-	// Try to find related pods based on labels
-	if labels := cr.GetLabels(); labels != nil {
-		var relatedPods []corev1.Pod
-		podList := &corev1.PodList{}
-		podListOpts := &client.ListOptions{}
-		
-		if crd.Spec.Scope == apiextensionsv1.NamespaceScoped {
-			podListOpts.Namespace = namespace
-		}
-
-		if err := h.K8sClient.Client.List(ctx, podList, podListOpts); err == nil {
-			for _, pod := range podList.Items {
-				if podLabels := pod.GetLabels(); podLabels != nil {
-					// Check if pod labels match CR labels (basic matching)
-					hasMatch := false
-					for crKey, crValue := range labels {
-						if podValue, exists := podLabels[crKey]; exists && podValue == crValue {
-							hasMatch = true
-							break
-						}
-					}
-					// Also check for common patterns like app, component, etc
-					if !hasMatch {
-						commonLabels := []string{"app", "component", "name", "instance"}
-						for _, commonLabel := range commonLabels {
-							if crValue, crExists := labels[commonLabel]; crExists {
-								if podValue, podExists := podLabels[commonLabel]; podExists && podValue == crValue {
-									hasMatch = true
-									break
-								}
-							}
-						}
-					}
-					if hasMatch {
-						relatedPods = append(relatedPods, pod)
-					}
-				}
-			}
+	templatePath := strings.Trim(restartRequest.TemplatePath, ".")
+	if templatePath == "" {
+		if _, found, _ := unstructured.NestedMap(cr.Object, "spec", "template"); found {
+			templatePath = defaultCRTemplatePath
 		}
-		relatedResources["pods"] = relatedPods
 	}
 
-	// Try to find related services
-	if crd.Spec.Scope == apiextensionsv1.NamespaceScoped {
-		var relatedServices []corev1.Service
-		serviceList := &corev1.ServiceList{}
-		serviceListOpts := &client.ListOptions{
-			Namespace: namespace,
-		}
-
-		if err := h.K8sClient.Client.List(ctx, serviceList, serviceListOpts); err == nil {
-			crLabels := cr.GetLabels()
-			for _, service := range serviceList.Items {
-				if service.Spec.Selector != nil && crLabels != nil {
-					serviceSelector := labels.SelectorFromSet(service.Spec.Selector)
-					if serviceSelector.Matches(labels.Set(crLabels)) {
-						relatedServices = append(relatedServices, service)
-					}
-				}
-			}
+	var warning string
+	if templatePath != "" {
+		fields := strings.Split(templatePath, ".")
+		applied, err := h.patchCRRestartAnnotation(ctx, cr, fields, restartedAt)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restart custom resource: " + err.Error()})
+			return
 		}
-		relatedResources["services"] = relatedServices
+		if applied {
+			c.JSON(http.StatusOK, gin.H{
+				"message":     "Custom resource restarted successfully",
+				"target":      templatePath,
+				"restartedAt": restartedAt,
+			})
+			return
+		}
+		// The apiserver prunes fields unknown to the CRD schema, so a path
+		// that doesn't exist in the schema is silently dropped
+		warning = fmt.Sprintf("%s is not writable on this custom resource, annotated metadata.annotations instead", templatePath)
 	}
 
-	c.JSON(http.StatusOK, relatedResources)
-}
-
-// RestartCR restarts a custom resource by updating its restart annotation
-func (h *CRHandler) RestartCR(c *gin.Context) {
-	crdName := c.Param("crd")
-	name := c.Param("name")
-	namespace := c.Param("namespace")
-	ctx := c.Request.Context()
-
-	if crdName == "" || name == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "CRD name and resource name are required"})
+	applied, err := h.patchCRRestartAnnotation(ctx, cr, []string{"metadata", "annotations"}, restartedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restart custom resource: " + err.Error()})
 		return
 	}
-
-	// Get the CRD definition
-	crd, err := h.getCRDByName(ctx, crdName)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "CustomResourceDefinition not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if !applied {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Neither the pod template nor the metadata of the custom resource accepted the restart annotation"})
 		return
 	}
 
-	// Get the custom resource
-	cr := &unstructured.Unstructured{}
-	gvr := h.getGVRFromCRD(crd)
-	cr.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   gvr.Group,
-		Version: gvr.Version,
-		Kind:    crd.Spec.Names.Kind,
-	})
-
-	var namespacedName types.NamespacedName
-	if crd.Spec.Scope == apiextensionsv1.NamespaceScoped {
-		if namespace == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "namespace is required for namespaced custom resources"})
-			return
-		}
-		namespacedName = types.NamespacedName{Namespace: namespace, Name: name}
-	} else {
-		namespacedName = types.NamespacedName{Name: name}
+	response := gin.H{
+		"message":     "Custom resource restarted successfully",
+		"target":      "metadata.annotations",
+		"restartedAt": restartedAt,
 	}
-
-	if err := h.K8sClient.Client.Get(ctx, namespacedName, cr); err != nil {
-		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Custom resource not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+	if warning != "" {
+		response["warning"] = warning
 	}
+	c.JSON(http.StatusOK, response)
+}
 
-	// Add restart annotation
-	annotations := cr.GetAnnotations()
-	if annotations == nil {
-		annotations = make(map[string]string)
+// patchCRRestartAnnotation merge-patches the restart annotation into the map
+// at fields and reports whether the stored object kept it
+func (h *CRHandler) patchCRRestartAnnotation(ctx context.Context, cr *unstructured.Unstructured, fields []string, restartedAt string) (bool, error) {
+	// Patch only the restart annotation so that concurrent changes made by
+	// the operator don't conflict with the restart
+	patchObj := map[string]interface{}{}
+	if err := unstructured.SetNestedStringMap(patchObj, map[string]string{restartedAtAnnotation: restartedAt}, fields...); err != nil {
+		return false, err
+	}
+	patch, err := json.Marshal(patchObj)
+	if err != nil {
+		return false, err
 	}
-	annotations["kite.kubernetes.io/restartedAt"] = time.Now().Format(time.RFC3339)
-	cr.SetAnnotations(annotations)
 
-	if err := h.K8sClient.Client.Update(ctx, cr); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restart custom resource: " + err.Error()})
-		return
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		return h.K8sClient.Client.Patch(ctx, cr, client.RawPatch(types.MergePatchType, patch))
+	}); err != nil {
+		return false, err
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Custom resource restarted successfully",
-	})
+	value, found, _ := unstructured.NestedString(cr.Object, append(fields, restartedAtAnnotation)...)
+	return found && value == restartedAt, nil
 }
 
 // ScaleCR scales a custom resource if it supports replicas
@@ -604,11 +808,7 @@ func (h *CRHandler) ScaleCR(c *gin.Context) {
 	// Get the CRD definition
 	crd, err := h.getCRDByName(ctx, crdName)
 	if err != nil {
-		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "CustomResourceDefinition not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeCRDLookupError(c, err)
 		return
 	}
 
@@ -654,14 +854,21 @@ func (h *CRHandler) ScaleCR(c *gin.Context) {
 		return
 	}
 
-	// Update the replica count
-	if err := unstructured.SetNestedField(cr.Object, int64(*scaleRequest.Replicas), "spec", "replicas"); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update replicas field: " + err.Error()})
+	// Patch only the replica count so that concurrent changes made by the
+	// operator don't conflict with the scale
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas": *scaleRequest.Replicas,
+		},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build scale patch: " + err.Error()})
 		return
 	}
 
-	// Update the custom resource
-	if err := h.K8sClient.Client.Update(ctx, cr); err != nil {
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		return h.K8sClient.Client.Patch(ctx, cr, client.RawPatch(types.MergePatchType, patch))
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scale custom resource: " + err.Error()})
 		return
 	}
@@ -673,7 +880,9 @@ func (h *CRHandler) ScaleCR(c *gin.Context) {
 	})
 }
 
-// GetCREvents gets events related to a custom resource
+// GetCREvents gets events related to a custom resource. Events are selected
+// server-side by involved object name, kind and namespace and then filtered
+// on the API group so CRs that share a kind with built-ins don't collide.
 func (h *CRHandler) GetCREvents(c *gin.Context) {
 	crdName := c.Param("crd")
 	name := c.Param("name")
@@ -685,30 +894,214 @@ func (h *CRHandler) GetCREvents(c *gin.Context) {
 		return
 	}
 
-	// Get events related to this custom resource
-	eventList := &corev1.EventList{}
-	eventListOpts := &client.ListOptions{}
-	
-	if namespace != "" {
-		eventListOpts.Namespace = namespace
+	limit := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+			return
+		}
+		limit = l
+	}
+
+	// Get the CRD definition
+	crd, err := h.getCRDByName(ctx, crdName)
+	if err != nil {
+		writeCRDLookupError(c, err)
+		return
+	}
+
+	selector := fields.Set{
+		"involvedObject.name": name,
+		"involvedObject.kind": crd.Spec.Names.Kind,
+	}
+	eventNamespace := ""
+	if crd.Spec.Scope == apiextensionsv1.NamespaceScoped {
+		if namespace == "" || namespace == "_all" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "namespace is required for namespaced custom resources"})
+			return
+		}
+		selector["involvedObject.namespace"] = namespace
+		eventNamespace = namespace
 	}
 
-	if err := h.K8sClient.Client.List(ctx, eventList, eventListOpts); err != nil {
+	eventList, err := h.K8sClient.ClientSet.CoreV1().Events(eventNamespace).List(ctx, metav1.ListOptions{
+		FieldSelector: selector.AsSelector().String(),
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list events: " + err.Error()})
 		return
 	}
 
-	// Filter events that are related to this custom resource
-	var relatedEvents []corev1.Event
+	// Events may reference any served version, so only the group is compared
+	relatedEvents := make([]corev1.Event, 0, len(eventList.Items))
 	for _, event := range eventList.Items {
-		if event.InvolvedObject.Name == name &&
-			(namespace == "" || event.InvolvedObject.Namespace == namespace) &&
-			strings.Contains(event.InvolvedObject.Kind, crdName) {
-			relatedEvents = append(relatedEvents, event)
+		gv, err := schema.ParseGroupVersion(event.InvolvedObject.APIVersion)
+		if err != nil || gv.Group != crd.Spec.Group {
+			continue
 		}
+		relatedEvents = append(relatedEvents, event)
+	}
+
+	sort.Slice(relatedEvents, func(i, j int) bool {
+		return eventTimestamp(&relatedEvents[i]).After(eventTimestamp(&relatedEvents[j]))
+	})
+	if limit > 0 && len(relatedEvents) > limit {
+		relatedEvents = relatedEvents[:limit]
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"events": relatedEvents,
 	})
 }
+
+// eventTimestamp returns the most meaningful time of an event, falling back
+// from the last observed time of its series to lastTimestamp to eventTime to
+// the creation timestamp
+func eventTimestamp(event *corev1.Event) time.Time {
+	if event.Series != nil && !event.Series.LastObservedTime.IsZero() {
+		return event.Series.LastObservedTime.Time
+	}
+	if !event.LastTimestamp.IsZero() {
+		return event.LastTimestamp.Time
+	}
+	if !event.EventTime.IsZero() {
+		return event.EventTime.Time
+	}
+	return event.CreationTimestamp.Time
+}
+
+// WatchCRs streams ADDED/MODIFIED/DELETED events for custom resources as
+// server-sent events. The stream starts with a sync event holding the current
+// list and re-lists whenever the watch's resourceVersion has expired.
+func (h *CRHandler) WatchCRs(c *gin.Context) {
+	crdName := c.Param("crd")
+	if crdName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "CRD name is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	// Get the CRD definition
+	crd, err := h.getCRDByName(ctx, crdName)
+	if err != nil {
+		writeCRDLookupError(c, err)
+		return
+	}
+
+	labelSelector := c.Query("labelSelector")
+	if labelSelector != "" {
+		if _, err := labels.Parse(labelSelector); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid labelSelector parameter: " + err.Error()})
+			return
+		}
+	}
+
+	gvr := h.getGVRFromCRD(crd)
+	var resourceClient dynamic.ResourceInterface = h.K8sClient.DynamicClient.Resource(gvr)
+	if crd.Spec.Scope == apiextensionsv1.NamespaceScoped {
+		namespace := c.Param("namespace")
+		if namespace != "" && namespace != "_all" {
+			resourceClient = h.K8sClient.DynamicClient.Resource(gvr).Namespace(namespace)
+		}
+	}
+
+	if err := startSSE(c); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	resourceVersion := ""
+	for {
+		if resourceVersion == "" {
+			list, err := resourceClient.List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+			if err != nil {
+				_ = writeSSEEvent(c, "error", gin.H{"error": "Failed to list custom resources: " + err.Error()})
+				return
+			}
+			resourceVersion = list.GetResourceVersion()
+			if err := writeSSEEvent(c, "sync", gin.H{
+				"type":            "SYNC",
+				"items":           list.Items,
+				"resourceVersion": resourceVersion,
+			}); err != nil {
+				return
+			}
+		}
+
+		watcher, err := resourceClient.Watch(ctx, metav1.ListOptions{
+			LabelSelector:       labelSelector,
+			ResourceVersion:     resourceVersion,
+			AllowWatchBookmarks: true,
+		})
+		if err != nil {
+			if errors.IsResourceExpired(err) || errors.IsGone(err) {
+				resourceVersion = ""
+				continue
+			}
+			if ctx.Err() == nil {
+				_ = writeSSEEvent(c, "error", gin.H{"error": "Failed to watch custom resources: " + err.Error()})
+			}
+			return
+		}
+
+		resourceVersion, err = h.streamCRWatch(c, watcher, heartbeat, resourceVersion)
+		watcher.Stop()
+		if err != nil {
+			// Client went away or the stream is broken
+			return
+		}
+	}
+}
+
+// streamCRWatch forwards watch events to the SSE stream until the watch is
+// closed. It returns the last seen resourceVersion, or an empty string when
+// the version has expired and the caller needs to re-list.
+func (h *CRHandler) streamCRWatch(c *gin.Context, watcher watch.Interface, heartbeat *time.Ticker, resourceVersion string) (string, error) {
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return resourceVersion, ctx.Err()
+		case <-heartbeat.C:
+			if err := writeSSEHeartbeat(c); err != nil {
+				return resourceVersion, err
+			}
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				// The apiserver closed the watch, resume from the last seen version
+				return resourceVersion, nil
+			}
+			switch event.Type {
+			case watch.Error:
+				statusErr := errors.FromObject(event.Object)
+				if errors.IsResourceExpired(statusErr) || errors.IsGone(statusErr) {
+					return "", nil
+				}
+				if err := writeSSEEvent(c, "error", gin.H{"error": statusErr.Error()}); err != nil {
+					return resourceVersion, err
+				}
+				return "", nil
+			case watch.Bookmark:
+				if obj, ok := event.Object.(*unstructured.Unstructured); ok {
+					resourceVersion = obj.GetResourceVersion()
+				}
+			case watch.Added, watch.Modified, watch.Deleted:
+				obj, ok := event.Object.(*unstructured.Unstructured)
+				if !ok {
+					continue
+				}
+				resourceVersion = obj.GetResourceVersion()
+				if err := writeSSEEvent(c, string(event.Type), gin.H{
+					"type":   event.Type,
+					"object": obj,
+				}); err != nil {
+					return resourceVersion, err
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestWriteCRMutationError(t *testing.T) {
+	resource := schema.GroupResource{Group: "argoproj.io", Resource: "workflows"}
+	webhookDenied := &errors.StatusError{ErrStatus: metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusForbidden,
+		Reason:  metav1.StatusReasonForbidden,
+		Message: `admission webhook "policy.example.com" denied the request: image is not signed`,
+	}}
+	webhookTeapot := &errors.StatusError{ErrStatus: metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusTeapot,
+		Message: `admission webhook "odd.example.com" denied the request`,
+	}}
+
+	tests := []struct {
+		name    string
+		err     error
+		code    int
+		details bool
+		hint    bool
+	}{
+		{name: "invalid", err: errors.NewInvalid(schema.GroupKind{Group: "argoproj.io", Kind: "Workflow"}, "wf", field.ErrorList{field.Required(field.NewPath("spec"), "")}), code: http.StatusUnprocessableEntity, details: true},
+		{name: "conflict", err: errors.NewConflict(resource, "wf", fmt.Errorf("the object has been modified")), code: http.StatusConflict, details: true, hint: true},
+		{name: "not found", err: errors.NewNotFound(resource, "wf"), code: http.StatusNotFound, details: true},
+		{name: "forbidden", err: errors.NewForbidden(resource, "wf", fmt.Errorf("no access")), code: http.StatusForbidden, details: true},
+		{name: "already exists", err: errors.NewAlreadyExists(resource, "wf"), code: http.StatusConflict, details: true},
+		{name: "bad request", err: errors.NewBadRequest("bad patch"), code: http.StatusBadRequest, details: true},
+		{name: "webhook denial", err: webhookDenied, code: http.StatusForbidden, details: true},
+		{name: "webhook with an unusual 4xx", err: webhookTeapot, code: http.StatusTeapot, details: true},
+		{name: "apiserver 5xx", err: errors.NewInternalError(fmt.Errorf("etcd is down")), code: http.StatusInternalServerError},
+		{name: "timeout", err: errors.NewTimeoutError("timed out", 1), code: http.StatusInternalServerError},
+		{name: "not an apiserver error", err: fmt.Errorf("connection refused"), code: http.StatusInternalServerError},
+	}
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			writeCRMutationError(c, tt.err, false)
+
+			if recorder.Code != tt.code {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.code)
+			}
+			var body map[string]any
+			if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if body["error"] != tt.err.Error() {
+				t.Errorf("error = %v, want %q", body["error"], tt.err.Error())
+			}
+			if _, ok := body["details"]; ok != tt.details {
+				t.Errorf("details present = %v, want %v", ok, tt.details)
+			}
+			if _, ok := body["hint"]; ok != tt.hint {
+				t.Errorf("hint present = %v, want %v", ok, tt.hint)
+			}
+			if _, ok := body["dryRun"]; ok {
+				t.Error("dryRun set on a real request")
+			}
+		})
+	}
+}
+
+func TestWriteCRMutationErrorDryRun(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	writeCRMutationError(c, errors.NewBadRequest("bad"), true)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil || body["dryRun"] != true {
+		t.Errorf("body = %s, want dryRun set", recorder.Body.String())
+	}
+}
@@ -0,0 +1,392 @@
+package resources
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// crRelatedKinds are the kinds searched for children of a custom resource
+var crRelatedKinds = []struct {
+	key     string
+	kind    string
+	newList func() client.ObjectList
+}{
+	{"deployments", "Deployment", func() client.ObjectList { return &appsv1.DeploymentList{} }},
+	{"statefulsets", "StatefulSet", func() client.ObjectList { return &appsv1.StatefulSetList{} }},
+	{"daemonsets", "DaemonSet", func() client.ObjectList { return &appsv1.DaemonSetList{} }},
+	{"replicasets", "ReplicaSet", func() client.ObjectList { return &appsv1.ReplicaSetList{} }},
+	{"jobs", "Job", func() client.ObjectList { return &batchv1.JobList{} }},
+	{"pods", "Pod", func() client.ObjectList { return &corev1.PodList{} }},
+	{"services", "Service", func() client.ObjectList { return &corev1.ServiceList{} }},
+	{"configmaps", "ConfigMap", func() client.ObjectList { return &corev1.ConfigMapList{} }},
+	{"secrets", "Secret", func() client.ObjectList { return &corev1.SecretList{} }},
+}
+
+const (
+	// defaultRelatedDepth is how many ownerReference levels below the custom
+	// resource are followed: its direct children and their children
+	defaultRelatedDepth = 2
+	// maxRelatedDepth bounds the ?depth= parameter
+	maxRelatedDepth = 6
+	// maxRelatedObjects caps the size of the ownership tree
+	maxRelatedObjects = 500
+)
+
+// relatedObject is a candidate child of a custom resource
+type relatedObject struct {
+	key  string
+	kind string
+	obj  client.Object
+}
+
+// RelatedResourceRef describes a related resource and how it is owned by the
+// custom resource. Path lists "Kind/name" from the custom resource down to
+// the resource itself.
+type RelatedResourceRef struct {
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Namespace string    `json:"namespace,omitempty"`
+	UID       types.UID `json:"uid"`
+	Path      []string  `json:"path"`
+	MatchedBy string    `json:"matchedBy"`
+}
+
+// RelatedResourceNode is a node of the ownership tree below a custom resource
+type RelatedResourceNode struct {
+	Kind      string                 `json:"kind"`
+	Name      string                 `json:"name"`
+	Namespace string                 `json:"namespace,omitempty"`
+	UID       types.UID              `json:"uid"`
+	Parent    string                 `json:"parent"`
+	Ready     string                 `json:"ready,omitempty"`
+	Children  []*RelatedResourceNode `json:"children,omitempty"`
+}
+
+// RelatedSecret describes a Secret owned by a custom resource by its
+// metadata, type and key names. Values are never returned.
+type RelatedSecret struct {
+	metav1.ObjectMeta `json:"metadata"`
+	Type              corev1.SecretType `json:"type"`
+	Keys              []string          `json:"keys"`
+}
+
+// redactSecret returns a copy of a secret without values. Data keeps its
+// keys with nil values, last-applied-configuration and managedFields are
+// dropped since they can hold the values too.
+func redactSecret(secret *corev1.Secret) *corev1.Secret {
+	redacted := &corev1.Secret{
+		TypeMeta:   secret.TypeMeta,
+		ObjectMeta: *secret.ObjectMeta.DeepCopy(),
+		Type:       secret.Type,
+		Data:       make(map[string][]byte, len(secret.Data)+len(secret.StringData)),
+	}
+	redacted.ManagedFields = nil
+	delete(redacted.Annotations, corev1.LastAppliedConfigAnnotation)
+	for key := range secret.Data {
+		redacted.Data[key] = nil
+	}
+	for key := range secret.StringData {
+		redacted.Data[key] = nil
+	}
+	return redacted
+}
+
+func relatedSecret(secret *corev1.Secret) RelatedSecret {
+	keys := make([]string, 0, len(secret.Data))
+	for key := range secret.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return RelatedSecret{ObjectMeta: secret.ObjectMeta, Type: secret.Type, Keys: keys}
+}
+
+// relatedTraversal walks ownerReferences downward from a custom resource
+type relatedTraversal struct {
+	children  map[types.UID][]*relatedObject
+	visited   map[types.UID]bool
+	count     int
+	truncated bool
+
+	refs    []RelatedResourceRef
+	objects map[string][]client.Object
+}
+
+// walk appends the children of owner to node, descending until depth is
+// exhausted. Visited UIDs are skipped so ownerReference cycles terminate.
+func (t *relatedTraversal) walk(owner types.UID, node *RelatedResourceNode, path []string, depth int) {
+	if depth <= 0 {
+		return
+	}
+	for _, child := range t.children[owner] {
+		uid := child.obj.GetUID()
+		if t.visited[uid] {
+			continue
+		}
+		if t.count >= maxRelatedObjects {
+			t.truncated = true
+			return
+		}
+		t.visited[uid] = true
+		t.count++
+
+		ref := child.kind + "/" + child.obj.GetName()
+		childPath := append(append([]string{}, path...), ref)
+		childNode := &RelatedResourceNode{
+			Kind:      child.kind,
+			Name:      child.obj.GetName(),
+			Namespace: child.obj.GetNamespace(),
+			UID:       uid,
+			Parent:    path[len(path)-1],
+			Ready:     readinessSummary(child.obj),
+		}
+		node.Children = append(node.Children, childNode)
+		t.objects[child.key] = append(t.objects[child.key], child.obj)
+		t.refs = append(t.refs, RelatedResourceRef{
+			Kind:      child.kind,
+			Name:      child.obj.GetName(),
+			Namespace: child.obj.GetNamespace(),
+			UID:       uid,
+			Path:      childPath,
+			MatchedBy: "ownerReference",
+		})
+
+		t.walk(uid, childNode, childPath, depth-1)
+	}
+}
+
+// readinessSummary renders a short "ready/desired" summary for workloads
+func readinessSummary(obj client.Object) string {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		return fmt.Sprintf("%d/%d", o.Status.ReadyReplicas, derefReplicas(o.Spec.Replicas))
+	case *appsv1.StatefulSet:
+		return fmt.Sprintf("%d/%d", o.Status.ReadyReplicas, derefReplicas(o.Spec.Replicas))
+	case *appsv1.ReplicaSet:
+		return fmt.Sprintf("%d/%d", o.Status.ReadyReplicas, derefReplicas(o.Spec.Replicas))
+	case *appsv1.DaemonSet:
+		return fmt.Sprintf("%d/%d", o.Status.NumberReady, o.Status.DesiredNumberScheduled)
+	case *batchv1.Job:
+		completions := int32(1)
+		if o.Spec.Completions != nil {
+			completions = *o.Spec.Completions
+		}
+		return fmt.Sprintf("%d/%d", o.Status.Succeeded, completions)
+	case *corev1.Pod:
+		ready := 0
+		for _, status := range o.Status.ContainerStatuses {
+			if status.Ready {
+				ready++
+			}
+		}
+		return fmt.Sprintf("%d/%d", ready, len(o.Spec.Containers))
+	}
+	return ""
+}
+
+func derefReplicas(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
+
+// GetCRRelatedResources lists resources owned by a custom resource by
+// following ownerReferences downward up to ?depth= levels (default 2). The
+// response groups the resources by kind, lists their ownership paths and
+// contains the ownership tree. The old label heuristic for pods and services
+// is only used with ?matchLabels=true.
+func (h *CRHandler) GetCRRelatedResources(c *gin.Context) {
+	crdName := c.Param("crd")
+	name := c.Param("name")
+	namespace := c.Param("namespace")
+	ctx := c.Request.Context()
+
+	if crdName == "" || name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "CRD name and resource name are required"})
+		return
+	}
+
+	depth := defaultRelatedDepth
+	if depthStr := c.Query("depth"); depthStr != "" {
+		d, err := strconv.Atoi(depthStr)
+		if err != nil || d < 1 || d > maxRelatedDepth {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("depth must be between 1 and %d", maxRelatedDepth)})
+			return
+		}
+		depth = d
+	}
+
+	// Get the CRD definition
+	crd, err := h.getCRDByName(ctx, crdName)
+	if err != nil {
+		writeCRDLookupError(c, err)
+		return
+	}
+
+	// Get the custom resource to access its UID and labels
+	cr := &unstructured.Unstructured{}
+	gvr := h.getGVRFromCRD(crd)
+	cr.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   gvr.Group,
+		Version: gvr.Version,
+		Kind:    crd.Spec.Names.Kind,
+	})
+
+	var namespacedName types.NamespacedName
+	if crd.Spec.Scope == apiextensionsv1.NamespaceScoped {
+		if namespace == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "namespace is required for namespaced custom resources"})
+			return
+		}
+		namespacedName = types.NamespacedName{Namespace: namespace, Name: name}
+	} else {
+		namespacedName = types.NamespacedName{Name: name}
+	}
+
+	if err := h.K8sClient.Client.Get(ctx, namespacedName, cr); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Custom resource not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Children of a namespaced CR live in its namespace, children of a
+	// cluster-scoped CR may live anywhere
+	listOpts := &client.ListOptions{}
+	if crd.Spec.Scope == apiextensionsv1.NamespaceScoped {
+		listOpts.Namespace = namespace
+	}
+
+	children := make(map[types.UID][]*relatedObject)
+	var candidates []*relatedObject
+	for _, kind := range crRelatedKinds {
+		list := kind.newList()
+		if err := h.K8sClient.Client.List(ctx, list, listOpts); err != nil {
+			klog.Warningf("Failed to list %s for custom resource %s: %v", kind.key, name, err)
+			continue
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			continue
+		}
+		for _, item := range items {
+			obj, ok := item.(client.Object)
+			if !ok {
+				continue
+			}
+			if secret, ok := obj.(*corev1.Secret); ok {
+				obj = redactSecret(secret)
+			}
+			entry := &relatedObject{key: kind.key, kind: kind.kind, obj: obj}
+			candidates = append(candidates, entry)
+			for _, ref := range obj.GetOwnerReferences() {
+				children[ref.UID] = append(children[ref.UID], entry)
+			}
+		}
+	}
+
+	rootRef := cr.GetKind() + "/" + cr.GetName()
+	root := &RelatedResourceNode{
+		Kind:      cr.GetKind(),
+		Name:      cr.GetName(),
+		Namespace: cr.GetNamespace(),
+		UID:       cr.GetUID(),
+	}
+	traversal := &relatedTraversal{
+		children: children,
+		visited:  map[types.UID]bool{cr.GetUID(): true},
+		objects:  make(map[string][]client.Object),
+	}
+	traversal.walk(cr.GetUID(), root, []string{rootRef}, depth)
+
+	if c.Query("matchLabels") == "true" {
+		for _, candidate := range h.matchCRByLabels(cr, candidates) {
+			if traversal.visited[candidate.obj.GetUID()] {
+				continue
+			}
+			traversal.visited[candidate.obj.GetUID()] = true
+			traversal.objects[candidate.key] = append(traversal.objects[candidate.key], candidate.obj)
+			traversal.refs = append(traversal.refs, RelatedResourceRef{
+				Kind:      candidate.kind,
+				Name:      candidate.obj.GetName(),
+				Namespace: candidate.obj.GetNamespace(),
+				UID:       candidate.obj.GetUID(),
+				Path:      []string{rootRef, candidate.kind + "/" + candidate.obj.GetName()},
+				MatchedBy: "labels",
+			})
+		}
+	}
+
+	relatedResources := gin.H{}
+	for _, kind := range crRelatedKinds {
+		objects := traversal.objects[kind.key]
+		if objects == nil {
+			objects = []client.Object{}
+		}
+		relatedResources[kind.key] = objects
+	}
+	secrets := []RelatedSecret{}
+	for _, obj := range traversal.objects["secrets"] {
+		if secret, ok := obj.(*corev1.Secret); ok {
+			secrets = append(secrets, relatedSecret(secret))
+		}
+	}
+	relatedResources["secrets"] = secrets
+	relatedResources["ownership"] = traversal.refs
+	relatedResources["tree"] = root
+	relatedResources["depth"] = depth
+	relatedResources["truncated"] = traversal.truncated
+
+	c.JSON(http.StatusOK, relatedResources)
+}
+
+// matchCRByLabels is the label heuristic used before ownerReferences were
+// followed: pods sharing a label value with the CR and services whose
+// selector matches the CR's labels
+func (h *CRHandler) matchCRByLabels(cr *unstructured.Unstructured, candidates []*relatedObject) []*relatedObject {
+	crLabels := cr.GetLabels()
+	if len(crLabels) == 0 {
+		return nil
+	}
+
+	var matched []*relatedObject
+	for _, candidate := range candidates {
+		switch obj := candidate.obj.(type) {
+		case *corev1.Pod:
+			podLabels := obj.GetLabels()
+			for crKey, crValue := range crLabels {
+				if podValue, exists := podLabels[crKey]; exists && podValue == crValue {
+					matched = append(matched, candidate)
+					break
+				}
+			}
+		case *corev1.Service:
+			if obj.Spec.Selector != nil {
+				serviceSelector := labels.SelectorFromSet(obj.Spec.Selector)
+				if serviceSelector.Matches(labels.Set(crLabels)) {
+					matched = append(matched, candidate)
+				}
+			}
+		}
+	}
+	return matched
+}
@@ -0,0 +1,229 @@
+package resources
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// crdCountParallelism bounds how many CRDs are counted at the same time
+	crdCountParallelism = 5
+	// crdCountTimeout bounds how long counting a single CRD may take
+	crdCountTimeout = 5 * time.Second
+	// crdCountPageSize and crdCountCap are used when the apiserver doesn't
+	// report remainingItemCount and the instances have to be paged through
+	crdCountPageSize = 500
+	crdCountCap      = 5000
+	// crNamespaceCountCap bounds how many instances are paged through when
+	// breaking the instances of a single CRD down by namespace
+	crNamespaceCountCap = 50000
+)
+
+// CRDSummary describes a CRD together with the number of its instances
+type CRDSummary struct {
+	Name           string   `json:"name"`
+	Group          string   `json:"group"`
+	Kind           string   `json:"kind"`
+	Plural         string   `json:"plural"`
+	Scope          string   `json:"scope"`
+	ServedVersions []string `json:"servedVersions"`
+	ShortNames     []string `json:"shortNames,omitempty"`
+	Categories     []string `json:"categories,omitempty"`
+	Count          *int64   `json:"count"`
+	CountCapped    bool     `json:"countCapped,omitempty"`
+	CountError     string   `json:"countError,omitempty"`
+}
+
+// GetCRDSummary lists all CRDs with their scope, served versions, names and
+// instance counts, optionally filtered by ?group=
+func (h *CRHandler) GetCRDSummary(c *gin.Context) {
+	ctx := c.Request.Context()
+	group := c.Query("group")
+
+	crds, err := h.K8sClient.CRDCache.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list CRDs: " + err.Error()})
+		return
+	}
+
+	summaries := make([]CRDSummary, 0, len(crds))
+	var gvrs []schema.GroupVersionResource
+	for _, crd := range crds {
+		if group != "" && crd.Spec.Group != group {
+			continue
+		}
+
+		summary := CRDSummary{
+			Name:       crd.Name,
+			Group:      crd.Spec.Group,
+			Kind:       crd.Spec.Names.Kind,
+			Plural:     crd.Spec.Names.Plural,
+			Scope:      string(crd.Spec.Scope),
+			ShortNames: crd.Spec.Names.ShortNames,
+			Categories: crd.Spec.Names.Categories,
+		}
+		for _, v := range crd.Spec.Versions {
+			if v.Served {
+				summary.ServedVersions = append(summary.ServedVersions, v.Name)
+			}
+		}
+		summaries = append(summaries, summary)
+		gvrs = append(gvrs, h.getGVRFromCRD(crd))
+	}
+
+	// Count instances with bounded parallelism, each count gets its own timeout
+	sem := make(chan struct{}, crdCountParallelism)
+	var wg sync.WaitGroup
+	for i := range summaries {
+		if gvrs[i].Version == "" {
+			summaries[i].CountError = "no served version"
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			countCtx, cancel := context.WithTimeout(ctx, crdCountTimeout)
+			defer cancel()
+			count, capped, err := h.countCustomResources(countCtx, gvrs[i])
+			if err != nil {
+				summaries[i].CountError = err.Error()
+				return
+			}
+			summaries[i].Count = &count
+			summaries[i].CountCapped = capped
+		}(i)
+	}
+	wg.Wait()
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Name < summaries[j].Name
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"crds":  summaries,
+		"total": len(summaries),
+	})
+}
+
+// countCustomResources counts the instances of a resource using a
+// metadata-only list with limit=1 and the remainingItemCount of the response.
+// When the apiserver doesn't report it the instances are paged through up to
+// crdCountCap, in which case capped is true if there were more.
+func (h *CRHandler) countCustomResources(ctx context.Context, gvr schema.GroupVersionResource) (int64, bool, error) {
+	resourceClient := h.K8sClient.MetadataClient.Resource(gvr)
+
+	list, err := resourceClient.List(ctx, metav1.ListOptions{Limit: 1})
+	if err != nil {
+		return 0, false, err
+	}
+	count := int64(len(list.Items))
+	if list.GetContinue() == "" {
+		return count, false, nil
+	}
+	if remaining := list.GetRemainingItemCount(); remaining != nil {
+		return count + *remaining, false, nil
+	}
+
+	count = 0
+	continueToken := ""
+	for {
+		page, err := resourceClient.List(ctx, metav1.ListOptions{
+			Limit:    crdCountPageSize,
+			Continue: continueToken,
+		})
+		if err != nil {
+			return 0, false, err
+		}
+		count += int64(len(page.Items))
+		continueToken = page.GetContinue()
+		if continueToken == "" {
+			return count, false, nil
+		}
+		if count >= crdCountCap {
+			return count, true, nil
+		}
+	}
+}
+
+// CRNamespaceCount is the number of instances of a CRD in one namespace
+type CRNamespaceCount struct {
+	Namespace string `json:"namespace"`
+	Count     int64  `json:"count"`
+}
+
+// GetCRCounts returns the number of instances of a CRD per namespace, sorted
+// by count in descending order. Cluster-scoped CRDs only report the total.
+// The instances are listed metadata-only and at most crNamespaceCountCap of
+// them are counted, truncated is set when there were more.
+func (h *CRHandler) GetCRCounts(c *gin.Context) {
+	crdName := c.Param("crd")
+	ctx := c.Request.Context()
+
+	crd, err := h.getCRDByName(ctx, crdName)
+	if err != nil {
+		writeCRDLookupError(c, err)
+		return
+	}
+
+	resourceClient := h.K8sClient.MetadataClient.Resource(h.getGVRFromCRD(crd))
+	namespaced := crd.Spec.Scope == apiextensionsv1.NamespaceScoped
+
+	var total int64
+	truncated := false
+	perNamespace := make(map[string]int64)
+	continueToken := ""
+	for {
+		page, err := resourceClient.List(ctx, metav1.ListOptions{
+			Limit:    crdCountPageSize,
+			Continue: continueToken,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list custom resources: " + err.Error()})
+			return
+		}
+		total += int64(len(page.Items))
+		if namespaced {
+			for _, item := range page.Items {
+				perNamespace[item.Namespace]++
+			}
+		}
+		continueToken = page.GetContinue()
+		if continueToken == "" {
+			break
+		}
+		if total >= crNamespaceCountCap {
+			truncated = true
+			break
+		}
+	}
+
+	response := gin.H{
+		"total":     total,
+		"truncated": truncated,
+	}
+	if namespaced {
+		counts := make([]CRNamespaceCount, 0, len(perNamespace))
+		for namespace, count := range perNamespace {
+			counts = append(counts, CRNamespaceCount{Namespace: namespace, Count: count})
+		}
+		sort.Slice(counts, func(i, j int) bool {
+			if counts[i].Count != counts[j].Count {
+				return counts[i].Count > counts[j].Count
+			}
+			return counts[i].Namespace < counts[j].Namespace
+		})
+		response["namespaces"] = counts
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,65 @@
+package resources
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// CRVersionResult is a custom resource as rendered in one served version.
+// Error is set instead of Object when the apiserver could not convert the
+// object to that version.
+type CRVersionResult struct {
+	Object *unstructured.Unstructured `json:"object,omitempty"`
+	Error  string                     `json:"error,omitempty"`
+}
+
+// GetCRVersions fetches a custom resource once per served version of its CRD
+// to help debug conversion webhooks. A failure in one version is reported
+// inline instead of failing the whole request.
+func (h *CRHandler) GetCRVersions(c *gin.Context) {
+	crd, cr, ok := h.getCRFromRequest(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+
+	versions := make(map[string]CRVersionResult)
+	var storageVersion string
+	for _, version := range crd.Spec.Versions {
+		if version.Storage {
+			storageVersion = version.Name
+		}
+		if !version.Served {
+			continue
+		}
+
+		gvr := schema.GroupVersionResource{
+			Group:    crd.Spec.Group,
+			Version:  version.Name,
+			Resource: crd.Spec.Names.Plural,
+		}
+		resource := h.K8sClient.DynamicClient.Resource(gvr)
+		var obj *unstructured.Unstructured
+		var err error
+		if crd.Spec.Scope == apiextensionsv1.NamespaceScoped {
+			obj, err = resource.Namespace(cr.GetNamespace()).Get(ctx, cr.GetName(), metav1.GetOptions{})
+		} else {
+			obj, err = resource.Get(ctx, cr.GetName(), metav1.GetOptions{})
+		}
+		if err != nil {
+			versions[version.Name] = CRVersionResult{Error: err.Error()}
+			continue
+		}
+		versions[version.Name] = CRVersionResult{Object: obj}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"storageVersion": storageVersion,
+		"versions":       versions,
+	})
+}
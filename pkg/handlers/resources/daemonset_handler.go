@@ -0,0 +1,150 @@
+package resources
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/kube"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type DaemonSetHandler struct {
+	*GenericResourceHandler[*appsv1.DaemonSet, *appsv1.DaemonSetList]
+}
+
+func NewDaemonSetHandler(client *kube.K8sClient) *DaemonSetHandler {
+	return &DaemonSetHandler{
+		GenericResourceHandler: NewGenericResourceHandler[*appsv1.DaemonSet, *appsv1.DaemonSetList](
+			client,
+			"daemonsets",
+			false, // DaemonSets are namespaced resources
+			true,
+		),
+	}
+}
+
+// DaemonSetRolloutStatus reports the rollout of a daemonset from its status
+type DaemonSetRolloutStatus struct {
+	UpdateStrategy     appsv1.DaemonSetUpdateStrategyType `json:"updateStrategy"`
+	MaxUnavailable     *intstr.IntOrString                `json:"maxUnavailable,omitempty"`
+	MaxSurge           *intstr.IntOrString                `json:"maxSurge,omitempty"`
+	Generation         int64                              `json:"generation"`
+	ObservedGeneration int64                              `json:"observedGeneration"`
+	Desired            int32                              `json:"desired"`
+	Current            int32                              `json:"current"`
+	Updated            int32                              `json:"updated"`
+	Ready              int32                              `json:"ready"`
+	Available          int32                              `json:"available"`
+	Unavailable        int32                              `json:"unavailable"`
+	Misscheduled       int32                              `json:"misscheduled"`
+	Complete           bool                               `json:"complete"`
+}
+
+// Restart triggers a rolling restart of a daemonset by setting
+// restartedAtAnnotation on its pod template
+func (h *DaemonSetHandler) Restart(ctx context.Context, namespace, name string) error {
+	daemonSet := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	return restartPodTemplate(ctx, h.K8sClient, daemonSet)
+}
+
+func (h *DaemonSetHandler) RestartDaemonSet(c *gin.Context) {
+	if err := h.Restart(c.Request.Context(), c.Param("namespace"), c.Param("name")); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "DaemonSet not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restart daemonset: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "DaemonSet restarted successfully",
+	})
+}
+
+// getDaemonSet fetches the daemonset named by the request, writing the
+// error response when it can't
+func (h *DaemonSetHandler) getDaemonSet(c *gin.Context) (*appsv1.DaemonSet, bool) {
+	var daemonSet appsv1.DaemonSet
+	if err := h.K8sClient.Client.Get(c.Request.Context(), types.NamespacedName{Namespace: c.Param("namespace"), Name: c.Param("name")}, &daemonSet); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "DaemonSet not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return &daemonSet, true
+}
+
+// daemonSetRolloutStatus summarizes the status of a daemonset. The rollout
+// is complete once the controller observed the latest generation and every
+// desired pod is updated and available.
+func daemonSetRolloutStatus(daemonSet *appsv1.DaemonSet) DaemonSetRolloutStatus {
+	status := daemonSet.Status
+	rollout := DaemonSetRolloutStatus{
+		UpdateStrategy:     daemonSet.Spec.UpdateStrategy.Type,
+		Generation:         daemonSet.Generation,
+		ObservedGeneration: status.ObservedGeneration,
+		Desired:            status.DesiredNumberScheduled,
+		Current:            status.CurrentNumberScheduled,
+		Updated:            status.UpdatedNumberScheduled,
+		Ready:              status.NumberReady,
+		Available:          status.NumberAvailable,
+		Unavailable:        status.NumberUnavailable,
+		Misscheduled:       status.NumberMisscheduled,
+	}
+	if rollout.UpdateStrategy == "" {
+		rollout.UpdateStrategy = appsv1.RollingUpdateDaemonSetStrategyType
+	}
+	if rollingUpdate := daemonSet.Spec.UpdateStrategy.RollingUpdate; rollingUpdate != nil {
+		rollout.MaxUnavailable = rollingUpdate.MaxUnavailable
+		rollout.MaxSurge = rollingUpdate.MaxSurge
+	}
+	rollout.Complete = status.ObservedGeneration >= daemonSet.Generation &&
+		status.UpdatedNumberScheduled == status.DesiredNumberScheduled &&
+		status.NumberAvailable == status.DesiredNumberScheduled
+	return rollout
+}
+
+// GetDaemonSetRolloutStatus reports the rollout progress of a daemonset
+func (h *DaemonSetHandler) GetDaemonSetRolloutStatus(c *gin.Context) {
+	daemonSet, ok := h.getDaemonSet(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, daemonSetRolloutStatus(daemonSet))
+}
+
+// listDaemonSetPods returns the pods controlled by a daemonset
+func (h *DaemonSetHandler) listDaemonSetPods(ctx context.Context, daemonSet *appsv1.DaemonSet) ([]corev1.Pod, error) {
+	selector, err := metav1.LabelSelectorAsSelector(daemonSet.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	var podList corev1.PodList
+	if err := h.K8sClient.Client.List(ctx, &podList, client.InNamespace(daemonSet.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+	pods := []corev1.Pod{}
+	for _, pod := range podList.Items {
+		if owner := metav1.GetControllerOf(&pod); owner != nil && owner.UID == daemonSet.UID {
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
+func (h *DaemonSetHandler) registerCustomRoutes(group *gin.RouterGroup) {
+	group.POST("/:namespace/:name/restart", h.RestartDaemonSet)
+	group.GET("/:namespace/:name/rollout-status", h.GetDaemonSetRolloutStatus)
+	group.GET("/:namespace/:name/nodes", h.GetDaemonSetNodes)
+	group.GET("/:namespace/:name/missing-nodes", h.GetDaemonSetMissingNodes)
+}
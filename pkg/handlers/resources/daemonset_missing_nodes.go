@@ -0,0 +1,223 @@
+package resources
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Verdicts for a Ready node without a running pod of a daemonset
+const (
+	// MissingVerdictNotEligible means the nodeSelector, node affinity or
+	// taints exclude the node, the controller never creates a pod for it
+	MissingVerdictNotEligible = "NotEligible"
+	// MissingVerdictUnschedulable means the node is eligible but a
+	// scheduling predicate such as resources or host ports fails
+	MissingVerdictUnschedulable = "Unschedulable"
+	// MissingVerdictFailedScheduling means the predicates pass here but the
+	// scheduler reported FailedScheduling for the node's pod
+	MissingVerdictFailedScheduling = "FailedScheduling"
+	// MissingVerdictPending means a pod exists for the node and is waiting to
+	// be scheduled without a reported failure
+	MissingVerdictPending = "Pending"
+	// MissingVerdictNotCreated means nothing explains the missing pod, the
+	// controller hasn't created it yet
+	MissingVerdictNotCreated = "NotCreated"
+)
+
+// DaemonSetMissingNode explains why a Ready node has no running pod of a
+// daemonset. FailedPredicates names the predicates that failed, Predicates
+// holds all of them.
+type DaemonSetMissingNode struct {
+	Node             string            `json:"node"`
+	Verdict          string            `json:"verdict"`
+	FailedPredicates []string          `json:"failedPredicates"`
+	Predicates       []PredicateResult `json:"predicates"`
+	Pod              string            `json:"pod,omitempty"`
+	SchedulingEvent  string            `json:"schedulingEvent,omitempty"`
+}
+
+// DaemonSetCrashLoopNode is a node where the daemonset's pod exists but a
+// container is in CrashLoopBackOff
+type DaemonSetCrashLoopNode struct {
+	Node         string `json:"node"`
+	Pod          string `json:"pod"`
+	Container    string `json:"container"`
+	Restarts     int32  `json:"restarts"`
+	Message      string `json:"message,omitempty"`
+	LastExitCode *int32 `json:"lastExitCode,omitempty"`
+}
+
+// crashLoopingContainer returns the status of the first container of a pod
+// in CrashLoopBackOff
+func crashLoopingContainer(pod *corev1.Pod) *corev1.ContainerStatus {
+	for i := range pod.Status.ContainerStatuses {
+		status := &pod.Status.ContainerStatuses[i]
+		if status.State.Waiting != nil && status.State.Waiting.Reason == "CrashLoopBackOff" {
+			return status
+		}
+	}
+	return nil
+}
+
+// latestFailedScheduling returns the message of the latest FailedScheduling
+// event of each pod in a namespace
+func (h *DaemonSetHandler) latestFailedScheduling(ctx context.Context, namespace string) (map[string]string, error) {
+	eventList, err := h.K8sClient.ClientSet.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.SelectorFromSet(fields.Set{
+			"involvedObject.kind": "Pod",
+			"reason":              "FailedScheduling",
+		}).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	latest := map[string]corev1.Event{}
+	for _, event := range eventList.Items {
+		name := event.InvolvedObject.Name
+		if previous, ok := latest[name]; !ok || eventTimestamp(&event).After(eventTimestamp(&previous)) {
+			latest[name] = event
+		}
+	}
+	messages := make(map[string]string, len(latest))
+	for name, event := range latest {
+		messages[name] = event.Message
+	}
+	return messages, nil
+}
+
+// GetDaemonSetMissingNodes explains, for every Ready node without a running
+// pod of a daemonset, why the pod is missing. The pod template, with the
+// tolerations the controller adds, is evaluated with the same predicates as
+// the node can-schedule simulation, and FailedScheduling events of pending
+// pods are attached. Nodes whose pod is in CrashLoopBackOff are listed
+// separately.
+func (h *DaemonSetHandler) GetDaemonSetMissingNodes(c *gin.Context) {
+	ctx := c.Request.Context()
+	daemonSet, ok := h.getDaemonSet(c)
+	if !ok {
+		return
+	}
+
+	pods, err := h.listDaemonSetPods(ctx, daemonSet)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list pods: " + err.Error()})
+		return
+	}
+	var nodeList corev1.NodeList
+	if err := h.K8sClient.Client.List(ctx, &nodeList); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list nodes: " + err.Error()})
+		return
+	}
+
+	warnings := []string{}
+	schedulingEvents, err := h.latestFailedScheduling(ctx, daemonSet.Namespace)
+	if err != nil {
+		warnings = append(warnings, "Failed to list FailedScheduling events: "+err.Error())
+		schedulingEvents = map[string]string{}
+	}
+
+	scheduled := map[string]*corev1.Pod{}
+	pending := map[string]*corev1.Pod{}
+	for i := range pods {
+		pod := &pods[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		if pod.Spec.NodeName != "" {
+			scheduled[pod.Spec.NodeName] = pod
+		} else if nodeName := daemonPodNode(pod); nodeName != "" {
+			pending[nodeName] = pod
+		}
+	}
+
+	template := daemonSetPod(daemonSet)
+	missing := []DaemonSetMissingNode{}
+	crashLooping := []DaemonSetCrashLoopNode{}
+	for i := range nodeList.Items {
+		node := &nodeList.Items[i]
+		if !isNodeReady(node) {
+			continue
+		}
+
+		if pod := scheduled[node.Name]; pod != nil {
+			if status := crashLoopingContainer(pod); status != nil {
+				entry := DaemonSetCrashLoopNode{
+					Node:      node.Name,
+					Pod:       pod.Name,
+					Container: status.Name,
+					Restarts:  status.RestartCount,
+					Message:   status.State.Waiting.Message,
+				}
+				if last := status.LastTerminationState.Terminated; last != nil {
+					exitCode := last.ExitCode
+					entry.LastExitCode = &exitCode
+				}
+				crashLooping = append(crashLooping, entry)
+			}
+			continue
+		}
+
+		nodePods, err := h.listPodsOnNode(ctx, node.Name)
+		if err != nil {
+			warnings = append(warnings, "Failed to list pods on node "+node.Name+": "+err.Error())
+		}
+		predicates, schedulable := evaluateSchedulingPredicates(template, node, nodePods)
+		entry := DaemonSetMissingNode{
+			Node:             node.Name,
+			FailedPredicates: []string{},
+			Predicates:       predicates,
+		}
+		eligible := true
+		for _, predicate := range predicates {
+			if predicate.Passed {
+				continue
+			}
+			entry.FailedPredicates = append(entry.FailedPredicates, predicate.Name)
+			if predicate.Name == PredicateNodeAffinity || predicate.Name == PredicateTaintToleration {
+				eligible = false
+			}
+		}
+		if pod := pending[node.Name]; pod != nil {
+			entry.Pod = pod.Name
+			entry.SchedulingEvent = schedulingEvents[pod.Name]
+		}
+
+		switch {
+		case !eligible:
+			entry.Verdict = MissingVerdictNotEligible
+		case !schedulable:
+			entry.Verdict = MissingVerdictUnschedulable
+		case entry.SchedulingEvent != "":
+			entry.Verdict = MissingVerdictFailedScheduling
+		case entry.Pod != "":
+			entry.Verdict = MissingVerdictPending
+		default:
+			entry.Verdict = MissingVerdictNotCreated
+		}
+		missing = append(missing, entry)
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i].Node < missing[j].Node })
+	sort.Slice(crashLooping, func(i, j int) bool { return crashLooping[i].Node < crashLooping[j].Node })
+
+	c.JSON(http.StatusOK, gin.H{
+		"missingNodes": missing,
+		"crashLooping": crashLooping,
+		"warnings":     warnings,
+	})
+}
+
+// listPodsOnNode lists the pods of all namespaces scheduled on a node
+func (h *DaemonSetHandler) listPodsOnNode(ctx context.Context, nodeName string) ([]corev1.Pod, error) {
+	var podList corev1.PodList
+	if err := h.K8sClient.Client.List(ctx, &podList, client.MatchingFields{"spec.nodeName": nodeName}); err != nil {
+		return nil, err
+	}
+	return podList.Items, nil
+}
@@ -0,0 +1,194 @@
+package resources
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Node coverage of a daemonset
+const (
+	DaemonSetNodeReady    = "ready"
+	DaemonSetNodeNotReady = "notReady"
+	DaemonSetNodeMissing  = "missing"
+
+	DaemonSetMissingNodeSelector     = "NodeSelectorMismatch"
+	DaemonSetMissingUntoleratedTaint = "UntoleratedTaint"
+	DaemonSetMissingPending          = "PendingScheduling"
+)
+
+// DaemonSetNodeCoverage is the state of a daemonset on one node. Eligible
+// nodes are the ones the daemonset should run on, a missing pod on a node
+// that isn't eligible is expected.
+type DaemonSetNodeCoverage struct {
+	Node     string   `json:"node"`
+	Status   string   `json:"status"`
+	Eligible bool     `json:"eligible"`
+	Pod      string   `json:"pod,omitempty"`
+	PodPhase string   `json:"podPhase,omitempty"`
+	Reason   string   `json:"reason,omitempty"`
+	Taints   []string `json:"taints,omitempty"`
+	Details  []string `json:"details,omitempty"`
+}
+
+// daemonSetPod returns a pod from the template of a daemonset with the
+// tolerations the daemonset controller adds to every daemon pod
+func daemonSetPod(daemonSet *appsv1.DaemonSet) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: *daemonSet.Spec.Template.ObjectMeta.DeepCopy(),
+		Spec:       *daemonSet.Spec.Template.Spec.DeepCopy(),
+	}
+	pod.Namespace = daemonSet.Namespace
+
+	defaults := []corev1.Toleration{
+		{Key: corev1.TaintNodeNotReady, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute},
+		{Key: corev1.TaintNodeUnreachable, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute},
+		{Key: corev1.TaintNodeDiskPressure, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+		{Key: corev1.TaintNodeMemoryPressure, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+		{Key: corev1.TaintNodePIDPressure, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+		{Key: corev1.TaintNodeUnschedulable, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+	}
+	if pod.Spec.HostNetwork {
+		defaults = append(defaults, corev1.Toleration{Key: corev1.TaintNodeNetworkUnavailable, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule})
+	}
+	pod.Spec.Tolerations = append(pod.Spec.Tolerations, defaults...)
+	return pod
+}
+
+// daemonPodNode returns the node a daemon pod runs on or, while it is
+// unscheduled, the node its metadata.name affinity targets
+func daemonPodNode(pod *corev1.Pod) string {
+	if pod.Spec.NodeName != "" {
+		return pod.Spec.NodeName
+	}
+	affinity := pod.Spec.Affinity
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return ""
+	}
+	for _, term := range affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		for _, field := range term.MatchFields {
+			if field.Key == "metadata.name" && field.Operator == corev1.NodeSelectorOpIn && len(field.Values) == 1 {
+				return field.Values[0]
+			}
+		}
+	}
+	return ""
+}
+
+// untoleratedTaints returns the NoSchedule and NoExecute taints of a node
+// that the pod doesn't tolerate
+func untoleratedTaints(pod *corev1.Pod, node *corev1.Node) []string {
+	var taints []string
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		if taint.Effect == corev1.TaintEffectPreferNoSchedule || toleratesTaint(pod.Spec.Tolerations, taint) {
+			continue
+		}
+		taints = append(taints, taint.ToString())
+	}
+	return taints
+}
+
+// daemonSetNodeCoverage cross-references the pods of a daemonset with the
+// nodes. A node without a pod is explained by the nodeSelector and required
+// node affinity, then by taints, and otherwise is pending scheduling.
+func daemonSetNodeCoverage(daemonSet *appsv1.DaemonSet, pods []corev1.Pod, nodes []corev1.Node) []DaemonSetNodeCoverage {
+	template := daemonSetPod(daemonSet)
+
+	podsByNode := make(map[string]*corev1.Pod, len(pods))
+	for i := range pods {
+		pod := &pods[i]
+		nodeName := daemonPodNode(pod)
+		if nodeName == "" {
+			continue
+		}
+		// Prefer the pod that isn't terminating when a node has two
+		if existing, ok := podsByNode[nodeName]; ok && existing.DeletionTimestamp == nil {
+			continue
+		}
+		podsByNode[nodeName] = pod
+	}
+
+	coverage := make([]DaemonSetNodeCoverage, 0, len(nodes))
+	for i := range nodes {
+		node := &nodes[i]
+		entry := DaemonSetNodeCoverage{Node: node.Name}
+
+		affinity := checkNodeAffinity(template, node)
+		taints := untoleratedTaints(template, node)
+		entry.Eligible = affinity.Passed && len(taints) == 0
+
+		pod := podsByNode[node.Name]
+		switch {
+		case pod != nil && pod.Spec.NodeName != "":
+			entry.Pod = pod.Name
+			entry.PodPhase = string(pod.Status.Phase)
+			entry.Status = DaemonSetNodeNotReady
+			if utils.IsPodReady(pod) {
+				entry.Status = DaemonSetNodeReady
+			}
+		case !affinity.Passed:
+			entry.Status = DaemonSetNodeMissing
+			entry.Reason = DaemonSetMissingNodeSelector
+			entry.Details = affinity.Reasons
+		case len(taints) > 0:
+			entry.Status = DaemonSetNodeMissing
+			entry.Reason = DaemonSetMissingUntoleratedTaint
+			entry.Taints = taints
+		default:
+			entry.Status = DaemonSetNodeMissing
+			entry.Reason = DaemonSetMissingPending
+			if pod != nil {
+				entry.Pod = pod.Name
+				entry.PodPhase = string(pod.Status.Phase)
+				entry.Details = []string{"pod " + pod.Name + " is not scheduled yet"}
+			} else {
+				entry.Details = []string{"no pod has been created for this node"}
+			}
+		}
+		coverage = append(coverage, entry)
+	}
+	sort.Slice(coverage, func(i, j int) bool { return coverage[i].Node < coverage[j].Node })
+	return coverage
+}
+
+// GetDaemonSetNodes reports for every node whether the daemonset's pod is
+// there and ready, and why it is missing where it isn't
+func (h *DaemonSetHandler) GetDaemonSetNodes(c *gin.Context) {
+	ctx := c.Request.Context()
+	daemonSet, ok := h.getDaemonSet(c)
+	if !ok {
+		return
+	}
+
+	pods, err := h.listDaemonSetPods(ctx, daemonSet)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list pods: " + err.Error()})
+		return
+	}
+	var nodeList corev1.NodeList
+	if err := h.K8sClient.Client.List(ctx, &nodeList); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list nodes: " + err.Error()})
+		return
+	}
+
+	coverage := daemonSetNodeCoverage(daemonSet, pods, nodeList.Items)
+	summary := map[string]int{}
+	missingEligible := 0
+	for _, entry := range coverage {
+		summary[entry.Status]++
+		if entry.Status == DaemonSetNodeMissing && entry.Eligible {
+			missingEligible++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"nodes":           coverage,
+		"summary":         summary,
+		"missingEligible": missingEligible,
+	})
+}
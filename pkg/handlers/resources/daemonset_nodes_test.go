@@ -0,0 +1,199 @@
+package resources
+
+import (
+	"slices"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func coverageTestDaemonSet() *appsv1.DaemonSet {
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "kube-system"},
+		Spec: appsv1.DaemonSetSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{NodeSelector: map[string]string{"kubernetes.io/os": "linux"}},
+			},
+		},
+	}
+}
+
+func coverageTestNode(name string, taints ...corev1.Taint) corev1.Node {
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"kubernetes.io/os": "linux"}},
+		Spec:       corev1.NodeSpec{Taints: taints},
+	}
+}
+
+func coverageTestPod(name, nodeName string, ready bool) corev1.Pod {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kube-system"},
+		Spec:       corev1.PodSpec{NodeName: nodeName},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	if ready {
+		pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+	}
+	return pod
+}
+
+// pendingCoveragePod returns an unscheduled daemon pod pinned to a node by
+// metadata.name matchFields, like the daemonset controller creates them
+func pendingCoveragePod(name, nodeName string) corev1.Pod {
+	pod := coverageTestPod(name, "", false)
+	pod.Status.Phase = corev1.PodPending
+	pod.Spec.Affinity = requiredAffinity(corev1.NodeSelectorTerm{MatchFields: []corev1.NodeSelectorRequirement{
+		{Key: "metadata.name", Operator: corev1.NodeSelectorOpIn, Values: []string{nodeName}},
+	}})
+	return pod
+}
+
+func TestDaemonSetNodeCoverage(t *testing.T) {
+	windows := coverageTestNode("windows-1")
+	windows.Labels["kubernetes.io/os"] = "windows"
+	terminating := coverageTestPod("agent-old", "node-1", true)
+	terminating.DeletionTimestamp = &metav1.Time{}
+
+	tests := []struct {
+		name        string
+		pods        []corev1.Pod
+		node        corev1.Node
+		want        DaemonSetNodeCoverage
+		hostNetwork bool
+	}{
+		{
+			name: "ready pod",
+			pods: []corev1.Pod{coverageTestPod("agent-a", "node-1", true)},
+			node: coverageTestNode("node-1"),
+			want: DaemonSetNodeCoverage{Node: "node-1", Status: DaemonSetNodeReady, Eligible: true, Pod: "agent-a", PodPhase: "Running"},
+		},
+		{
+			name: "pod not ready",
+			pods: []corev1.Pod{coverageTestPod("agent-a", "node-1", false)},
+			node: coverageTestNode("node-1"),
+			want: DaemonSetNodeCoverage{Node: "node-1", Status: DaemonSetNodeNotReady, Eligible: true, Pod: "agent-a", PodPhase: "Running"},
+		},
+		{
+			name: "selector mismatch",
+			node: windows,
+			want: DaemonSetNodeCoverage{
+				Node:    "windows-1",
+				Status:  DaemonSetNodeMissing,
+				Reason:  DaemonSetMissingNodeSelector,
+				Details: []string{"nodeSelector kubernetes.io/os=linux does not match"},
+			},
+		},
+		{
+			name: "untolerated taint",
+			node: coverageTestNode("gpu-1", corev1.Taint{Key: "nvidia.com/gpu", Value: "true", Effect: corev1.TaintEffectNoSchedule}),
+			want: DaemonSetNodeCoverage{
+				Node:   "gpu-1",
+				Status: DaemonSetNodeMissing,
+				Reason: DaemonSetMissingUntoleratedTaint,
+				Taints: []string{"nvidia.com/gpu=true:NoSchedule"},
+			},
+		},
+		{
+			name: "PreferNoSchedule taint keeps the node eligible",
+			node: coverageTestNode("node-1", corev1.Taint{Key: "spot", Effect: corev1.TaintEffectPreferNoSchedule}),
+			want: DaemonSetNodeCoverage{
+				Node:     "node-1",
+				Status:   DaemonSetNodeMissing,
+				Eligible: true,
+				Reason:   DaemonSetMissingPending,
+				Details:  []string{"no pod has been created for this node"},
+			},
+		},
+		{
+			name: "controller default tolerations cover cordoned and not-ready nodes",
+			node: coverageTestNode("node-1",
+				corev1.Taint{Key: corev1.TaintNodeUnschedulable, Effect: corev1.TaintEffectNoSchedule},
+				corev1.Taint{Key: corev1.TaintNodeNotReady, Effect: corev1.TaintEffectNoExecute},
+				corev1.Taint{Key: corev1.TaintNodeUnreachable, Effect: corev1.TaintEffectNoExecute},
+				corev1.Taint{Key: corev1.TaintNodeMemoryPressure, Effect: corev1.TaintEffectNoSchedule},
+			),
+			want: DaemonSetNodeCoverage{
+				Node:     "node-1",
+				Status:   DaemonSetNodeMissing,
+				Eligible: true,
+				Reason:   DaemonSetMissingPending,
+				Details:  []string{"no pod has been created for this node"},
+			},
+		},
+		{
+			name: "network-unavailable is only tolerated with hostNetwork",
+			node: coverageTestNode("node-1", corev1.Taint{Key: corev1.TaintNodeNetworkUnavailable, Effect: corev1.TaintEffectNoSchedule}),
+			want: DaemonSetNodeCoverage{
+				Node:   "node-1",
+				Status: DaemonSetNodeMissing,
+				Reason: DaemonSetMissingUntoleratedTaint,
+				Taints: []string{corev1.TaintNodeNetworkUnavailable + ":NoSchedule"},
+			},
+		},
+		{
+			name:        "network-unavailable with hostNetwork",
+			node:        coverageTestNode("node-1", corev1.Taint{Key: corev1.TaintNodeNetworkUnavailable, Effect: corev1.TaintEffectNoSchedule}),
+			hostNetwork: true,
+			pods:        []corev1.Pod{coverageTestPod("agent-a", "node-1", true)},
+			want:        DaemonSetNodeCoverage{Node: "node-1", Status: DaemonSetNodeReady, Eligible: true, Pod: "agent-a", PodPhase: "Running"},
+		},
+		{
+			name: "pending pod pinned by matchFields",
+			pods: []corev1.Pod{pendingCoveragePod("agent-p", "node-1"), pendingCoveragePod("agent-q", "node-2")},
+			node: coverageTestNode("node-1"),
+			want: DaemonSetNodeCoverage{
+				Node:     "node-1",
+				Status:   DaemonSetNodeMissing,
+				Eligible: true,
+				Reason:   DaemonSetMissingPending,
+				Pod:      "agent-p",
+				PodPhase: "Pending",
+				Details:  []string{"pod agent-p is not scheduled yet"},
+			},
+		},
+		{
+			name: "replacement preferred over a terminating pod listed first",
+			pods: []corev1.Pod{terminating, coverageTestPod("agent-new", "node-1", false)},
+			node: coverageTestNode("node-1"),
+			want: DaemonSetNodeCoverage{Node: "node-1", Status: DaemonSetNodeNotReady, Eligible: true, Pod: "agent-new", PodPhase: "Running"},
+		},
+		{
+			name: "replacement kept over a terminating pod listed last",
+			pods: []corev1.Pod{coverageTestPod("agent-new", "node-1", false), terminating},
+			node: coverageTestNode("node-1"),
+			want: DaemonSetNodeCoverage{Node: "node-1", Status: DaemonSetNodeNotReady, Eligible: true, Pod: "agent-new", PodPhase: "Running"},
+		},
+		{
+			name: "terminating pod is reported while it is the only one",
+			pods: []corev1.Pod{terminating},
+			node: coverageTestNode("node-1"),
+			want: DaemonSetNodeCoverage{Node: "node-1", Status: DaemonSetNodeReady, Eligible: true, Pod: "agent-old", PodPhase: "Running"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			daemonSet := coverageTestDaemonSet()
+			daemonSet.Spec.Template.Spec.HostNetwork = tt.hostNetwork
+			coverage := daemonSetNodeCoverage(daemonSet, tt.pods, []corev1.Node{tt.node})
+			if len(coverage) != 1 {
+				t.Fatalf("got %d entries, want 1", len(coverage))
+			}
+			got := coverage[0]
+			if got.Node != tt.want.Node || got.Status != tt.want.Status || got.Eligible != tt.want.Eligible ||
+				got.Reason != tt.want.Reason || got.Pod != tt.want.Pod || got.PodPhase != tt.want.PodPhase ||
+				!slices.Equal(got.Taints, tt.want.Taints) || !slices.Equal(got.Details, tt.want.Details) {
+				t.Errorf("coverage = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDaemonSetNodeCoverageSortsNodes(t *testing.T) {
+	nodes := []corev1.Node{coverageTestNode("node-b"), coverageTestNode("node-a")}
+	coverage := daemonSetNodeCoverage(coverageTestDaemonSet(), nil, nodes)
+	if len(coverage) != 2 || coverage[0].Node != "node-a" || coverage[1].Node != "node-b" {
+		t.Errorf("coverage = %+v, want node-a then node-b", coverage)
+	}
+}
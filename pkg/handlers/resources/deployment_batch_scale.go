@@ -0,0 +1,322 @@
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// previousReplicasAnnotation records the replicas a deployment had before
+// the last scale so that the scale can be undone
+const previousReplicasAnnotation = "kite.kubernetes.io/previous-replicas"
+
+// DeploymentScaleTarget is a deployment and the replicas to scale it to
+type DeploymentScaleTarget struct {
+	Namespace string `json:"namespace" binding:"required"`
+	Name      string `json:"name" binding:"required"`
+	Replicas  *int32 `json:"replicas" binding:"required,min=0"`
+}
+
+// BatchScaleRequest scales either the listed deployments or every deployment
+// in a namespace matching a label selector
+type BatchScaleRequest struct {
+	Deployments []DeploymentScaleTarget `json:"deployments" binding:"dive"`
+	// Namespace, LabelSelector and Replicas select deployments by label
+	// instead of listing them
+	Namespace     string `json:"namespace"`
+	LabelSelector string `json:"labelSelector"`
+	Replicas      *int32 `json:"replicas" binding:"omitempty,min=0"`
+	// MaxConcurrency bounds how many deployments are scaled at the same
+	// time, see utils.BatchConcurrency
+	MaxConcurrency int `json:"maxConcurrency,omitempty" binding:"min=0"`
+	// RecordChangeCause annotates each deployment with the change cause,
+	// defaults to true
+	RecordChangeCause *bool `json:"recordChangeCause,omitempty"`
+}
+
+// DeploymentScaleResult represents the result of scaling a single deployment
+type DeploymentScaleResult struct {
+	Namespace        string `json:"namespace"`
+	Name             string `json:"name"`
+	Success          bool   `json:"success"`
+	PreviousReplicas *int32 `json:"previousReplicas,omitempty"`
+	Replicas         int32  `json:"replicas"`
+	Error            string `json:"error,omitempty"`
+}
+
+// resolveScaleTargets returns the deployments a batch scale request applies to
+func (h *DeploymentHandler) resolveScaleTargets(ctx context.Context, req *BatchScaleRequest) ([]DeploymentScaleTarget, error) {
+	if req.LabelSelector == "" {
+		if req.Namespace != "" || req.Replicas != nil {
+			return nil, fmt.Errorf("namespace and replicas require a labelSelector")
+		}
+		if len(req.Deployments) == 0 {
+			return nil, fmt.Errorf("No deployments specified for scale")
+		}
+		return req.Deployments, nil
+	}
+
+	if len(req.Deployments) > 0 {
+		return nil, fmt.Errorf("deployments and labelSelector are mutually exclusive")
+	}
+	if req.Namespace == "" || req.Replicas == nil {
+		return nil, fmt.Errorf("labelSelector requires namespace and replicas")
+	}
+	selector, err := labels.Parse(req.LabelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid labelSelector: %w", err)
+	}
+	var deploymentList appsv1.DeploymentList
+	if err := h.K8sClient.Client.List(ctx, &deploymentList, client.InNamespace(req.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+	targets := make([]DeploymentScaleTarget, 0, len(deploymentList.Items))
+	for _, deployment := range deploymentList.Items {
+		targets = append(targets, DeploymentScaleTarget{
+			Namespace: deployment.Namespace,
+			Name:      deployment.Name,
+			Replicas:  req.Replicas,
+		})
+	}
+	return targets, nil
+}
+
+// ScaleDeploymentsBatch scales multiple deployments concurrently. The
+// previous replicas of each deployment are recorded in
+// previousReplicasAnnotation.
+func (h *DeploymentHandler) ScaleDeploymentsBatch(c *gin.Context) {
+	var req BatchScaleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request body: %v", err)})
+		return
+	}
+
+	targets, err := h.resolveScaleTargets(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(targets) == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"message":    "No deployments match the label selector",
+			"total":      0,
+			"successful": 0,
+			"failed":     0,
+			"results":    []DeploymentScaleResult{},
+			"timestamp":  time.Now().Format(time.RFC3339),
+		})
+		return
+	}
+
+	klog.Infof("Starting batch scale for %d deployments", len(targets))
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
+	defer cancel()
+
+	results := utils.RunBatch(ctx, len(targets), utils.BatchConcurrency(req.MaxConcurrency),
+		func(ctx context.Context, i int) DeploymentScaleResult {
+			annotations := changeCauseAnnotations(c, req.RecordChangeCause, fmt.Sprintf("batch scale to %d", *targets[i].Replicas))
+			return h.scaleSingleDeployment(ctx, targets[i], annotations)
+		}, func(i int, err error) DeploymentScaleResult {
+			return DeploymentScaleResult{
+				Namespace: targets[i].Namespace,
+				Name:      targets[i].Name,
+				Replicas:  *targets[i].Replicas,
+				Error:     fmt.Sprintf("Not processed: %v", err),
+			}
+		})
+
+	var successCount, failureCount int
+	for _, result := range results {
+		if result.Success {
+			successCount++
+		} else {
+			failureCount++
+		}
+	}
+
+	klog.Infof("Batch deployment scale completed: %d successful, %d failed", successCount, failureCount)
+
+	response := gin.H{
+		"message":    fmt.Sprintf("Batch deployment scale completed: %d successful, %d failed", successCount, failureCount),
+		"total":      len(targets),
+		"successful": successCount,
+		"failed":     failureCount,
+		"results":    results,
+		"timestamp":  time.Now().Format(time.RFC3339),
+	}
+
+	if failureCount > 0 {
+		c.JSON(http.StatusPartialContent, response)
+	} else {
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// scaleSingleDeployment records the current replicas of a deployment in
+// previousReplicasAnnotation, along with the given annotations, and scales
+// it to the target replicas
+func (h *DeploymentHandler) scaleSingleDeployment(ctx context.Context, target DeploymentScaleTarget, annotations map[string]string) DeploymentScaleResult {
+	result := DeploymentScaleResult{
+		Namespace: target.Namespace,
+		Name:      target.Name,
+		Replicas:  *target.Replicas,
+	}
+
+	var deployment appsv1.Deployment
+	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Namespace: target.Namespace, Name: target.Name}, &deployment); err != nil {
+		if errors.IsNotFound(err) {
+			result.Error = "Deployment not found"
+		} else {
+			result.Error = fmt.Sprintf("Failed to get deployment: %v", err)
+		}
+		return result
+	}
+
+	previous := int32(1)
+	if deployment.Spec.Replicas != nil {
+		previous = *deployment.Spec.Replicas
+	}
+	result.PreviousReplicas = &previous
+	if previous == *target.Replicas {
+		result.Success = true
+		return result
+	}
+
+	recorded := map[string]string{previousReplicasAnnotation: strconv.Itoa(int(previous))}
+	for key, value := range annotations {
+		recorded[key] = value
+	}
+	if err := h.setDeploymentAnnotations(ctx, target.Namespace, target.Name, recorded); err != nil {
+		result.Error = fmt.Sprintf("Failed to record previous replicas: %v", err)
+		return result
+	}
+
+	if _, err := h.scaleReplicas(ctx, target.Namespace, target.Name, *target.Replicas); err != nil {
+		result.Error = fmt.Sprintf("Failed to scale deployment: %v", err)
+		klog.Errorf("Failed to scale deployment %s/%s: %v", target.Namespace, target.Name, err)
+		return result
+	}
+
+	result.Success = true
+	klog.Infof("Scaled deployment %s/%s from %d to %d replicas", target.Namespace, target.Name, previous, *target.Replicas)
+	return result
+}
+
+// UndoScaleDeployment scales a deployment back to the replicas recorded in
+// previousReplicasAnnotation by the last scale and clears the annotation.
+// Without the annotation there is nothing to undo and 409 is returned.
+func (h *DeploymentHandler) UndoScaleDeployment(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	ctx := c.Request.Context()
+
+	var undoRequest struct {
+		RecordChangeCause *bool `json:"recordChangeCause"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&undoRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+	}
+
+	var deployment appsv1.Deployment
+	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &deployment); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Deployment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get deployment: " + err.Error()})
+		return
+	}
+
+	value, ok := deployment.Annotations[previousReplicasAnnotation]
+	if !ok {
+		c.JSON(http.StatusConflict, gin.H{"error": "Nothing to undo, the deployment has no recorded previous replicas"})
+		return
+	}
+	previous, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || previous < 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Nothing to undo, the recorded previous replicas %q are invalid", value)})
+		return
+	}
+	current := int32(1)
+	if deployment.Spec.Replicas != nil {
+		current = *deployment.Spec.Replicas
+	}
+
+	scale, err := h.scaleReplicas(ctx, namespace, name, int32(previous))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scale deployment: " + err.Error()})
+		return
+	}
+
+	// Clearing the annotation also keeps a second undo from scaling back
+	// and forth
+	annotations := map[string]*string{previousReplicasAnnotation: nil}
+	for key, value := range changeCauseAnnotations(c, undoRequest.RecordChangeCause, fmt.Sprintf("undo scale to %d", previous)) {
+		annotations[key] = &value
+	}
+	response := gin.H{
+		"message":          fmt.Sprintf("Deployment scaled back from %d to %d replicas", current, previous),
+		"scale":            scale,
+		"replicas":         scale.Spec.Replicas,
+		"previousReplicas": current,
+	}
+	if err := h.patchDeploymentAnnotations(ctx, namespace, name, annotations); err != nil {
+		klog.Warningf("Failed to clear %s on deployment %s/%s: %v", previousReplicasAnnotation, namespace, name, err)
+		response["warning"] = fmt.Sprintf("Failed to clear %s annotation: %v", previousReplicasAnnotation, err)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// setDeploymentAnnotation sets an annotation on a deployment with a merge
+// patch
+func (h *DeploymentHandler) setDeploymentAnnotation(ctx context.Context, namespace, name, key, value string) error {
+	return h.patchDeploymentAnnotations(ctx, namespace, name, map[string]*string{key: &value})
+}
+
+// setDeploymentAnnotations sets annotations on a deployment with a merge
+// patch
+func (h *DeploymentHandler) setDeploymentAnnotations(ctx context.Context, namespace, name string, annotations map[string]string) error {
+	values := make(map[string]*string, len(annotations))
+	for key, value := range annotations {
+		values[key] = &value
+	}
+	return h.patchDeploymentAnnotations(ctx, namespace, name, values)
+}
+
+// removeDeploymentAnnotation removes an annotation from a deployment with a
+// merge patch
+func (h *DeploymentHandler) removeDeploymentAnnotation(ctx context.Context, namespace, name, key string) error {
+	return h.patchDeploymentAnnotations(ctx, namespace, name, map[string]*string{key: nil})
+}
+
+// patchDeploymentAnnotations merge patches the annotations of a deployment,
+// nil values remove the annotation
+func (h *DeploymentHandler) patchDeploymentAnnotations(ctx context.Context, namespace, name string, annotations map[string]*string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	return h.K8sClient.Client.Patch(ctx, deployment, client.RawPatch(types.MergePatchType, patch))
+}
@@ -0,0 +1,115 @@
+package resources
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/utils"
+	"k8s.io/klog/v2"
+)
+
+// wantsEventStream reports whether the client asked for a server-sent
+// events response with Accept: text/event-stream
+func wantsEventStream(c *gin.Context) bool {
+	return strings.Contains(c.GetHeader("Accept"), "text/event-stream")
+}
+
+// writeDeploymentBatchResults consumes the results of a batch operation and
+// either streams them as server-sent events or, by default, responds with
+// all results at once. Both paths read the same channel so they report the
+// same results. operation names the batch in messages, e.g. "Batch
+// deployment restart".
+func writeDeploymentBatchResults(c *gin.Context, operation string, total int, items <-chan utils.BatchItem[DeploymentRestartResult]) {
+	if wantsEventStream(c) {
+		streamDeploymentBatchResults(c, operation, total, items)
+		return
+	}
+
+	results := make([]DeploymentRestartResult, total)
+	var successCount, failureCount, stoppedAt int
+	for item := range items {
+		results[item.Index] = item.Result
+		if item.Result.Success {
+			successCount++
+		} else {
+			failureCount++
+			stoppedAt = stoppedAtGroup(stoppedAt, item.Result)
+		}
+	}
+
+	response := deploymentBatchSummary(operation, total, successCount, failureCount, stoppedAt)
+	response["results"] = results
+
+	if failureCount > 0 {
+		c.JSON(http.StatusPartialContent, response)
+	} else {
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// deploymentBatchSummary logs and returns the totals of a batch operation.
+// stoppedAt is the first failed group of a staggered restart, or 0.
+func deploymentBatchSummary(operation string, total, successCount, failureCount, stoppedAt int) gin.H {
+	klog.Infof("%s completed: %d successful, %d failed", operation, successCount, failureCount)
+	summary := gin.H{
+		"message":    fmt.Sprintf("%s completed: %d successful, %d failed", operation, successCount, failureCount),
+		"total":      total,
+		"successful": successCount,
+		"failed":     failureCount,
+		"timestamp":  time.Now().Format(time.RFC3339),
+	}
+	if stoppedAt > 0 {
+		summary["stoppedAtGroup"] = stoppedAt
+	}
+	return summary
+}
+
+// stoppedAtGroup returns the earlier of stoppedAt and the group of a failed
+// result, ignoring results outside of groups
+func stoppedAtGroup(stoppedAt int, failed DeploymentRestartResult) int {
+	if failed.Group > 0 && (stoppedAt == 0 || failed.Group < stoppedAt) {
+		return failed.Group
+	}
+	return stoppedAt
+}
+
+// streamDeploymentBatchResults writes a result event per finished item,
+// heartbeats while items are running and a summary event at the end
+func streamDeploymentBatchResults(c *gin.Context, operation string, total int, items <-chan utils.BatchItem[DeploymentRestartResult]) {
+	connected := startSSE(c) == nil
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	var successCount, failureCount, stoppedAt int
+	for {
+		select {
+		case item, ok := <-items:
+			if !ok {
+				summary := deploymentBatchSummary(operation, total, successCount, failureCount, stoppedAt)
+				if connected {
+					_ = writeSSEEvent(c, "summary", summary)
+				}
+				return
+			}
+			if item.Result.Success {
+				successCount++
+			} else {
+				failureCount++
+				stoppedAt = stoppedAtGroup(stoppedAt, item.Result)
+			}
+			// Keep draining after the client went away so the totals are
+			// still logged
+			if connected && writeSSEEvent(c, "result", gin.H{"index": item.Index, "result": item.Result}) != nil {
+				connected = false
+			}
+		case <-heartbeat.C:
+			if connected && writeSSEHeartbeat(c) != nil {
+				connected = false
+			}
+		}
+	}
+}
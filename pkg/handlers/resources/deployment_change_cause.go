@@ -0,0 +1,28 @@
+package resources
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/utils"
+)
+
+// changedByAnnotation records the user who last changed a deployment
+// through kite
+const changedByAnnotation = "kite.kubernetes.io/changed-by"
+
+// changeCauseAnnotations returns the annotations that record why and by whom
+// kite changed a deployment, e.g. "kite: restart by alice". The deployment
+// controller copies them to the new ReplicaSet, so they show up in the
+// rollout history. record defaults to true, false returns nil.
+func changeCauseAnnotations(c *gin.Context, record *bool, action string) map[string]string {
+	if record != nil && !*record {
+		return nil
+	}
+	user := utils.ActingUser(c)
+	if user == "unknown" || user == "anonymous" {
+		return map[string]string{changeCauseAnnotation: "kite: " + action}
+	}
+	return map[string]string{
+		changeCauseAnnotation: "kite: " + action + " by " + user,
+		changedByAnnotation:   user,
+	}
+}
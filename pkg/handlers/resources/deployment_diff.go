@@ -0,0 +1,143 @@
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/utils"
+	"gomodules.xyz/jsonpatch/v2"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// DiffError is a validation error the apiserver returned for the proposed
+// manifest, such as a change to an immutable field
+type DiffError struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// normalizeDeploymentForDiff returns a deployment as YAML and JSON without
+// the fields that change on every write
+func normalizeDeploymentForDiff(deployment *appsv1.Deployment) ([]byte, []byte, error) {
+	normalized := deployment.DeepCopy()
+	normalized.APIVersion = appsv1.SchemeGroupVersion.String()
+	normalized.Kind = "Deployment"
+	normalized.ManagedFields = nil
+	normalized.ResourceVersion = ""
+
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, nil, err
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, nil, err
+	}
+	delete(object, "status")
+	data, err = json.Marshal(object)
+	if err != nil {
+		return nil, nil, err
+	}
+	yamlData, err := yaml.JSONToYAML(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, yamlData, nil
+}
+
+// diffErrors extracts the field causes of an apiserver validation error
+func diffErrors(err error) []DiffError {
+	diffErrs := []DiffError{}
+	if status, ok := err.(errors.APIStatus); ok && status.Status().Details != nil {
+		for _, cause := range status.Status().Details.Causes {
+			diffErrs = append(diffErrs, DiffError{Field: cause.Field, Message: cause.Message})
+		}
+	}
+	if len(diffErrs) == 0 {
+		diffErrs = append(diffErrs, DiffError{Message: err.Error()})
+	}
+	return diffErrs
+}
+
+// DiffDeployment dry-runs an update of a deployment with the submitted
+// manifest and returns how the stored object would change, including
+// defaulting and mutating webhooks. Validation errors such as selector
+// changes are returned in the response instead of failing the request.
+func (h *DeploymentHandler) DiffDeployment(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	ctx := c.Request.Context()
+
+	var proposed appsv1.Deployment
+	if err := c.ShouldBindJSON(&proposed); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if (proposed.Name != "" && proposed.Name != name) || (proposed.Namespace != "" && proposed.Namespace != namespace) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("manifest is not for deployment %s/%s", namespace, name)})
+		return
+	}
+	proposed.Name = name
+	proposed.Namespace = namespace
+
+	var live appsv1.Deployment
+	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &live); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Deployment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get deployment: " + err.Error()})
+		return
+	}
+	if proposed.ResourceVersion == "" {
+		proposed.ResourceVersion = live.ResourceVersion
+	}
+
+	if err := h.K8sClient.Client.Update(ctx, &proposed, client.DryRunAll); err != nil {
+		switch {
+		case errors.IsInvalid(err), errors.IsBadRequest(err):
+			c.JSON(http.StatusOK, gin.H{
+				"valid":  false,
+				"errors": diffErrors(err),
+			})
+		case errors.IsConflict(err):
+			c.JSON(http.StatusConflict, gin.H{"error": "The deployment was modified after the manifest was loaded: " + err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to dry-run update: " + err.Error()})
+		}
+		return
+	}
+
+	liveJSON, liveYAML, err := normalizeDeploymentForDiff(&live)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to normalize deployment: " + err.Error()})
+		return
+	}
+	proposedJSON, proposedYAML, err := normalizeDeploymentForDiff(&proposed)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to normalize deployment: " + err.Error()})
+		return
+	}
+	patch, err := jsonpatch.CreatePatch(liveJSON, proposedJSON)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute diff: " + err.Error()})
+		return
+	}
+	if patch == nil {
+		patch = []jsonpatch.Operation{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"valid":   true,
+		"changed": len(patch) > 0,
+		"patch":   patch,
+		"diff":    utils.UnifiedDiff("live", "dry-run", string(liveYAML), string(proposedYAML)),
+		"errors":  []DiffError{},
+	})
+}
@@ -0,0 +1,193 @@
+package resources
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/retry"
+)
+
+// maskedEnvValue replaces the value of secret-backed env vars in responses
+const maskedEnvValue = "******"
+
+// DeploymentEnvRequest edits the env list of one container
+type DeploymentEnvRequest struct {
+	Container string            `json:"container" binding:"required"`
+	Set       map[string]string `json:"set"`
+	Remove    []string          `json:"remove"`
+	// ReplaceValueFrom allows set to turn a valueFrom entry into a literal
+	ReplaceValueFrom bool `json:"replaceValueFrom"`
+	// RecordChangeCause annotates the deployment with the change cause,
+	// defaults to true
+	RecordChangeCause *bool `json:"recordChangeCause,omitempty"`
+}
+
+// DeploymentEnvVar is an env var as shown to the user. Values read from
+// secrets are masked, other valueFrom entries name their source.
+type DeploymentEnvVar struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source,omitempty"`
+	Masked bool   `json:"masked,omitempty"`
+}
+
+func newDeploymentEnvVar(env corev1.EnvVar) DeploymentEnvVar {
+	result := DeploymentEnvVar{Name: env.Name, Value: env.Value}
+	from := env.ValueFrom
+	switch {
+	case from == nil:
+	case from.SecretKeyRef != nil:
+		result.Value = maskedEnvValue
+		result.Masked = true
+		result.Source = fmt.Sprintf("secret %s/%s", from.SecretKeyRef.Name, from.SecretKeyRef.Key)
+	case from.ConfigMapKeyRef != nil:
+		result.Source = fmt.Sprintf("configMap %s/%s", from.ConfigMapKeyRef.Name, from.ConfigMapKeyRef.Key)
+	case from.FieldRef != nil:
+		result.Source = "field " + from.FieldRef.FieldPath
+	case from.ResourceFieldRef != nil:
+		result.Source = "resource " + from.ResourceFieldRef.Resource
+	}
+	return result
+}
+
+// findPodTemplateContainer returns the container or init container with the
+// given name
+func findPodTemplateContainer(spec *corev1.PodSpec, name string) *corev1.Container {
+	for i := range spec.Containers {
+		if spec.Containers[i].Name == name {
+			return &spec.Containers[i]
+		}
+	}
+	for i := range spec.InitContainers {
+		if spec.InitContainers[i].Name == name {
+			return &spec.InitContainers[i]
+		}
+	}
+	return nil
+}
+
+// applyEnvChanges sets and removes env vars of a container in place, keeping
+// the order of the existing entries and appending new ones in set order
+func applyEnvChanges(container *corev1.Container, req DeploymentEnvRequest, setOrder []string) error {
+	remove := make(map[string]bool, len(req.Remove))
+	for _, key := range req.Remove {
+		remove[key] = true
+	}
+
+	env := make([]corev1.EnvVar, 0, len(container.Env)+len(req.Set))
+	present := map[string]bool{}
+	for _, existing := range container.Env {
+		if remove[existing.Name] {
+			continue
+		}
+		if value, ok := req.Set[existing.Name]; ok {
+			if existing.ValueFrom != nil && !req.ReplaceValueFrom {
+				return fmt.Errorf("env var %s is set from %s, pass replaceValueFrom to replace it with a literal value",
+					existing.Name, newDeploymentEnvVar(existing).Source)
+			}
+			existing.Value = value
+			existing.ValueFrom = nil
+		}
+		present[existing.Name] = true
+		env = append(env, existing)
+	}
+	for _, key := range setOrder {
+		if !present[key] {
+			env = append(env, corev1.EnvVar{Name: key, Value: req.Set[key]})
+			present[key] = true
+		}
+	}
+	container.Env = env
+	return nil
+}
+
+// UpdateDeploymentEnv sets and removes env vars of one container of a
+// deployment. valueFrom entries are only replaced with replaceValueFrom.
+func (h *DeploymentHandler) UpdateDeploymentEnv(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	ctx := c.Request.Context()
+
+	var req DeploymentEnvRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if len(req.Set) == 0 && len(req.Remove) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one env var to set or remove is required"})
+		return
+	}
+
+	setOrder := make([]string, 0, len(req.Set))
+	for key := range req.Set {
+		if errs := validation.IsEnvVarName(key); len(errs) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid env var name %q: %s", key, strings.Join(errs, "; "))})
+			return
+		}
+		setOrder = append(setOrder, key)
+	}
+	// Map order is random, append new keys in a stable order
+	sort.Strings(setOrder)
+	for _, key := range req.Remove {
+		if _, ok := req.Set[key]; ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("env var %q is both set and removed", key)})
+			return
+		}
+	}
+
+	annotations := changeCauseAnnotations(c, req.RecordChangeCause, fmt.Sprintf("update env of %s", req.Container))
+	var env []corev1.EnvVar
+	var changeErr error
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var deployment appsv1.Deployment
+		if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &deployment); err != nil {
+			return err
+		}
+		container := findPodTemplateContainer(&deployment.Spec.Template.Spec, req.Container)
+		if container == nil {
+			changeErr = fmt.Errorf("container %q not found in deployment", req.Container)
+			return nil
+		}
+		if changeErr = applyEnvChanges(container, req, setOrder); changeErr != nil {
+			return nil
+		}
+		env = container.Env
+		if len(annotations) > 0 && deployment.Annotations == nil {
+			deployment.Annotations = map[string]string{}
+		}
+		for key, value := range annotations {
+			deployment.Annotations[key] = value
+		}
+		return h.K8sClient.Client.Update(ctx, &deployment)
+	})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Deployment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update env: " + err.Error()})
+		return
+	}
+	if changeErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": changeErr.Error()})
+		return
+	}
+
+	result := make([]DeploymentEnvVar, 0, len(env))
+	for _, e := range env {
+		result = append(result, newDeploymentEnvVar(e))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":   fmt.Sprintf("Env of container %s updated successfully", req.Container),
+		"container": req.Container,
+		"env":       result,
+	})
+}
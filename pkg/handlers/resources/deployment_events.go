@@ -0,0 +1,215 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// EventSource is the object an event was recorded for
+type EventSource struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// DeploymentEvent is an event of a deployment, one of its ReplicaSets or
+// one of their pods. Repeats of the same reason and message for the same
+// object are merged into one entry.
+type DeploymentEvent struct {
+	Source         EventSource `json:"source"`
+	Type           string      `json:"type"`
+	Reason         string      `json:"reason"`
+	Message        string      `json:"message"`
+	Count          int32       `json:"count"`
+	FirstTimestamp time.Time   `json:"firstTimestamp"`
+	LastTimestamp  time.Time   `json:"lastTimestamp"`
+}
+
+// listDeploymentPods returns the pods matching the deployment's selector
+// that are controlled by one of the given ReplicaSets
+func (h *DeploymentHandler) listDeploymentPods(ctx context.Context, deployment *appsv1.Deployment, replicaSets []appsv1.ReplicaSet) ([]corev1.Pod, error) {
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid deployment selector: %w", err)
+	}
+	var podList corev1.PodList
+	if err := h.K8sClient.Client.List(ctx, &podList, client.InNamespace(deployment.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+	owners := make(map[types.UID]bool, len(replicaSets))
+	for _, rs := range replicaSets {
+		owners[rs.UID] = true
+	}
+	pods := make([]corev1.Pod, 0, len(podList.Items))
+	for _, pod := range podList.Items {
+		if owner := metav1.GetControllerOf(&pod); owner != nil && owners[owner.UID] {
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
+// listObjectEvents lists the events of one object from both the core and
+// the events.k8s.io API, each event once
+func (h *DeploymentHandler) listObjectEvents(ctx context.Context, namespace string, source EventSource) ([]corev1.Event, error) {
+	coreList, err := h.K8sClient.ClientSet.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.SelectorFromSet(fields.Set{
+			"involvedObject.kind": source.Kind,
+			"involvedObject.name": source.Name,
+		}).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	events := coreList.Items
+
+	seriesList, err := h.K8sClient.ClientSet.EventsV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.SelectorFromSet(fields.Set{
+			"regarding.kind": source.Kind,
+			"regarding.name": source.Name,
+		}).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[types.UID]bool, len(events))
+	for _, event := range events {
+		seen[event.UID] = true
+	}
+	for i := range seriesList.Items {
+		if !seen[seriesList.Items[i].UID] {
+			events = append(events, utils.CoreEventFromEventsV1(&seriesList.Items[i]))
+		}
+	}
+	return events, nil
+}
+
+// eventCount returns how often an event occurred
+func eventCount(event *corev1.Event) int32 {
+	if event.Series != nil && event.Series.Count > event.Count {
+		return event.Series.Count
+	}
+	return max(event.Count, 1)
+}
+
+// eventFirstTimestamp returns when an event was first observed
+func eventFirstTimestamp(event *corev1.Event) time.Time {
+	if !event.FirstTimestamp.IsZero() {
+		return event.FirstTimestamp.Time
+	}
+	if !event.EventTime.IsZero() {
+		return event.EventTime.Time
+	}
+	return event.CreationTimestamp.Time
+}
+
+// GetDeploymentEvents returns the events of a deployment, its ReplicaSets
+// and their pods, merged and newest first
+func (h *DeploymentHandler) GetDeploymentEvents(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	ctx := c.Request.Context()
+
+	var deployment appsv1.Deployment
+	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &deployment); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Deployment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get deployment: " + err.Error()})
+		return
+	}
+
+	warnings := []string{}
+	sources := []EventSource{{Kind: "Deployment", Name: name}}
+	replicaSets, err := h.listDeploymentReplicaSets(ctx, &deployment)
+	if err != nil {
+		warnings = append(warnings, "Failed to list ReplicaSets: "+err.Error())
+	}
+	for _, rs := range replicaSets {
+		sources = append(sources, EventSource{Kind: "ReplicaSet", Name: rs.Name})
+	}
+	pods, err := h.listDeploymentPods(ctx, &deployment, replicaSets)
+	if err != nil {
+		warnings = append(warnings, "Failed to list pods: "+err.Error())
+	}
+	for _, pod := range pods {
+		sources = append(sources, EventSource{Kind: "Pod", Name: pod.Name})
+	}
+
+	type sourceEvents struct {
+		events []corev1.Event
+		err    error
+	}
+	results := utils.RunBatch(ctx, len(sources), utils.DefaultBatchConcurrency,
+		func(ctx context.Context, i int) sourceEvents {
+			events, err := h.listObjectEvents(ctx, namespace, sources[i])
+			return sourceEvents{events: events, err: err}
+		}, func(i int, err error) sourceEvents {
+			return sourceEvents{err: err}
+		})
+
+	type eventKey struct {
+		source  EventSource
+		reason  string
+		message string
+	}
+	merged := map[eventKey]*DeploymentEvent{}
+	for i, result := range results {
+		if result.err != nil {
+			warnings = append(warnings, fmt.Sprintf("Failed to list events of %s %s: %v", sources[i].Kind, sources[i].Name, result.err))
+			continue
+		}
+		for j := range result.events {
+			event := &result.events[j]
+			key := eventKey{source: sources[i], reason: event.Reason, message: event.Message}
+			first, last := eventFirstTimestamp(event), eventTimestamp(event)
+			existing, ok := merged[key]
+			if !ok {
+				merged[key] = &DeploymentEvent{
+					Source:         sources[i],
+					Type:           event.Type,
+					Reason:         event.Reason,
+					Message:        event.Message,
+					Count:          eventCount(event),
+					FirstTimestamp: first,
+					LastTimestamp:  last,
+				}
+				continue
+			}
+			existing.Count += eventCount(event)
+			if first.Before(existing.FirstTimestamp) {
+				existing.FirstTimestamp = first
+			}
+			if last.After(existing.LastTimestamp) {
+				existing.LastTimestamp = last
+				existing.Type = event.Type
+			}
+		}
+	}
+
+	events := make([]DeploymentEvent, 0, len(merged))
+	for _, event := range merged {
+		events = append(events, *event)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].LastTimestamp.After(events[j].LastTimestamp)
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"events":   events,
+		"warnings": warnings,
+	})
+}
@@ -2,16 +2,24 @@ package resources
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
-	"sync"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/zxh326/kite/pkg/kube"
+	"github.com/zxh326/kite/pkg/utils"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog/v2"
@@ -33,16 +41,66 @@ func NewDeploymentHandler(client *kube.K8sClient) *DeploymentHandler {
 	}
 }
 
+// restartedAtAnnotation is set on pod templates to the restart time to
+// trigger a rollout. All workload restarts use the same key.
+const restartedAtAnnotation = "kite.kubernetes.io/restartedAt"
+
+const (
+	// originalReplicasAnnotation holds the replicas a deployment had before
+	// a scale-restart while the scale-restart is in progress
+	originalReplicasAnnotation = "kite.kubernetes.io/scale-restart-original-replicas"
+)
+
+// Restart triggers a rollout of a deployment with a strategic merge patch
+// that only sets restartedAtAnnotation on the pod template, so it doesn't
+// conflict with controllers updating the deployment at the same time
 func (h *DeploymentHandler) Restart(ctx context.Context, namespace, name string) error {
-	var deployment appsv1.Deployment
-	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &deployment); err != nil {
+	return h.restart(ctx, namespace, name, nil)
+}
+
+// restart triggers a rollout like Restart and sets annotations on the
+// deployment in the same patch
+func (h *DeploymentHandler) restart(ctx context.Context, namespace, name string, annotations map[string]string) error {
+	body := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{
+						restartedAtAnnotation: time.Now().Format(time.RFC3339),
+					},
+				},
+			},
+		},
+	}
+	if len(annotations) > 0 {
+		body["metadata"] = map[string]interface{}{"annotations": annotations}
+	}
+	patch, err := json.Marshal(body)
+	if err != nil {
 		return err
 	}
-	if deployment.Spec.Template.Annotations == nil {
-		deployment.Spec.Template.Annotations = make(map[string]string)
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	return h.K8sClient.Client.Patch(ctx, deployment, client.RawPatch(types.StrategicMergePatchType, patch))
+}
+
+// restartPodTemplate triggers a rollout of a workload other than a
+// deployment by patching only restartedAtAnnotation on its pod template
+func restartPodTemplate(ctx context.Context, k8sClient *kube.K8sClient, obj client.Object) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{
+						restartedAtAnnotation: time.Now().Format(time.RFC3339),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
 	}
-	deployment.Spec.Template.Annotations["kite.kubernetes.io/restartedAt"] = time.Now().Format(time.RFC3339)
-	return h.K8sClient.Client.Update(ctx, &deployment)
+	return k8sClient.Client.Patch(ctx, obj, client.RawPatch(types.StrategicMergePatchType, patch))
 }
 
 func (h *DeploymentHandler) RestartDeployment(c *gin.Context) {
@@ -50,7 +108,18 @@ func (h *DeploymentHandler) RestartDeployment(c *gin.Context) {
 	name := c.Param("name")
 	ctx := c.Request.Context()
 
-	if err := h.Restart(ctx, namespace, name); err != nil {
+	var restartRequest struct {
+		RecordChangeCause *bool `json:"recordChangeCause"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&restartRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+	}
+
+	annotations := changeCauseAnnotations(c, restartRequest.RecordChangeCause, "restart")
+	if err := h.restart(ctx, namespace, name, annotations); err != nil {
 		if errors.IsNotFound(err) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Deployment not found"})
 			return
@@ -64,8 +133,11 @@ func (h *DeploymentHandler) RestartDeployment(c *gin.Context) {
 	})
 }
 
-// ListDeploymentRelatedResources lists resources related to a deployment
-// such as pods, services, etc..
+// ListDeploymentRelatedResources lists resources related to a deployment:
+// its pods and ReplicaSets, the services selecting its pods, the ingresses
+// routing to those services, and the HPAs and PDBs targeting it. Each kind
+// is fetched on its own, a failure leaves that list empty and adds a
+// warning instead of failing the request.
 func (h *DeploymentHandler) ListDeploymentRelatedResources(c *gin.Context) {
 	namespace := c.Param("namespace")
 	name := c.Param("name")
@@ -82,46 +154,133 @@ func (h *DeploymentHandler) ListDeploymentRelatedResources(c *gin.Context) {
 		return
 	}
 
-	// Get deployment selector labels to find related resources
-	selector := deployment.Spec.Selector
-	if selector == nil || selector.MatchLabels == nil {
-		c.JSON(http.StatusOK, gin.H{
-			"services": []corev1.Service{},
-		})
-		return
+	warnings := []string{}
+	podLabels := labels.Set(deployment.Spec.Template.Labels)
+
+	pods := []corev1.Pod{}
+	if selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector); err != nil {
+		warnings = append(warnings, "Invalid deployment selector: "+err.Error())
+	} else {
+		var podList corev1.PodList
+		if err := h.K8sClient.Client.List(ctx, &podList, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			warnings = append(warnings, "Failed to list pods: "+err.Error())
+		} else {
+			pods = podList.Items
+		}
+	}
+
+	replicaSets, err := h.listDeploymentReplicaSets(ctx, &deployment)
+	if err != nil {
+		warnings = append(warnings, "Failed to list ReplicaSets: "+err.Error())
+		replicaSets = []appsv1.ReplicaSet{}
 	}
 
-	// Find related services (services that may select this deployment's pods)
+	// Services that select the deployment's pods
+	services := []corev1.Service{}
 	var serviceList corev1.ServiceList
-	serviceListOpts := &client.ListOptions{
-		Namespace: namespace,
+	if err := h.K8sClient.Client.List(ctx, &serviceList, client.InNamespace(namespace)); err != nil {
+		warnings = append(warnings, "Failed to list services: "+err.Error())
+	} else {
+		for _, service := range serviceList.Items {
+			if len(service.Spec.Selector) > 0 && labels.SelectorFromSet(service.Spec.Selector).Matches(podLabels) {
+				services = append(services, service)
+			}
+		}
 	}
-	if err := h.K8sClient.Client.List(ctx, &serviceList, serviceListOpts); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list services: " + err.Error()})
-		return
+
+	// Ingresses with a backend pointing at one of those services
+	ingresses := []networkingv1.Ingress{}
+	if len(services) > 0 {
+		serviceNames := make(map[string]bool, len(services))
+		for _, service := range services {
+			serviceNames[service.Name] = true
+		}
+		var ingressList networkingv1.IngressList
+		if err := h.K8sClient.Client.List(ctx, &ingressList, client.InNamespace(namespace)); err != nil {
+			warnings = append(warnings, "Failed to list ingresses: "+err.Error())
+		} else {
+			for _, ingress := range ingressList.Items {
+				if ingressReferencesServices(&ingress, serviceNames) {
+					ingresses = append(ingresses, ingress)
+				}
+			}
+		}
+	}
+
+	hpas, err := h.listDeploymentHPAs(ctx, namespace, name)
+	if err != nil {
+		warnings = append(warnings, "Failed to list HorizontalPodAutoscalers: "+err.Error())
+		hpas = []autoscalingv2.HorizontalPodAutoscaler{}
 	}
 
-	// Filter services that select the deployment's pods
-	var relatedServices []corev1.Service
-	for _, service := range serviceList.Items {
-		if service.Spec.Selector != nil {
-			serviceSelector := labels.SelectorFromSet(service.Spec.Selector)
-			// Check if the service selector matches any of the deployment's pod labels
-			if serviceSelector.Matches(labels.Set(selector.MatchLabels)) {
-				relatedServices = append(relatedServices, service)
+	pdbs := []policyv1.PodDisruptionBudget{}
+	var pdbList policyv1.PodDisruptionBudgetList
+	if err := h.K8sClient.Client.List(ctx, &pdbList, client.InNamespace(namespace)); err != nil {
+		warnings = append(warnings, "Failed to list PodDisruptionBudgets: "+err.Error())
+	} else {
+		for _, pdb := range pdbList.Items {
+			if pdb.Spec.Selector == nil {
+				continue
+			}
+			selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+			if err == nil && selector.Matches(podLabels) {
+				pdbs = append(pdbs, pdb)
 			}
 		}
 	}
 
 	// Return all related resources
 	response := gin.H{
-		"services": relatedServices,
+		"pods":                     pods,
+		"replicaSets":              replicaSets,
+		"services":                 services,
+		"ingresses":                ingresses,
+		"horizontalPodAutoscalers": hpas,
+		"podDisruptionBudgets":     pdbs,
+		"warnings":                 warnings,
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-// ScaleDeployment scales a deployment to the specified number of replicas
+// ingressReferencesServices reports whether the default backend or a rule
+// of an ingress points at one of the named services
+func ingressReferencesServices(ingress *networkingv1.Ingress, serviceNames map[string]bool) bool {
+	if backend := ingress.Spec.DefaultBackend; backend != nil && backend.Service != nil && serviceNames[backend.Service.Name] {
+		return true
+	}
+	for _, rule := range ingress.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			if path.Backend.Service != nil && serviceNames[path.Backend.Service.Name] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// listDeploymentHPAs returns the HorizontalPodAutoscalers that scale a
+// deployment
+func (h *DeploymentHandler) listDeploymentHPAs(ctx context.Context, namespace, name string) ([]autoscalingv2.HorizontalPodAutoscaler, error) {
+	var hpaList autoscalingv2.HorizontalPodAutoscalerList
+	if err := h.K8sClient.Client.List(ctx, &hpaList, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	hpas := []autoscalingv2.HorizontalPodAutoscaler{}
+	for _, hpa := range hpaList.Items {
+		ref := hpa.Spec.ScaleTargetRef
+		if ref.Kind == "Deployment" && ref.Name == name && (ref.APIVersion == "" || strings.HasPrefix(ref.APIVersion, "apps/")) {
+			hpas = append(hpas, hpa)
+		}
+	}
+	return hpas, nil
+}
+
+// ScaleDeployment scales a deployment to the specified number of replicas.
+// The replicas before the scale are recorded in previousReplicasAnnotation.
 func (h *DeploymentHandler) ScaleDeployment(c *gin.Context) {
 	namespace := c.Param("namespace")
 	name := c.Param("name")
@@ -129,7 +288,8 @@ func (h *DeploymentHandler) ScaleDeployment(c *gin.Context) {
 
 	// Parse the request body to get the desired replica count
 	var scaleRequest struct {
-		Replicas *int32 `json:"replicas" binding:"required,min=0"`
+		Replicas          *int32 `json:"replicas" binding:"required,min=0"`
+		RecordChangeCause *bool  `json:"recordChangeCause"`
 	}
 
 	if err := c.ShouldBindJSON(&scaleRequest); err != nil {
@@ -142,37 +302,87 @@ func (h *DeploymentHandler) ScaleDeployment(c *gin.Context) {
 		return
 	}
 
-	// Get the current deployment
-	var deployment appsv1.Deployment
-	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &deployment); err != nil {
+	current, err := h.K8sClient.ClientSet.AppsV1().Deployments(namespace).GetScale(ctx, name, metav1.GetOptions{})
+	if err != nil {
 		if errors.IsNotFound(err) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Deployment not found"})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get deployment scale: " + err.Error()})
 		return
 	}
 
-	// Update the replica count
-	deployment.Spec.Replicas = scaleRequest.Replicas
+	// Remember the replicas before the scale so that it can be undone
+	annotations := changeCauseAnnotations(c, scaleRequest.RecordChangeCause, fmt.Sprintf("scale to %d", *scaleRequest.Replicas))
+	if current.Spec.Replicas != *scaleRequest.Replicas {
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[previousReplicasAnnotation] = strconv.Itoa(int(current.Spec.Replicas))
+	}
+	if len(annotations) > 0 {
+		if err := h.setDeploymentAnnotations(ctx, namespace, name, annotations); err != nil {
+			if errors.IsNotFound(err) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Deployment not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record previous replicas: " + err.Error()})
+			return
+		}
+	}
 
-	// Update the deployment
-	if err := h.K8sClient.Client.Update(ctx, &deployment); err != nil {
+	scale, err := h.scaleReplicas(ctx, namespace, name, *scaleRequest.Replicas)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Deployment not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scale deployment: " + err.Error()})
 		return
 	}
 
+	deployment, err := h.K8sClient.ClientSet.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		klog.Warningf("Failed to get deployment %s/%s after scaling it: %v", namespace, name, err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"message":    "Deployment scaled successfully",
-		"deployment": deployment,
-		"replicas":   *scaleRequest.Replicas,
+		"message":          "Deployment scaled successfully",
+		"deployment":       deployment,
+		"replicas":         scale.Spec.Replicas,
+		"previousReplicas": current.Spec.Replicas,
 	})
 }
 
+// scaleReplicas sets the replicas of a deployment through the scale
+// subresource. The update carries no resourceVersion, so it doesn't conflict
+// with controllers updating the deployment and doesn't go through webhooks
+// for the deployment object.
+func (h *DeploymentHandler) scaleReplicas(ctx context.Context, namespace, name string, replicas int32) (*autoscalingv1.Scale, error) {
+	scale := &autoscalingv1.Scale{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       autoscalingv1.ScaleSpec{Replicas: replicas},
+	}
+	return h.K8sClient.ClientSet.AppsV1().Deployments(namespace).UpdateScale(ctx, name, scale, metav1.UpdateOptions{})
+}
+
 func (h *DeploymentHandler) registerCustomRoutes(group *gin.RouterGroup) {
 	group.GET("/:namespace/:name/related", h.ListDeploymentRelatedResources)
 	group.POST("/:namespace/:name/scale", h.ScaleDeployment)
+	group.POST("/:namespace/:name/scale/undo", h.UndoScaleDeployment)
 	group.POST("/:namespace/:name/restart", h.RestartDeployment)
+	group.POST("/:namespace/:name/rollback", h.RollbackDeployment)
+	group.POST("/:namespace/:name/env", h.UpdateDeploymentEnv)
+	group.POST("/:namespace/:name/resources", h.UpdateDeploymentResources)
+	group.POST("/:namespace/:name/probes", h.UpdateDeploymentProbe)
+	group.POST("/:namespace/:name/placement", h.UpdateDeploymentPlacement)
+	group.POST("/:namespace/:name/diff", h.DiffDeployment)
+	group.GET("/:namespace/:name/history", h.GetDeploymentHistory)
+	group.GET("/:namespace/:name/events", h.GetDeploymentEvents)
+	group.GET("/:namespace/:name/logs", h.GetDeploymentLogs)
+	group.GET("/:namespace/:name/health", h.GetDeploymentHealth)
+	group.POST("/:namespace/restart-by-selector", h.RestartDeploymentsBySelector)
+	group.POST("/batch/scale", h.ScaleDeploymentsBatch)
 	group.POST("/batch/restart", h.RestartDeploymentsBatch)
 	group.POST("/batch/scale-restart", h.ScaleRestartDeploymentsBatch)
 }
@@ -180,6 +390,21 @@ func (h *DeploymentHandler) registerCustomRoutes(group *gin.RouterGroup) {
 // BatchDeploymentRestartRequest represents the request body for batch deployment restart
 type BatchDeploymentRestartRequest struct {
 	Deployments []DeploymentIdentifier `json:"deployments" binding:"required"`
+	// MaxConcurrency bounds how many deployments are restarted at the same
+	// time, see utils.BatchConcurrency
+	MaxConcurrency int `json:"maxConcurrency,omitempty" binding:"min=0"`
+	// RecordChangeCause annotates each deployment with the change cause,
+	// defaults to true
+	RecordChangeCause *bool `json:"recordChangeCause,omitempty"`
+	// Strategy is parallel (the default) or staggered. A staggered restart
+	// restarts GroupSize deployments at a time, no more than MaxConcurrency
+	// of them at once, and with WaitForReady waits for their rollouts before
+	// starting the next group.
+	Strategy     string `json:"strategy,omitempty" binding:"omitempty,oneof=parallel staggered"`
+	GroupSize    int    `json:"groupSize,omitempty" binding:"min=0"`
+	WaitForReady bool   `json:"waitForReady,omitempty"`
+	// Timeout bounds a staggered restart in seconds, defaults to 15 minutes
+	Timeout int `json:"timeout,omitempty" binding:"min=0"`
 }
 
 // DeploymentIdentifier represents a deployment to be restarted
@@ -191,18 +416,46 @@ type DeploymentIdentifier struct {
 // ScaleRestartRequest represents the request body for scale-restart operation
 type ScaleRestartRequest struct {
 	Deployments []DeploymentIdentifier `json:"deployments" binding:"required"`
+	// FinalReplicas is kept for compatibility when RestoreOriginal is unset,
+	// 1 restores deployments that had a single replica and larger values
+	// are the surge replicas, which are kept. Use RestoreOriginal instead.
 	FinalReplicas *int32 `json:"finalReplicas,omitempty"`
+	// SurgeReplicas is how many replicas each deployment is scaled up to
+	// before the restart, deployments with more replicas keep theirs.
+	// Defaults to one more than the current replicas.
+	SurgeReplicas *int32 `json:"surgeReplicas,omitempty"`
+	// RestoreOriginal scales each deployment back to its original replicas
+	// once the restarted pods are ready, defaults to true
+	RestoreOriginal *bool `json:"restoreOriginal,omitempty"`
+	// MaxConcurrency bounds how many deployments are processed at the same
+	// time, see utils.BatchConcurrency
+	MaxConcurrency int `json:"maxConcurrency,omitempty" binding:"min=0"`
+	// StepTimeout is how many seconds each step may wait for the deployment
+	// to become ready, defaults to 2 minutes
+	StepTimeout int `json:"stepTimeout,omitempty" binding:"min=0"`
+	// RecordChangeCause annotates each deployment with the change cause,
+	// defaults to true
+	RecordChangeCause *bool `json:"recordChangeCause,omitempty"`
 }
 
 // DeploymentRestartResult represents the result of restarting a single deployment
 type DeploymentRestartResult struct {
-	Namespace string `json:"namespace"`
-	Name      string `json:"name"`
-	Success   bool   `json:"success"`
-	Error     string `json:"error,omitempty"`
+	Namespace string   `json:"namespace"`
+	Name      string   `json:"name"`
+	Success   bool     `json:"success"`
+	Error     string   `json:"error,omitempty"`
+	Warnings  []string `json:"warnings,omitempty"`
+	// Skipped is set when the deployment was left unchanged, such as a
+	// scale-restart of a deployment with 0 replicas
+	Skipped bool `json:"skipped,omitempty"`
+	// Group is the group of a staggered restart the deployment was in,
+	// starting at 1
+	Group int `json:"group,omitempty"`
 }
 
-// RestartDeploymentsBatch restarts multiple deployments concurrently
+// RestartDeploymentsBatch restarts multiple deployments concurrently, or
+// group by group with the staggered strategy. With Accept: text/event-stream
+// each result is streamed as it completes.
 func (h *DeploymentHandler) RestartDeploymentsBatch(c *gin.Context) {
 	var req BatchDeploymentRestartRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -215,74 +468,68 @@ func (h *DeploymentHandler) RestartDeploymentsBatch(c *gin.Context) {
 		return
 	}
 
+	annotations := changeCauseAnnotations(c, req.RecordChangeCause, "batch restart")
+
+	if req.Strategy == BatchRestartStrategyStaggered {
+		groupSize := defaultStaggeredGroupSize
+		if req.GroupSize > 0 {
+			groupSize = req.GroupSize
+		}
+		timeout := defaultStaggeredRestartTimeout
+		if req.Timeout > 0 {
+			timeout = time.Duration(req.Timeout) * time.Second
+		}
+
+		klog.Infof("Starting staggered restart for %d deployments in groups of %d", len(req.Deployments), groupSize)
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		concurrency := min(groupSize, utils.BatchConcurrency(req.MaxConcurrency))
+		items := h.staggeredRestart(ctx, req.Deployments, groupSize, concurrency, req.WaitForReady, annotations)
+		writeDeploymentBatchResults(c, "Staggered deployment restart", len(req.Deployments), items)
+		return
+	}
+
 	klog.Infof("Starting batch restart for %d deployments", len(req.Deployments))
 
 	// Use a context with timeout for all operations
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
 	defer cancel()
 
-	// Channel to collect results
-	resultChan := make(chan DeploymentRestartResult, len(req.Deployments))
-	var wg sync.WaitGroup
-
-	// Process each deployment restart concurrently
-	for _, deployment := range req.Deployments {
-		wg.Add(1)
-		go func(deployment DeploymentIdentifier) {
-			defer wg.Done()
-			result := h.restartSingleDeployment(ctx, deployment.Namespace, deployment.Name)
-			resultChan <- result
-		}(deployment)
-	}
-
-	// Wait for all goroutines to complete
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
-
-	// Collect results
-	var results []DeploymentRestartResult
-	var successCount, failureCount int
-
-	for result := range resultChan {
-		results = append(results, result)
-		if result.Success {
-			successCount++
-		} else {
-			failureCount++
-		}
-	}
-
-	klog.Infof("Batch deployment restart completed: %d successful, %d failed", successCount, failureCount)
+	// Process the deployments with bounded concurrency
+	items := utils.StreamBatch(ctx, len(req.Deployments), utils.BatchConcurrency(req.MaxConcurrency),
+		func(ctx context.Context, i int) DeploymentRestartResult {
+			deployment := req.Deployments[i]
+			return h.restartSingleDeployment(ctx, deployment.Namespace, deployment.Name, annotations)
+		}, func(i int, err error) DeploymentRestartResult {
+			return cancelledDeploymentResult(req.Deployments[i], err)
+		})
 
-	// Return response
-	response := gin.H{
-		"message":      fmt.Sprintf("Batch deployment restart completed: %d successful, %d failed", successCount, failureCount),
-		"total":        len(req.Deployments),
-		"successful":   successCount,
-		"failed":       failureCount,
-		"results":      results,
-		"timestamp":    time.Now().Format(time.RFC3339),
-	}
+	writeDeploymentBatchResults(c, "Batch deployment restart", len(req.Deployments), items)
+}
 
-	if failureCount > 0 {
-		c.JSON(http.StatusPartialContent, response)
-	} else {
-		c.JSON(http.StatusOK, response)
+// cancelledDeploymentResult is the result of a deployment that was not
+// processed because the batch was cancelled or timed out
+func cancelledDeploymentResult(deployment DeploymentIdentifier, err error) DeploymentRestartResult {
+	return DeploymentRestartResult{
+		Namespace: deployment.Namespace,
+		Name:      deployment.Name,
+		Error:     fmt.Sprintf("Not processed: %v", err),
 	}
 }
 
-// restartSingleDeployment restarts a single deployment and returns the result
-func (h *DeploymentHandler) restartSingleDeployment(ctx context.Context, namespace, name string) DeploymentRestartResult {
+// restartSingleDeployment restarts a single deployment, setting the given
+// annotations in the same patch, and returns the result
+func (h *DeploymentHandler) restartSingleDeployment(ctx context.Context, namespace, name string, annotations map[string]string) DeploymentRestartResult {
 	result := DeploymentRestartResult{
 		Namespace: namespace,
 		Name:      name,
 		Success:   false,
 	}
 
-	// Restart the deployment using existing Restart method
-	if err := h.Restart(ctx, namespace, name); err != nil {
+	// Restart the deployment using existing restart method
+	if err := h.restart(ctx, namespace, name, annotations); err != nil {
 		if errors.IsNotFound(err) {
 			result.Error = "Deployment not found"
 		} else {
@@ -297,7 +544,9 @@ func (h *DeploymentHandler) restartSingleDeployment(ctx context.Context, namespa
 	return result
 }
 
-// ScaleRestartDeploymentsBatch scales deployments to 3 replicas, restarts them, then optionally scales back
+// ScaleRestartDeploymentsBatch scales deployments up to the surge replicas,
+// restarts them, then optionally scales them back to their original replicas.
+// With Accept: text/event-stream each result is streamed as it completes.
 func (h *DeploymentHandler) ScaleRestartDeploymentsBatch(c *gin.Context) {
 	var req ScaleRestartRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -310,65 +559,89 @@ func (h *DeploymentHandler) ScaleRestartDeploymentsBatch(c *gin.Context) {
 		return
 	}
 
+	if req.SurgeReplicas != nil && *req.SurgeReplicas < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "surgeReplicas must be at least 1"})
+		return
+	}
+
 	klog.Infof("Starting scale-restart for %d deployments", len(req.Deployments))
 
-	// Use a context with longer timeout for scale operations
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
+	opts := scaleRestartOptions{
+		surgeReplicas:   req.SurgeReplicas,
+		restoreOriginal: req.RestoreOriginal,
+		finalReplicas:   req.FinalReplicas,
+		stepTimeout:     defaultDeploymentStepTimeout,
+		annotations:     changeCauseAnnotations(c, req.RecordChangeCause, "scale-restart"),
+	}
+	// The legacy finalReplicas above 1 is the replicas to keep after the surge
+	if req.SurgeReplicas == nil && req.RestoreOriginal == nil && req.FinalReplicas != nil && *req.FinalReplicas > 1 {
+		opts.surgeReplicas = req.FinalReplicas
+	}
+	if req.StepTimeout > 0 {
+		opts.stepTimeout = time.Duration(req.StepTimeout) * time.Second
+	}
+
+	// Use a context with longer timeout for scale operations, each
+	// deployment waits for up to two steps
+	ctx, cancel := context.WithTimeout(c.Request.Context(), max(5*time.Minute, 2*opts.stepTimeout+time.Minute))
 	defer cancel()
 
-	// Channel to collect results
-	resultChan := make(chan DeploymentRestartResult, len(req.Deployments))
-	var wg sync.WaitGroup
-
-	// Process each deployment
-	for _, deployment := range req.Deployments {
-		wg.Add(1)
-		go func(namespace, name string) {
-			defer wg.Done()
-			result := h.scaleRestartSingleDeployment(ctx, namespace, name, req.FinalReplicas)
-			resultChan <- result
-		}(deployment.Namespace, deployment.Name)
-	}
-
-	// Wait for all operations to complete
-	wg.Wait()
-	close(resultChan)
-
-	// Collect results
-	var results []DeploymentRestartResult
-	successCount := 0
-	failureCount := 0
-
-	for result := range resultChan {
-		results = append(results, result)
-		if result.Success {
-			successCount++
-		} else {
-			failureCount++
-		}
-	}
+	// Process the deployments with bounded concurrency
+	items := utils.StreamBatch(ctx, len(req.Deployments), utils.BatchConcurrency(req.MaxConcurrency),
+		func(ctx context.Context, i int) DeploymentRestartResult {
+			deployment := req.Deployments[i]
+			return h.scaleRestartSingleDeployment(ctx, deployment.Namespace, deployment.Name, opts)
+		}, func(i int, err error) DeploymentRestartResult {
+			return cancelledDeploymentResult(req.Deployments[i], err)
+		})
 
-	klog.Infof("Scale-restart operation completed: %d successful, %d failed", successCount, failureCount)
+	writeDeploymentBatchResults(c, "Scale-restart operation", len(req.Deployments), items)
+}
 
-	// Return response
-	response := gin.H{
-		"message":      fmt.Sprintf("Scale-restart operation completed: %d successful, %d failed", successCount, failureCount),
-		"total":        len(req.Deployments),
-		"successful":   successCount,
-		"failed":       failureCount,
-		"results":      results,
-		"timestamp":    time.Now().Format(time.RFC3339),
+// scaleRestartOptions are the per-deployment settings of a scale-restart
+type scaleRestartOptions struct {
+	// surgeReplicas is nil for one more than the original replicas
+	surgeReplicas *int32
+	// restoreOriginal is nil for the default, see ScaleRestartRequest
+	restoreOriginal *bool
+	finalReplicas   *int32
+	stepTimeout     time.Duration
+	// annotations are set on the deployment with the restart
+	annotations map[string]string
+}
+
+// surgeReplicasFor returns the replicas a deployment with original replicas
+// is scaled up to, one more than the original unless requested is set.
+// Deployments with more replicas than requested keep theirs.
+func surgeReplicasFor(original int32, requested *int32) int32 {
+	if requested != nil {
+		return max(original, *requested)
 	}
+	return max(original+1, 1)
+}
 
-	if failureCount > 0 {
-		c.JSON(http.StatusPartialContent, response)
-	} else {
-		c.JSON(http.StatusOK, response)
+// shouldRestoreReplicas reports whether a deployment is scaled back to its
+// original replicas after a scale-restart. Without restoreOriginal it is,
+// unless the legacy finalReplicas is set: 1 restores deployments that had
+// 1 replica and other values keep the surge replicas.
+func shouldRestoreReplicas(opts scaleRestartOptions, original int32) bool {
+	if opts.restoreOriginal != nil {
+		return *opts.restoreOriginal
 	}
+	if opts.finalReplicas != nil {
+		return *opts.finalReplicas == 1 && original == 1
+	}
+	return true
 }
 
-// scaleRestartSingleDeployment handles scale-restart for a single deployment
-func (h *DeploymentHandler) scaleRestartSingleDeployment(ctx context.Context, namespace, name string, finalReplicas *int32) DeploymentRestartResult {
+// scaleRestartSingleDeployment scales a deployment up to the surge replicas,
+// restarts it and optionally restores the original replicas. The original
+// replicas are kept in originalReplicasAnnotation until the operation has
+// finished, so a scale-restart interrupted midway restores the count from
+// before the first attempt. Deployments managed by a HorizontalPodAutoscaler
+// are restarted without the surge, the autoscaler would undo it, and
+// deployments scaled to 0 are skipped.
+func (h *DeploymentHandler) scaleRestartSingleDeployment(ctx context.Context, namespace, name string, opts scaleRestartOptions) DeploymentRestartResult {
 	result := DeploymentRestartResult{
 		Namespace: namespace,
 		Name:      name,
@@ -386,52 +659,91 @@ func (h *DeploymentHandler) scaleRestartSingleDeployment(ctx context.Context, na
 		return result
 	}
 
-	originalReplicas := deployment.Spec.Replicas
-	if originalReplicas == nil {
-		replicas := int32(1)
-		originalReplicas = &replicas
+	originalReplicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		originalReplicas = *deployment.Spec.Replicas
+	}
+	if originalReplicas == 0 {
+		result.Success = true
+		result.Skipped = true
+		result.Warnings = append(result.Warnings, "Scaled to 0 replicas, not restarted")
+		return result
+	}
+	if value, ok := deployment.Annotations[originalReplicasAnnotation]; ok {
+		if recorded, err := strconv.ParseInt(value, 10, 32); err == nil && recorded >= 0 {
+			klog.Infof("Resuming scale-restart of deployment %s/%s with original replicas %d", namespace, name, recorded)
+			originalReplicas = int32(recorded)
+		}
+	}
+
+	hpas, err := h.listDeploymentHPAs(ctx, namespace, name)
+	if err != nil {
+		result.Error = fmt.Sprintf("Failed to list HorizontalPodAutoscalers: %v", err)
+		return result
+	}
+	surgeReplicas := surgeReplicasFor(originalReplicas, opts.surgeReplicas)
+	if len(hpas) > 0 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Managed by HorizontalPodAutoscaler %s, restarted without scaling", hpas[0].Name))
+		surgeReplicas = originalReplicas
 	}
+	current := surgeReplicas
+	if deployment.Spec.Replicas != nil {
+		current = *deployment.Spec.Replicas
+	}
+
+	restore := shouldRestoreReplicas(opts, originalReplicas) && len(hpas) == 0
 
-	// Step 1: Scale to 3 replicas if currently 1
-	if *originalReplicas == 1 {
-		klog.Infof("Scaling deployment %s/%s to 3 replicas", namespace, name)
-		deployment.Spec.Replicas = &[]int32{3}[0]
-		if err := h.K8sClient.Client.Update(ctx, &deployment); err != nil {
-			result.Error = fmt.Sprintf("Failed to scale to 3 replicas: %v", err)
+	// Step 1: Scale up to the surge replicas
+	if surgeReplicas > originalReplicas {
+		if err := h.setDeploymentAnnotation(ctx, namespace, name, originalReplicasAnnotation, strconv.Itoa(int(originalReplicas))); err != nil {
+			result.Error = fmt.Sprintf("Failed to record original replicas: %v", err)
 			return result
 		}
+		if current != surgeReplicas {
+			klog.Infof("Scaling deployment %s/%s to %d replicas", namespace, name, surgeReplicas)
+			if _, err := h.scaleReplicas(ctx, namespace, name, surgeReplicas); err != nil {
+				result.Error = fmt.Sprintf("Failed to scale to %d replicas: %v", surgeReplicas, err)
+				return result
+			}
+		}
 
-		// Wait for scaling to take effect
-		time.Sleep(3 * time.Second)
+		// Wait for the extra replicas so that the restart never runs with
+		// fewer ready pods than before
+		if err := h.waitForDeploymentReady(ctx, namespace, name, opts.stepTimeout); err != nil {
+			result.Error = fmt.Sprintf("Scaled to %d replicas but %v", surgeReplicas, err)
+			return result
+		}
 	}
 
 	// Step 2: Restart the deployment
 	klog.Infof("Restarting deployment %s/%s", namespace, name)
-	if err := h.Restart(ctx, namespace, name); err != nil {
+	if err := h.restart(ctx, namespace, name, opts.annotations); err != nil {
 		result.Error = fmt.Sprintf("Failed to restart deployment: %v", err)
 		return result
 	}
 
-	// Step 3: Scale back if requested
-	if finalReplicas != nil && *originalReplicas == 1 && *finalReplicas == 1 {
-		// Wait for restart to take effect
-		time.Sleep(5 * time.Second)
-		
-		klog.Infof("Scaling deployment %s/%s back to 1 replica", namespace, name)
-		
-		// Get the deployment again to ensure we have the latest version
-		if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &deployment); err != nil {
-			result.Error = fmt.Sprintf("Failed to get deployment for scale-back: %v", err)
+	// Step 3: Restore the original replicas if requested
+	if restore && surgeReplicas != originalReplicas {
+		// Only scale back once the restarted pods are ready
+		if err := h.waitForDeploymentReady(ctx, namespace, name, opts.stepTimeout); err != nil {
+			result.Error = fmt.Sprintf("Restarted but %v, not scaling back", err)
 			return result
 		}
-		
-		deployment.Spec.Replicas = &[]int32{1}[0]
-		if err := h.K8sClient.Client.Update(ctx, &deployment); err != nil {
-			result.Error = fmt.Sprintf("Failed to scale back to 1 replica: %v", err)
+
+		klog.Infof("Scaling deployment %s/%s back to %d replicas", namespace, name, originalReplicas)
+
+		if _, err := h.scaleReplicas(ctx, namespace, name, originalReplicas); err != nil {
+			result.Error = fmt.Sprintf("Failed to scale back to %d replicas: %v", originalReplicas, err)
 			return result
 		}
 	}
 
+	if _, ok := deployment.Annotations[originalReplicasAnnotation]; ok || surgeReplicas > originalReplicas {
+		if err := h.removeDeploymentAnnotation(ctx, namespace, name, originalReplicasAnnotation); err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Failed to remove %s annotation: %v", originalReplicasAnnotation, err))
+		}
+	}
+
 	result.Success = true
 	klog.Infof("Successfully completed scale-restart for deployment %s/%s", namespace, name)
 	return result
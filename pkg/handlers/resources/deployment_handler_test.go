@@ -0,0 +1,347 @@
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/kube"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestSurgeReplicasFor(t *testing.T) {
+	tests := []struct {
+		name      string
+		original  int32
+		requested *int32
+		want      int32
+	}{
+		{name: "one more than a single replica", original: 1, want: 2},
+		{name: "one more than several replicas", original: 5, want: 6},
+		{name: "at least 1", original: 0, want: 1},
+		{name: "requested", original: 1, requested: ptr.To[int32](3), want: 3},
+		{name: "more replicas than requested", original: 4, requested: ptr.To[int32](3), want: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := surgeReplicasFor(tt.original, tt.requested); got != tt.want {
+				t.Errorf("surgeReplicasFor(%d) = %d, want %d", tt.original, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShouldRestoreReplicas(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     scaleRestartOptions
+		original int32
+		want     bool
+	}{
+		{name: "restores by default", original: 3, want: true},
+		{name: "restoreOriginal false", opts: scaleRestartOptions{restoreOriginal: ptr.To(false)}, original: 3},
+		{name: "restoreOriginal wins over finalReplicas", opts: scaleRestartOptions{restoreOriginal: ptr.To(true), finalReplicas: ptr.To[int32](3)}, original: 3, want: true},
+		{name: "legacy finalReplicas 1 with a single replica", opts: scaleRestartOptions{finalReplicas: ptr.To[int32](1)}, original: 1, want: true},
+		{name: "legacy finalReplicas 1 with several replicas", opts: scaleRestartOptions{finalReplicas: ptr.To[int32](1)}, original: 2},
+		{name: "legacy finalReplicas keeps the surge", opts: scaleRestartOptions{finalReplicas: ptr.To[int32](3)}, original: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldRestoreReplicas(tt.opts, tt.original); got != tt.want {
+				t.Errorf("shouldRestoreReplicas() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScaleReplicas(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	var updated *autoscalingv1.Scale
+	clientset.PrependReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "scale" {
+			return false, nil, nil
+		}
+		scale := action.(k8stesting.UpdateAction).GetObject().(*autoscalingv1.Scale)
+		if scale.Name == "missing" {
+			return true, nil, errors.NewNotFound(schema.GroupResource{Group: "apps", Resource: "deployments"}, scale.Name)
+		}
+		updated = scale
+		return true, scale, nil
+	})
+	handler := &DeploymentHandler{
+		GenericResourceHandler: &GenericResourceHandler[*appsv1.Deployment, *appsv1.DeploymentList]{
+			K8sClient: &kube.K8sClient{ClientSet: clientset},
+		},
+	}
+
+	scale, err := handler.scaleReplicas(context.Background(), "default", "web", 4)
+	if err != nil {
+		t.Fatalf("scaleReplicas: %v", err)
+	}
+	if scale.Spec.Replicas != 4 || updated.Namespace != "default" || updated.Name != "web" {
+		t.Errorf("scale = %+v, want default/web with 4 replicas", updated)
+	}
+	// Without a resourceVersion the update can't conflict
+	if updated.ResourceVersion != "" {
+		t.Errorf("scale update carries resourceVersion %q", updated.ResourceVersion)
+	}
+	for _, action := range clientset.Actions() {
+		if action.GetSubresource() != "scale" {
+			t.Errorf("unexpected %s of %s, only the scale subresource should be touched", action.GetVerb(), action.GetResource().Resource)
+		}
+	}
+
+	if _, err := handler.scaleReplicas(context.Background(), "default", "missing", 1); !errors.IsNotFound(err) {
+		t.Errorf("scaleReplicas of a missing deployment: %v, want NotFound", err)
+	}
+}
+
+// scaleTestHandler returns a deployment handler whose clientset serves the
+// scale subresource of deployment like the apiserver, with its
+// resourceVersion as a string counter. onGetScale runs after each scale is
+// read, to update the deployment in between.
+func scaleTestHandler(deployment *appsv1.Deployment, onGetScale func()) *DeploymentHandler {
+	resource := schema.GroupResource{Group: "apps", Resource: "deployments"}
+	bump := func() {
+		version, _ := strconv.Atoi(deployment.ResourceVersion)
+		deployment.ResourceVersion = strconv.Itoa(version + 1)
+	}
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("get", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.(k8stesting.GetAction).GetName() != deployment.Name {
+			return true, nil, errors.NewNotFound(resource, action.(k8stesting.GetAction).GetName())
+		}
+		if action.GetSubresource() != "scale" {
+			return true, deployment.DeepCopy(), nil
+		}
+		scale := &autoscalingv1.Scale{
+			ObjectMeta: metav1.ObjectMeta{Namespace: deployment.Namespace, Name: deployment.Name, ResourceVersion: deployment.ResourceVersion},
+			Spec:       autoscalingv1.ScaleSpec{Replicas: *deployment.Spec.Replicas},
+		}
+		if onGetScale != nil {
+			onGetScale()
+		}
+		return true, scale, nil
+	})
+	clientset.PrependReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "scale" {
+			return true, nil, errors.NewBadRequest("only the scale subresource may be updated")
+		}
+		scale := action.(k8stesting.UpdateAction).GetObject().(*autoscalingv1.Scale)
+		if scale.ResourceVersion != "" && scale.ResourceVersion != deployment.ResourceVersion {
+			return true, nil, errors.NewConflict(resource, scale.Name, fmt.Errorf("the object has been modified"))
+		}
+		deployment.Spec.Replicas = ptr.To(scale.Spec.Replicas)
+		bump()
+		scale = scale.DeepCopy()
+		scale.ResourceVersion = deployment.ResourceVersion
+		return true, scale, nil
+	})
+	// Annotations are patched through the controller-runtime client
+	k8sClient := crfake.NewClientBuilder().
+		WithObjects(deployment.DeepCopy()).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				bump()
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+		}).
+		Build()
+	return &DeploymentHandler{
+		GenericResourceHandler: &GenericResourceHandler[*appsv1.Deployment, *appsv1.DeploymentList]{
+			K8sClient: &kube.K8sClient{Client: k8sClient, ClientSet: clientset},
+		},
+	}
+}
+
+func scaleDeploymentRequest(handler *DeploymentHandler, replicas int32) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Params = gin.Params{{Key: "namespace", Value: "apps"}, {Key: "name", Value: "web"}}
+	c.Request = httptest.NewRequest(http.MethodPost, "/deployments/apps/web/scale", strings.NewReader(fmt.Sprintf(`{"replicas": %d}`, replicas)))
+	c.Request.Header.Set("Content-Type", "application/json")
+	handler.ScaleDeployment(c)
+	return recorder
+}
+
+func scaleTestDeployment(replicas int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "web", ResourceVersion: "1"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.To(replicas),
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "web", Image: "web:1"}}}},
+		},
+	}
+}
+
+func TestScaleDeploymentToZero(t *testing.T) {
+	deployment := scaleTestDeployment(3)
+	recorder := scaleDeploymentRequest(scaleTestHandler(deployment, nil), 0)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d (%s), want %d", recorder.Code, recorder.Body.String(), http.StatusOK)
+	}
+	if *deployment.Spec.Replicas != 0 {
+		t.Errorf("replicas = %d, want 0", *deployment.Spec.Replicas)
+	}
+
+	var response struct {
+		Deployment       *appsv1.Deployment `json:"deployment"`
+		Replicas         *int32             `json:"replicas"`
+		PreviousReplicas int32              `json:"previousReplicas"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if response.Replicas == nil || *response.Replicas != 0 || response.PreviousReplicas != 3 {
+		t.Errorf("replicas = %v, previousReplicas = %d, want 0 and 3", response.Replicas, response.PreviousReplicas)
+	}
+	if response.Deployment == nil || response.Deployment.Spec.Replicas == nil || *response.Deployment.Spec.Replicas != 0 {
+		t.Errorf("deployment = %+v, want the deployment scaled to 0", response.Deployment)
+	}
+}
+
+func TestScaleDeploymentConcurrentSpecUpdate(t *testing.T) {
+	deployment := scaleTestDeployment(1)
+	// A rollout updates the spec between reading and updating the scale
+	handler := scaleTestHandler(deployment, func() {
+		deployment.Spec.Template.Spec.Containers[0].Image = "web:2"
+		version, _ := strconv.Atoi(deployment.ResourceVersion)
+		deployment.ResourceVersion = strconv.Itoa(version + 1)
+	})
+
+	recorder := scaleDeploymentRequest(handler, 4)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d (%s), want %d without a conflict", recorder.Code, recorder.Body.String(), http.StatusOK)
+	}
+	if *deployment.Spec.Replicas != 4 || deployment.Spec.Template.Spec.Containers[0].Image != "web:2" {
+		t.Errorf("deployment has %d replicas of %s, want 4 of web:2", *deployment.Spec.Replicas, deployment.Spec.Template.Spec.Containers[0].Image)
+	}
+
+	// The same update pinned to the resourceVersion read before conflicts
+	stale := &autoscalingv1.Scale{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "web", ResourceVersion: "1"},
+		Spec:       autoscalingv1.ScaleSpec{Replicas: 4},
+	}
+	if _, err := handler.K8sClient.ClientSet.AppsV1().Deployments("apps").UpdateScale(context.Background(), "web", stale, metav1.UpdateOptions{}); !errors.IsConflict(err) {
+		t.Errorf("stale scale update: %v, want a conflict", err)
+	}
+}
+
+func TestRestartDeploymentsBatchConcurrency(t *testing.T) {
+	const n, ceiling = 12, 3
+	var inFlight, peak atomic.Int32
+	started := make(chan string, n)
+	release := make(chan struct{})
+
+	var objects []client.Object
+	body := BatchDeploymentRestartRequest{MaxConcurrency: ceiling}
+	for i := range n {
+		name := fmt.Sprintf("web-%d", i)
+		// web-0 doesn't exist
+		if i > 0 {
+			objects = append(objects, &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: name}})
+		}
+		body.Deployments = append(body.Deployments, DeploymentIdentifier{Namespace: "apps", Name: name})
+	}
+	k8sClient := crfake.NewClientBuilder().
+		WithObjects(objects...).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				current := inFlight.Add(1)
+				for {
+					seen := peak.Load()
+					if current <= seen || peak.CompareAndSwap(seen, current) {
+						break
+					}
+				}
+				started <- obj.GetName()
+				<-release
+				inFlight.Add(-1)
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+		}).
+		Build()
+	handler := &DeploymentHandler{
+		GenericResourceHandler: &GenericResourceHandler[*appsv1.Deployment, *appsv1.DeploymentList]{
+			K8sClient: &kube.K8sClient{Client: k8sClient},
+		},
+	}
+
+	payload, _ := json.Marshal(body)
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/deployments/batch/restart", strings.NewReader(string(payload)))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	done := make(chan struct{})
+	go func() {
+		handler.RestartDeploymentsBatch(c)
+		close(done)
+	}()
+	// Release an update each time the ceiling is reached, then the rest
+	for i := range n {
+		select {
+		case <-started:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timed out waiting for update %d", i+1)
+		}
+		if i >= ceiling-1 {
+			release <- struct{}{}
+		}
+	}
+	for range ceiling - 1 {
+		release <- struct{}{}
+	}
+	<-done
+
+	if got := peak.Load(); got != ceiling {
+		t.Errorf("%d updates in flight at the same time, want %d", got, ceiling)
+	}
+	if recorder.Code != http.StatusPartialContent {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusPartialContent)
+	}
+	var response struct {
+		Successful int                       `json:"successful"`
+		Failed     int                       `json:"failed"`
+		Results    []DeploymentRestartResult `json:"results"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if response.Successful != n-1 || response.Failed != 1 || len(response.Results) != n {
+		t.Fatalf("response = %+v, want %d successful and 1 failed", response, n-1)
+	}
+	for i, result := range response.Results {
+		if result.Name != body.Deployments[i].Name {
+			t.Errorf("results[%d] is %s, want %s", i, result.Name, body.Deployments[i].Name)
+		}
+		if want := i > 0; result.Success != want {
+			t.Errorf("%s success = %v (%s), want %v", result.Name, result.Success, result.Error, want)
+		}
+	}
+	if response.Results[0].Error != "Deployment not found" {
+		t.Errorf("web-0 error = %q, want Deployment not found", response.Results[0].Error)
+	}
+}
@@ -0,0 +1,246 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// imagePullReasons are the waiting reasons of containers whose image can't
+// be pulled
+var imagePullReasons = map[string]bool{
+	"ErrImagePull":      true,
+	"ImagePullBackOff":  true,
+	"InvalidImageName":  true,
+	"ErrImageNeverPull": true,
+}
+
+// HealthIssue is one problem found by the deployment health check
+type HealthIssue struct {
+	Pod          string `json:"pod,omitempty"`
+	Container    string `json:"container,omitempty"`
+	Image        string `json:"image,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+	Message      string `json:"message"`
+	Restarts     int32  `json:"restarts,omitempty"`
+	LastExitCode *int32 `json:"lastExitCode,omitempty"`
+	Count        int32  `json:"count,omitempty"`
+}
+
+// DeploymentHealth is the diagnosis of a deployment, grouped by category
+type DeploymentHealth struct {
+	Healthy       bool          `json:"healthy"`
+	NewReplicaSet string        `json:"newReplicaSet,omitempty"`
+	Rollout       []HealthIssue `json:"rollout"`
+	ImagePull     []HealthIssue `json:"imagePull"`
+	CrashLoop     []HealthIssue `json:"crashLoop"`
+	Unschedulable []HealthIssue `json:"unschedulable"`
+	ProbeFailures []HealthIssue `json:"probeFailures"`
+	QuotaDenials  []HealthIssue `json:"quotaDenials"`
+	Warnings      []string      `json:"warnings"`
+}
+
+// newReplicaSet returns the ReplicaSet of the deployment's current revision
+func newReplicaSet(deployment *appsv1.Deployment, replicaSets []appsv1.ReplicaSet) *appsv1.ReplicaSet {
+	revision, ok := objectRevision(deployment)
+	if !ok {
+		return nil
+	}
+	for i := range replicaSets {
+		if rsRevision, ok := objectRevision(&replicaSets[i]); ok && rsRevision == revision {
+			return &replicaSets[i]
+		}
+	}
+	return nil
+}
+
+// listEventsByReason lists the core events of an object with a reason
+func (h *DeploymentHandler) listEventsByReason(ctx context.Context, namespace, kind, name, reason string) ([]corev1.Event, error) {
+	eventList, err := h.K8sClient.ClientSet.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.SelectorFromSet(fields.Set{
+			"involvedObject.kind": kind,
+			"involvedObject.name": name,
+			"reason":              reason,
+		}).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return eventList.Items, nil
+}
+
+// diagnoseContainer adds the image pull and crash loop issues of a container
+func diagnoseContainer(health *DeploymentHealth, pod *corev1.Pod, status corev1.ContainerStatus) {
+	if waiting := status.State.Waiting; waiting != nil && imagePullReasons[waiting.Reason] {
+		health.ImagePull = append(health.ImagePull, HealthIssue{
+			Pod:       pod.Name,
+			Container: status.Name,
+			Image:     status.Image,
+			Reason:    waiting.Reason,
+			Message:   waiting.Message,
+		})
+	}
+
+	crashLooping := status.State.Waiting != nil && status.State.Waiting.Reason == "CrashLoopBackOff"
+	last := status.LastTerminationState.Terminated
+	if crashLooping || (status.RestartCount > 0 && last != nil && last.ExitCode != 0) {
+		issue := HealthIssue{
+			Pod:       pod.Name,
+			Container: status.Name,
+			Restarts:  status.RestartCount,
+		}
+		if crashLooping {
+			issue.Reason = "CrashLoopBackOff"
+			issue.Message = status.State.Waiting.Message
+		}
+		if last != nil {
+			exitCode := last.ExitCode
+			issue.LastExitCode = &exitCode
+			if issue.Reason == "" {
+				issue.Reason = last.Reason
+			}
+			if issue.Message == "" {
+				issue.Message = fmt.Sprintf("last terminated with exit code %d (%s)", last.ExitCode, last.Reason)
+			}
+		}
+		health.CrashLoop = append(health.CrashLoop, issue)
+	}
+}
+
+// GetDeploymentHealth diagnoses why a deployment is unhealthy from its
+// conditions, the pods of its new ReplicaSet and the events of those
+func (h *DeploymentHandler) GetDeploymentHealth(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	ctx := c.Request.Context()
+
+	var deployment appsv1.Deployment
+	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &deployment); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Deployment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get deployment: " + err.Error()})
+		return
+	}
+
+	health := &DeploymentHealth{
+		Rollout:       []HealthIssue{},
+		ImagePull:     []HealthIssue{},
+		CrashLoop:     []HealthIssue{},
+		Unschedulable: []HealthIssue{},
+		ProbeFailures: []HealthIssue{},
+		QuotaDenials:  []HealthIssue{},
+		Warnings:      []string{},
+	}
+
+	for _, condition := range deployment.Status.Conditions {
+		switch {
+		case condition.Type == appsv1.DeploymentAvailable && condition.Status == corev1.ConditionFalse,
+			condition.Type == appsv1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded":
+			health.Rollout = append(health.Rollout, HealthIssue{Reason: condition.Reason, Message: condition.Message})
+		case condition.Type == appsv1.DeploymentReplicaFailure && condition.Status == corev1.ConditionTrue:
+			health.QuotaDenials = append(health.QuotaDenials, HealthIssue{Reason: condition.Reason, Message: condition.Message})
+		}
+	}
+
+	replicaSets, err := h.listDeploymentReplicaSets(ctx, &deployment)
+	if err != nil {
+		health.Warnings = append(health.Warnings, "Failed to list ReplicaSets: "+err.Error())
+	}
+	rs := newReplicaSet(&deployment, replicaSets)
+	if rs == nil {
+		health.Warnings = append(health.Warnings, "The new ReplicaSet has not been created yet")
+		health.Healthy = len(health.Rollout) == 0 && len(health.QuotaDenials) == 0
+		c.JSON(http.StatusOK, health)
+		return
+	}
+	health.NewReplicaSet = rs.Name
+
+	// Pods that can't be created, e.g. because of a ResourceQuota
+	failedCreates, err := h.listEventsByReason(ctx, namespace, "ReplicaSet", rs.Name, "FailedCreate")
+	if err != nil {
+		health.Warnings = append(health.Warnings, "Failed to list ReplicaSet events: "+err.Error())
+	}
+	for i := range failedCreates {
+		health.QuotaDenials = append(health.QuotaDenials, HealthIssue{
+			Reason:  failedCreates[i].Reason,
+			Message: failedCreates[i].Message,
+			Count:   eventCount(&failedCreates[i]),
+		})
+	}
+
+	pods, err := h.listDeploymentPods(ctx, &deployment, []appsv1.ReplicaSet{*rs})
+	if err != nil {
+		health.Warnings = append(health.Warnings, "Failed to list pods: "+err.Error())
+	}
+	probePods := []*corev1.Pod{}
+	for i := range pods {
+		pod := &pods[i]
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse && condition.Reason == corev1.PodReasonUnschedulable {
+				health.Unschedulable = append(health.Unschedulable, HealthIssue{
+					Pod:     pod.Name,
+					Reason:  "FailedScheduling",
+					Message: condition.Message,
+				})
+			}
+		}
+		for _, status := range pod.Status.InitContainerStatuses {
+			diagnoseContainer(health, pod, status)
+		}
+		probing := false
+		for _, status := range pod.Status.ContainerStatuses {
+			diagnoseContainer(health, pod, status)
+			if status.State.Running != nil && !status.Ready {
+				probing = true
+			}
+		}
+		if probing {
+			probePods = append(probePods, pod)
+		}
+	}
+
+	// Running containers that aren't ready are usually failing a probe, the
+	// kubelet records those as Unhealthy events
+	type podEvents struct {
+		events []corev1.Event
+		err    error
+	}
+	results := utils.RunBatch(ctx, len(probePods), utils.DefaultBatchConcurrency,
+		func(ctx context.Context, i int) podEvents {
+			events, err := h.listEventsByReason(ctx, namespace, "Pod", probePods[i].Name, "Unhealthy")
+			return podEvents{events: events, err: err}
+		}, func(i int, err error) podEvents {
+			return podEvents{err: err}
+		})
+	for i, result := range results {
+		if result.err != nil {
+			health.Warnings = append(health.Warnings, fmt.Sprintf("Failed to list events of pod %s: %v", probePods[i].Name, result.err))
+			continue
+		}
+		for j := range result.events {
+			event := &result.events[j]
+			health.ProbeFailures = append(health.ProbeFailures, HealthIssue{
+				Pod:       probePods[i].Name,
+				Container: utils.EventContainerName(event),
+				Reason:    event.Reason,
+				Message:   event.Message,
+				Count:     eventCount(event),
+			})
+		}
+	}
+
+	health.Healthy = len(health.Rollout) == 0 && len(health.ImagePull) == 0 && len(health.CrashLoop) == 0 &&
+		len(health.Unschedulable) == 0 && len(health.ProbeFailures) == 0 && len(health.QuotaDenials) == 0
+	c.JSON(http.StatusOK, health)
+}
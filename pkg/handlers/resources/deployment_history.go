@@ -0,0 +1,156 @@
+package resources
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// changeCauseAnnotation records the reason of a rollout
+const changeCauseAnnotation = "kubernetes.io/change-cause"
+
+// ContainerImage is the image of a container in a pod template
+type ContainerImage struct {
+	Container string `json:"container"`
+	Image     string `json:"image"`
+	Init      bool   `json:"init,omitempty"`
+}
+
+// ImageChange is a container image that differs from the previous revision.
+// From is empty for added containers and To for removed ones.
+type ImageChange struct {
+	Container string `json:"container"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+}
+
+// DeploymentRevision describes a ReplicaSet of a deployment
+type DeploymentRevision struct {
+	Revision          int64            `json:"revision,omitempty"`
+	ReplicaSet        string           `json:"replicaSet"`
+	CreatedAt         metav1.Time      `json:"createdAt"`
+	Replicas          int32            `json:"replicas"`
+	ReadyReplicas     int32            `json:"readyReplicas"`
+	AvailableReplicas int32            `json:"availableReplicas"`
+	Images            []ContainerImage `json:"images"`
+	ChangeCause       string           `json:"changeCause,omitempty"`
+	Current           bool             `json:"current"`
+	ImageChanges      []ImageChange    `json:"imageChanges"`
+}
+
+func templateImages(template *corev1.PodTemplateSpec) []ContainerImage {
+	images := make([]ContainerImage, 0, len(template.Spec.InitContainers)+len(template.Spec.Containers))
+	for _, container := range template.Spec.InitContainers {
+		images = append(images, ContainerImage{Container: container.Name, Image: container.Image, Init: true})
+	}
+	for _, container := range template.Spec.Containers {
+		images = append(images, ContainerImage{Container: container.Name, Image: container.Image})
+	}
+	return images
+}
+
+// diffImages compares the container images of two revisions by container
+// name
+func diffImages(previous, current []ContainerImage) []ImageChange {
+	changes := []ImageChange{}
+	before := make(map[string]string, len(previous))
+	for _, image := range previous {
+		before[image.Container] = image.Image
+	}
+	seen := make(map[string]bool, len(current))
+	for _, image := range current {
+		seen[image.Container] = true
+		if from, ok := before[image.Container]; !ok || from != image.Image {
+			changes = append(changes, ImageChange{Container: image.Container, From: from, To: image.Image})
+		}
+	}
+	for _, image := range previous {
+		if !seen[image.Container] {
+			changes = append(changes, ImageChange{Container: image.Container, From: image.Image})
+		}
+	}
+	return changes
+}
+
+func newDeploymentRevision(rs *appsv1.ReplicaSet) DeploymentRevision {
+	revision := DeploymentRevision{
+		ReplicaSet:        rs.Name,
+		CreatedAt:         rs.CreationTimestamp,
+		ReadyReplicas:     rs.Status.ReadyReplicas,
+		AvailableReplicas: rs.Status.AvailableReplicas,
+		Images:            templateImages(&rs.Spec.Template),
+		ChangeCause:       rs.Annotations[changeCauseAnnotation],
+		ImageChanges:      []ImageChange{},
+	}
+	if rs.Spec.Replicas != nil {
+		revision.Replicas = *rs.Spec.Replicas
+	}
+	return revision
+}
+
+// GetDeploymentHistory lists the revisions of a deployment, newest first,
+// with the image changes relative to the preceding revision. ReplicaSets
+// without a revision annotation are returned separately as orphaned.
+func (h *DeploymentHandler) GetDeploymentHistory(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	ctx := c.Request.Context()
+
+	var deployment appsv1.Deployment
+	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &deployment); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Deployment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	replicaSets, err := h.listDeploymentReplicaSets(ctx, &deployment)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list ReplicaSets: " + err.Error()})
+		return
+	}
+
+	currentRevision, _ := objectRevision(&deployment)
+	revisions := []DeploymentRevision{}
+	orphaned := []DeploymentRevision{}
+	for i := range replicaSets {
+		entry := newDeploymentRevision(&replicaSets[i])
+		revision, ok := objectRevision(&replicaSets[i])
+		if !ok {
+			orphaned = append(orphaned, entry)
+			continue
+		}
+		entry.Revision = revision
+		entry.Current = revision == currentRevision
+		revisions = append(revisions, entry)
+	}
+
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision < revisions[j].Revision
+	})
+	for i := 1; i < len(revisions); i++ {
+		revisions[i].ImageChanges = diffImages(revisions[i-1].Images, revisions[i].Images)
+	}
+	// Newest first for the revision picker
+	for i, j := 0, len(revisions)-1; i < j; i, j = i+1, j-1 {
+		revisions[i], revisions[j] = revisions[j], revisions[i]
+	}
+	sort.Slice(orphaned, func(i, j int) bool {
+		return orphaned[i].CreatedAt.After(orphaned[j].CreatedAt.Time)
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"deployment":      name,
+		"currentRevision": currentRevision,
+		"revisions":       revisions,
+		"orphaned":        orphaned,
+	})
+}
@@ -0,0 +1,187 @@
+package resources
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// defaultDeploymentLogTailLines is how many lines are read from each
+	// container unless ?tailLines= is given
+	defaultDeploymentLogTailLines = 100
+	// maxDeploymentLogTailLines caps ?tailLines=
+	maxDeploymentLogTailLines = 10000
+	// maxDeploymentLogBytes caps the size of the merged log lines
+	maxDeploymentLogBytes = 4 << 20
+)
+
+// DeploymentLogLine is one log line of a container of a deployment's pod
+type DeploymentLogLine struct {
+	Timestamp time.Time `json:"timestamp"`
+	Pod       string    `json:"pod"`
+	Container string    `json:"container"`
+	// Prefix is "[pod/container]"
+	Prefix  string `json:"prefix"`
+	Message string `json:"message"`
+}
+
+// logTarget is a container to read logs from
+type logTarget struct {
+	pod       string
+	container string
+}
+
+// readContainerLogLines reads the logs of one container with timestamps and
+// splits them into lines
+func (h *DeploymentHandler) readContainerLogLines(ctx context.Context, namespace string, target logTarget, opts corev1.PodLogOptions) ([]DeploymentLogLine, error) {
+	opts.Container = target.container
+	opts.Timestamps = true
+	limitBytes := int64(maxDeploymentLogBytes)
+	opts.LimitBytes = &limitBytes
+
+	stream, err := h.K8sClient.ClientSet.CoreV1().Pods(namespace).GetLogs(target.pod, &opts).Stream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = stream.Close()
+	}()
+
+	prefix := fmt.Sprintf("[%s/%s]", target.pod, target.container)
+	lines := []DeploymentLogLine{}
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 8*1024), 64*1024)
+	for scanner.Scan() {
+		line := DeploymentLogLine{Pod: target.pod, Container: target.container, Prefix: prefix}
+		timestamp, message, found := strings.Cut(scanner.Text(), " ")
+		if parsed, err := time.Parse(time.RFC3339Nano, timestamp); found && err == nil {
+			line.Timestamp = parsed
+			line.Message = message
+		} else {
+			line.Message = scanner.Text()
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// GetDeploymentLogs returns the last lines of the logs of every container of
+// a deployment's pods merged and sorted by timestamp. ?container= limits the
+// containers, ?tailLines= (default 100), ?sinceSeconds= and ?previous=true
+// are passed on for each container.
+func (h *DeploymentHandler) GetDeploymentLogs(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	ctx := c.Request.Context()
+
+	opts := corev1.PodLogOptions{Previous: c.Query("previous") == "true"}
+	tailLines := int64(defaultDeploymentLogTailLines)
+	if value := c.Query("tailLines"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tailLines parameter"})
+			return
+		}
+		tailLines = min(parsed, maxDeploymentLogTailLines)
+	}
+	opts.TailLines = &tailLines
+	if value := c.Query("sinceSeconds"); value != "" {
+		since, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || since <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sinceSeconds parameter"})
+			return
+		}
+		opts.SinceSeconds = &since
+	}
+	container := c.Query("container")
+
+	var deployment appsv1.Deployment
+	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &deployment); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Deployment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get deployment: " + err.Error()})
+		return
+	}
+	replicaSets, err := h.listDeploymentReplicaSets(ctx, &deployment)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list ReplicaSets: " + err.Error()})
+		return
+	}
+	pods, err := h.listDeploymentPods(ctx, &deployment, replicaSets)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list pods: " + err.Error()})
+		return
+	}
+
+	targets := []logTarget{}
+	for _, pod := range pods {
+		if container != "" {
+			// A named container may also be an init container
+			if findPodTemplateContainer(&pod.Spec, container) != nil {
+				targets = append(targets, logTarget{pod: pod.Name, container: container})
+			}
+			continue
+		}
+		for _, podContainer := range pod.Spec.Containers {
+			targets = append(targets, logTarget{pod: pod.Name, container: podContainer.Name})
+		}
+	}
+
+	type targetLines struct {
+		lines []DeploymentLogLine
+		err   error
+	}
+	results := utils.RunBatch(ctx, len(targets), utils.DefaultBatchConcurrency,
+		func(ctx context.Context, i int) targetLines {
+			lines, err := h.readContainerLogLines(ctx, namespace, targets[i], opts)
+			return targetLines{lines: lines, err: err}
+		}, func(i int, err error) targetLines {
+			return targetLines{err: err}
+		})
+
+	warnings := []string{}
+	lines := []DeploymentLogLine{}
+	for i, result := range results {
+		if result.err != nil {
+			warnings = append(warnings, fmt.Sprintf("Failed to read logs of %s/%s: %v", targets[i].pod, targets[i].container, result.err))
+		}
+		lines = append(lines, result.lines...)
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].Timestamp.Before(lines[j].Timestamp)
+	})
+
+	// Keep the newest lines within the size cap
+	truncated := false
+	size := 0
+	for i := len(lines) - 1; i >= 0; i-- {
+		size += len(lines[i].Prefix) + len(lines[i].Message) + 1
+		if size > maxDeploymentLogBytes {
+			lines = lines[i+1:]
+			truncated = true
+			break
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pods":      len(pods),
+		"lines":     lines,
+		"truncated": truncated,
+		"warnings":  warnings,
+	})
+}
@@ -0,0 +1,243 @@
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DeploymentPlacementRequest replaces the node placement of a deployment's
+// pod template. Fields that are left out are not changed, an empty map or
+// list clears them.
+type DeploymentPlacementRequest struct {
+	NodeSelector map[string]string   `json:"nodeSelector"`
+	Tolerations  []corev1.Toleration `json:"tolerations"`
+	// RequiredNodeLabels replaces the required node affinity with a term
+	// requiring each label to have the given value
+	RequiredNodeLabels map[string]string `json:"requiredNodeLabels"`
+	// RecordChangeCause annotates the deployment with the change cause,
+	// defaults to true
+	RecordChangeCause *bool `json:"recordChangeCause,omitempty"`
+}
+
+// DeploymentPlacement is the node placement of a pod template
+type DeploymentPlacement struct {
+	NodeSelector map[string]string    `json:"nodeSelector,omitempty"`
+	Tolerations  []corev1.Toleration  `json:"tolerations,omitempty"`
+	NodeAffinity *corev1.NodeAffinity `json:"nodeAffinity,omitempty"`
+}
+
+func podTemplatePlacement(spec *corev1.PodSpec) DeploymentPlacement {
+	placement := DeploymentPlacement{NodeSelector: spec.NodeSelector, Tolerations: spec.Tolerations}
+	if spec.Affinity != nil {
+		placement.NodeAffinity = spec.Affinity.NodeAffinity
+	}
+	return placement
+}
+
+// validateNodeLabels checks label keys and values
+func validateNodeLabels(field string, nodeLabels map[string]string) error {
+	for key, value := range nodeLabels {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid %s key %q: %s", field, key, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return fmt.Errorf("invalid %s value %q for %s: %s", field, value, key, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+// validateTolerations checks the operators, effects and keys of tolerations
+func validateTolerations(tolerations []corev1.Toleration) error {
+	for i, toleration := range tolerations {
+		switch toleration.Operator {
+		case "", corev1.TolerationOpEqual:
+		case corev1.TolerationOpExists:
+			if toleration.Value != "" {
+				return fmt.Errorf("toleration %d: value must be empty with operator Exists", i)
+			}
+		default:
+			return fmt.Errorf("toleration %d: operator must be Equal or Exists", i)
+		}
+		switch toleration.Effect {
+		case "", corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+		default:
+			return fmt.Errorf("toleration %d: invalid effect %q", i, toleration.Effect)
+		}
+		if toleration.Key == "" && toleration.Operator != corev1.TolerationOpExists {
+			return fmt.Errorf("toleration %d: an empty key requires operator Exists", i)
+		}
+		if toleration.Key != "" {
+			if errs := validation.IsQualifiedName(toleration.Key); len(errs) > 0 {
+				return fmt.Errorf("toleration %d: invalid key %q: %s", i, toleration.Key, strings.Join(errs, "; "))
+			}
+		}
+		if toleration.TolerationSeconds != nil && toleration.Effect != corev1.TaintEffectNoExecute {
+			return fmt.Errorf("toleration %d: tolerationSeconds requires effect NoExecute", i)
+		}
+	}
+	return nil
+}
+
+// requiredNodeAffinity converts labels into a required node affinity with a
+// single term, so that all labels have to match
+func requiredNodeAffinity(nodeLabels map[string]string) *corev1.NodeSelector {
+	keys := make([]string, 0, len(nodeLabels))
+	for key := range nodeLabels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	expressions := make([]corev1.NodeSelectorRequirement, 0, len(keys))
+	for _, key := range keys {
+		expressions = append(expressions, corev1.NodeSelectorRequirement{
+			Key:      key,
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{nodeLabels[key]},
+		})
+	}
+	return &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{MatchExpressions: expressions}}}
+}
+
+// isNodeReady reports whether a node's Ready condition is true
+func isNodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// UpdateDeploymentPlacement replaces the nodeSelector, tolerations and
+// required node affinity of a deployment's pod template with a strategic
+// merge patch. The response warns when no Ready node satisfies the
+// resulting constraints.
+func (h *DeploymentHandler) UpdateDeploymentPlacement(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	ctx := c.Request.Context()
+
+	var req DeploymentPlacementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if req.NodeSelector == nil && req.Tolerations == nil && req.RequiredNodeLabels == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "nodeSelector, tolerations or requiredNodeLabels is required"})
+		return
+	}
+	if err := validateNodeLabels("nodeSelector", req.NodeSelector); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateNodeLabels("requiredNodeLabels", req.RequiredNodeLabels); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateTolerations(req.Tolerations); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var deployment appsv1.Deployment
+	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &deployment); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Deployment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get deployment: " + err.Error()})
+		return
+	}
+	previous := podTemplatePlacement(deployment.Spec.Template.Spec.DeepCopy())
+
+	// Maps are merged by default, the directive replaces the nodeSelector.
+	// Tolerations and node selector terms are lists without a merge key,
+	// which are always replaced.
+	spec := map[string]interface{}{}
+	if req.NodeSelector != nil {
+		nodeSelector := map[string]interface{}{"$patch": "replace"}
+		for key, value := range req.NodeSelector {
+			nodeSelector[key] = value
+		}
+		spec["nodeSelector"] = nodeSelector
+	}
+	if req.Tolerations != nil {
+		spec["tolerations"] = req.Tolerations
+	}
+	if req.RequiredNodeLabels != nil {
+		var required interface{}
+		if len(req.RequiredNodeLabels) > 0 {
+			required = requiredNodeAffinity(req.RequiredNodeLabels)
+		}
+		spec["affinity"] = map[string]interface{}{
+			"nodeAffinity": map[string]interface{}{
+				"requiredDuringSchedulingIgnoredDuringExecution": required,
+			},
+		}
+	}
+	body := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{"spec": spec},
+		},
+	}
+	if annotations := changeCauseAnnotations(c, req.RecordChangeCause, "update node placement"); len(annotations) > 0 {
+		body["metadata"] = map[string]interface{}{"annotations": annotations}
+	}
+	patch, err := json.Marshal(body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build patch: " + err.Error()})
+		return
+	}
+	if err := h.K8sClient.Client.Patch(ctx, &deployment, client.RawPatch(types.StrategicMergePatchType, patch)); err != nil {
+		if errors.IsInvalid(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid placement: " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update placement: " + err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"message":  "Node placement updated successfully",
+		"previous": previous,
+		"new":      podTemplatePlacement(&deployment.Spec.Template.Spec),
+		"warnings": []string{},
+	}
+
+	// Count the Ready nodes the new pods could be scheduled on
+	var nodeList corev1.NodeList
+	if err := h.K8sClient.Client.List(ctx, &nodeList); err != nil {
+		response["warnings"] = []string{"Failed to list nodes: " + err.Error()}
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		Spec:       deployment.Spec.Template.Spec,
+	}
+	matching := 0
+	for i := range nodeList.Items {
+		node := &nodeList.Items[i]
+		if isNodeReady(node) && checkNodeUnschedulable(pod, node).Passed &&
+			checkNodeAffinity(pod, node).Passed && checkTaintToleration(pod, node).Passed {
+			matching++
+		}
+	}
+	response["matchingNodes"] = matching
+	if matching == 0 {
+		response["warnings"] = []string{"No Ready node matches the new placement, new pods will stay Pending"}
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,215 @@
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// probeFields maps the probe names of DeploymentProbeRequest to container
+// fields
+var probeFields = map[string]string{
+	"liveness":  "livenessProbe",
+	"readiness": "readinessProbe",
+	"startup":   "startupProbe",
+}
+
+// DeploymentProbeRequest sets or removes one probe of a container
+type DeploymentProbeRequest struct {
+	Container string        `json:"container" binding:"required"`
+	Probe     string        `json:"probe" binding:"required,oneof=liveness readiness startup"`
+	Spec      *corev1.Probe `json:"spec"`
+	Remove    bool          `json:"remove"`
+	// Confirm is required to remove a liveness probe
+	Confirm bool `json:"confirm"`
+	// RecordChangeCause annotates the deployment with the change cause,
+	// defaults to true
+	RecordChangeCause *bool `json:"recordChangeCause,omitempty"`
+}
+
+// containerProbe returns the named probe of a container
+func containerProbe(container *corev1.Container, probe string) *corev1.Probe {
+	switch probe {
+	case "liveness":
+		return container.LivenessProbe
+	case "readiness":
+		return container.ReadinessProbe
+	case "startup":
+		return container.StartupProbe
+	}
+	return nil
+}
+
+// validateProbe checks that a probe has exactly one handler and sane
+// thresholds
+func validateProbe(kind string, probe *corev1.Probe) error {
+	handlers := 0
+	if probe.HTTPGet != nil {
+		handlers++
+		if probe.HTTPGet.Port.String() == "" || probe.HTTPGet.Port.String() == "0" {
+			return fmt.Errorf("httpGet requires a port")
+		}
+	}
+	if probe.TCPSocket != nil {
+		handlers++
+		if probe.TCPSocket.Port.String() == "" || probe.TCPSocket.Port.String() == "0" {
+			return fmt.Errorf("tcpSocket requires a port")
+		}
+	}
+	if probe.Exec != nil {
+		handlers++
+		if len(probe.Exec.Command) == 0 {
+			return fmt.Errorf("exec requires a command")
+		}
+	}
+	if probe.GRPC != nil {
+		handlers++
+		if probe.GRPC.Port <= 0 {
+			return fmt.Errorf("grpc requires a port")
+		}
+	}
+	if handlers != 1 {
+		return fmt.Errorf("exactly one of httpGet, tcpSocket, exec or grpc is required, got %d", handlers)
+	}
+
+	if probe.InitialDelaySeconds < 0 || probe.TimeoutSeconds < 0 || probe.PeriodSeconds < 0 ||
+		probe.SuccessThreshold < 0 || probe.FailureThreshold < 0 {
+		return fmt.Errorf("probe delays, timeouts and thresholds must not be negative")
+	}
+	if kind != "readiness" && probe.SuccessThreshold > 1 {
+		return fmt.Errorf("successThreshold of a %s probe must be 1", kind)
+	}
+	if probe.TerminationGracePeriodSeconds != nil {
+		if kind == "readiness" {
+			return fmt.Errorf("terminationGracePeriodSeconds is not allowed on readiness probes")
+		}
+		if *probe.TerminationGracePeriodSeconds <= 0 {
+			return fmt.Errorf("terminationGracePeriodSeconds must be positive")
+		}
+	}
+	return nil
+}
+
+// UpdateDeploymentProbe sets or removes a liveness, readiness or startup
+// probe of one container with a strategic merge patch. Removing a liveness
+// probe requires confirm, since the container is no longer restarted when
+// it hangs.
+func (h *DeploymentHandler) UpdateDeploymentProbe(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	ctx := c.Request.Context()
+
+	var req DeploymentProbeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	req.Confirm = req.Confirm || c.Query("confirm") == "true"
+	if req.Remove == (req.Spec != nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "exactly one of spec or remove is required"})
+		return
+	}
+	if req.Spec != nil {
+		if err := validateProbe(req.Probe, req.Spec); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid probe: " + err.Error()})
+			return
+		}
+	}
+	if req.Remove && req.Probe == "liveness" && !req.Confirm {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "removing a liveness probe changes when the container is restarted, pass confirm=true"})
+		return
+	}
+
+	var deployment appsv1.Deployment
+	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &deployment); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Deployment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get deployment: " + err.Error()})
+		return
+	}
+	container := findPodTemplateContainer(&deployment.Spec.Template.Spec, req.Container)
+	if container == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("container %q not found in deployment", req.Container)})
+		return
+	}
+	containersKey := "containers"
+	for _, initContainer := range deployment.Spec.Template.Spec.InitContainers {
+		if initContainer.Name == req.Container {
+			containersKey = "initContainers"
+			break
+		}
+	}
+	// Copied, the patch response is decoded into deployment
+	previous := containerProbe(container, req.Probe).DeepCopy()
+
+	// Replace the probe as a whole, merging would keep the handler of the
+	// previous probe next to the new one
+	var probe interface{}
+	if req.Spec != nil {
+		data, err := json.Marshal(req.Spec)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build patch: " + err.Error()})
+			return
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build patch: " + err.Error()})
+			return
+		}
+		fields["$patch"] = "replace"
+		probe = fields
+	}
+	body := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					containersKey: []map[string]interface{}{
+						{"name": req.Container, probeFields[req.Probe]: probe},
+					},
+				},
+			},
+		},
+	}
+	action := fmt.Sprintf("set %s probe of %s", req.Probe, req.Container)
+	if req.Remove {
+		action = fmt.Sprintf("remove %s probe of %s", req.Probe, req.Container)
+	}
+	if annotations := changeCauseAnnotations(c, req.RecordChangeCause, action); len(annotations) > 0 {
+		body["metadata"] = map[string]interface{}{"annotations": annotations}
+	}
+	patch, err := json.Marshal(body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build patch: " + err.Error()})
+		return
+	}
+
+	if err := h.K8sClient.Client.Patch(ctx, &deployment, client.RawPatch(types.StrategicMergePatchType, patch)); err != nil {
+		if errors.IsInvalid(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid probe: " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update probe: " + err.Error()})
+		return
+	}
+
+	var updated *corev1.Probe
+	if patched := findPodTemplateContainer(&deployment.Spec.Template.Spec, req.Container); patched != nil {
+		updated = containerProbe(patched, req.Probe)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":   fmt.Sprintf("%s probe of container %s updated successfully", req.Probe, req.Container),
+		"container": req.Container,
+		"probe":     req.Probe,
+		"previous":  previous,
+		"new":       updated,
+	})
+}
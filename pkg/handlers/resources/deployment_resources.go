@@ -0,0 +1,398 @@
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DeploymentResourcesRequest changes the requests and limits of one container
+type DeploymentResourcesRequest struct {
+	Container string            `json:"container" binding:"required"`
+	Requests  map[string]string `json:"requests"`
+	Limits    map[string]string `json:"limits"`
+	// DryRun validates the change and checks it against the namespace's
+	// LimitRanges and ResourceQuotas without applying it
+	DryRun bool `json:"dryRun"`
+	// RecordChangeCause annotates the deployment with the change cause,
+	// defaults to true
+	RecordChangeCause *bool `json:"recordChangeCause,omitempty"`
+}
+
+// ContainerResources is a snapshot of a container's requests and limits
+type ContainerResources struct {
+	Requests corev1.ResourceList `json:"requests,omitempty"`
+	Limits   corev1.ResourceList `json:"limits,omitempty"`
+}
+
+// ResourceAdmission reports whether pods with the new resources would be
+// admitted by the namespace's LimitRanges and ResourceQuotas
+type ResourceAdmission struct {
+	Admitted   bool     `json:"admitted"`
+	Violations []string `json:"violations,omitempty"`
+	// Skipped lists quotas with scopes, which are not evaluated
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// parseResourceList parses a name to quantity map from a request
+func parseResourceList(values map[string]string) (corev1.ResourceList, error) {
+	list := corev1.ResourceList{}
+	for name, value := range values {
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity %q for %s: %w", value, name, err)
+		}
+		if quantity.Sign() < 0 {
+			return nil, fmt.Errorf("quantity for %s must not be negative", name)
+		}
+		list[corev1.ResourceName(name)] = quantity
+	}
+	return list, nil
+}
+
+// mergeResourceList returns base overlaid with changes
+func mergeResourceList(base, changes corev1.ResourceList) corev1.ResourceList {
+	merged := base.DeepCopy()
+	if merged == nil {
+		merged = corev1.ResourceList{}
+	}
+	for name, quantity := range changes {
+		merged[name] = quantity
+	}
+	return merged
+}
+
+// checkRequestsWithinLimits returns an error for the first resource whose
+// request exceeds its limit
+func checkRequestsWithinLimits(requests, limits corev1.ResourceList) error {
+	for _, name := range sortedResourceNames(requests) {
+		request := requests[name]
+		limit, ok := limits[name]
+		if ok && request.Cmp(limit) > 0 {
+			return fmt.Errorf("%s request %s exceeds limit %s", name, request.String(), limit.String())
+		}
+	}
+	return nil
+}
+
+func sortedResourceNames(list corev1.ResourceList) []corev1.ResourceName {
+	names := make([]corev1.ResourceName, 0, len(list))
+	for name := range list {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+// UpdateDeploymentResources patches the requests and limits of one container
+// of a deployment. With dryRun the patch is only validated by the apiserver
+// and checked against LimitRanges and ResourceQuotas.
+func (h *DeploymentHandler) UpdateDeploymentResources(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	ctx := c.Request.Context()
+
+	var req DeploymentResourcesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if len(req.Requests) == 0 && len(req.Limits) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "requests or limits are required"})
+		return
+	}
+	requests, err := parseResourceList(req.Requests)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limits, err := parseResourceList(req.Limits)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var deployment appsv1.Deployment
+	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &deployment); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Deployment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get deployment: " + err.Error()})
+		return
+	}
+
+	containersKey := "containers"
+	container := findPodTemplateContainer(&deployment.Spec.Template.Spec, req.Container)
+	if container == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("container %q not found in deployment", req.Container)})
+		return
+	}
+	for _, initContainer := range deployment.Spec.Template.Spec.InitContainers {
+		if initContainer.Name == req.Container {
+			containersKey = "initContainers"
+			break
+		}
+	}
+
+	old := ContainerResources{Requests: container.Resources.Requests, Limits: container.Resources.Limits}
+	updated := ContainerResources{
+		Requests: mergeResourceList(old.Requests, requests),
+		Limits:   mergeResourceList(old.Limits, limits),
+	}
+	if err := checkRequestsWithinLimits(updated.Requests, updated.Limits); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resources := map[string]corev1.ResourceList{}
+	if len(requests) > 0 {
+		resources["requests"] = requests
+	}
+	if len(limits) > 0 {
+		resources["limits"] = limits
+	}
+	body := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					containersKey: []map[string]interface{}{
+						{"name": req.Container, "resources": resources},
+					},
+				},
+			},
+		},
+	}
+	if annotations := changeCauseAnnotations(c, req.RecordChangeCause, fmt.Sprintf("set resources of %s", req.Container)); len(annotations) > 0 {
+		body["metadata"] = map[string]interface{}{"annotations": annotations}
+	}
+	patch, err := json.Marshal(body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build patch: " + err.Error()})
+		return
+	}
+
+	opts := []client.PatchOption{}
+	if req.DryRun {
+		opts = append(opts, client.DryRunAll)
+	}
+	// Patch a copy, the dry run response must not replace the current spec
+	// used for the admission check below
+	target := deployment.DeepCopy()
+	if err := h.K8sClient.Client.Patch(ctx, target, client.RawPatch(types.StrategicMergePatchType, patch), opts...); err != nil {
+		if errors.IsInvalid(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid resources: " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update resources: " + err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"container": req.Container,
+		"old":       old,
+		"new":       updated,
+		"dryRun":    req.DryRun,
+	}
+	if !req.DryRun {
+		response["message"] = fmt.Sprintf("Resources of container %s updated successfully", req.Container)
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	admission, err := h.checkResourceAdmission(ctx, &deployment, target)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check admission: " + err.Error()})
+		return
+	}
+	response["admission"] = admission
+	c.JSON(http.StatusOK, response)
+}
+
+// checkResourceAdmission evaluates the pod template of updated against the
+// namespace's LimitRanges, and the change in pod resources from current to
+// updated across all replicas against its unscoped ResourceQuotas
+func (h *DeploymentHandler) checkResourceAdmission(ctx context.Context, current, updated *appsv1.Deployment) (*ResourceAdmission, error) {
+	namespace := current.Namespace
+	var limitRanges corev1.LimitRangeList
+	if err := h.K8sClient.Client.List(ctx, &limitRanges, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list limit ranges: %w", err)
+	}
+	var quotas corev1.ResourceQuotaList
+	if err := h.K8sClient.Client.List(ctx, &quotas, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list resource quotas: %w", err)
+	}
+
+	admission := &ResourceAdmission{Violations: []string{}}
+	oldSpec := current.Spec.Template.Spec.DeepCopy()
+	newSpec := updated.Spec.Template.Spec.DeepCopy()
+	for _, limitRange := range limitRanges.Items {
+		// LimitRanger fills in defaults before quota is charged
+		applyLimitRangeDefaults(oldSpec, &limitRange)
+		applyLimitRangeDefaults(newSpec, &limitRange)
+	}
+	for _, limitRange := range limitRanges.Items {
+		admission.Violations = append(admission.Violations, limitRangeViolations(newSpec, &limitRange)...)
+	}
+
+	replicas := int64(1)
+	if current.Spec.Replicas != nil {
+		replicas = int64(*current.Spec.Replicas)
+	}
+	oldRequests, oldLimits := podResourceTotals(oldSpec)
+	newRequests, newLimits := podResourceTotals(newSpec)
+	for _, quota := range quotas.Items {
+		if len(quota.Spec.Scopes) > 0 || quota.Spec.ScopeSelector != nil {
+			admission.Skipped = append(admission.Skipped, quota.Name)
+			continue
+		}
+		for _, hardName := range sortedResourceNames(quota.Spec.Hard) {
+			var oldTotal, newTotal corev1.ResourceList
+			var resourceName corev1.ResourceName
+			switch hardName {
+			case corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage:
+				oldTotal, newTotal, resourceName = oldRequests, newRequests, hardName
+			case corev1.ResourceRequestsCPU, corev1.ResourceRequestsMemory, corev1.ResourceRequestsEphemeralStorage:
+				oldTotal, newTotal, resourceName = oldRequests, newRequests, hardName[len("requests."):]
+			case corev1.ResourceLimitsCPU, corev1.ResourceLimitsMemory, corev1.ResourceLimitsEphemeralStorage:
+				oldTotal, newTotal, resourceName = oldLimits, newLimits, hardName[len("limits."):]
+			default:
+				continue
+			}
+			if _, ok := newTotal[resourceName]; !ok {
+				admission.Violations = append(admission.Violations,
+					fmt.Sprintf("quota %s tracks %s but the pod does not set it", quota.Name, hardName))
+				continue
+			}
+			oldValue, newValue := oldTotal[resourceName], newTotal[resourceName]
+			delta := newValue.MilliValue() - oldValue.MilliValue()
+			if delta <= 0 {
+				continue
+			}
+			hard := quota.Spec.Hard[hardName]
+			used := quota.Status.Used[hardName]
+			required := used.MilliValue() + delta*replicas
+			if required > hard.MilliValue() {
+				needed := resource.NewMilliQuantity(required, hard.Format)
+				admission.Violations = append(admission.Violations,
+					fmt.Sprintf("quota %s: %s would need %s across %d replicas, hard limit is %s",
+						quota.Name, hardName, needed.String(), replicas, hard.String()))
+			}
+		}
+	}
+	admission.Admitted = len(admission.Violations) == 0
+	return admission, nil
+}
+
+// applyLimitRangeDefaults fills in the default requests and limits of a
+// LimitRange's Container items the way the LimitRanger admission plugin does
+func applyLimitRangeDefaults(spec *corev1.PodSpec, limitRange *corev1.LimitRange) {
+	for _, item := range limitRange.Spec.Limits {
+		if item.Type != corev1.LimitTypeContainer {
+			continue
+		}
+		apply := func(container *corev1.Container) {
+			for name, quantity := range item.Default {
+				if _, ok := container.Resources.Limits[name]; !ok {
+					if container.Resources.Limits == nil {
+						container.Resources.Limits = corev1.ResourceList{}
+					}
+					container.Resources.Limits[name] = quantity.DeepCopy()
+				}
+			}
+			for name, quantity := range item.DefaultRequest {
+				if _, ok := container.Resources.Requests[name]; !ok {
+					if container.Resources.Requests == nil {
+						container.Resources.Requests = corev1.ResourceList{}
+					}
+					container.Resources.Requests[name] = quantity.DeepCopy()
+				}
+			}
+			// A missing request defaults to the limit
+			for name, quantity := range container.Resources.Limits {
+				if _, ok := container.Resources.Requests[name]; !ok {
+					if container.Resources.Requests == nil {
+						container.Resources.Requests = corev1.ResourceList{}
+					}
+					container.Resources.Requests[name] = quantity.DeepCopy()
+				}
+			}
+		}
+		for i := range spec.InitContainers {
+			apply(&spec.InitContainers[i])
+		}
+		for i := range spec.Containers {
+			apply(&spec.Containers[i])
+		}
+	}
+}
+
+// limitRangeViolations checks the containers and pod totals of spec against
+// the min, max and maxLimitRequestRatio of a LimitRange
+func limitRangeViolations(spec *corev1.PodSpec, limitRange *corev1.LimitRange) []string {
+	violations := []string{}
+	check := func(subject string, item corev1.LimitRangeItem, requests, limits corev1.ResourceList) {
+		for _, name := range sortedResourceNames(item.Min) {
+			minimum := item.Min[name]
+			if request, ok := requests[name]; ok && request.Cmp(minimum) < 0 {
+				violations = append(violations, fmt.Sprintf("limit range %s: %s %s request %s is below minimum %s",
+					limitRange.Name, subject, name, request.String(), minimum.String()))
+			}
+		}
+		for _, name := range sortedResourceNames(item.Max) {
+			maximum := item.Max[name]
+			limit, ok := limits[name]
+			if !ok {
+				violations = append(violations, fmt.Sprintf("limit range %s: %s must set a %s limit",
+					limitRange.Name, subject, name))
+				continue
+			}
+			if limit.Cmp(maximum) > 0 {
+				violations = append(violations, fmt.Sprintf("limit range %s: %s %s limit %s exceeds maximum %s",
+					limitRange.Name, subject, name, limit.String(), maximum.String()))
+			}
+		}
+		for _, name := range sortedResourceNames(item.MaxLimitRequestRatio) {
+			ratio := item.MaxLimitRequestRatio[name]
+			request, hasRequest := requests[name]
+			limit, hasLimit := limits[name]
+			if !hasRequest || !hasLimit || request.IsZero() {
+				continue
+			}
+			if float64(limit.MilliValue())/float64(request.MilliValue()) > ratio.AsApproximateFloat64() {
+				violations = append(violations, fmt.Sprintf("limit range %s: %s %s limit to request ratio exceeds %s",
+					limitRange.Name, subject, name, ratio.String()))
+			}
+		}
+	}
+
+	for _, item := range limitRange.Spec.Limits {
+		switch item.Type {
+		case corev1.LimitTypeContainer:
+			for _, container := range append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...) {
+				check("container "+container.Name, item, container.Resources.Requests, container.Resources.Limits)
+			}
+		case corev1.LimitTypePod:
+			requests, limits := podResourceTotals(spec)
+			check("pod", item, requests, limits)
+		}
+	}
+	return violations
+}
+
+// podResourceTotals returns the effective requests and limits of a pod
+// with the given spec
+func podResourceTotals(spec *corev1.PodSpec) (corev1.ResourceList, corev1.ResourceList) {
+	return utils.GetPodRequestsAndLimits(&corev1.Pod{Spec: *spec})
+}
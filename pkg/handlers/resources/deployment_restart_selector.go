@@ -0,0 +1,99 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/common"
+	"github.com/zxh326/kite/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RestartBySelectorRequest restarts the deployments of a namespace that
+// match a label selector
+type RestartBySelectorRequest struct {
+	LabelSelector string `json:"labelSelector" binding:"required"`
+	DryRun        bool   `json:"dryRun"`
+	// MaxConcurrency bounds how many deployments are restarted at the same
+	// time, see utils.BatchConcurrency
+	MaxConcurrency int `json:"maxConcurrency,omitempty" binding:"min=0"`
+	// RecordChangeCause annotates each deployment with the change cause,
+	// defaults to true
+	RecordChangeCause *bool `json:"recordChangeCause,omitempty"`
+}
+
+// RestartDeploymentsBySelector restarts every deployment in a namespace
+// matching a label selector through the batch restart. Empty selectors are
+// refused, and so are selectors matching more than
+// common.RestartBySelectorMax deployments. With dryRun the matched
+// deployments are only listed.
+func (h *DeploymentHandler) RestartDeploymentsBySelector(c *gin.Context) {
+	namespace := c.Param("namespace")
+	if namespace == "_all" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a namespace is required"})
+		return
+	}
+
+	var req RestartBySelectorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request body: %v", err)})
+		return
+	}
+	selector, err := labels.Parse(req.LabelSelector)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid labelSelector: " + err.Error()})
+		return
+	}
+	if selector.Empty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "labelSelector must not be empty"})
+		return
+	}
+
+	var deploymentList appsv1.DeploymentList
+	if err := h.K8sClient.Client.List(c.Request.Context(), &deploymentList, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list deployments: " + err.Error()})
+		return
+	}
+	if len(deploymentList.Items) > common.RestartBySelectorMax {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("labelSelector matches %d deployments, at most %d can be restarted at once",
+			len(deploymentList.Items), common.RestartBySelectorMax)})
+		return
+	}
+
+	deployments := make([]DeploymentIdentifier, 0, len(deploymentList.Items))
+	for _, deployment := range deploymentList.Items {
+		deployments = append(deployments, DeploymentIdentifier{Namespace: deployment.Namespace, Name: deployment.Name})
+	}
+	sort.Slice(deployments, func(i, j int) bool { return deployments[i].Name < deployments[j].Name })
+
+	if req.DryRun || len(deployments) == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"dryRun":      req.DryRun,
+			"total":       len(deployments),
+			"deployments": deployments,
+		})
+		return
+	}
+
+	klog.Infof("Restarting %d deployments in %s matching %q", len(deployments), namespace, req.LabelSelector)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
+	defer cancel()
+
+	annotations := changeCauseAnnotations(c, req.RecordChangeCause, fmt.Sprintf("restart of %s", req.LabelSelector))
+	items := utils.StreamBatch(ctx, len(deployments), utils.BatchConcurrency(req.MaxConcurrency),
+		func(ctx context.Context, i int) DeploymentRestartResult {
+			return h.restartSingleDeployment(ctx, deployments[i].Namespace, deployments[i].Name, annotations)
+		}, func(i int, err error) DeploymentRestartResult {
+			return cancelledDeploymentResult(deployments[i], err)
+		})
+
+	writeDeploymentBatchResults(c, "Selector deployment restart", len(deployments), items)
+}
@@ -0,0 +1,196 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// deploymentRevisionAnnotation is set by the deployment controller on
+	// deployments and their ReplicaSets
+	deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+	// podTemplateHashLabel is added to pod templates by the deployment
+	// controller and must not be copied back into the deployment
+	podTemplateHashLabel = appsv1.DefaultDeploymentUniqueLabelKey
+)
+
+// rollbackSkippedAnnotations are ReplicaSet annotations that are not copied
+// to the deployment on rollback, as kubectl rollout undo does
+var rollbackSkippedAnnotations = map[string]bool{
+	"kubectl.kubernetes.io/last-applied-configuration": true,
+	deploymentRevisionAnnotation:                       true,
+	"deployment.kubernetes.io/revision-history":        true,
+	"deployment.kubernetes.io/desired-replicas":        true,
+	"deployment.kubernetes.io/max-replicas":            true,
+	"deprecated.deployment.rollback.to":                true,
+}
+
+// objectRevision parses the revision annotation of a deployment or
+// ReplicaSet
+func objectRevision(obj metav1.Object) (int64, bool) {
+	value, ok := obj.GetAnnotations()[deploymentRevisionAnnotation]
+	if !ok {
+		return 0, false
+	}
+	revision, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return revision, true
+}
+
+// listDeploymentReplicaSets lists the ReplicaSets controlled by a deployment
+func (h *DeploymentHandler) listDeploymentReplicaSets(ctx context.Context, deployment *appsv1.Deployment) ([]appsv1.ReplicaSet, error) {
+	var rsList appsv1.ReplicaSetList
+	if err := h.K8sClient.Client.List(ctx, &rsList, client.InNamespace(deployment.Namespace)); err != nil {
+		return nil, err
+	}
+	owned := make([]appsv1.ReplicaSet, 0, len(rsList.Items))
+	for _, rs := range rsList.Items {
+		if owner := metav1.GetControllerOf(&rs); owner != nil && owner.UID == deployment.UID {
+			owned = append(owned, rs)
+		}
+	}
+	return owned, nil
+}
+
+// RollbackDeployment rolls a deployment back to the pod template of an
+// earlier revision like kubectl rollout undo. Without a revision the
+// previous one is used.
+func (h *DeploymentHandler) RollbackDeployment(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	ctx := c.Request.Context()
+
+	var rollbackRequest struct {
+		Revision int64 `json:"revision" binding:"min=0"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&rollbackRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+	}
+
+	var fromRevision, toRevision int64
+	var skipped bool
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var deployment appsv1.Deployment
+		if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &deployment); err != nil {
+			return err
+		}
+		if deployment.Spec.Paused {
+			return &rollbackError{status: http.StatusConflict, message: "cannot roll back a paused deployment, resume it first"}
+		}
+
+		replicaSets, err := h.listDeploymentReplicaSets(ctx, &deployment)
+		if err != nil {
+			return err
+		}
+		fromRevision, _ = objectRevision(&deployment)
+
+		revisions := make([]int64, 0, len(replicaSets))
+		byRevision := make(map[int64]*appsv1.ReplicaSet, len(replicaSets))
+		for i := range replicaSets {
+			if revision, ok := objectRevision(&replicaSets[i]); ok {
+				revisions = append(revisions, revision)
+				byRevision[revision] = &replicaSets[i]
+			}
+		}
+		sort.Slice(revisions, func(i, j int) bool { return revisions[i] < revisions[j] })
+
+		toRevision = rollbackRequest.Revision
+		if toRevision == 0 {
+			for _, revision := range revisions {
+				if revision < fromRevision {
+					toRevision = revision
+				}
+			}
+			if toRevision == 0 {
+				return &rollbackError{status: http.StatusConflict, message: "no previous revision to roll back to", revisions: revisions}
+			}
+		}
+		target, ok := byRevision[toRevision]
+		if !ok {
+			return &rollbackError{
+				status:    http.StatusGone,
+				message:   fmt.Sprintf("revision %d not found, its ReplicaSet may have been garbage collected", toRevision),
+				revisions: revisions,
+			}
+		}
+
+		template := target.Spec.Template.DeepCopy()
+		delete(template.Labels, podTemplateHashLabel)
+		if equality.Semantic.DeepEqual(&deployment.Spec.Template, template) {
+			skipped = true
+			return nil
+		}
+
+		patch := client.MergeFromWithOptions(deployment.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		deployment.Spec.Template = *template
+		for key, value := range target.Annotations {
+			if rollbackSkippedAnnotations[key] {
+				continue
+			}
+			if deployment.Annotations == nil {
+				deployment.Annotations = make(map[string]string)
+			}
+			deployment.Annotations[key] = value
+		}
+		return h.K8sClient.Client.Patch(ctx, &deployment, patch)
+	})
+	if err != nil {
+		if rbErr, ok := err.(*rollbackError); ok {
+			response := gin.H{"error": rbErr.message}
+			if rbErr.revisions != nil {
+				response["availableRevisions"] = rbErr.revisions
+			}
+			c.JSON(rbErr.status, response)
+			return
+		}
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Deployment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to roll back deployment: " + err.Error()})
+		return
+	}
+
+	if skipped {
+		c.JSON(http.StatusOK, gin.H{
+			"message":      fmt.Sprintf("Skipped rollback, the current template already matches revision %d", toRevision),
+			"fromRevision": fromRevision,
+			"toRevision":   toRevision,
+			"skipped":      true,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":      fmt.Sprintf("Deployment rolled back to revision %d", toRevision),
+		"fromRevision": fromRevision,
+		"toRevision":   toRevision,
+	})
+}
+
+// rollbackError is a rollback failure that maps to a specific status code
+type rollbackError struct {
+	status    int
+	message   string
+	revisions []int64
+}
+
+func (e *rollbackError) Error() string {
+	return e.message
+}
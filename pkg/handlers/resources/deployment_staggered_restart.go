@@ -0,0 +1,78 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/zxh326/kite/pkg/utils"
+)
+
+const (
+	// BatchRestartStrategyParallel restarts all deployments of a batch with
+	// bounded concurrency
+	BatchRestartStrategyParallel = "parallel"
+	// BatchRestartStrategyStaggered restarts deployments group by group
+	BatchRestartStrategyStaggered = "staggered"
+
+	// defaultStaggeredGroupSize is the group size of a staggered restart
+	// unless the request says otherwise
+	defaultStaggeredGroupSize = 5
+	// defaultStaggeredRestartTimeout bounds a whole staggered restart unless
+	// the request says otherwise
+	defaultStaggeredRestartTimeout = 15 * time.Minute
+)
+
+// staggeredRestart restarts deployments in groups of groupSize, at most
+// concurrency of a group at the same time. With
+// waitForReady each deployment's rollout has to complete before its result
+// counts as successful, and the next group only starts once every
+// deployment of the current group succeeded. Once a group fails the
+// remaining deployments are not processed. Results are published as they
+// complete, with their group set.
+func (h *DeploymentHandler) staggeredRestart(ctx context.Context, deployments []DeploymentIdentifier, groupSize, concurrency int, waitForReady bool, annotations map[string]string) <-chan utils.BatchItem[DeploymentRestartResult] {
+	out := make(chan utils.BatchItem[DeploymentRestartResult], len(deployments))
+	go func() {
+		defer close(out)
+		stoppedAt := 0
+		for start := 0; start < len(deployments); start += groupSize {
+			group := start/groupSize + 1
+			members := deployments[start:min(start+groupSize, len(deployments))]
+
+			if stoppedAt > 0 {
+				for i, deployment := range members {
+					result := cancelledDeploymentResult(deployment, fmt.Errorf("group %d did not become ready", stoppedAt))
+					result.Group = group
+					out <- utils.BatchItem[DeploymentRestartResult]{Index: start + i, Result: result}
+				}
+				continue
+			}
+
+			failed := false
+			items := utils.StreamBatch(ctx, len(members), concurrency,
+				func(ctx context.Context, i int) DeploymentRestartResult {
+					result := h.restartSingleDeployment(ctx, members[i].Namespace, members[i].Name, annotations)
+					if result.Success && waitForReady {
+						if err := h.waitForDeploymentReady(ctx, members[i].Namespace, members[i].Name, defaultDeploymentStepTimeout); err != nil {
+							result.Success = false
+							result.Error = fmt.Sprintf("Restarted but %v", err)
+						}
+					}
+					return result
+				}, func(i int, err error) DeploymentRestartResult {
+					return cancelledDeploymentResult(members[i], err)
+				})
+			for item := range items {
+				item.Result.Group = group
+				if !item.Result.Success {
+					failed = true
+				}
+				out <- utils.BatchItem[DeploymentRestartResult]{Index: start + item.Index, Result: item.Result}
+			}
+			if failed {
+				stoppedAt = group
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,99 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// defaultDeploymentStepTimeout bounds each wait for a deployment to become
+// ready during a scale-restart
+const defaultDeploymentStepTimeout = 2 * time.Minute
+
+// deploymentRolloutPending returns the rollout conditions a deployment
+// doesn't meet yet: the controller has observed the latest generation, all
+// replicas are updated and all replicas are ready
+func deploymentRolloutPending(deployment *appsv1.Deployment) []string {
+	desired := int32(1)
+	if deployment.Spec.Replicas != nil {
+		desired = *deployment.Spec.Replicas
+	}
+
+	var pending []string
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		pending = append(pending, fmt.Sprintf("observedGeneration %d has not caught up with generation %d",
+			deployment.Status.ObservedGeneration, deployment.Generation))
+	}
+	if deployment.Status.UpdatedReplicas != desired {
+		pending = append(pending, fmt.Sprintf("updatedReplicas %d/%d", deployment.Status.UpdatedReplicas, desired))
+	}
+	if deployment.Status.ReadyReplicas < desired {
+		pending = append(pending, fmt.Sprintf("readyReplicas %d/%d", deployment.Status.ReadyReplicas, desired))
+	}
+	return pending
+}
+
+// waitForDeploymentReady watches a deployment until deploymentRolloutPending
+// reports nothing. On timeout the error names the conditions that weren't
+// met.
+func (h *DeploymentHandler) waitForDeploymentReady(ctx context.Context, namespace, name string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	deployments := h.K8sClient.ClientSet.AppsV1().Deployments(namespace)
+	pending := []string{"deployment status not observed yet"}
+	for {
+		// Without a resourceVersion the watch starts with the current state
+		watcher, err := deployments.Watch(ctx, metav1.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector("metadata.name", name).String(),
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return fmt.Errorf("failed to watch deployment: %w", err)
+		}
+
+		done, err := func() (bool, error) {
+			defer watcher.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return false, nil
+				case event, ok := <-watcher.ResultChan():
+					if !ok {
+						// The watch expired, start a new one
+						return false, nil
+					}
+					if event.Type == watch.Deleted {
+						return false, fmt.Errorf("deployment was deleted")
+					}
+					deployment, ok := event.Object.(*appsv1.Deployment)
+					if !ok {
+						continue
+					}
+					pending = deploymentRolloutPending(deployment)
+					if len(pending) == 0 {
+						return true, nil
+					}
+				}
+			}
+		}()
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return fmt.Errorf("not ready after %s: %s", timeout, strings.Join(pending, ", "))
+}
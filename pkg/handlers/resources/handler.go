@@ -57,9 +57,9 @@ func RegisterRoutes(group *gin.RouterGroup, k8sClient *kube.K8sClient) {
 		"events":                 NewEventHandler(k8sClient),
 		"deployments":            NewDeploymentHandler(k8sClient),
 		"replicasets":            NewGenericResourceHandler[*appsv1.ReplicaSet, *appsv1.ReplicaSetList](k8sClient, "replicasets", false, false),
-		"statefulsets":           NewGenericResourceHandler[*appsv1.StatefulSet, *appsv1.StatefulSetList](k8sClient, "statefulsets", false, false),
-		"daemonsets":             NewGenericResourceHandler[*appsv1.DaemonSet, *appsv1.DaemonSetList](k8sClient, "daemonsets", false, true),
-		"jobs":                   NewGenericResourceHandler[*batchv1.Job, *batchv1.JobList](k8sClient, "jobs", false, false),
+		"statefulsets":           NewStatefulSetHandler(k8sClient),
+		"daemonsets":             NewDaemonSetHandler(k8sClient),
+		"jobs":                   NewJobHandler(k8sClient),
 		"cronjobs":               NewGenericResourceHandler[*batchv1.CronJob, *batchv1.CronJobList](k8sClient, "cronjobs", false, false),
 		"ingresses":              NewGenericResourceHandler[*networkingv1.Ingress, *networkingv1.IngressList](k8sClient, "ingresses", false, false),
 		"storageclasses":         NewGenericResourceHandler[*storagev1.StorageClass, *storagev1.StorageClassList](k8sClient, "storageclasses", true, false),
@@ -86,10 +86,14 @@ func RegisterRoutes(group *gin.RouterGroup, k8sClient *kube.K8sClient) {
 	}
 
 	crHandler := NewCRHandler(k8sClient)
+	group.GET("/crds/summary", crHandler.GetCRDSummary)
 	otherGroup := group.Group("/:crd")
 	{
 		otherGroup.GET("", crHandler.List)
 		otherGroup.GET("/_all", crHandler.List)
+		otherGroup.GET("/_counts", crHandler.GetCRCounts)
+		otherGroup.GET("/_all/watch", crHandler.WatchCRs)
+		otherGroup.GET("/_all/table", crHandler.GetCRTable)
 		otherGroup.GET("/_all/:name", crHandler.Get)
 		otherGroup.POST("/_all", crHandler.Create)  // 添加集群级别CRD创建路由
 		otherGroup.PUT("/_all/:name", crHandler.Update)
@@ -99,8 +103,14 @@ func RegisterRoutes(group *gin.RouterGroup, k8sClient *kube.K8sClient) {
 		otherGroup.GET("/_all/:name/events", crHandler.GetCREvents)
 		otherGroup.POST("/_all/:name/restart", crHandler.RestartCR)
 		otherGroup.POST("/_all/:name/scale", crHandler.ScaleCR)
+		otherGroup.GET("/_all/:name/finalizers", crHandler.GetCRFinalizers)
+		otherGroup.GET("/_all/:name/export", crHandler.ExportCR)
+		otherGroup.GET("/_all/:name/versions", crHandler.GetCRVersions)
+		otherGroup.DELETE("/_all/:name/finalizers/*finalizer", crHandler.RemoveCRFinalizer)
 
 		otherGroup.GET("/:namespace", crHandler.List)
+		otherGroup.GET("/:namespace/watch", crHandler.WatchCRs)
+		otherGroup.GET("/:namespace/table", crHandler.GetCRTable)
 		otherGroup.GET("/:namespace/:name", crHandler.Get)
 		otherGroup.POST("/:namespace", crHandler.Create)  // 添加命名空间级别CRD创建路由
 		otherGroup.PUT("/:namespace/:name", crHandler.Update)
@@ -110,6 +120,10 @@ func RegisterRoutes(group *gin.RouterGroup, k8sClient *kube.K8sClient) {
 		otherGroup.GET("/:namespace/:name/events", crHandler.GetCREvents)
 		otherGroup.POST("/:namespace/:name/restart", crHandler.RestartCR)
 		otherGroup.POST("/:namespace/:name/scale", crHandler.ScaleCR)
+		otherGroup.GET("/:namespace/:name/finalizers", crHandler.GetCRFinalizers)
+		otherGroup.GET("/:namespace/:name/export", crHandler.ExportCR)
+		otherGroup.GET("/:namespace/:name/versions", crHandler.GetCRVersions)
+		otherGroup.DELETE("/:namespace/:name/finalizers/*finalizer", crHandler.RemoveCRFinalizer)
 	}
 }
 
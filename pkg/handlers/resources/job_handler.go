@@ -0,0 +1,229 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/kube"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type JobHandler struct {
+	*GenericResourceHandler[*batchv1.Job, *batchv1.JobList]
+}
+
+func NewJobHandler(client *kube.K8sClient) *JobHandler {
+	return &JobHandler{
+		GenericResourceHandler: NewGenericResourceHandler[*batchv1.Job, *batchv1.JobList](
+			client,
+			"jobs",
+			false, // Jobs are namespaced resources
+			false,
+		),
+	}
+}
+
+const (
+	// rerunOfAnnotation is set on a rerun job to the name of the job it
+	// was created from
+	rerunOfAnnotation = "kite.kubernetes.io/rerun-of"
+	// defaultJobRerunTimeout bounds waitForCompletion
+	defaultJobRerunTimeout = 10 * time.Minute
+	// maxJobRerunTimeout bounds ?timeoutSeconds=
+	maxJobRerunTimeout = time.Hour
+	// jobCompletionPollInterval is how often a rerun job is checked while
+	// waiting for it to finish
+	jobCompletionPollInterval = 2 * time.Second
+	// maxJobNameLength keeps job names usable as the job-name label value
+	maxJobNameLength = 63
+)
+
+// jobControllerLabels are set by the job controller on the job and its pod
+// template, a copy must not carry them over
+var jobControllerLabels = []string{
+	"controller-uid",
+	batchv1.ControllerUidLabel,
+	"job-name",
+	batchv1.JobNameLabel,
+}
+
+// jobFinished returns the Complete or Failed condition of a job, or nil
+// while it is still running
+func jobFinished(job *batchv1.Job) *batchv1.JobCondition {
+	for i := range job.Status.Conditions {
+		condition := &job.Status.Conditions[i]
+		if (condition.Type == batchv1.JobComplete || condition.Type == batchv1.JobFailed) && condition.Status == corev1.ConditionTrue {
+			return condition
+		}
+	}
+	return nil
+}
+
+// rerunJob returns a new job with the spec of job. Status, server-set
+// metadata and the labels and selector generated by the job controller are
+// dropped, and the ownerReference to a CronJob isn't copied so the CronJob's
+// history limit doesn't garbage-collect the rerun.
+func rerunJob(job *batchv1.Job, now time.Time) *batchv1.Job {
+	rerun := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   job.Namespace,
+			Labels:      map[string]string{},
+			Annotations: map[string]string{},
+		},
+		Spec: *job.Spec.DeepCopy(),
+	}
+
+	if job.GenerateName != "" {
+		rerun.GenerateName = job.GenerateName
+	} else {
+		suffix := "-rerun-" + strconv.FormatInt(now.Unix(), 10)
+		base := job.Name
+		if len(base)+len(suffix) > maxJobNameLength {
+			base = base[:maxJobNameLength-len(suffix)]
+		}
+		rerun.Name = base + suffix
+	}
+
+	for key, value := range job.Labels {
+		rerun.Labels[key] = value
+	}
+	for key, value := range job.Annotations {
+		rerun.Annotations[key] = value
+	}
+	delete(rerun.Annotations, "batch.kubernetes.io/job-tracking")
+	rerun.Annotations[rerunOfAnnotation] = job.Name
+
+	for _, owner := range job.OwnerReferences {
+		if owner.Kind != "CronJob" {
+			rerun.OwnerReferences = append(rerun.OwnerReferences, owner)
+		}
+	}
+
+	for _, label := range jobControllerLabels {
+		delete(rerun.Labels, label)
+		delete(rerun.Spec.Template.Labels, label)
+	}
+	if rerun.Spec.ManualSelector == nil || !*rerun.Spec.ManualSelector {
+		rerun.Spec.Selector = nil
+	}
+	return rerun
+}
+
+// RerunJob creates a new job from the spec of an existing one, since the
+// spec of a job can't be changed to run it again. With
+// ?waitForCompletion=true the response waits for the new job to finish,
+// with ?deleteOriginal=true the original job is deleted once it has
+// finished and the rerun was created.
+func (h *JobHandler) RerunJob(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	ctx := c.Request.Context()
+
+	waitForCompletion := c.Query("waitForCompletion") == "true"
+	deleteOriginal := c.Query("deleteOriginal") == "true"
+	timeout := defaultJobRerunTimeout
+	if value := c.Query("timeoutSeconds"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "timeoutSeconds must be a positive integer"})
+			return
+		}
+		timeout = min(time.Duration(seconds)*time.Second, maxJobRerunTimeout)
+	}
+
+	var job batchv1.Job
+	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &job); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if deleteOriginal && jobFinished(&job) == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Job is still running, deleteOriginal requires it to have finished"})
+		return
+	}
+
+	rerun := rerunJob(&job, time.Now())
+	if err := h.K8sClient.Client.Create(ctx, rerun); err != nil {
+		if errors.IsAlreadyExists(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Job " + rerun.Name + " already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create job: " + err.Error()})
+		return
+	}
+	klog.Infof("Created job %s/%s as a rerun of %s", rerun.Namespace, rerun.Name, name)
+
+	response := gin.H{
+		"message": fmt.Sprintf("Job %s created from %s", rerun.Name, name),
+		"job":     rerun,
+	}
+	if deleteOriginal {
+		propagation := metav1.DeletePropagationBackground
+		if err := h.K8sClient.Client.Delete(ctx, &job, &client.DeleteOptions{PropagationPolicy: &propagation}); err != nil && !errors.IsNotFound(err) {
+			response["warning"] = "Failed to delete the original job: " + err.Error()
+		} else {
+			response["originalDeleted"] = true
+		}
+	}
+	if !waitForCompletion {
+		c.JSON(http.StatusCreated, response)
+		return
+	}
+
+	finished, err := h.waitForJobCompletion(ctx, rerun, timeout)
+	response["job"] = rerun
+	if err != nil {
+		response["error"] = err.Error()
+		c.JSON(http.StatusGatewayTimeout, response)
+		return
+	}
+	response["completed"] = finished.Type == batchv1.JobComplete
+	response["condition"] = finished
+	c.JSON(http.StatusCreated, response)
+}
+
+// waitForJobCompletion polls a job until it has a Complete or Failed
+// condition, updating job with the last state seen
+func (h *JobHandler) waitForJobCompletion(ctx context.Context, job *batchv1.Job, timeout time.Duration) (*batchv1.JobCondition, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(jobCompletionPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("job did not finish within %s", timeout)
+		case <-ticker.C:
+		}
+
+		var current batchv1.Job
+		if err := h.K8sClient.Client.Get(ctx, client.ObjectKeyFromObject(job), &current); err != nil {
+			if errors.IsNotFound(err) {
+				return nil, fmt.Errorf("job was deleted")
+			}
+			continue
+		}
+		*job = current
+		if condition := jobFinished(job); condition != nil {
+			return condition, nil
+		}
+	}
+}
+
+func (h *JobHandler) registerCustomRoutes(group *gin.RouterGroup) {
+	group.POST("/:namespace/:name/rerun", h.RerunJob)
+}
@@ -0,0 +1,105 @@
+package resources
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ResourceAllocation compares the requests and limits of the pods on a node
+// with the node's allocatable amount of a resource
+type ResourceAllocation struct {
+	Resource        corev1.ResourceName `json:"resource"`
+	Allocatable     resource.Quantity   `json:"allocatable"`
+	Requests        resource.Quantity   `json:"requests"`
+	Limits          resource.Quantity   `json:"limits"`
+	RequestsPercent float64             `json:"requestsPercent"`
+	LimitsPercent   float64             `json:"limitsPercent"`
+}
+
+// GetNodeAllocation sums the requests and limits of the non-terminated pods
+// on a node and compares them with its allocatable resources, like the
+// "Allocated resources" section of kubectl describe node
+func (h *NodeHandler) GetNodeAllocation(c *gin.Context) {
+	nodeName := c.Param("name")
+	ctx := c.Request.Context()
+
+	var node corev1.Node
+	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Name: nodeName}, &node); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	pods, err := h.listNodePods(ctx, nodeName, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list pods: " + err.Error()})
+		return
+	}
+
+	requests := corev1.ResourceList{}
+	limits := corev1.ResourceList{}
+	podCount := 0
+	for i := range pods {
+		if utils.IsPodErrorOrSuccess(&pods[i]) {
+			continue
+		}
+		podCount++
+		podRequests, podLimits := utils.GetPodRequestsAndLimits(&pods[i])
+		utils.AddResourceList(requests, podRequests)
+		utils.AddResourceList(limits, podLimits)
+	}
+	requests[corev1.ResourcePods] = *resource.NewQuantity(int64(podCount), resource.DecimalSI)
+
+	names := map[corev1.ResourceName]bool{}
+	for name := range node.Status.Allocatable {
+		names[name] = true
+	}
+	for name := range requests {
+		names[name] = true
+	}
+	for name := range limits {
+		names[name] = true
+	}
+
+	allocations := make([]ResourceAllocation, 0, len(names))
+	for name := range names {
+		allocation := ResourceAllocation{
+			Resource:    name,
+			Allocatable: node.Status.Allocatable[name],
+			Requests:    requests[name],
+			Limits:      limits[name],
+		}
+		allocation.RequestsPercent = quantityPercent(allocation.Requests, allocation.Allocatable)
+		allocation.LimitsPercent = quantityPercent(allocation.Limits, allocation.Allocatable)
+		allocations = append(allocations, allocation)
+	}
+	sort.Slice(allocations, func(i, j int) bool {
+		return allocations[i].Resource < allocations[j].Resource
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"node":      nodeName,
+		"pods":      podCount,
+		"resources": allocations,
+	})
+}
+
+// quantityPercent returns used as a percentage of total, rounded to two
+// decimals. A zero total yields 0.
+func quantityPercent(used, total resource.Quantity) float64 {
+	if total.IsZero() {
+		return 0
+	}
+	percent := float64(used.MilliValue()) / float64(total.MilliValue()) * 100
+	return float64(int64(percent*100+0.5)) / 100
+}
@@ -0,0 +1,117 @@
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// kiteAnnotationPrefix is the prefix of the annotations kite sets itself
+const kiteAnnotationPrefix = "kite.kubernetes.io/"
+
+// managedNodeAnnotations are node annotations maintained by the kubelet,
+// the controller manager or kubeadm, editing them by hand breaks those
+// components
+var managedNodeAnnotations = map[string]bool{
+	"node.alpha.kubernetes.io/ttl":                           true,
+	"volumes.kubernetes.io/controller-managed-attach-detach": true,
+	"kubeadm.alpha.kubernetes.io/cri-socket":                 true,
+	"kubectl.kubernetes.io/last-applied-configuration":       true,
+}
+
+// isManagedNodeAnnotation reports whether an annotation is managed by kite
+// or by a Kubernetes component and must not be edited through kite
+func isManagedNodeAnnotation(key string) bool {
+	return strings.HasPrefix(key, kiteAnnotationPrefix) || managedNodeAnnotations[key]
+}
+
+// UpdateNodeAnnotations adds and removes node annotations with a merge
+// patch guarded by the node's resourceVersion and returns the resulting
+// annotations
+func (h *NodeHandler) UpdateNodeAnnotations(c *gin.Context) {
+	nodeName := c.Param("name")
+	ctx := c.Request.Context()
+
+	var annotationRequest struct {
+		Add    map[string]string `json:"add"`
+		Remove []string          `json:"remove"`
+	}
+	if err := c.ShouldBindJSON(&annotationRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if len(annotationRequest.Add) == 0 && len(annotationRequest.Remove) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one annotation to add or remove is required"})
+		return
+	}
+
+	annotations := map[string]interface{}{}
+	for key, value := range annotationRequest.Add {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid annotation key %q: %s", key, strings.Join(errs, "; "))})
+			return
+		}
+		if isManagedNodeAnnotation(key) {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("annotation %q is managed automatically and cannot be edited", key)})
+			return
+		}
+		annotations[key] = value
+	}
+	for _, key := range annotationRequest.Remove {
+		if _, ok := annotationRequest.Add[key]; ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("annotation %q is both added and removed", key)})
+			return
+		}
+		if isManagedNodeAnnotation(key) {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("annotation %q is managed automatically and cannot be edited", key)})
+			return
+		}
+		// null removes the key in a JSON merge patch
+		annotations[key] = nil
+	}
+
+	// The patch carries the resourceVersion it was built against, so that a
+	// concurrent update makes it fail with a conflict and it is retried on
+	// the latest node
+	node := &corev1.Node{}
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Name: nodeName}, node); err != nil {
+			return err
+		}
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"resourceVersion": node.ResourceVersion,
+				"annotations":     annotations,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		return h.K8sClient.Client.Patch(ctx, node, client.RawPatch(types.MergePatchType, patch))
+	}); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update node annotations: " + err.Error()})
+		return
+	}
+
+	result := node.Annotations
+	if result == nil {
+		result = map[string]string{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":     fmt.Sprintf("Annotations of node %s updated successfully", nodeName),
+		"annotations": result,
+	})
+}
@@ -0,0 +1,181 @@
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/kube"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestIsManagedNodeAnnotation(t *testing.T) {
+	tests := map[string]bool{
+		"kite.kubernetes.io/restartedAt":                         true,
+		"node.alpha.kubernetes.io/ttl":                           true,
+		"volumes.kubernetes.io/controller-managed-attach-detach": true,
+		"kubectl.kubernetes.io/last-applied-configuration":       true,
+		"example.com/owner":                                      false,
+		"kite.kubernetes.io.example.com/owner":                   false,
+		"team":                                                   false,
+	}
+	for key, want := range tests {
+		if got := isManagedNodeAnnotation(key); got != want {
+			t.Errorf("isManagedNodeAnnotation(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+// updateNodeAnnotations runs UpdateNodeAnnotations for worker-1 with body
+// against a fake client holding node and calling funcs
+func updateNodeAnnotations(t *testing.T, node *corev1.Node, body string, funcs interceptor.Funcs) (*httptest.ResponseRecorder, client.Client) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	k8sClient := fake.NewClientBuilder().WithObjects(node).WithInterceptorFuncs(funcs).Build()
+	handler := &NodeHandler{
+		GenericResourceHandler: &GenericResourceHandler[*corev1.Node, *corev1.NodeList]{
+			K8sClient: &kube.K8sClient{Client: k8sClient},
+		},
+	}
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/nodes/_all/worker-1/annotations", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "name", Value: "worker-1"}}
+	handler.UpdateNodeAnnotations(c)
+	return recorder, k8sClient
+}
+
+func TestUpdateNodeAnnotations(t *testing.T) {
+	newNode := func() *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name: "worker-1",
+			Annotations: map[string]string{
+				"example.com/owner":            "team-a",
+				"example.com/rack":             "r1",
+				"node.alpha.kubernetes.io/ttl": "0",
+			},
+		}}
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		want   map[string]string
+	}{
+		{
+			name:   "add and remove",
+			body:   `{"add": {"example.com/owner": "team-b", "example.com/zone": "a"}, "remove": ["example.com/rack"]}`,
+			status: http.StatusOK,
+			want:   map[string]string{"example.com/owner": "team-b", "example.com/zone": "a", "node.alpha.kubernetes.io/ttl": "0"},
+		},
+		{
+			name:   "remove a key that isn't set",
+			body:   `{"remove": ["example.com/missing"]}`,
+			status: http.StatusOK,
+			want:   newNode().Annotations,
+		},
+		{name: "add with the kite prefix", body: `{"add": {"kite.kubernetes.io/restartedAt": "now"}}`, status: http.StatusForbidden},
+		{name: "remove a managed annotation", body: `{"remove": ["node.alpha.kubernetes.io/ttl"]}`, status: http.StatusForbidden},
+		{name: "invalid key", body: `{"add": {"not a key": "x"}}`, status: http.StatusBadRequest},
+		{name: "added and removed", body: `{"add": {"example.com/rack": "r2"}, "remove": ["example.com/rack"]}`, status: http.StatusBadRequest},
+		{name: "nothing to change", body: `{}`, status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder, k8sClient := updateNodeAnnotations(t, newNode(), tt.body, interceptor.Funcs{})
+			if recorder.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.status, recorder.Body.String())
+			}
+
+			var node corev1.Node
+			if err := k8sClient.Get(context.Background(), types.NamespacedName{Name: "worker-1"}, &node); err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			want := tt.want
+			if want == nil {
+				// Rejected requests leave the node unchanged
+				want = newNode().Annotations
+			}
+			if !maps.Equal(node.Annotations, want) {
+				t.Errorf("annotations = %v, want %v", node.Annotations, want)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var response struct {
+				Annotations map[string]string `json:"annotations"`
+			}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if !maps.Equal(response.Annotations, want) {
+				t.Errorf("response annotations = %v, want %v", response.Annotations, want)
+			}
+		})
+	}
+}
+
+func TestUpdateNodeAnnotationsNodeNotFound(t *testing.T) {
+	other := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-2"}}
+	recorder, _ := updateNodeAnnotations(t, other, `{"add": {"example.com/owner": "team-a"}}`, interceptor.Funcs{})
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+}
+
+func TestUpdateNodeAnnotationsRetriesConflicts(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1"}}
+	var patches []string
+	conflicted := false
+	recorder, k8sClient := updateNodeAnnotations(t, node, `{"add": {"example.com/owner": "team-a"}}`, interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			data, _ := patch.Data(obj)
+			patches = append(patches, string(data))
+			if !conflicted {
+				// Another writer updates the node first
+				conflicted = true
+				current := &corev1.Node{}
+				if err := c.Get(ctx, client.ObjectKeyFromObject(obj), current); err != nil {
+					return err
+				}
+				current.Labels = map[string]string{"updated": "true"}
+				if err := c.Update(ctx, current); err != nil {
+					return err
+				}
+			}
+			return c.Patch(ctx, obj, patch, opts...)
+		},
+	})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body.String())
+	}
+	if len(patches) != 2 {
+		t.Fatalf("%d patches, want a conflict and a retry: %v", len(patches), patches)
+	}
+	for _, patch := range patches {
+		if !strings.Contains(patch, `"resourceVersion"`) {
+			t.Errorf("patch %s has no resourceVersion", patch)
+		}
+	}
+
+	var updated corev1.Node
+	if err := k8sClient.Get(context.Background(), types.NamespacedName{Name: "worker-1"}, &updated); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if updated.Annotations["example.com/owner"] != "team-a" || updated.Labels["updated"] != "true" {
+		t.Errorf("node = %+v, want the annotation and the concurrent label", updated.ObjectMeta)
+	}
+}
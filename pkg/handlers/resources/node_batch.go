@@ -0,0 +1,189 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// nodeBatchConcurrency bounds how many nodes a batch operation changes at the
+// same time
+const nodeBatchConcurrency = 5
+
+// NodeBatchRequest selects the nodes of a batch operation, either by name or
+// by label selector
+type NodeBatchRequest struct {
+	Nodes         []string `json:"nodes"`
+	LabelSelector string   `json:"labelSelector"`
+}
+
+// NodeBatchResult represents the result of a batch operation on a single node
+type NodeBatchResult struct {
+	Name    string `json:"name"`
+	Success bool   `json:"success"`
+	Skipped bool   `json:"skipped,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// resolveBatchNodes returns the sorted, de-duplicated names of the nodes
+// selected by a batch request, writing the error response when it can't:
+// 400 for an invalid selection and 500 when the nodes can't be listed
+func (h *NodeHandler) resolveBatchNodes(ctx context.Context, c *gin.Context, req NodeBatchRequest) ([]string, bool) {
+	if len(req.Nodes) > 0 && req.LabelSelector != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "specify either nodes or labelSelector, not both"})
+		return nil, false
+	}
+
+	names := map[string]bool{}
+	if req.LabelSelector != "" {
+		selector, err := labels.Parse(req.LabelSelector)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid labelSelector: " + err.Error()})
+			return nil, false
+		}
+		nodeList := &corev1.NodeList{}
+		if err := h.K8sClient.Client.List(ctx, nodeList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list nodes: " + err.Error()})
+			return nil, false
+		}
+		for _, node := range nodeList.Items {
+			names[node.Name] = true
+		}
+	}
+	for _, name := range req.Nodes {
+		if name != "" {
+			names[name] = true
+		}
+	}
+	if len(names) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no nodes selected"})
+		return nil, false
+	}
+
+	result := make([]string, 0, len(names))
+	for name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result, true
+}
+
+// cancelledNodeResult is the result of a node that was not processed because
+// the batch was cancelled or timed out
+func cancelledNodeResult(nodeName string, err error) NodeBatchResult {
+	return NodeBatchResult{Name: nodeName, Error: fmt.Sprintf("Not processed: %v", err)}
+}
+
+// setNodeSchedulableResult flips the schedulability of a node unless it is
+// already in the desired state
+func (h *NodeHandler) setNodeSchedulableResult(ctx context.Context, nodeName string, schedulable bool) NodeBatchResult {
+	result := NodeBatchResult{Name: nodeName}
+
+	var node corev1.Node
+	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Name: nodeName}, &node); err != nil {
+		if errors.IsNotFound(err) {
+			result.Error = "Node not found"
+		} else {
+			result.Error = err.Error()
+		}
+		return result
+	}
+	if node.Spec.Unschedulable == !schedulable {
+		result.Success = true
+		result.Skipped = true
+		if schedulable {
+			result.Reason = "already uncordoned"
+		} else {
+			result.Reason = "already cordoned"
+		}
+		return result
+	}
+
+	if err := h.markNodeSchedulable(ctx, nodeName, schedulable); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Success = true
+	return result
+}
+
+// BatchCordonNodes cordons multiple nodes
+func (h *NodeHandler) BatchCordonNodes(c *gin.Context) {
+	h.batchSetSchedulable(c, false)
+}
+
+// BatchUncordonNodes uncordons multiple nodes
+func (h *NodeHandler) BatchUncordonNodes(c *gin.Context) {
+	h.batchSetSchedulable(c, true)
+}
+
+func (h *NodeHandler) batchSetSchedulable(c *gin.Context, schedulable bool) {
+	var req NodeBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request body: %v", err)})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
+	defer cancel()
+
+	nodes, ok := h.resolveBatchNodes(ctx, c, req)
+	if !ok {
+		return
+	}
+
+	operation := "cordon"
+	if schedulable {
+		operation = "uncordon"
+	}
+	klog.Infof("Starting batch %s for %d nodes", operation, len(nodes))
+
+	results := utils.RunBatch(ctx, len(nodes), nodeBatchConcurrency,
+		func(ctx context.Context, i int) NodeBatchResult {
+			return h.setNodeSchedulableResult(ctx, nodes[i], schedulable)
+		}, func(i int, err error) NodeBatchResult {
+			return cancelledNodeResult(nodes[i], err)
+		})
+
+	var successCount, skippedCount, failureCount int
+	for _, result := range results {
+		switch {
+		case !result.Success:
+			failureCount++
+		case result.Skipped:
+			skippedCount++
+		default:
+			successCount++
+		}
+	}
+
+	klog.Infof("Batch node %s completed: %d successful, %d skipped, %d failed", operation, successCount, skippedCount, failureCount)
+
+	response := gin.H{
+		"message":    fmt.Sprintf("Batch node %s completed: %d successful, %d skipped, %d failed", operation, successCount, skippedCount, failureCount),
+		"total":      len(nodes),
+		"successful": successCount,
+		"skipped":    skippedCount,
+		"failed":     failureCount,
+		"results":    results,
+		"timestamp":  time.Now().Format(time.RFC3339),
+	}
+
+	if failureCount > 0 {
+		c.JSON(http.StatusPartialContent, response)
+	} else {
+		c.JSON(http.StatusOK, response)
+	}
+}
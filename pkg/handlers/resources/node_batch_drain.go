@@ -0,0 +1,293 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog/v2"
+)
+
+// Batch drain phases. Nodes use pending, draining, done, failed and
+// cancelled, the batch itself running, done, failed and cancelled.
+const (
+	BatchDrainPending   = "pending"
+	BatchDrainDraining  = "draining"
+	BatchDrainRunning   = "running"
+	BatchDrainDone      = "done"
+	BatchDrainFailed    = "failed"
+	BatchDrainCancelled = "cancelled"
+)
+
+// BatchDrainRequest drains the selected nodes with the given drain options
+type BatchDrainRequest struct {
+	NodeBatchRequest
+	DrainRequest
+	// MaxConcurrent bounds how many nodes are drained at the same time
+	MaxConcurrent int `json:"maxConcurrent" binding:"min=0"`
+	// MaxUnavailablePercent bounds the share of the selected nodes that is
+	// drained at the same time
+	MaxUnavailablePercent int `json:"maxUnavailablePercent" binding:"min=0,max=100"`
+	// FailFast stops the batch, including the drains in flight, once a node
+	// fails to drain
+	FailFast *bool `json:"failFast"`
+}
+
+// BatchDrainNode is the progress of a single node within a batch drain
+type BatchDrainNode struct {
+	Name  string `json:"name"`
+	Phase string `json:"phase"`
+	JobID string `json:"jobId,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// drainBatch drains several nodes with bounded parallelism
+type drainBatch struct {
+	ID            string
+	MaxConcurrent int
+	FailFast      bool
+	StartedAt     time.Time
+	cancel        context.CancelFunc
+
+	mu         sync.Mutex
+	phase      string
+	nodes      []BatchDrainNode
+	finishedAt *time.Time
+}
+
+// BatchDrainStatus is a snapshot of a batch drain
+type BatchDrainStatus struct {
+	ID            string           `json:"id"`
+	Phase         string           `json:"phase"`
+	MaxConcurrent int              `json:"maxConcurrent"`
+	FailFast      bool             `json:"failFast"`
+	Nodes         []BatchDrainNode `json:"nodes"`
+	StartedAt     time.Time        `json:"startedAt"`
+	FinishedAt    *time.Time       `json:"finishedAt,omitempty"`
+}
+
+func (b *drainBatch) setNode(i int, phase, jobID, err string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nodes[i].Phase = phase
+	if jobID != "" {
+		b.nodes[i].JobID = jobID
+	}
+	b.nodes[i].Error = err
+}
+
+func (b *drainBatch) finish(phase string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.phase = phase
+	b.finishedAt = &now
+}
+
+// Status returns a consistent snapshot of the batch
+func (b *drainBatch) Status() BatchDrainStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return BatchDrainStatus{
+		ID:            b.ID,
+		Phase:         b.phase,
+		MaxConcurrent: b.MaxConcurrent,
+		FailFast:      b.FailFast,
+		Nodes:         append([]BatchDrainNode{}, b.nodes...),
+		StartedAt:     b.StartedAt,
+		FinishedAt:    b.finishedAt,
+	}
+}
+
+// batchDrainConcurrency combines maxConcurrent and maxUnavailablePercent
+// into the number of nodes drained at the same time, at least one
+func batchDrainConcurrency(nodeCount, maxConcurrent, maxUnavailablePercent int) int {
+	concurrency := nodeCount
+	if maxConcurrent > 0 && maxConcurrent < concurrency {
+		concurrency = maxConcurrent
+	}
+	if maxUnavailablePercent > 0 {
+		if byPercent := nodeCount * maxUnavailablePercent / 100; byPercent < concurrency {
+			concurrency = byPercent
+		}
+	}
+	if maxConcurrent == 0 && maxUnavailablePercent == 0 {
+		concurrency = 1
+	}
+	return max(concurrency, 1)
+}
+
+// BatchDrainNodes starts draining several nodes in the background, at most
+// maxConcurrent (default 1) and maxUnavailablePercent of them at a time.
+// With failFast (the default) the batch stops once a node fails to drain in
+// time: no further drains are started, the drains in flight are aborted and
+// the batch ends as failed.
+func (h *NodeHandler) BatchDrainNodes(c *gin.Context) {
+	var req BatchDrainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request body: %v", err)})
+		return
+	}
+
+	nodes, ok := h.resolveBatchNodes(c.Request.Context(), c, req.NodeBatchRequest)
+	if !ok {
+		return
+	}
+
+	// The batch must outlive the request that started it
+	ctx, cancel := context.WithCancel(context.Background())
+	batch := &drainBatch{
+		ID:            fmt.Sprintf("batch-drain-%d", time.Now().UnixNano()),
+		MaxConcurrent: batchDrainConcurrency(len(nodes), req.MaxConcurrent, req.MaxUnavailablePercent),
+		FailFast:      req.FailFast == nil || *req.FailFast,
+		StartedAt:     time.Now(),
+		cancel:        cancel,
+		phase:         BatchDrainRunning,
+		nodes:         make([]BatchDrainNode, len(nodes)),
+	}
+	for i, name := range nodes {
+		batch.nodes[i] = BatchDrainNode{Name: name, Phase: BatchDrainPending}
+	}
+	h.drains.addBatch(batch)
+
+	klog.Infof("Starting batch drain %s for %d nodes, %d at a time", batch.ID, len(nodes), batch.MaxConcurrent)
+	go h.runDrainBatch(ctx, batch, req.DrainRequest.options())
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": fmt.Sprintf("Batch drain of %d nodes started", len(nodes)),
+		"jobId":   batch.ID,
+		"status":  batch.Status(),
+	})
+}
+
+// runDrainBatch drains the nodes of a batch with a pool of MaxConcurrent
+// workers
+func (h *NodeHandler) runDrainBatch(ctx context.Context, batch *drainBatch, opts drainOptions) {
+	defer batch.cancel()
+
+	queue := make(chan int)
+	var stopOnce sync.Once
+	stopped := make(chan struct{})
+	stop := func() { stopOnce.Do(func() { close(stopped) }) }
+
+	var wg sync.WaitGroup
+	failed, failedFast := false, false
+	var failedMu sync.Mutex
+	for w := 0; w < batch.MaxConcurrent; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				// A node handed over as the batch stopped is not drained
+				select {
+				case <-stopped:
+					batch.setNode(i, BatchDrainCancelled, "", "batch stopped before this node was drained")
+					continue
+				default:
+				}
+				if err := h.drainBatchNode(ctx, batch, i, opts); err != nil {
+					failedMu.Lock()
+					failed = true
+					// Drains aborted by the cancel below fail too, only a
+					// failure of a running batch stops it
+					if batch.FailFast && ctx.Err() == nil {
+						failedFast = true
+						stop()
+						batch.cancel()
+					}
+					failedMu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i := range batch.nodes {
+		// select picks randomly among ready cases, so the stop is checked
+		// first: no node may be dispatched after a failFast failure
+		select {
+		case <-stopped:
+		default:
+			select {
+			case <-ctx.Done():
+			case <-stopped:
+			case queue <- i:
+				continue
+			}
+		}
+		// Nodes that were never started
+		for j := i; j < len(batch.nodes); j++ {
+			batch.setNode(j, BatchDrainCancelled, "", "batch stopped before this node was drained")
+		}
+		break
+	}
+	close(queue)
+	wg.Wait()
+
+	switch {
+	case failedFast:
+		batch.finish(BatchDrainFailed)
+	case ctx.Err() != nil:
+		batch.finish(BatchDrainCancelled)
+	case failed:
+		batch.finish(BatchDrainFailed)
+	default:
+		batch.finish(BatchDrainDone)
+	}
+	klog.Infof("Batch drain %s finished: %s", batch.ID, batch.Status().Phase)
+}
+
+// drainBatchNode drains a single node of a batch through the regular drain
+// job and reports whether it failed
+func (h *NodeHandler) drainBatchNode(ctx context.Context, batch *drainBatch, i int, opts drainOptions) error {
+	nodeName := batch.nodes[i].Name
+	job, drainCtx, err := h.drains.start(ctx, nodeName, opts)
+	if err != nil {
+		batch.setNode(i, BatchDrainFailed, "", err.Error())
+		return err
+	}
+	batch.setNode(i, BatchDrainDraining, job.ID, "")
+	h.runDrain(drainCtx, job)
+
+	status := job.Status()
+	switch {
+	case status.Phase == DrainPhaseCancelled:
+		err = fmt.Errorf("drain was cancelled")
+		batch.setNode(i, BatchDrainCancelled, "", err.Error())
+	case status.Phase != DrainPhaseDone:
+		err = fmt.Errorf("%s", status.Error)
+		batch.setNode(i, BatchDrainFailed, "", status.Error)
+	case status.Failed > 0:
+		err = fmt.Errorf("%d pods could not be evicted", status.Failed)
+		batch.setNode(i, BatchDrainFailed, "", err.Error())
+	default:
+		batch.setNode(i, BatchDrainDone, "", "")
+	}
+	return err
+}
+
+// GetBatchDrainStatus reports the progress of a batch drain
+func (h *NodeHandler) GetBatchDrainStatus(c *gin.Context) {
+	batch, ok := h.drains.getBatch(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Batch drain not found"})
+		return
+	}
+	c.JSON(http.StatusOK, batch.Status())
+}
+
+// CancelBatchDrain cancels a batch drain including the drains in flight.
+// Nodes that were cordoned stay cordoned.
+func (h *NodeHandler) CancelBatchDrain(c *gin.Context) {
+	batch, ok := h.drains.getBatch(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Batch drain not found"})
+		return
+	}
+	batch.cancel()
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("Batch drain %s cancelled", batch.ID),
+	})
+}
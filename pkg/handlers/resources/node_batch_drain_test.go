@@ -0,0 +1,91 @@
+package resources
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zxh326/kite/pkg/kube"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestRunDrainBatchFailFastAbortsDrainsInFlight(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "apps",
+			Name:            "web-0",
+			OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web", Controller: ptr.To(true)}},
+		},
+		Spec: corev1.PodSpec{NodeName: "node-b"},
+	}
+	// node-b's eviction is blocked by a PodDisruptionBudget, node-a fails
+	// once that drain is in flight
+	var blockedOnce sync.Once
+	blocked := make(chan struct{})
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		blockedOnce.Do(func() { close(blocked) })
+		return true, nil, errors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+	})
+	k8sClient := crfake.NewClientBuilder().
+		WithObjects(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-b"}}, pod).
+		WithIndex(&corev1.Pod{}, "spec.nodeName", func(obj client.Object) []string {
+			return []string{obj.(*corev1.Pod).Spec.NodeName}
+		}).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				if key.Name == "node-a" {
+					<-blocked
+				}
+				return c.Get(ctx, key, obj, opts...)
+			},
+		}).
+		Build()
+	handler := &NodeHandler{
+		GenericResourceHandler: &GenericResourceHandler[*corev1.Node, *corev1.NodeList]{
+			K8sClient: &kube.K8sClient{Client: k8sClient, ClientSet: clientset},
+		},
+		drains: newDrainJobStore(),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	batch := &drainBatch{
+		ID:            "batch-drain-test",
+		MaxConcurrent: 2,
+		FailFast:      true,
+		cancel:        cancel,
+		phase:         BatchDrainRunning,
+		nodes:         []BatchDrainNode{{Name: "node-a", Phase: BatchDrainPending}, {Name: "node-b", Phase: BatchDrainPending}},
+	}
+	done := make(chan struct{})
+	go func() {
+		handler.runDrainBatch(ctx, batch, drainOptions{Timeout: time.Minute})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the drain of node-b kept running after node-a failed")
+	}
+
+	status := batch.Status()
+	if status.Phase != BatchDrainFailed {
+		t.Errorf("batch phase = %s, want %s", status.Phase, BatchDrainFailed)
+	}
+	if status.Nodes[0].Phase != BatchDrainFailed || status.Nodes[1].Phase != BatchDrainCancelled {
+		t.Errorf("nodes = %+v, want node-a failed and node-b cancelled", status.Nodes)
+	}
+}
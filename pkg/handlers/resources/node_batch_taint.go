@@ -0,0 +1,231 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+)
+
+// Modes of a batch taint request
+const (
+	BatchTaintModeAdd    = "add"
+	BatchTaintModeRemove = "remove"
+)
+
+// BatchTaintRequest adds taints to or removes taints from multiple nodes.
+// When removing, a taint without effect removes the key with every effect.
+type BatchTaintRequest struct {
+	NodeBatchRequest
+	Taints []corev1.Taint `json:"taints" binding:"required,min=1"`
+	Mode   string         `json:"mode"`
+}
+
+// NodeTaintBatchResult is the result of a batch taint on a single node
+type NodeTaintBatchResult struct {
+	NodeBatchResult
+	Added          []corev1.Taint `json:"added,omitempty"`
+	Updated        []corev1.Taint `json:"updated,omitempty"`
+	AlreadyPresent []corev1.Taint `json:"alreadyPresent,omitempty"`
+	Removed        []corev1.Taint `json:"removed,omitempty"`
+	NotPresent     []corev1.Taint `json:"notPresent,omitempty"`
+	// UntoleratingPods counts the running pods that don't tolerate an added
+	// NoExecute taint and will be evicted
+	UntoleratingPods int `json:"untoleratingPods,omitempty"`
+}
+
+// validateBatchTaints checks the taints of a batch request up front so that
+// no node is changed when one of them is invalid
+func validateBatchTaints(taints []corev1.Taint, mode string) error {
+	seen := map[string]bool{}
+	for _, taint := range taints {
+		if mode == BatchTaintModeRemove {
+			if errs := validation.IsQualifiedName(taint.Key); len(errs) > 0 {
+				return fmt.Errorf("invalid taint key %q: %s", taint.Key, strings.Join(errs, "; "))
+			}
+			if taint.Effect != "" {
+				if err := validateTaint(corev1.Taint{Key: taint.Key, Effect: taint.Effect}); err != nil {
+					return err
+				}
+			}
+		} else if err := validateTaint(taint); err != nil {
+			return err
+		}
+		id := taint.Key + ":" + string(taint.Effect)
+		if seen[id] {
+			return fmt.Errorf("duplicate taint %s", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// applyBatchTaints changes the taints of a node in place and records the
+// outcome for every requested taint. Adding a taint whose key and effect
+// exist with another value overwrites the value.
+func applyBatchTaints(node *corev1.Node, taints []corev1.Taint, mode string, result *NodeTaintBatchResult) {
+	result.Added, result.Updated, result.AlreadyPresent = nil, nil, nil
+	result.Removed, result.NotPresent = nil, nil
+
+	if mode == BatchTaintModeRemove {
+		for _, taint := range taints {
+			kept := node.Spec.Taints[:0]
+			found := false
+			for _, existing := range node.Spec.Taints {
+				if existing.Key == taint.Key && (taint.Effect == "" || existing.Effect == taint.Effect) {
+					result.Removed = append(result.Removed, existing)
+					found = true
+					continue
+				}
+				kept = append(kept, existing)
+			}
+			node.Spec.Taints = kept
+			if !found {
+				result.NotPresent = append(result.NotPresent, taint)
+			}
+		}
+		return
+	}
+
+	for _, taint := range taints {
+		index := -1
+		for i := range node.Spec.Taints {
+			if node.Spec.Taints[i].MatchTaint(&taint) {
+				index = i
+				break
+			}
+		}
+		switch {
+		case index >= 0 && node.Spec.Taints[index].Value == taint.Value:
+			result.AlreadyPresent = append(result.AlreadyPresent, node.Spec.Taints[index])
+		case index >= 0:
+			node.Spec.Taints[index].Value = taint.Value
+			result.Updated = append(result.Updated, node.Spec.Taints[index])
+		default:
+			if taint.Effect == corev1.TaintEffectNoExecute {
+				now := metav1.Now()
+				taint.TimeAdded = &now
+			}
+			node.Spec.Taints = append(node.Spec.Taints, taint)
+			result.Added = append(result.Added, taint)
+		}
+	}
+}
+
+// taintNodeResult applies the taints of a batch request to one node
+func (h *NodeHandler) taintNodeResult(ctx context.Context, nodeName string, req BatchTaintRequest) NodeTaintBatchResult {
+	result := NodeTaintBatchResult{NodeBatchResult: NodeBatchResult{Name: nodeName}}
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var node corev1.Node
+		if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Name: nodeName}, &node); err != nil {
+			return err
+		}
+		applyBatchTaints(&node, req.Taints, req.Mode, &result)
+		if len(result.Added) == 0 && len(result.Updated) == 0 && len(result.Removed) == 0 {
+			return nil
+		}
+		return h.K8sClient.Client.Update(ctx, &node)
+	})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			result.Error = "Node not found"
+		} else {
+			result.Error = err.Error()
+		}
+		return result
+	}
+
+	result.Success = true
+	if len(result.Added) == 0 && len(result.Updated) == 0 && len(result.Removed) == 0 {
+		result.Skipped = true
+		if req.Mode == BatchTaintModeRemove {
+			result.Reason = "taints not present"
+		} else {
+			result.Reason = "taints already present"
+		}
+	}
+	newTaints := append(append([]corev1.Taint{}, result.Added...), result.Updated...)
+	result.UntoleratingPods = len(h.podsNotToleratingNoExecute(ctx, nodeName, newTaints))
+	return result
+}
+
+// BatchTaintNodes adds or removes taints on multiple nodes
+func (h *NodeHandler) BatchTaintNodes(c *gin.Context) {
+	var req BatchTaintRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request body: %v", err)})
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = BatchTaintModeAdd
+	}
+	if req.Mode != BatchTaintModeAdd && req.Mode != BatchTaintModeRemove {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid mode %q, must be add or remove", req.Mode)})
+		return
+	}
+	if err := validateBatchTaints(req.Taints, req.Mode); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
+	defer cancel()
+
+	nodes, ok := h.resolveBatchNodes(ctx, c, req.NodeBatchRequest)
+	if !ok {
+		return
+	}
+
+	klog.Infof("Starting batch taint %s for %d nodes", req.Mode, len(nodes))
+
+	results := utils.RunBatch(ctx, len(nodes), nodeBatchConcurrency,
+		func(ctx context.Context, i int) NodeTaintBatchResult {
+			return h.taintNodeResult(ctx, nodes[i], req)
+		}, func(i int, err error) NodeTaintBatchResult {
+			return NodeTaintBatchResult{NodeBatchResult: cancelledNodeResult(nodes[i], err)}
+		})
+
+	var successCount, skippedCount, failureCount, untolerating int
+	for _, result := range results {
+		untolerating += result.UntoleratingPods
+		switch {
+		case !result.Success:
+			failureCount++
+		case result.Skipped:
+			skippedCount++
+		default:
+			successCount++
+		}
+	}
+
+	klog.Infof("Batch taint %s completed: %d successful, %d skipped, %d failed", req.Mode, successCount, skippedCount, failureCount)
+
+	response := gin.H{
+		"message":          fmt.Sprintf("Batch taint %s completed: %d successful, %d skipped, %d failed", req.Mode, successCount, skippedCount, failureCount),
+		"total":            len(nodes),
+		"successful":       successCount,
+		"skipped":          skippedCount,
+		"failed":           failureCount,
+		"untoleratingPods": untolerating,
+		"results":          results,
+		"timestamp":        time.Now().Format(time.RFC3339),
+	}
+
+	if failureCount > 0 {
+		c.JSON(http.StatusPartialContent, response)
+	} else {
+		c.JSON(http.StatusOK, response)
+	}
+}
@@ -0,0 +1,128 @@
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/kube"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func batchTestNode(name string, labels map[string]string, unschedulable bool) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Spec:       corev1.NodeSpec{Unschedulable: unschedulable},
+	}
+}
+
+// nodeBatchTestHandler returns a node handler over a fake client holding
+// three nodes, worker-b is already cordoned
+func nodeBatchTestHandler(funcs interceptor.Funcs) *NodeHandler {
+	k8sClient := fake.NewClientBuilder().
+		WithObjects(
+			batchTestNode("worker-a", map[string]string{"pool": "blue"}, false),
+			batchTestNode("worker-b", map[string]string{"pool": "blue"}, true),
+			batchTestNode("worker-c", map[string]string{"pool": "green"}, false),
+		).
+		WithInterceptorFuncs(funcs).
+		Build()
+	return &NodeHandler{
+		GenericResourceHandler: &GenericResourceHandler[*corev1.Node, *corev1.NodeList]{
+			K8sClient: &kube.K8sClient{Client: k8sClient},
+		},
+	}
+}
+
+func nodeBatchTestContext(body string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/nodes/_all/batch/cordon", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	return c, recorder
+}
+
+func TestResolveBatchNodes(t *testing.T) {
+	failingList := interceptor.Funcs{
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			return fmt.Errorf("connection refused")
+		},
+	}
+
+	tests := []struct {
+		name   string
+		req    NodeBatchRequest
+		funcs  interceptor.Funcs
+		want   []string
+		status int
+	}{
+		{name: "names are sorted and de-duplicated", req: NodeBatchRequest{Nodes: []string{"worker-c", "", "worker-a", "worker-c"}}, want: []string{"worker-a", "worker-c"}},
+		{name: "label selector", req: NodeBatchRequest{LabelSelector: "pool=blue"}, want: []string{"worker-a", "worker-b"}},
+		{name: "names and selector", req: NodeBatchRequest{Nodes: []string{"worker-a"}, LabelSelector: "pool=blue"}, status: http.StatusBadRequest},
+		{name: "invalid selector", req: NodeBatchRequest{LabelSelector: "pool in (blue"}, status: http.StatusBadRequest},
+		{name: "selector matches nothing", req: NodeBatchRequest{LabelSelector: "pool=red"}, status: http.StatusBadRequest},
+		{name: "nothing selected", req: NodeBatchRequest{}, status: http.StatusBadRequest},
+		{name: "node list fails", req: NodeBatchRequest{LabelSelector: "pool=blue"}, funcs: failingList, status: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, recorder := nodeBatchTestContext("")
+			nodes, ok := nodeBatchTestHandler(tt.funcs).resolveBatchNodes(context.Background(), c, tt.req)
+			if tt.status != 0 {
+				if ok || recorder.Code != tt.status {
+					t.Errorf("resolveBatchNodes() = %v, %v with status %d, want status %d", nodes, ok, recorder.Code, tt.status)
+				}
+				return
+			}
+			if !ok || !slices.Equal(nodes, tt.want) {
+				t.Errorf("resolveBatchNodes() = %v, %v (%s), want %v", nodes, ok, recorder.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestBatchCordonNodes(t *testing.T) {
+	handler := nodeBatchTestHandler(interceptor.Funcs{})
+	c, recorder := nodeBatchTestContext(`{"nodes": ["worker-a", "worker-b", "worker-missing"]}`)
+	handler.BatchCordonNodes(c)
+
+	if recorder.Code != http.StatusPartialContent {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusPartialContent)
+	}
+	var response struct {
+		Successful int               `json:"successful"`
+		Skipped    int               `json:"skipped"`
+		Failed     int               `json:"failed"`
+		Results    []NodeBatchResult `json:"results"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if response.Successful != 1 || response.Skipped != 1 || response.Failed != 1 {
+		t.Errorf("response = %+v, want 1 successful, 1 skipped and 1 failed", response)
+	}
+	want := []NodeBatchResult{
+		{Name: "worker-a", Success: true},
+		{Name: "worker-b", Success: true, Skipped: true, Reason: "already cordoned"},
+		{Name: "worker-missing", Error: "Node not found"},
+	}
+	if !slices.Equal(response.Results, want) {
+		t.Errorf("results = %+v, want %+v", response.Results, want)
+	}
+
+	var node corev1.Node
+	if err := handler.K8sClient.Client.Get(context.Background(), client.ObjectKey{Name: "worker-a"}, &node); err != nil || !node.Spec.Unschedulable {
+		t.Errorf("worker-a unschedulable = %v (%v), want true", node.Spec.Unschedulable, err)
+	}
+}
@@ -0,0 +1,377 @@
+package resources
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Scheduling predicates evaluated by CanScheduleOnNode, named after the
+// kube-scheduler plugins they follow
+const (
+	PredicateNodeUnschedulable = "NodeUnschedulable"
+	PredicateNodeResourcesFit  = "NodeResourcesFit"
+	PredicateNodeAffinity      = "NodeAffinity"
+	PredicateTaintToleration   = "TaintToleration"
+	PredicateNodePorts         = "NodePorts"
+)
+
+// PredicateResult is the outcome of one scheduling predicate
+type PredicateResult struct {
+	Name    string   `json:"name"`
+	Passed  bool     `json:"passed"`
+	Reasons []string `json:"reasons"`
+	// Notes carry information that doesn't affect the outcome, such as
+	// matching preferred affinity terms
+	Notes []string `json:"notes,omitempty"`
+}
+
+// CanScheduleRequest holds either a pod spec or a reference to a
+// deployment whose pod template is evaluated
+type CanScheduleRequest struct {
+	PodSpec    *corev1.PodSpec `json:"podSpec"`
+	Deployment *struct {
+		Namespace string `json:"namespace" binding:"required"`
+		Name      string `json:"name" binding:"required"`
+	} `json:"deployment"`
+}
+
+// checkNodeUnschedulable fails for cordoned nodes unless the pod tolerates
+// the unschedulable taint
+func checkNodeUnschedulable(pod *corev1.Pod, node *corev1.Node) PredicateResult {
+	result := PredicateResult{Name: PredicateNodeUnschedulable, Passed: true, Reasons: []string{}}
+	if !node.Spec.Unschedulable {
+		return result
+	}
+	taint := &corev1.Taint{Key: corev1.TaintNodeUnschedulable, Effect: corev1.TaintEffectNoSchedule}
+	if toleratesTaint(pod.Spec.Tolerations, taint) {
+		result.Notes = []string{"node is cordoned but the pod tolerates " + corev1.TaintNodeUnschedulable}
+		return result
+	}
+	result.Passed = false
+	result.Reasons = append(result.Reasons, "node is cordoned (unschedulable)")
+	return result
+}
+
+// checkNodeResourcesFit compares the pod's requests with what the
+// non-terminated pods on the node leave of its allocatable resources. Like
+// the scheduler only requested resources and the pod count are checked.
+func checkNodeResourcesFit(pod *corev1.Pod, node *corev1.Node, nodePods []corev1.Pod) PredicateResult {
+	result := PredicateResult{Name: PredicateNodeResourcesFit, Passed: true, Reasons: []string{}}
+
+	requested := corev1.ResourceList{}
+	podCount := 0
+	for i := range nodePods {
+		if utils.IsPodErrorOrSuccess(&nodePods[i]) {
+			continue
+		}
+		podCount++
+		podRequests, _ := utils.GetPodRequestsAndLimits(&nodePods[i])
+		utils.AddResourceList(requested, podRequests)
+	}
+
+	allocatable := node.Status.Allocatable
+	if maxPods, ok := allocatable[corev1.ResourcePods]; ok && int64(podCount+1) > maxPods.Value() {
+		result.Passed = false
+		result.Reasons = append(result.Reasons, fmt.Sprintf("too many pods: %d of %d already running", podCount, maxPods.Value()))
+	}
+
+	podRequests, _ := utils.GetPodRequestsAndLimits(pod)
+	for name, quantity := range podRequests {
+		if name == corev1.ResourcePods || quantity.IsZero() {
+			continue
+		}
+		total := allocatable[name]
+		free := total.DeepCopy()
+		used := requested[name]
+		free.Sub(used)
+		if quantity.Cmp(free) > 0 {
+			result.Passed = false
+			result.Reasons = append(result.Reasons, fmt.Sprintf("insufficient %s: requested %s, free %s of %s allocatable",
+				name, quantity.String(), free.String(), total.String()))
+		}
+	}
+	return result
+}
+
+// nodeSelectorRequirementMatches evaluates a node selector requirement
+// against a value looked up on the node
+func nodeSelectorRequirementMatches(req corev1.NodeSelectorRequirement, value string, exists bool) bool {
+	switch req.Operator {
+	case corev1.NodeSelectorOpIn:
+		if !exists {
+			return false
+		}
+		for _, v := range req.Values {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	case corev1.NodeSelectorOpNotIn:
+		if !exists {
+			return true
+		}
+		for _, v := range req.Values {
+			if v == value {
+				return false
+			}
+		}
+		return true
+	case corev1.NodeSelectorOpExists:
+		return exists
+	case corev1.NodeSelectorOpDoesNotExist:
+		return !exists
+	case corev1.NodeSelectorOpGt, corev1.NodeSelectorOpLt:
+		if !exists || len(req.Values) != 1 {
+			return false
+		}
+		actual, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return false
+		}
+		bound, err := strconv.ParseInt(req.Values[0], 10, 64)
+		if err != nil {
+			return false
+		}
+		if req.Operator == corev1.NodeSelectorOpGt {
+			return actual > bound
+		}
+		return actual < bound
+	}
+	return false
+}
+
+// nodeSelectorTermMatches reports whether all expressions and fields of a
+// term match the node. A term without requirements matches nothing.
+func nodeSelectorTermMatches(term corev1.NodeSelectorTerm, node *corev1.Node) bool {
+	if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+		return false
+	}
+	for _, req := range term.MatchExpressions {
+		value, exists := node.Labels[req.Key]
+		if !nodeSelectorRequirementMatches(req, value, exists) {
+			return false
+		}
+	}
+	for _, req := range term.MatchFields {
+		// metadata.name is the only supported field
+		if req.Key != "metadata.name" || !nodeSelectorRequirementMatches(req, node.Name, true) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkNodeAffinity checks the pod's nodeSelector and required node
+// affinity, where the terms are ORed. Preferred terms only influence
+// scoring and are reported as notes.
+func checkNodeAffinity(pod *corev1.Pod, node *corev1.Node) PredicateResult {
+	result := PredicateResult{Name: PredicateNodeAffinity, Passed: true, Reasons: []string{}}
+
+	for key, value := range pod.Spec.NodeSelector {
+		if actual, ok := node.Labels[key]; !ok || actual != value {
+			result.Passed = false
+			result.Reasons = append(result.Reasons, fmt.Sprintf("nodeSelector %s=%s does not match", key, value))
+		}
+	}
+
+	affinity := pod.Spec.Affinity
+	if affinity == nil || affinity.NodeAffinity == nil {
+		return result
+	}
+	if required := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution; required != nil {
+		matched := false
+		for _, term := range required.NodeSelectorTerms {
+			if nodeSelectorTermMatches(term, node) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			result.Passed = false
+			result.Reasons = append(result.Reasons, "none of the required node affinity terms match")
+		}
+	}
+	for i, term := range affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+		if nodeSelectorTermMatches(term.Preference, node) {
+			result.Notes = append(result.Notes, fmt.Sprintf("preferred term %d matches (weight %d)", i, term.Weight))
+		} else {
+			result.Notes = append(result.Notes, fmt.Sprintf("preferred term %d does not match (weight %d)", i, term.Weight))
+		}
+	}
+	return result
+}
+
+// checkTaintToleration fails for NoSchedule and NoExecute taints the pod
+// doesn't tolerate. PreferNoSchedule taints only affect scoring.
+func checkTaintToleration(pod *corev1.Pod, node *corev1.Node) PredicateResult {
+	result := PredicateResult{Name: PredicateTaintToleration, Passed: true, Reasons: []string{}}
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		var toleration *corev1.Toleration
+		for j := range pod.Spec.Tolerations {
+			if pod.Spec.Tolerations[j].ToleratesTaint(taint) {
+				toleration = &pod.Spec.Tolerations[j]
+				break
+			}
+		}
+
+		switch {
+		case taint.Effect == corev1.TaintEffectPreferNoSchedule:
+			if toleration == nil {
+				result.Notes = append(result.Notes, fmt.Sprintf("untolerated taint %s lowers the node's score", taint.ToString()))
+			}
+		case toleration == nil:
+			result.Passed = false
+			result.Reasons = append(result.Reasons, fmt.Sprintf("untolerated taint %s", taint.ToString()))
+		case taint.Effect == corev1.TaintEffectNoExecute && toleration.TolerationSeconds != nil:
+			// Scheduling is allowed, the pod is evicted once the toleration
+			// expires
+			result.Notes = append(result.Notes, fmt.Sprintf("taint %s is tolerated for %ds, the pod would be evicted afterwards",
+				taint.ToString(), *toleration.TolerationSeconds))
+		}
+	}
+	return result
+}
+
+// hostPortKey identifies a host port the way the scheduler compares them
+type hostPortKey struct {
+	IP       string
+	Protocol corev1.Protocol
+	Port     int32
+}
+
+func podHostPorts(pod *corev1.Pod) []hostPortKey {
+	var ports []hostPortKey
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			if port.HostPort <= 0 {
+				continue
+			}
+			key := hostPortKey{IP: port.HostIP, Protocol: port.Protocol, Port: port.HostPort}
+			if key.IP == "" {
+				key.IP = "0.0.0.0"
+			}
+			if key.Protocol == "" {
+				key.Protocol = corev1.ProtocolTCP
+			}
+			ports = append(ports, key)
+		}
+	}
+	return ports
+}
+
+// checkNodePorts fails when a host port of the pod is already used by a pod
+// on the node. 0.0.0.0 conflicts with every IP.
+func checkNodePorts(pod *corev1.Pod, nodePods []corev1.Pod) PredicateResult {
+	result := PredicateResult{Name: PredicateNodePorts, Passed: true, Reasons: []string{}}
+	wanted := podHostPorts(pod)
+	if len(wanted) == 0 {
+		return result
+	}
+	for i := range nodePods {
+		existing := &nodePods[i]
+		if utils.IsPodErrorOrSuccess(existing) {
+			continue
+		}
+		for _, used := range podHostPorts(existing) {
+			for _, want := range wanted {
+				if want.Port != used.Port || want.Protocol != used.Protocol {
+					continue
+				}
+				if want.IP == used.IP || want.IP == "0.0.0.0" || used.IP == "0.0.0.0" {
+					result.Passed = false
+					result.Reasons = append(result.Reasons, fmt.Sprintf("host port %s/%d is used by pod %s/%s",
+						want.Protocol, want.Port, existing.Namespace, existing.Name))
+				}
+			}
+		}
+	}
+	return result
+}
+
+// evaluateSchedulingPredicates runs all scheduling predicates for a pod
+// against a node and the pods already on it, and reports whether they all
+// passed
+func evaluateSchedulingPredicates(pod *corev1.Pod, node *corev1.Node, nodePods []corev1.Pod) ([]PredicateResult, bool) {
+	predicates := []PredicateResult{
+		checkNodeUnschedulable(pod, node),
+		checkNodeResourcesFit(pod, node, nodePods),
+		checkNodeAffinity(pod, node),
+		checkTaintToleration(pod, node),
+		checkNodePorts(pod, nodePods),
+	}
+	schedulable := true
+	for _, predicate := range predicates {
+		schedulable = schedulable && predicate.Passed
+	}
+	return predicates, schedulable
+}
+
+// CanScheduleOnNode evaluates the basic kube-scheduler filters for a pod
+// spec, or the pod template of a deployment, against a node. It doesn't
+// consider inter-pod affinity, topology spread or volume constraints.
+func (h *NodeHandler) CanScheduleOnNode(c *gin.Context) {
+	nodeName := c.Param("name")
+	ctx := c.Request.Context()
+
+	var req CanScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if (req.PodSpec == nil) == (req.Deployment == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "exactly one of podSpec or deployment is required"})
+		return
+	}
+
+	pod := &corev1.Pod{}
+	if req.PodSpec != nil {
+		pod.Spec = *req.PodSpec
+	} else {
+		var deployment appsv1.Deployment
+		if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Namespace: req.Deployment.Namespace, Name: req.Deployment.Name}, &deployment); err != nil {
+			if errors.IsNotFound(err) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Deployment not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		pod.ObjectMeta = deployment.Spec.Template.ObjectMeta
+		pod.Namespace = deployment.Namespace
+		pod.Spec = deployment.Spec.Template.Spec
+	}
+
+	var node corev1.Node
+	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Name: nodeName}, &node); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	nodePods, err := h.listNodePods(ctx, nodeName, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list pods: " + err.Error()})
+		return
+	}
+
+	predicates, schedulable := evaluateSchedulingPredicates(pod, &node, nodePods)
+
+	c.JSON(http.StatusOK, gin.H{
+		"node":        nodeName,
+		"schedulable": schedulable,
+		"predicates":  predicates,
+	})
+}
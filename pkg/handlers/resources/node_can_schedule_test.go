@@ -0,0 +1,270 @@
+package resources
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func scheduleTestNode() *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "worker-1",
+			Labels: map[string]string{
+				"kubernetes.io/os":  "linux",
+				"zone":              "a",
+				"example.com/cores": "16",
+			},
+		},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("2"),
+				corev1.ResourceMemory: resource.MustParse("4Gi"),
+				corev1.ResourcePods:   resource.MustParse("10"),
+			},
+		},
+	}
+}
+
+func requiredAffinity(terms ...corev1.NodeSelectorTerm) *corev1.Affinity {
+	return &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: terms},
+	}}
+}
+
+func expression(key string, operator corev1.NodeSelectorOperator, values ...string) corev1.NodeSelectorTerm {
+	return corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: key, Operator: operator, Values: values}}}
+}
+
+func TestCheckNodeAffinity(t *testing.T) {
+	tests := []struct {
+		name         string
+		nodeSelector map[string]string
+		affinity     *corev1.Affinity
+		passed       bool
+		reason       string
+	}{
+		{name: "no constraints", passed: true},
+		{name: "nodeSelector matches", nodeSelector: map[string]string{"kubernetes.io/os": "linux"}, passed: true},
+		{name: "nodeSelector value differs", nodeSelector: map[string]string{"kubernetes.io/os": "windows"}, reason: "nodeSelector kubernetes.io/os=windows"},
+		{name: "nodeSelector label missing", nodeSelector: map[string]string{"gpu": "true"}, reason: "nodeSelector gpu=true"},
+		{name: "In matches", affinity: requiredAffinity(expression("zone", corev1.NodeSelectorOpIn, "a", "b")), passed: true},
+		{name: "In does not match", affinity: requiredAffinity(expression("zone", corev1.NodeSelectorOpIn, "b")), reason: "required node affinity"},
+		{name: "In on a missing label", affinity: requiredAffinity(expression("rack", corev1.NodeSelectorOpIn, "a"))},
+		{name: "NotIn matches", affinity: requiredAffinity(expression("zone", corev1.NodeSelectorOpNotIn, "b")), passed: true},
+		{name: "NotIn does not match", affinity: requiredAffinity(expression("zone", corev1.NodeSelectorOpNotIn, "a"))},
+		{name: "NotIn on a missing label", affinity: requiredAffinity(expression("rack", corev1.NodeSelectorOpNotIn, "a")), passed: true},
+		{name: "Exists matches", affinity: requiredAffinity(expression("zone", corev1.NodeSelectorOpExists)), passed: true},
+		{name: "Exists on a missing label", affinity: requiredAffinity(expression("gpu", corev1.NodeSelectorOpExists))},
+		{name: "DoesNotExist", affinity: requiredAffinity(expression("gpu", corev1.NodeSelectorOpDoesNotExist)), passed: true},
+		{name: "Gt matches", affinity: requiredAffinity(expression("example.com/cores", corev1.NodeSelectorOpGt, "8")), passed: true},
+		{name: "Gt does not match", affinity: requiredAffinity(expression("example.com/cores", corev1.NodeSelectorOpGt, "16"))},
+		{name: "Gt on a non-integer label", affinity: requiredAffinity(expression("zone", corev1.NodeSelectorOpGt, "1"))},
+		{name: "Lt matches", affinity: requiredAffinity(expression("example.com/cores", corev1.NodeSelectorOpLt, "32")), passed: true},
+		{
+			name: "expressions of a term are ANDed",
+			affinity: requiredAffinity(corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{
+				{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a"}},
+				{Key: "gpu", Operator: corev1.NodeSelectorOpExists},
+			}}),
+		},
+		{
+			name:     "terms are ORed",
+			affinity: requiredAffinity(expression("zone", corev1.NodeSelectorOpIn, "b"), expression("zone", corev1.NodeSelectorOpIn, "a")),
+			passed:   true,
+		},
+		{name: "empty term matches nothing", affinity: requiredAffinity(corev1.NodeSelectorTerm{})},
+		{
+			name: "matchFields on the node name",
+			affinity: requiredAffinity(corev1.NodeSelectorTerm{MatchFields: []corev1.NodeSelectorRequirement{
+				{Key: "metadata.name", Operator: corev1.NodeSelectorOpIn, Values: []string{"worker-1"}},
+			}}),
+			passed: true,
+		},
+		{
+			name: "matchFields on another node",
+			affinity: requiredAffinity(corev1.NodeSelectorTerm{MatchFields: []corev1.NodeSelectorRequirement{
+				{Key: "metadata.name", Operator: corev1.NodeSelectorOpIn, Values: []string{"worker-2"}},
+			}}),
+		},
+		{
+			name: "matchFields on an unsupported field",
+			affinity: requiredAffinity(corev1.NodeSelectorTerm{MatchFields: []corev1.NodeSelectorRequirement{
+				{Key: "spec.podCIDR", Operator: corev1.NodeSelectorOpExists},
+			}}),
+		},
+		{
+			name:         "nodeSelector and affinity must both match",
+			nodeSelector: map[string]string{"kubernetes.io/os": "windows"},
+			affinity:     requiredAffinity(expression("zone", corev1.NodeSelectorOpIn, "a")),
+			reason:       "nodeSelector",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{Spec: corev1.PodSpec{NodeSelector: tt.nodeSelector, Affinity: tt.affinity}}
+			result := checkNodeAffinity(pod, scheduleTestNode())
+			if result.Passed != tt.passed {
+				t.Fatalf("passed = %v, want %v (reasons %v)", result.Passed, tt.passed, result.Reasons)
+			}
+			if !tt.passed && !strings.Contains(strings.Join(result.Reasons, "; "), tt.reason) {
+				t.Errorf("reasons = %v, want one containing %q", result.Reasons, tt.reason)
+			}
+		})
+	}
+}
+
+func TestCheckNodeAffinityPreferredTerms(t *testing.T) {
+	pod := &corev1.Pod{Spec: corev1.PodSpec{Affinity: &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+		PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{
+			{Weight: 10, Preference: expression("zone", corev1.NodeSelectorOpIn, "b")},
+		},
+	}}}}
+	result := checkNodeAffinity(pod, scheduleTestNode())
+	if !result.Passed || len(result.Notes) != 1 || !strings.Contains(result.Notes[0], "does not match") {
+		t.Errorf("checkNodeAffinity() = %+v, want passed with a note on the preferred term", result)
+	}
+}
+
+func TestCheckTaintToleration(t *testing.T) {
+	dedicated := func(effect corev1.TaintEffect) corev1.Taint {
+		return corev1.Taint{Key: "dedicated", Value: "db", Effect: effect}
+	}
+
+	tests := []struct {
+		name        string
+		taints      []corev1.Taint
+		tolerations []corev1.Toleration
+		passed      bool
+		reason      string
+		note        string
+	}{
+		{name: "no taints", passed: true},
+		{name: "untolerated NoSchedule", taints: []corev1.Taint{dedicated(corev1.TaintEffectNoSchedule)}, reason: "dedicated=db:NoSchedule"},
+		{name: "untolerated NoExecute", taints: []corev1.Taint{dedicated(corev1.TaintEffectNoExecute)}, reason: "dedicated=db:NoExecute"},
+		{name: "untolerated PreferNoSchedule only lowers the score", taints: []corev1.Taint{dedicated(corev1.TaintEffectPreferNoSchedule)}, passed: true, note: "lowers the node's score"},
+		{
+			name:        "Equal toleration",
+			taints:      []corev1.Taint{dedicated(corev1.TaintEffectNoSchedule)},
+			tolerations: []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "db", Effect: corev1.TaintEffectNoSchedule}},
+			passed:      true,
+		},
+		{
+			name:        "toleration for another effect",
+			taints:      []corev1.Taint{dedicated(corev1.TaintEffectNoExecute)},
+			tolerations: []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "db", Effect: corev1.TaintEffectNoSchedule}},
+			reason:      "dedicated=db:NoExecute",
+		},
+		{
+			name:        "toleration without an effect tolerates every effect",
+			taints:      []corev1.Taint{dedicated(corev1.TaintEffectNoSchedule), dedicated(corev1.TaintEffectNoExecute)},
+			tolerations: []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}},
+			passed:      true,
+		},
+		{
+			name:        "Exists with an empty key tolerates everything",
+			taints:      []corev1.Taint{dedicated(corev1.TaintEffectNoSchedule), {Key: "gpu", Effect: corev1.TaintEffectNoExecute}},
+			tolerations: []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+			passed:      true,
+		},
+		{
+			name:        "Exists with an empty key and an effect",
+			taints:      []corev1.Taint{dedicated(corev1.TaintEffectNoSchedule), {Key: "gpu", Effect: corev1.TaintEffectNoExecute}},
+			tolerations: []corev1.Toleration{{Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}},
+			reason:      "gpu:NoExecute",
+		},
+		{
+			name:        "NoExecute tolerated for a while",
+			taints:      []corev1.Taint{dedicated(corev1.TaintEffectNoExecute)},
+			tolerations: []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists, TolerationSeconds: ptr.To[int64](30)}},
+			passed:      true,
+			note:        "tolerated for 30s",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := scheduleTestNode()
+			node.Spec.Taints = tt.taints
+			pod := &corev1.Pod{Spec: corev1.PodSpec{Tolerations: tt.tolerations}}
+			result := checkTaintToleration(pod, node)
+			if result.Passed != tt.passed {
+				t.Fatalf("passed = %v, want %v (reasons %v)", result.Passed, tt.passed, result.Reasons)
+			}
+			if !tt.passed && !strings.Contains(strings.Join(result.Reasons, "; "), tt.reason) {
+				t.Errorf("reasons = %v, want one containing %q", result.Reasons, tt.reason)
+			}
+			if tt.note != "" && !strings.Contains(strings.Join(result.Notes, "; "), tt.note) {
+				t.Errorf("notes = %v, want one containing %q", result.Notes, tt.note)
+			}
+		})
+	}
+}
+
+func TestCheckNodeUnschedulable(t *testing.T) {
+	node := scheduleTestNode()
+	node.Spec.Unschedulable = true
+
+	if result := checkNodeUnschedulable(&corev1.Pod{}, node); result.Passed {
+		t.Error("cordoned node passed")
+	}
+	pod := &corev1.Pod{Spec: corev1.PodSpec{Tolerations: []corev1.Toleration{
+		{Key: corev1.TaintNodeUnschedulable, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+	}}}
+	if result := checkNodeUnschedulable(pod, node); !result.Passed {
+		t.Errorf("cordoned node failed for a pod tolerating it: %v", result.Reasons)
+	}
+}
+
+func TestEvaluateSchedulingPredicates(t *testing.T) {
+	container := func(cpu string, hostPort int32) corev1.Container {
+		c := corev1.Container{
+			Name:      "app",
+			Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)}},
+		}
+		if hostPort > 0 {
+			c.Ports = []corev1.ContainerPort{{ContainerPort: 8080, HostPort: hostPort}}
+		}
+		return c
+	}
+	running := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "default"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{container("1500m", 8080)}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	succeeded := running
+	succeeded.Status.Phase = corev1.PodSucceeded
+
+	tests := []struct {
+		name     string
+		pod      corev1.Container
+		nodePods []corev1.Pod
+		failed   []string
+	}{
+		{name: "fits", pod: container("500m", 0), nodePods: []corev1.Pod{running}},
+		{name: "insufficient cpu", pod: container("1", 0), nodePods: []corev1.Pod{running}, failed: []string{PredicateNodeResourcesFit}},
+		{name: "host port in use", pod: container("100m", 8080), nodePods: []corev1.Pod{running}, failed: []string{PredicateNodePorts}},
+		{name: "finished pods are not counted", pod: container("1", 8080), nodePods: []corev1.Pod{succeeded}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{tt.pod}}}
+			predicates, schedulable := evaluateSchedulingPredicates(pod, scheduleTestNode(), tt.nodePods)
+			var failed []string
+			for _, predicate := range predicates {
+				if !predicate.Passed {
+					failed = append(failed, predicate.Name)
+				}
+			}
+			if strings.Join(failed, ",") != strings.Join(tt.failed, ",") {
+				t.Errorf("failed predicates = %v, want %v", failed, tt.failed)
+			}
+			if schedulable != (len(tt.failed) == 0) {
+				t.Errorf("schedulable = %v with failed predicates %v", schedulable, failed)
+			}
+		})
+	}
+}
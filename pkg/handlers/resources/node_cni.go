@@ -0,0 +1,92 @@
+package resources
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// cniConfigDir is the CNI configuration directory read from nodes
+const cniConfigDir = "/etc/cni/net.d"
+
+// Markers written by cniConfigScript to separate the sections of its output
+const (
+	cniMarkerNoDir   = "===KITE:NO-DIR==="
+	cniMarkerListing = "===KITE:LISTING==="
+	cniMarkerFile    = "===KITE:FILE "
+	cniMarkerEnd     = "==="
+)
+
+// cniConfigScript prints the listing of the CNI directory followed by every
+// file in it, or only cniMarkerNoDir when the directory doesn't exist
+const cniConfigScript = `dir=/host` + cniConfigDir + `
+if [ ! -d "$dir" ]; then echo '` + cniMarkerNoDir + `'; exit 0; fi
+echo '` + cniMarkerListing + `'
+ls -la "$dir"
+for f in "$dir"/*; do
+  [ -f "$f" ] || continue
+  echo "` + cniMarkerFile + `$(basename "$f")` + cniMarkerEnd + `"
+  cat "$f"
+  echo
+done`
+
+// CNIConfigFile is a file from the CNI configuration directory. Config holds
+// the parsed content of .conf, .conflist and .json files that are valid JSON.
+type CNIConfigFile struct {
+	Name    string          `json:"name"`
+	Content string          `json:"content"`
+	Config  json.RawMessage `json:"config,omitempty"`
+}
+
+// cniConfig is the parsed output of cniConfigScript
+type cniConfig struct {
+	Exists  bool
+	Listing string
+	Files   []CNIConfigFile
+}
+
+// parseCNIConfigOutput splits the output of cniConfigScript into the
+// directory listing and the individual files
+func parseCNIConfigOutput(output string) cniConfig {
+	if strings.TrimSpace(output) == cniMarkerNoDir {
+		return cniConfig{Files: []CNIConfigFile{}}
+	}
+
+	result := cniConfig{Exists: true, Files: []CNIConfigFile{}}
+	var section []string
+	var current *CNIConfigFile
+	flush := func() {
+		text := strings.Join(section, "\n")
+		section = nil
+		if current == nil {
+			result.Listing = strings.TrimSpace(text)
+			return
+		}
+		current.Content = strings.TrimRight(text, "\n")
+		if isCNIConfigFile(current.Name) && json.Valid([]byte(current.Content)) {
+			current.Config = json.RawMessage(current.Content)
+		}
+		result.Files = append(result.Files, *current)
+	}
+
+	inListing := false
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case line == cniMarkerListing:
+			inListing = true
+		case strings.HasPrefix(line, cniMarkerFile) && strings.HasSuffix(line, cniMarkerEnd):
+			flush()
+			name := strings.TrimSuffix(strings.TrimPrefix(line, cniMarkerFile), cniMarkerEnd)
+			current = &CNIConfigFile{Name: name}
+		case inListing:
+			section = append(section, line)
+		}
+	}
+	if inListing {
+		flush()
+	}
+	return result
+}
+
+func isCNIConfigFile(name string) bool {
+	return strings.HasSuffix(name, ".conf") || strings.HasSuffix(name, ".conflist") || strings.HasSuffix(name, ".json")
+}
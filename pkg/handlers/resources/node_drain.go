@@ -0,0 +1,429 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// defaultDrainTimeout bounds how long evictions blocked by a
+	// PodDisruptionBudget are retried
+	defaultDrainTimeout = 10 * time.Minute
+	// drainInitialBackoff and drainMaxBackoff bound the wait between
+	// eviction retries
+	drainInitialBackoff = time.Second
+	drainMaxBackoff     = 10 * time.Second
+	// drainJobTTL is how long finished drain jobs are kept for polling
+	drainJobTTL = 30 * time.Minute
+)
+
+// Drain job phases
+const (
+	DrainPhaseCordoning = "cordoning"
+	DrainPhaseEvicting  = "evicting"
+	DrainPhaseDone      = "done"
+	DrainPhaseFailed    = "failed"
+	DrainPhaseCancelled = "cancelled"
+)
+
+// Drain results reported for each pod on the node
+const (
+	DrainPodEvicted = "evicted"
+	DrainPodSkipped = "skipped"
+	DrainPodFailed  = "failed"
+)
+
+// drainOptions controls which pods are evicted from a node. A zero
+// GracePeriod keeps the pods' own termination grace period.
+type drainOptions struct {
+	Force            bool
+	GracePeriod      int
+	DeleteLocalData  bool
+	IgnoreDaemonsets bool
+	Timeout          time.Duration
+}
+
+// DrainRequest holds the drain options of a request body. Bools are optional
+// and default to false: gin's required validator rejects a false value.
+// A gracePeriod of 0 keeps each pod's terminationGracePeriodSeconds.
+type DrainRequest struct {
+	Force            *bool `json:"force"`
+	GracePeriod      int   `json:"gracePeriod" binding:"min=0"`
+	DeleteLocal      *bool `json:"deleteLocalData"`
+	IgnoreDaemonsets *bool `json:"ignoreDaemonsets"`
+	Timeout          int   `json:"timeout" binding:"min=0"`
+}
+
+func (r DrainRequest) options() drainOptions {
+	return drainOptions{
+		Force:            boolValue(r.Force),
+		GracePeriod:      r.GracePeriod,
+		DeleteLocalData:  boolValue(r.DeleteLocal),
+		IgnoreDaemonsets: boolValue(r.IgnoreDaemonsets),
+		Timeout:          time.Duration(r.Timeout) * time.Second,
+	}
+}
+
+// DrainPodResult is the outcome of draining a single pod
+type DrainPodResult struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// drainJob tracks a drain running in the background
+type drainJob struct {
+	ID         string
+	Node       string
+	opts       drainOptions
+	cancel     context.CancelFunc
+	StartedAt  time.Time
+	FinishedAt *time.Time
+
+	mu      sync.Mutex
+	phase   string
+	err     string
+	results []DrainPodResult
+	blocked map[string]bool
+}
+
+// DrainStatus is a snapshot of a drain job
+type DrainStatus struct {
+	ID          string           `json:"id"`
+	Node        string           `json:"node"`
+	Phase       string           `json:"phase"`
+	Error       string           `json:"error,omitempty"`
+	Evicted     int              `json:"evicted"`
+	Pending     int              `json:"pending"`
+	Blocked     int              `json:"blocked"`
+	Skipped     int              `json:"skipped"`
+	Failed      int              `json:"failed"`
+	BlockedPods []string         `json:"blockedPods"`
+	Pods        []DrainPodResult `json:"pods"`
+	StartedAt   time.Time        `json:"startedAt"`
+	FinishedAt  *time.Time       `json:"finishedAt,omitempty"`
+}
+
+func (j *drainJob) setPhase(phase, err string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.phase = phase
+	j.err = err
+	if phase == DrainPhaseDone || phase == DrainPhaseFailed || phase == DrainPhaseCancelled {
+		now := time.Now()
+		j.FinishedAt = &now
+	}
+}
+
+func (j *drainJob) setResult(i int, status, reason string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.results[i].Status = status
+	j.results[i].Reason = reason
+	delete(j.blocked, j.results[i].Namespace+"/"+j.results[i].Name)
+}
+
+func (j *drainJob) setBlocked(pod *corev1.Pod) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.blocked[pod.Namespace+"/"+pod.Name] = true
+}
+
+func (j *drainJob) finished() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.FinishedAt != nil
+}
+
+// Status returns a consistent snapshot of the job
+func (j *drainJob) Status() DrainStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	status := DrainStatus{
+		ID:          j.ID,
+		Node:        j.Node,
+		Phase:       j.phase,
+		Error:       j.err,
+		Blocked:     len(j.blocked),
+		BlockedPods: make([]string, 0, len(j.blocked)),
+		Pods:        append([]DrainPodResult{}, j.results...),
+		StartedAt:   j.StartedAt,
+		FinishedAt:  j.FinishedAt,
+	}
+	for pod := range j.blocked {
+		status.BlockedPods = append(status.BlockedPods, pod)
+	}
+	sort.Strings(status.BlockedPods)
+	for _, result := range j.results {
+		switch result.Status {
+		case DrainPodEvicted:
+			status.Evicted++
+		case DrainPodSkipped:
+			status.Skipped++
+		case DrainPodFailed:
+			status.Failed++
+		default:
+			status.Pending++
+		}
+	}
+	return status
+}
+
+// drainJobStore keeps the latest drain job of every node and the batch
+// drains in memory. Finished jobs are dropped after drainJobTTL.
+type drainJobStore struct {
+	mu      sync.Mutex
+	jobs    map[string]*drainJob
+	batches map[string]*drainBatch
+}
+
+func newDrainJobStore() *drainJobStore {
+	return &drainJobStore{
+		jobs:    make(map[string]*drainJob),
+		batches: make(map[string]*drainBatch),
+	}
+}
+
+// start registers a new job for the node unless one is still running. The
+// job's context derives from parent, not from the request that started it.
+func (s *drainJobStore) start(parent context.Context, nodeName string, opts drainOptions) (*drainJob, context.Context, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireLocked()
+
+	if existing, ok := s.jobs[nodeName]; ok && !existing.finished() {
+		return nil, nil, fmt.Errorf("drain %s is already running on node %s", existing.ID, nodeName)
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	job := &drainJob{
+		ID:        fmt.Sprintf("drain-%s-%d", nodeName, time.Now().Unix()),
+		Node:      nodeName,
+		opts:      opts,
+		cancel:    cancel,
+		StartedAt: time.Now(),
+		phase:     DrainPhaseCordoning,
+		blocked:   make(map[string]bool),
+	}
+	s.jobs[nodeName] = job
+	return job, ctx, nil
+}
+
+func (s *drainJobStore) get(nodeName string) (*drainJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireLocked()
+	job, ok := s.jobs[nodeName]
+	return job, ok
+}
+
+func (s *drainJobStore) addBatch(batch *drainBatch) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireLocked()
+	s.batches[batch.ID] = batch
+}
+
+func (s *drainJobStore) getBatch(id string) (*drainBatch, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireLocked()
+	batch, ok := s.batches[id]
+	return batch, ok
+}
+
+func (s *drainJobStore) expireLocked() {
+	for nodeName, job := range s.jobs {
+		job.mu.Lock()
+		expired := job.FinishedAt != nil && time.Since(*job.FinishedAt) > drainJobTTL
+		job.mu.Unlock()
+		if expired {
+			delete(s.jobs, nodeName)
+		}
+	}
+	for id, batch := range s.batches {
+		batch.mu.Lock()
+		expired := batch.finishedAt != nil && time.Since(*batch.finishedAt) > drainJobTTL
+		batch.mu.Unlock()
+		if expired {
+			delete(s.batches, id)
+		}
+	}
+}
+
+// runDrain cordons the node and evicts its pods through the Eviction API,
+// recording progress on the job. Mirror pods are always skipped, DaemonSet
+// pods are skipped when IgnoreDaemonsets is set, pods with emptyDir volumes
+// need DeleteLocalData and pods without a controller need Force. Evictions
+// blocked by a PodDisruptionBudget are retried with backoff until Timeout.
+func (h *NodeHandler) runDrain(ctx context.Context, job *drainJob) {
+	defer job.cancel()
+
+	if err := h.markNodeSchedulable(ctx, job.Node, false); err != nil {
+		job.setPhase(DrainPhaseFailed, "failed to cordon node: "+err.Error())
+		return
+	}
+
+	pods, err := h.listNodePods(ctx, job.Node, "")
+	if err != nil {
+		job.setPhase(DrainPhaseFailed, "failed to list pods on node: "+err.Error())
+		return
+	}
+
+	job.mu.Lock()
+	job.results = make([]DrainPodResult, len(pods))
+	for i := range pods {
+		job.results[i] = DrainPodResult{Name: pods[i].Name, Namespace: pods[i].Namespace}
+	}
+	job.mu.Unlock()
+
+	var toEvict []int
+	for i := range pods {
+		if status, reason := drainPodFilter(&pods[i], job.opts); status != "" {
+			job.setResult(i, status, reason)
+			continue
+		}
+		toEvict = append(toEvict, i)
+	}
+	job.setPhase(DrainPhaseEvicting, "")
+
+	timeout := job.opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultDrainTimeout
+	}
+	evictCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, i := range toEvict {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			pod := &pods[i]
+			if err := h.evictPod(evictCtx, job, pod); err != nil {
+				job.setResult(i, DrainPodFailed, err.Error())
+				return
+			}
+			job.setResult(i, DrainPodEvicted, "")
+		}(i)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		job.setPhase(DrainPhaseCancelled, "drain was cancelled")
+		return
+	}
+	job.setPhase(DrainPhaseDone, "")
+	klog.Infof("Drain %s of node %s finished", job.ID, job.Node)
+}
+
+// drainPodInfo describes the properties of a pod that decide how a drain
+// treats it. The drain and its preview share it so that they can't diverge.
+type drainPodInfo struct {
+	Mirror    bool
+	DaemonSet bool
+	// EmptyDirVolume is the first emptyDir volume of the pod, if any
+	EmptyDirVolume string
+	Unmanaged      bool
+}
+
+func classifyDrainPod(pod *corev1.Pod) drainPodInfo {
+	var info drainPodInfo
+	if _, ok := pod.Annotations[corev1.MirrorPodAnnotationKey]; ok {
+		info.Mirror = true
+	}
+	controller := metav1.GetControllerOf(pod)
+	info.DaemonSet = controller != nil && controller.Kind == "DaemonSet"
+	info.Unmanaged = controller == nil
+	for _, volume := range pod.Spec.Volumes {
+		if volume.EmptyDir != nil {
+			info.EmptyDirVolume = volume.Name
+			break
+		}
+	}
+	return info
+}
+
+// decision returns the status a drain with opts reports for the pod without
+// evicting it, or an empty status for pods that should be evicted
+func (info drainPodInfo) decision(opts drainOptions) (string, string) {
+	if info.Mirror {
+		return DrainPodSkipped, "mirror pod"
+	}
+	if info.DaemonSet {
+		if opts.IgnoreDaemonsets {
+			return DrainPodSkipped, "managed by DaemonSet"
+		}
+		return DrainPodFailed, "managed by DaemonSet, set ignoreDaemonsets to skip it"
+	}
+	if info.EmptyDirVolume != "" && !opts.DeleteLocalData {
+		return DrainPodFailed, fmt.Sprintf("uses emptyDir volume %s, set deleteLocalData to evict it", info.EmptyDirVolume)
+	}
+	if info.Unmanaged && !opts.Force {
+		return DrainPodFailed, "not managed by a controller, set force to evict it"
+	}
+	return "", ""
+}
+
+// drainPodFilter decides up front whether a pod is left alone. It returns an
+// empty status for pods that should be evicted.
+func drainPodFilter(pod *corev1.Pod, opts drainOptions) (string, string) {
+	return classifyDrainPod(pod).decision(opts)
+}
+
+// evictPod evicts a pod, retrying with backoff while a PodDisruptionBudget
+// blocks the eviction and ctx is not done
+func (h *NodeHandler) evictPod(ctx context.Context, job *drainJob, pod *corev1.Pod) error {
+	eviction := &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+		},
+		DeleteOptions: &metav1.DeleteOptions{},
+	}
+	// Without an explicit grace period the pod's own
+	// terminationGracePeriodSeconds applies
+	if job.opts.GracePeriod > 0 {
+		gracePeriodSeconds := int64(job.opts.GracePeriod)
+		eviction.DeleteOptions.GracePeriodSeconds = &gracePeriodSeconds
+	}
+
+	backoff := drainInitialBackoff
+	for {
+		err := h.K8sClient.ClientSet.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction)
+		if err == nil || errors.IsNotFound(err) {
+			return nil
+		}
+		if !errors.IsTooManyRequests(err) {
+			return err
+		}
+
+		job.setBlocked(pod)
+		klog.V(2).Infof("Eviction of pod %s/%s blocked by PodDisruptionBudget, retrying in %s", pod.Namespace, pod.Name, backoff)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("blocked by PodDisruptionBudget: %s", err.Error())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > drainMaxBackoff {
+			backoff = drainMaxBackoff
+		}
+	}
+}
+
+// boolValue dereferences an optional bool from a request body, nil is false
+func boolValue(b *bool) bool {
+	return b != nil && *b
+}
@@ -0,0 +1,165 @@
+package resources
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Actions a drain would take for a pod
+const (
+	DrainActionEvict   = "evict"
+	DrainActionSkip    = "skip"
+	DrainActionBlocked = "blocked"
+)
+
+// Categories of pods that a drain treats specially
+const (
+	DrainCategoryDaemonSet  = "daemonset"
+	DrainCategoryMirror     = "mirror"
+	DrainCategoryLocalData  = "localData"
+	DrainCategoryUnmanaged  = "unmanaged"
+	DrainCategoryPDBBlocked = "pdbBlocked"
+)
+
+// DrainPreviewPod is what a drain would do with a pod
+type DrainPreviewPod struct {
+	Name       string   `json:"name"`
+	Namespace  string   `json:"namespace"`
+	Action     string   `json:"action"`
+	Reason     string   `json:"reason,omitempty"`
+	Categories []string `json:"categories"`
+	PDB        string   `json:"pdb,omitempty"`
+}
+
+// blockingPDB returns the name of a PodDisruptionBudget selecting the pod
+// that currently allows no disruptions
+func blockingPDB(pod *corev1.Pod, pdbs []policyv1.PodDisruptionBudget) string {
+	for i := range pdbs {
+		pdb := &pdbs[i]
+		if pdb.Namespace != pod.Namespace || pdb.Spec.Selector == nil || pdb.Status.DisruptionsAllowed > 0 {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			continue
+		}
+		if selector.Matches(labels.Set(pod.Labels)) {
+			return pdb.Name
+		}
+	}
+	return ""
+}
+
+// previewDrainPod classifies a pod the same way runDrain does and adds
+// whether a PodDisruptionBudget would block its eviction
+func previewDrainPod(pod *corev1.Pod, opts drainOptions, pdbs []policyv1.PodDisruptionBudget) DrainPreviewPod {
+	info := classifyDrainPod(pod)
+	preview := DrainPreviewPod{
+		Name:       pod.Name,
+		Namespace:  pod.Namespace,
+		Categories: []string{},
+	}
+	if info.DaemonSet {
+		preview.Categories = append(preview.Categories, DrainCategoryDaemonSet)
+	}
+	if info.Mirror {
+		preview.Categories = append(preview.Categories, DrainCategoryMirror)
+	}
+	if info.EmptyDirVolume != "" {
+		preview.Categories = append(preview.Categories, DrainCategoryLocalData)
+	}
+	if info.Unmanaged {
+		preview.Categories = append(preview.Categories, DrainCategoryUnmanaged)
+	}
+
+	status, reason := info.decision(opts)
+	switch status {
+	case DrainPodSkipped:
+		preview.Action = DrainActionSkip
+		preview.Reason = reason
+		return preview
+	case DrainPodFailed:
+		preview.Action = DrainActionBlocked
+		preview.Reason = reason
+	default:
+		preview.Action = DrainActionEvict
+	}
+
+	if pdb := blockingPDB(pod, pdbs); pdb != "" {
+		preview.Categories = append(preview.Categories, DrainCategoryPDBBlocked)
+		preview.PDB = pdb
+		if preview.Action == DrainActionEvict {
+			preview.Action = DrainActionBlocked
+			preview.Reason = "PodDisruptionBudget " + pdb + " allows no disruptions"
+		}
+	}
+	return preview
+}
+
+// listPodDisruptionBudgets lists the PodDisruptionBudgets of all namespaces
+func (h *NodeHandler) listPodDisruptionBudgets(ctx context.Context) ([]policyv1.PodDisruptionBudget, error) {
+	var pdbList policyv1.PodDisruptionBudgetList
+	if err := h.K8sClient.Client.List(ctx, &pdbList); err != nil {
+		return nil, err
+	}
+	return pdbList.Items, nil
+}
+
+// PreviewDrain reports what draining a node would do with each of its pods
+// without changing anything. It takes the drain options as query
+// parameters: force, deleteLocalData and ignoreDaemonsets.
+func (h *NodeHandler) PreviewDrain(c *gin.Context) {
+	nodeName := c.Param("name")
+	ctx := c.Request.Context()
+
+	opts := drainOptions{
+		Force:            c.Query("force") == "true",
+		DeleteLocalData:  c.Query("deleteLocalData") == "true",
+		IgnoreDaemonsets: c.Query("ignoreDaemonsets") == "true",
+	}
+
+	var node corev1.Node
+	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Name: nodeName}, &node); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	pods, err := h.listNodePods(ctx, nodeName, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list pods: " + err.Error()})
+		return
+	}
+	pdbs, err := h.listPodDisruptionBudgets(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list PodDisruptionBudgets: " + err.Error()})
+		return
+	}
+
+	previews := make([]DrainPreviewPod, 0, len(pods))
+	counts := map[string]int{DrainActionEvict: 0, DrainActionSkip: 0, DrainActionBlocked: 0}
+	for i := range pods {
+		preview := previewDrainPod(&pods[i], opts, pdbs)
+		counts[preview.Action]++
+		previews = append(previews, preview)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"node":          nodeName,
+		"unschedulable": node.Spec.Unschedulable,
+		"canDrain":      counts[DrainActionBlocked] == 0,
+		"summary":       counts,
+		"pods":          previews,
+	})
+}
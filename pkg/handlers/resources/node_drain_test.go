@@ -0,0 +1,182 @@
+package resources
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zxh326/kite/pkg/kube"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+)
+
+func drainTestPod(controllerKind string, volumes ...corev1.Volume) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-0", Namespace: "default"},
+		Spec:       corev1.PodSpec{Volumes: volumes},
+	}
+	if controllerKind != "" {
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: controllerKind, Name: "web", Controller: ptr.To(true)}}
+	}
+	return pod
+}
+
+func TestDrainPodFilter(t *testing.T) {
+	emptyDir := corev1.Volume{Name: "cache", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}
+	mirror := drainTestPod("")
+	mirror.Annotations = map[string]string{corev1.MirrorPodAnnotationKey: "hash"}
+
+	tests := []struct {
+		name   string
+		pod    *corev1.Pod
+		opts   drainOptions
+		status string
+		reason string
+	}{
+		{name: "managed pod is evicted", pod: drainTestPod("ReplicaSet")},
+		{name: "unmanaged pod without force", pod: drainTestPod(""), status: DrainPodFailed, reason: "set force"},
+		{name: "unmanaged pod with force", pod: drainTestPod(""), opts: drainOptions{Force: true}},
+		{name: "local storage without deleteLocalData", pod: drainTestPod("ReplicaSet", emptyDir), status: DrainPodFailed, reason: "emptyDir volume cache"},
+		{name: "local storage is refused before force applies", pod: drainTestPod("", emptyDir), opts: drainOptions{Force: true}, status: DrainPodFailed, reason: "deleteLocalData"},
+		{name: "local storage with deleteLocalData", pod: drainTestPod("ReplicaSet", emptyDir), opts: drainOptions{DeleteLocalData: true}},
+		{name: "daemonset pod", pod: drainTestPod("DaemonSet"), status: DrainPodFailed, reason: "ignoreDaemonsets"},
+		{name: "daemonset pod ignored", pod: drainTestPod("DaemonSet"), opts: drainOptions{IgnoreDaemonsets: true}, status: DrainPodSkipped},
+		{name: "mirror pod", pod: mirror, opts: drainOptions{Force: true}, status: DrainPodSkipped, reason: "mirror pod"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, reason := drainPodFilter(tt.pod, tt.opts)
+			if status != tt.status {
+				t.Fatalf("status = %q (%s), want %q", status, reason, tt.status)
+			}
+			if !strings.Contains(reason, tt.reason) {
+				t.Errorf("reason = %q, want it to contain %q", reason, tt.reason)
+			}
+		})
+	}
+}
+
+func TestClassifyDrainPod(t *testing.T) {
+	info := classifyDrainPod(drainTestPod("", corev1.Volume{Name: "config"}, corev1.Volume{Name: "scratch", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}))
+	want := drainPodInfo{Unmanaged: true, EmptyDirVolume: "scratch"}
+	if info != want {
+		t.Errorf("classifyDrainPod() = %+v, want %+v", info, want)
+	}
+}
+
+// evictionTestHandler returns a node handler over a fake clientset whose
+// evictions are answered by evict
+func evictionTestHandler(evict func(eviction *policyv1.Eviction) error) (*NodeHandler, *fake.Clientset) {
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		return true, nil, evict(action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction))
+	})
+	handler := &NodeHandler{
+		GenericResourceHandler: &GenericResourceHandler[*corev1.Node, *corev1.NodeList]{
+			K8sClient: &kube.K8sClient{ClientSet: clientset},
+		},
+	}
+	return handler, clientset
+}
+
+func evictionTestJob(opts drainOptions) *drainJob {
+	return &drainJob{opts: opts, blocked: map[string]bool{}}
+}
+
+func TestEvictPod(t *testing.T) {
+	pod := drainTestPod("ReplicaSet")
+	podResource := schema.GroupResource{Resource: "pods"}
+
+	t.Run("keeps the pod grace period by default", func(t *testing.T) {
+		var got *policyv1.Eviction
+		handler, _ := evictionTestHandler(func(eviction *policyv1.Eviction) error {
+			got = eviction
+			return nil
+		})
+		if err := handler.evictPod(context.Background(), evictionTestJob(drainOptions{}), pod); err != nil {
+			t.Fatalf("evictPod: %v", err)
+		}
+		if got.Name != pod.Name || got.DeleteOptions.GracePeriodSeconds != nil {
+			t.Errorf("eviction = %+v, want %s without a grace period", got, pod.Name)
+		}
+	})
+
+	t.Run("sets the requested grace period", func(t *testing.T) {
+		var got *policyv1.Eviction
+		handler, _ := evictionTestHandler(func(eviction *policyv1.Eviction) error {
+			got = eviction
+			return nil
+		})
+		if err := handler.evictPod(context.Background(), evictionTestJob(drainOptions{GracePeriod: 5}), pod); err != nil {
+			t.Fatalf("evictPod: %v", err)
+		}
+		if seconds := got.DeleteOptions.GracePeriodSeconds; seconds == nil || *seconds != 5 {
+			t.Errorf("gracePeriodSeconds = %v, want 5", seconds)
+		}
+	})
+
+	t.Run("pod already gone", func(t *testing.T) {
+		handler, _ := evictionTestHandler(func(*policyv1.Eviction) error {
+			return errors.NewNotFound(podResource, pod.Name)
+		})
+		if err := handler.evictPod(context.Background(), evictionTestJob(drainOptions{}), pod); err != nil {
+			t.Errorf("evictPod: %v, want nil", err)
+		}
+	})
+
+	t.Run("other errors are not retried", func(t *testing.T) {
+		handler, clientset := evictionTestHandler(func(*policyv1.Eviction) error {
+			return errors.NewForbidden(podResource, pod.Name, nil)
+		})
+		if err := handler.evictPod(context.Background(), evictionTestJob(drainOptions{}), pod); !errors.IsForbidden(err) {
+			t.Errorf("evictPod error = %v, want Forbidden", err)
+		}
+		if n := len(clientset.Actions()); n != 1 {
+			t.Errorf("%d evictions, want 1", n)
+		}
+	})
+
+	t.Run("retries while a PodDisruptionBudget blocks it", func(t *testing.T) {
+		attempts := 0
+		job := evictionTestJob(drainOptions{})
+		handler, _ := evictionTestHandler(func(*policyv1.Eviction) error {
+			attempts++
+			if attempts == 1 {
+				return errors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+			}
+			return nil
+		})
+		if err := handler.evictPod(context.Background(), job, pod); err != nil {
+			t.Fatalf("evictPod: %v", err)
+		}
+		if attempts != 2 {
+			t.Errorf("%d evictions, want 2", attempts)
+		}
+		if !job.blocked["default/web-0"] {
+			t.Error("pod was not reported as blocked")
+		}
+	})
+
+	t.Run("gives up when the drain times out", func(t *testing.T) {
+		handler, _ := evictionTestHandler(func(*policyv1.Eviction) error {
+			return errors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		err := handler.evictPod(ctx, evictionTestJob(drainOptions{}), pod)
+		if err == nil || !strings.Contains(err.Error(), "blocked by PodDisruptionBudget") {
+			t.Errorf("evictPod error = %v, want blocked by PodDisruptionBudget", err)
+		}
+	})
+}
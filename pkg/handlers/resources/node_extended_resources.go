@@ -0,0 +1,125 @@
+package resources
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// standardNodeResources are the resources every node reports, anything else
+// in capacity is treated as an extended resource
+var standardNodeResources = map[corev1.ResourceName]bool{
+	corev1.ResourceCPU:              true,
+	corev1.ResourceMemory:           true,
+	corev1.ResourcePods:             true,
+	corev1.ResourceEphemeralStorage: true,
+}
+
+// ExtendedResource is the capacity and usage of an extended resource such
+// as nvidia.com/gpu on a node
+type ExtendedResource struct {
+	Resource    corev1.ResourceName `json:"resource"`
+	Capacity    resource.Quantity   `json:"capacity"`
+	Allocatable resource.Quantity   `json:"allocatable"`
+	Requested   resource.Quantity   `json:"requested"`
+	Free        resource.Quantity   `json:"free"`
+}
+
+// resourceDomain returns the domain prefix of a resource or label key, e.g.
+// nvidia.com for nvidia.com/gpu
+func resourceDomain(name string) string {
+	if i := strings.Index(name, "/"); i > 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+// GetNodeExtendedResources returns the capacity, allocatable amount and
+// requests of the extended resources of a node, together with the node
+// labels of the same vendor domains that describe the hardware
+func (h *NodeHandler) GetNodeExtendedResources(c *gin.Context) {
+	nodeName := c.Param("name")
+	ctx := c.Request.Context()
+
+	var node corev1.Node
+	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Name: nodeName}, &node); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	names := map[corev1.ResourceName]bool{}
+	for name := range node.Status.Capacity {
+		if !standardNodeResources[name] {
+			names[name] = true
+		}
+	}
+	for name := range node.Status.Allocatable {
+		if !standardNodeResources[name] {
+			names[name] = true
+		}
+	}
+
+	resources := make([]ExtendedResource, 0, len(names))
+	labels := map[string]string{}
+	if len(names) > 0 {
+		pods, err := h.listNodePods(ctx, nodeName, "")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list pods: " + err.Error()})
+			return
+		}
+		requests := corev1.ResourceList{}
+		for i := range pods {
+			if utils.IsPodErrorOrSuccess(&pods[i]) {
+				continue
+			}
+			podRequests, _ := utils.GetPodRequestsAndLimits(&pods[i])
+			utils.AddResourceList(requests, podRequests)
+		}
+
+		domains := map[string]bool{}
+		for name := range names {
+			extended := ExtendedResource{
+				Resource:    name,
+				Capacity:    node.Status.Capacity[name],
+				Allocatable: node.Status.Allocatable[name],
+				Requested:   requests[name],
+			}
+			free := extended.Allocatable.DeepCopy()
+			free.Sub(extended.Requested)
+			if free.Sign() < 0 {
+				free = resource.Quantity{Format: free.Format}
+			}
+			extended.Free = free
+			resources = append(resources, extended)
+
+			if domain := resourceDomain(string(name)); domain != "" {
+				domains[domain] = true
+			}
+		}
+		for key, value := range node.Labels {
+			if domains[resourceDomain(key)] {
+				labels[key] = value
+			}
+		}
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].Resource < resources[j].Resource
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"node":      nodeName,
+		"resources": resources,
+		"labels":    labels,
+	})
+}
@@ -6,15 +6,18 @@ import (
 	"net/http"
 	"os"
 	"sort"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/zxh326/kite/pkg/kube"
+	"github.com/zxh326/kite/pkg/utils"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/types"
-	"sigs.k8s.io/controller-runtime/pkg/client"
+	"k8s.io/klog/v2"
 )
 
 // getNodeOperationImage returns the image to use for node operations
@@ -32,17 +35,23 @@ func getNodeOperationImage() string {
 
 type NodeHandler struct {
 	*GenericResourceHandler[*corev1.Node, *corev1.NodeList]
+	drains *drainJobStore
 }
 
 func NewNodeHandler(client *kube.K8sClient) *NodeHandler {
-	return &NodeHandler{
+	h := &NodeHandler{
 		GenericResourceHandler: NewGenericResourceHandler[*corev1.Node, *corev1.NodeList](
 			client,
 			"nodes",
 			true, // Nodes are cluster-scoped resources
 			true,
 		),
+		drains: newDrainJobStore(),
 	}
+	if client.ClientSet != nil {
+		go h.reapHelperPods(context.Background())
+	}
+	return h
 }
 
 // DrainNode drains a node by evicting all pods
@@ -51,13 +60,7 @@ func (h *NodeHandler) DrainNode(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	// Parse the request body for drain options
-	var drainRequest struct {
-		Force            bool `json:"force" binding:"required"`
-		GracePeriod      int  `json:"gracePeriod" binding:"min=0"`
-		DeleteLocal      bool `json:"deleteLocalData"`
-		IgnoreDaemonsets bool `json:"ignoreDaemonsets"`
-	}
-
+	var drainRequest DrainRequest
 	if err := c.ShouldBindJSON(&drainRequest); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
 		return
@@ -74,18 +77,59 @@ func (h *NodeHandler) DrainNode(c *gin.Context) {
 		return
 	}
 
-	// TODO: Implement actual drain logic
-	// For now, we'll simulate the drain operation
-	// In a real implementation, you would:
-	// 1. Mark the node as unschedulable (cordon)
-	// 2. Evict all pods from the node
-	// 3. Handle daemonsets appropriately
-	// 4. Wait for pods to be evicted or force delete them
+	// The drain must outlive the request that started it
+	job, drainCtx, err := h.drains.start(context.Background(), nodeName, drainRequest.options())
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	go h.runDrain(drainCtx, job)
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": fmt.Sprintf("Node %s drain initiated", nodeName),
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": fmt.Sprintf("Node %s drain started", nodeName),
 		"node":    node.Name,
-		"options": drainRequest,
+		"jobId":   job.ID,
+	})
+}
+
+// GetDrainStatus reports the progress of the latest drain of a node
+func (h *NodeHandler) GetDrainStatus(c *gin.Context) {
+	nodeName := c.Param("name")
+
+	job, ok := h.drains.get(nodeName)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("No drain found for node %s", nodeName)})
+		return
+	}
+	c.JSON(http.StatusOK, job.Status())
+}
+
+// CancelDrain cancels the in-flight drain of a node. With ?uncordon=true the
+// node is made schedulable again.
+func (h *NodeHandler) CancelDrain(c *gin.Context) {
+	nodeName := c.Param("name")
+	ctx := c.Request.Context()
+
+	job, ok := h.drains.get(nodeName)
+	if !ok || job.finished() {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("No drain in progress for node %s", nodeName)})
+		return
+	}
+	job.cancel()
+
+	uncordoned := false
+	if c.Query("uncordon") == "true" {
+		if err := h.markNodeSchedulable(ctx, nodeName, true); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Drain cancelled but failed to uncordon node: " + err.Error()})
+			return
+		}
+		uncordoned = true
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    fmt.Sprintf("Drain %s of node %s cancelled", job.ID, nodeName),
+		"jobId":      job.ID,
+		"uncordoned": uncordoned,
 	})
 }
 
@@ -257,41 +301,115 @@ func (h *NodeHandler) UntaintNode(c *gin.Context) {
 	})
 }
 
-// GetNodeEvents retrieves events related to a specific node
+// GetNodeEvents retrieves events related to a specific node, newest first.
+// ?type= filters by Normal or Warning. With ?limit= the events are paged and
+// the token for the next page is returned in the X-Continue-Token header, to
+// be passed back as ?continue=. Event series recorded through the
+// events.k8s.io API are merged in.
 func (h *NodeHandler) GetNodeEvents(c *gin.Context) {
 	nodeName := c.Param("name")
 	ctx := c.Request.Context()
 
-	// Get all events and filter by node name
-	eventList := &corev1.EventList{}
-	err := h.K8sClient.Client.List(ctx, eventList)
+	coreSelector := fields.Set{
+		"involvedObject.kind": "Node",
+		"involvedObject.name": nodeName,
+	}
+	eventsSelector := fields.Set{
+		"regarding.kind": "Node",
+		"regarding.name": nodeName,
+	}
+	if eventType := c.Query("type"); eventType != "" {
+		if eventType != corev1.EventTypeNormal && eventType != corev1.EventTypeWarning {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "type must be Normal or Warning"})
+			return
+		}
+		coreSelector["type"] = eventType
+		eventsSelector["type"] = eventType
+	}
+
+	listOpts := metav1.ListOptions{
+		FieldSelector: fields.SelectorFromSet(coreSelector).String(),
+		Continue:      c.Query("continue"),
+	}
+	if c.Query("limit") != "" {
+		limit, err := strconv.ParseInt(c.Query("limit"), 10, 64)
+		if err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+			return
+		}
+		listOpts.Limit = limit
+	}
 
+	eventList, err := h.K8sClient.ClientSet.CoreV1().Events("").List(ctx, listOpts)
 	if err != nil {
+		if errors.IsResourceExpired(err) {
+			c.JSON(http.StatusGone, gin.H{"error": "continue token expired, restart from the first page"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events: " + err.Error()})
 		return
 	}
+	nodeEvents := eventList.Items
 
-	// Filter events related to this node
-	var nodeEvents []corev1.Event
-	for _, event := range eventList.Items {
-		if event.InvolvedObject.Kind == "Node" && event.InvolvedObject.Name == nodeName {
-			nodeEvents = append(nodeEvents, event)
+	// Both APIs serve the same events, the events.k8s.io view carries the
+	// series of repeated events
+	seriesList, err := h.K8sClient.ClientSet.EventsV1().Events("").List(ctx, metav1.ListOptions{
+		FieldSelector: fields.SelectorFromSet(eventsSelector).String(),
+	})
+	if err != nil {
+		klog.Warningf("Failed to list events.k8s.io events for node %s: %v", nodeName, err)
+	} else {
+		byUID := make(map[types.UID]int, len(nodeEvents))
+		for i := range nodeEvents {
+			byUID[nodeEvents[i].UID] = i
+		}
+		for i := range seriesList.Items {
+			event := &seriesList.Items[i]
+			if index, ok := byUID[event.UID]; ok {
+				if event.Series != nil && nodeEvents[index].Series == nil {
+					nodeEvents[index].Series = &corev1.EventSeries{
+						Count:            event.Series.Count,
+						LastObservedTime: event.Series.LastObservedTime,
+					}
+					nodeEvents[index].Count = event.Series.Count
+				}
+				continue
+			}
+			// Events missing from the paged core list belong to other pages
+			if listOpts.Limit == 0 {
+				nodeEvents = append(nodeEvents, utils.CoreEventFromEventsV1(event))
+			}
 		}
 	}
 
 	// Sort events by last timestamp (most recent first)
 	sort.Slice(nodeEvents, func(i, j int) bool {
-		return nodeEvents[i].LastTimestamp.After(nodeEvents[j].LastTimestamp.Time)
+		return eventTimestamp(&nodeEvents[i]).After(eventTimestamp(&nodeEvents[j]))
 	})
 
+	if eventList.Continue != "" {
+		c.Header("X-Continue-Token", eventList.Continue)
+	}
 	c.JSON(http.StatusOK, nodeEvents)
 }
 
-// RestartKubelet restarts the kubelet service on a node
+// RestartKubelet restarts the kubelet service on a node. With ?wait=true
+// the response is sent once the kubelet has rejoined, bounded by ?timeout=
+// seconds.
 func (h *NodeHandler) RestartKubelet(c *gin.Context) {
 	nodeName := c.Param("name")
 	ctx := c.Request.Context()
 
+	wait := c.Query("wait") == "true"
+	timeout := defaultKubeletRestartTimeout
+	if value := c.Query("timeout"); value != "" {
+		var err error
+		if timeout, err = helperPodTimeout(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	// Verify node exists
 	var node corev1.Node
 	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Name: nodeName}, &node); err != nil {
@@ -307,7 +425,7 @@ func (h *NodeHandler) RestartKubelet(c *gin.Context) {
 	restartPod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      fmt.Sprintf("restart-kubelet-%s-%d", nodeName, time.Now().Unix()),
-			Namespace: "kube-system",
+			Namespace: helperPodNamespace,
 			Labels: map[string]string{
 				"app":  "kite-node-restart",
 				"type": "kubelet",
@@ -315,10 +433,11 @@ func (h *NodeHandler) RestartKubelet(c *gin.Context) {
 			},
 		},
 		Spec: corev1.PodSpec{
-			NodeName:      nodeName,
-			HostPID:       true,
-			HostNetwork:   true,
-			RestartPolicy: corev1.RestartPolicyNever,
+			NodeName:              nodeName,
+			HostPID:               true,
+			HostNetwork:           true,
+			RestartPolicy:         corev1.RestartPolicyNever,
+			ActiveDeadlineSeconds: &[]int64{helperPodActiveDeadline}[0],
 			Containers: []corev1.Container{
 				{
 					Name:  "restart-kubelet",
@@ -348,6 +467,11 @@ func (h *NodeHandler) RestartKubelet(c *gin.Context) {
 		},
 	}
 
+	if wait {
+		h.restartKubeletAndWait(c, nodeName, restartPod, timeout)
+		return
+	}
+
 	if err := h.K8sClient.Client.Create(ctx, restartPod); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create restart pod: " + err.Error()})
 		return
@@ -359,69 +483,18 @@ func (h *NodeHandler) RestartKubelet(c *gin.Context) {
 	})
 }
 
-// RestartKubeProxy restarts the kube-proxy on a node
-func (h *NodeHandler) RestartKubeProxy(c *gin.Context) {
+// GetContainerdConfig retrieves the containerd configuration from a node by
+// running a helper pod and returning its output
+func (h *NodeHandler) GetContainerdConfig(c *gin.Context) {
 	nodeName := c.Param("name")
 	ctx := c.Request.Context()
 
-	// Verify node exists
-	var node corev1.Node
-	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Name: nodeName}, &node); err != nil {
-		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	// Find and delete the kube-proxy pod on this node
-	podList := &corev1.PodList{}
-	err := h.K8sClient.Client.List(ctx, podList, client.InNamespace("kube-system"))
-
+	timeout, err := helperPodTimeout(c.Query("timeout"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list kube-proxy pods: " + err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Find the kube-proxy pod on this specific node
-	var targetPod *corev1.Pod
-	for i := range podList.Items {
-		pod := &podList.Items[i]
-		// Check if it's a kube-proxy pod on this node
-		if pod.Spec.NodeName == nodeName {
-			// Check labels to identify kube-proxy
-			if labels := pod.Labels; labels != nil {
-				if labels["k8s-app"] == "kube-proxy" || labels["component"] == "kube-proxy" {
-					targetPod = pod
-					break
-				}
-			}
-		}
-	}
-
-	if targetPod == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("kube-proxy pod not found on node %s", nodeName)})
-		return
-	}
-
-	// Delete the pod to trigger restart
-	if err := h.K8sClient.Client.Delete(ctx, targetPod); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete kube-proxy pod: " + err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"message": fmt.Sprintf("kube-proxy restart initiated on node %s", nodeName),
-		"pod":     targetPod.Name,
-	})
-}
-
-// GetContainerdConfig retrieves the containerd configuration from a node
-func (h *NodeHandler) GetContainerdConfig(c *gin.Context) {
-	nodeName := c.Param("name")
-	ctx := c.Request.Context()
-
 	// Verify node exists
 	var node corev1.Node
 	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Name: nodeName}, &node); err != nil {
@@ -434,72 +507,43 @@ func (h *NodeHandler) GetContainerdConfig(c *gin.Context) {
 	}
 
 	// Create a pod to read containerd config
-	configPod := &corev1.Pod{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      fmt.Sprintf("read-containerd-config-%s-%d", nodeName, time.Now().Unix()),
-			Namespace: "kube-system",
-			Labels: map[string]string{
-				"app":  "kite-node-config",
-				"type": "containerd",
-				"node": nodeName,
-			},
-		},
-		Spec: corev1.PodSpec{
-			NodeName:      nodeName,
-			HostPID:       true,
-			RestartPolicy: corev1.RestartPolicyNever,
-			Containers: []corev1.Container{
-				{
-					Name:  "read-config",
-					Image: getNodeOperationImage(),
-					Command: []string{
-						"cat",
-						"/host/etc/containerd/config.toml",
-					},
-					VolumeMounts: []corev1.VolumeMount{
-						{
-							Name:      "host-etc",
-							MountPath: "/host/etc",
-							ReadOnly:  true,
-						},
-					},
-				},
-			},
-			Volumes: []corev1.Volume{
-				{
-					Name: "host-etc",
-					VolumeSource: corev1.VolumeSource{
-						HostPath: &corev1.HostPathVolumeSource{
-							Path: "/etc",
-						},
-					},
-				},
-			},
-			Tolerations: []corev1.Toleration{
-				{
-					Operator: corev1.TolerationOpExists,
-				},
-			},
-		},
-	}
+	configPod := newNodeReadOnlyPod(nodeName, "read-containerd-config", "containerd",
+		[]string{"cat", "/host/etc/containerd/config.toml"}, "/etc")
 
-	if err := h.K8sClient.Client.Create(ctx, configPod); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create config reader pod: " + err.Error()})
+	result, err := h.runHelperPod(ctx, configPod, timeout)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read containerd config: " + err.Error()})
+		return
+	}
+	if result.Phase == corev1.PodFailed {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":    "Failed to read containerd config: " + result.Message,
+			"exitCode": result.ExitCode,
+			"output":   result.Output,
+		})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Containerd config retrieval initiated",
-		"pod":     configPod.Name,
-		"note":    "Use pod logs to view the configuration",
+		"node":      nodeName,
+		"path":      "/etc/containerd/config.toml",
+		"config":    result.Output,
+		"truncated": result.Truncated,
 	})
 }
 
-// GetCNIConfig retrieves the CNI configuration from a node
+// GetCNIConfig retrieves the CNI configuration files from a node by running
+// a helper pod and parsing its output
 func (h *NodeHandler) GetCNIConfig(c *gin.Context) {
 	nodeName := c.Param("name")
 	ctx := c.Request.Context()
 
+	timeout, err := helperPodTimeout(c.Query("timeout"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Verify node exists
 	var node corev1.Node
 	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Name: nodeName}, &node); err != nil {
@@ -512,77 +556,69 @@ func (h *NodeHandler) GetCNIConfig(c *gin.Context) {
 	}
 
 	// Create a pod to read CNI config
-	configPod := &corev1.Pod{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      fmt.Sprintf("read-cni-config-%s-%d", nodeName, time.Now().Unix()),
-			Namespace: "kube-system",
-			Labels: map[string]string{
-				"app":  "kite-node-config",
-				"type": "cni",
-				"node": nodeName,
-			},
-		},
-		Spec: corev1.PodSpec{
-			NodeName:      nodeName,
-			HostPID:       true,
-			RestartPolicy: corev1.RestartPolicyNever,
-			Containers: []corev1.Container{
-				{
-					Name:  "read-config",
-					Image: getNodeOperationImage(),
-					Command: []string{
-						"sh",
-						"-c",
-						"ls -la /host/etc/cni/net.d/ && cat /host/etc/cni/net.d/*.conf* 2>/dev/null || echo 'No CNI config found'",
-					},
-					VolumeMounts: []corev1.VolumeMount{
-						{
-							Name:      "host-cni",
-							MountPath: "/host/etc/cni",
-							ReadOnly:  true,
-						},
-					},
-				},
-			},
-			Volumes: []corev1.Volume{
-				{
-					Name: "host-cni",
-					VolumeSource: corev1.VolumeSource{
-						HostPath: &corev1.HostPathVolumeSource{
-							Path: "/etc/cni",
-						},
-					},
-				},
-			},
-			Tolerations: []corev1.Toleration{
-				{
-					Operator: corev1.TolerationOpExists,
-				},
-			},
-		},
-	}
+	configPod := newNodeReadOnlyPod(nodeName, "read-cni-config", "cni",
+		[]string{"sh", "-c", cniConfigScript}, "/etc/cni")
 
-	if err := h.K8sClient.Client.Create(ctx, configPod); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create config reader pod: " + err.Error()})
+	result, err := h.runHelperPod(ctx, configPod, timeout)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read CNI config: " + err.Error()})
+		return
+	}
+	if result.Phase == corev1.PodFailed {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":    "Failed to read CNI config: " + result.Message,
+			"exitCode": result.ExitCode,
+			"output":   result.Output,
+		})
 		return
 	}
 
+	cniConfig := parseCNIConfigOutput(result.Output)
 	c.JSON(http.StatusOK, gin.H{
-		"message": "CNI config retrieval initiated",
-		"pod":     configPod.Name,
-		"note":    "Use pod logs to view the configuration",
+		"node":      nodeName,
+		"directory": cniConfigDir,
+		"exists":    cniConfig.Exists,
+		"listing":   cniConfig.Listing,
+		"files":     cniConfig.Files,
+		"truncated": result.Truncated,
 	})
 }
 
 func (h *NodeHandler) registerCustomRoutes(group *gin.RouterGroup) {
+	group.POST("/batch/cordon", h.BatchCordonNodes)
+	group.POST("/batch/uncordon", h.BatchUncordonNodes)
+	group.POST("/batch/taint", h.BatchTaintNodes)
+	group.POST("/batch/drain", h.BatchDrainNodes)
+	group.POST("/kube-proxy/restart", h.RestartKubeProxyDaemonSet)
+	group.GET("/batch/drain/:id", h.GetBatchDrainStatus)
+	group.DELETE("/batch/drain/:id", h.CancelBatchDrain)
+	group.GET("/_all/helper-pods", h.ListHelperPods)
+	group.DELETE("/_all/helper-pods", h.CleanupHelperPods)
+	group.GET("/_all/summary", h.ListNodeSummaries)
 	group.POST("/_all/:name/drain", h.DrainNode)
+	group.GET("/_all/:name/drain/preview", h.PreviewDrain)
+	group.GET("/_all/:name/drain/status", h.GetDrainStatus)
+	group.DELETE("/_all/:name/drain/status", h.CancelDrain)
 	group.POST("/_all/:name/cordon", h.CordonNode)
 	group.POST("/_all/:name/uncordon", h.UncordonNode)
 	group.POST("/_all/:name/taint", h.TaintNode)
 	group.POST("/_all/:name/untaint", h.UntaintNode)
+	group.PUT("/_all/:name/taints", h.ReplaceNodeTaints)
 	group.GET("/_all/:name/events", h.GetNodeEvents)
+	group.GET("/_all/:name/pods", h.GetNodePods)
+	group.GET("/_all/:name/allocation", h.GetNodeAllocation)
+	group.GET("/_all/:name/extended-resources", h.GetNodeExtendedResources)
+	group.POST("/_all/:name/can-schedule", h.CanScheduleOnNode)
+	group.GET("/_all/:name/usage", h.GetNodeUsage)
+	group.GET("/_all/:name/summary", h.GetNodeSummary)
+	group.GET("/_all/:name/labels", h.GetNodeLabels)
+	group.POST("/_all/:name/labels", h.UpdateNodeLabels)
+	group.POST("/_all/:name/annotations", h.UpdateNodeAnnotations)
 	group.POST("/_all/:name/restart-kubelet", h.RestartKubelet)
 	group.POST("/_all/:name/restart-kubeproxy", h.RestartKubeProxy)
 	group.GET("/_all/:name/containerd-config", h.GetContainerdConfig)
 	group.GET("/_all/:name/cni-config", h.GetCNIConfig)
+	group.POST("/_all/:name/inspect", h.InspectNode)
+	group.GET("/_all/:name/kubelet-config", h.GetKubeletConfig)
+	group.GET("/_all/:name/logs", h.GetNodeLogs)
 }
@@ -0,0 +1,289 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/common"
+	"github.com/zxh326/kite/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// helperPodNamespace is where node operation pods are created
+	helperPodNamespace = "kube-system"
+	// helperPodSelector matches the app labels of node operation pods
+	helperPodSelector = "app in (kite-node-restart,kite-node-config)"
+	// helperPodReapInterval is how often finished helper pods are cleaned up
+	helperPodReapInterval = 10 * time.Minute
+	// helperPodActiveDeadline stops helper pods that hang, e.g. on a node
+	// that never finishes a restart
+	helperPodActiveDeadline = int64(300)
+	// defaultHelperPodTimeout bounds how long a synchronous helper pod may
+	// take to run to completion
+	defaultHelperPodTimeout = 60 * time.Second
+	// maxHelperPodOutput caps how much of a helper pod's output is returned
+	maxHelperPodOutput = 1 << 20
+)
+
+// helperPodResult is the outcome of a helper pod that ran to completion
+type helperPodResult struct {
+	Output    string
+	Truncated bool
+	Phase     corev1.PodPhase
+	ExitCode  int32
+	Message   string
+	// TerminationMessage is what the container wrote to its termination
+	// message path, without falling back to the termination reason
+	TerminationMessage string
+}
+
+// runHelperPod creates a one-shot pod, waits until it has succeeded or failed
+// and returns the logs of its first container. The pod is deleted afterwards
+// regardless of the outcome.
+func (h *NodeHandler) runHelperPod(ctx context.Context, pod *corev1.Pod, timeout time.Duration) (*helperPodResult, error) {
+	pods := h.K8sClient.ClientSet.CoreV1().Pods(pod.Namespace)
+
+	if pod.Spec.ActiveDeadlineSeconds == nil {
+		deadline := int64(timeout.Seconds())
+		pod.Spec.ActiveDeadlineSeconds = &deadline
+	}
+	created, err := pods.Create(ctx, pod, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create helper pod: %w", err)
+	}
+	defer func() {
+		// The request context may already be done, clean up regardless
+		if err := pods.Delete(context.Background(), created.Name, metav1.DeleteOptions{}); err != nil {
+			klog.Warningf("Failed to delete helper pod %s/%s: %v", created.Namespace, created.Name, err)
+		}
+	}()
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	finished, err := h.waitForHelperPod(waitCtx, created)
+	if err != nil {
+		result := &helperPodResult{Phase: finished.Status.Phase, Message: helperPodPendingReason(finished)}
+		return result, err
+	}
+
+	result := &helperPodResult{Phase: finished.Status.Phase}
+	for _, status := range finished.Status.ContainerStatuses {
+		if status.State.Terminated != nil {
+			result.ExitCode = status.State.Terminated.ExitCode
+			result.Message = status.State.Terminated.Message
+			result.TerminationMessage = status.State.Terminated.Message
+			if result.Message == "" {
+				result.Message = status.State.Terminated.Reason
+			}
+			break
+		}
+	}
+
+	limitBytes := int64(maxHelperPodOutput + 1)
+	stream, err := pods.GetLogs(created.Name, &corev1.PodLogOptions{LimitBytes: &limitBytes}).Stream(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to read helper pod logs: %w", err)
+	}
+	defer stream.Close()
+	output, err := io.ReadAll(stream)
+	if err != nil {
+		return result, fmt.Errorf("failed to read helper pod logs: %w", err)
+	}
+	if len(output) > maxHelperPodOutput {
+		output = output[:maxHelperPodOutput]
+		result.Truncated = true
+	}
+	result.Output = string(output)
+	return result, nil
+}
+
+// waitForHelperPod watches a pod until it has succeeded or failed. On error
+// the last observed state of the pod is returned along with the error.
+func (h *NodeHandler) waitForHelperPod(ctx context.Context, pod *corev1.Pod) (*corev1.Pod, error) {
+	pods := h.K8sClient.ClientSet.CoreV1().Pods(pod.Namespace)
+	watcher, err := pods.Watch(ctx, metav1.ListOptions{
+		FieldSelector:   fields.OneTermEqualSelector("metadata.name", pod.Name).String(),
+		ResourceVersion: pod.ResourceVersion,
+	})
+	if err != nil {
+		return pod, fmt.Errorf("failed to watch helper pod: %w", err)
+	}
+	defer watcher.Stop()
+
+	last := pod
+	for {
+		select {
+		case <-ctx.Done():
+			return last, fmt.Errorf("helper pod %s did not finish in time: %w", pod.Name, ctx.Err())
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return last, fmt.Errorf("watch of helper pod %s closed unexpectedly", pod.Name)
+			}
+			if event.Type == watch.Deleted {
+				return last, fmt.Errorf("helper pod %s was deleted before it finished", pod.Name)
+			}
+			current, ok := event.Object.(*corev1.Pod)
+			if !ok {
+				continue
+			}
+			last = current
+			if current.Status.Phase == corev1.PodSucceeded || current.Status.Phase == corev1.PodFailed {
+				return current, nil
+			}
+		}
+	}
+}
+
+// helperPodPendingReason explains why a helper pod hasn't started, using
+// the scheduler's message when the pod is unschedulable
+func helperPodPendingReason(pod *corev1.Pod) string {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse {
+			return condition.Message
+		}
+	}
+	return utils.GetPodErrorMessage(pod)
+}
+
+// HelperPod describes a node operation pod
+type HelperPod struct {
+	Name      string          `json:"name"`
+	Namespace string          `json:"namespace"`
+	Node      string          `json:"node"`
+	App       string          `json:"app"`
+	Type      string          `json:"type"`
+	Phase     corev1.PodPhase `json:"phase"`
+	CreatedAt metav1.Time     `json:"createdAt"`
+}
+
+// listHelperPods lists the node operation pods created by kite
+func (h *NodeHandler) listHelperPods(ctx context.Context) ([]corev1.Pod, error) {
+	podList, err := h.K8sClient.ClientSet.CoreV1().Pods(helperPodNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: helperPodSelector,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return podList.Items, nil
+}
+
+// cleanupHelperPods deletes finished helper pods older than maxAge and
+// returns the names of the deleted pods
+func (h *NodeHandler) cleanupHelperPods(ctx context.Context, maxAge time.Duration) ([]string, error) {
+	pods, err := h.listHelperPods(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := []string{}
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+			continue
+		}
+		if time.Since(pod.CreationTimestamp.Time) < maxAge {
+			continue
+		}
+		if err := h.K8sClient.ClientSet.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			klog.Warningf("Failed to delete helper pod %s/%s: %v", pod.Namespace, pod.Name, err)
+			continue
+		}
+		deleted = append(deleted, pod.Name)
+	}
+	return deleted, nil
+}
+
+// reapHelperPods periodically deletes finished helper pods older than
+// common.NodeHelperPodMaxAge until ctx is done
+func (h *NodeHandler) reapHelperPods(ctx context.Context) {
+	ticker := time.NewTicker(helperPodReapInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := h.cleanupHelperPods(ctx, common.NodeHelperPodMaxAge)
+			if err != nil {
+				klog.Warningf("Failed to clean up node helper pods: %v", err)
+				continue
+			}
+			if len(deleted) > 0 {
+				klog.Infof("Cleaned up %d node helper pods", len(deleted))
+			}
+		}
+	}
+}
+
+// ListHelperPods lists the node operation pods in kube-system
+func (h *NodeHandler) ListHelperPods(c *gin.Context) {
+	pods, err := h.listHelperPods(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list helper pods: " + err.Error()})
+		return
+	}
+
+	helperPods := make([]HelperPod, 0, len(pods))
+	for _, pod := range pods {
+		helperPods = append(helperPods, HelperPod{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+			Node:      pod.Spec.NodeName,
+			App:       pod.Labels["app"],
+			Type:      pod.Labels["type"],
+			Phase:     pod.Status.Phase,
+			CreatedAt: pod.CreationTimestamp,
+		})
+	}
+	sort.Slice(helperPods, func(i, j int) bool {
+		return helperPods[i].CreatedAt.After(helperPods[j].CreatedAt.Time)
+	})
+	c.JSON(http.StatusOK, helperPods)
+}
+
+// CleanupHelperPods deletes finished node operation pods right away. With
+// ?maxAge= (a duration such as 30m) only older pods are deleted.
+func (h *NodeHandler) CleanupHelperPods(c *gin.Context) {
+	var maxAge time.Duration
+	if value := c.Query("maxAge"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid maxAge parameter"})
+			return
+		}
+		maxAge = d
+	}
+
+	deleted, err := h.cleanupHelperPods(c.Request.Context(), maxAge)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clean up helper pods: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("Deleted %d helper pods", len(deleted)),
+		"deleted": deleted,
+	})
+}
+
+// helperPodTimeout reads the ?timeout= query parameter in seconds
+func helperPodTimeout(value string) (time.Duration, error) {
+	if value == "" {
+		return defaultHelperPodTimeout, nil
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 {
+		return 0, fmt.Errorf("invalid timeout parameter")
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
@@ -0,0 +1,181 @@
+package resources
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// nodeProbe is a read-only inspection command run on a node's host
+type nodeProbe struct {
+	Description string
+	Script      string
+	// HostPaths are mounted read-only below /host
+	HostPaths   []string
+	HostNetwork bool
+}
+
+// nodeProbes is the server-side whitelist of inspection commands. Clients
+// only choose a probe by name and never send commands.
+var nodeProbes = map[string]nodeProbe{
+	"kernel": {
+		Description: "Kernel version and operating system release",
+		Script:      "uname -a; echo; cat /host/etc/os-release",
+		HostPaths:   []string{"/etc"},
+	},
+	"disk": {
+		Description: "Disk usage of the host filesystems",
+		Script:      "df -h | grep -E '^Filesystem| /host'",
+		HostPaths:   []string{"/"},
+	},
+	"sysctl-net": {
+		Description: "IPv4 network sysctls",
+		Script:      "sysctl -a 2>/dev/null | grep '^net\\.ipv4'",
+		HostNetwork: true,
+	},
+	"dns-resolv": {
+		Description: "DNS resolver configuration",
+		// resolv.conf is often an absolute symlink into /run, which has to
+		// be resolved below /host
+		Script: `f=/host/etc/resolv.conf
+if [ -L "$f" ]; then t=$(readlink "$f"); case "$t" in /*) f="/host$t";; *) f="/host/etc/$t";; esac; fi
+echo "# $f"; cat "$f"`,
+		HostPaths: []string{"/etc", "/run"},
+	},
+	"kubelet-flags": {
+		Description: "Command line and environment files of the kubelet",
+		Script: `pid=$(pidof kubelet) || { echo "kubelet process not found" >&2; exit 1; }
+tr '\0' ' ' < /proc/$pid/cmdline; echo
+for f in /host/etc/default/kubelet /host/etc/sysconfig/kubelet; do [ -f "$f" ] && { echo; echo "# $f"; cat "$f"; }; done; true`,
+		HostPaths: []string{"/etc"},
+	},
+}
+
+// newNodeReadOnlyPod builds a one-shot pod pinned to a node that runs
+// command with the given host paths mounted read-only below /host
+func newNodeReadOnlyPod(nodeName, namePrefix, podType string, command []string, hostPaths ...string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s-%d", namePrefix, nodeName, time.Now().Unix()),
+			Namespace: helperPodNamespace,
+			Labels: map[string]string{
+				"app":  "kite-node-config",
+				"type": podType,
+				"node": nodeName,
+			},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:      nodeName,
+			HostPID:       true,
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:                     "read-config",
+					Image:                    getNodeOperationImage(),
+					Command:                  command,
+					TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+				},
+			},
+			Tolerations: []corev1.Toleration{
+				{
+					Operator: corev1.TolerationOpExists,
+				},
+			},
+		},
+	}
+
+	propagation := corev1.MountPropagationHostToContainer
+	for i, hostPath := range hostPaths {
+		name := fmt.Sprintf("host-%d", i)
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name: name,
+			VolumeSource: corev1.VolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{
+					Path: hostPath,
+				},
+			},
+		})
+		pod.Spec.Containers[0].VolumeMounts = append(pod.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{
+			Name:             name,
+			MountPath:        path.Join("/host", hostPath),
+			ReadOnly:         true,
+			MountPropagation: &propagation,
+		})
+	}
+	return pod
+}
+
+// InspectNode runs one of the whitelisted nodeProbes on a node and returns
+// its stdout, stderr and exit code
+func (h *NodeHandler) InspectNode(c *gin.Context) {
+	nodeName := c.Param("name")
+	ctx := c.Request.Context()
+
+	timeout, err := helperPodTimeout(c.Query("timeout"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var inspectRequest struct {
+		Probe string `json:"probe" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&inspectRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	probe, ok := nodeProbes[inspectRequest.Probe]
+	if !ok {
+		names := make([]string, 0, len(nodeProbes))
+		for name := range nodeProbes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown probe %q, must be one of: %s", inspectRequest.Probe, strings.Join(names, ", "))})
+		return
+	}
+
+	// Verify node exists
+	var node corev1.Node
+	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Name: nodeName}, &node); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// stderr goes to the termination message so that it can be told apart
+	// from stdout, which is read from the logs
+	script := "exec 2>/dev/termination-log\n" + probe.Script
+	inspectPod := newNodeReadOnlyPod(nodeName, "inspect-"+inspectRequest.Probe, "inspect", []string{"sh", "-c", script}, probe.HostPaths...)
+	inspectPod.Labels["probe"] = inspectRequest.Probe
+	inspectPod.Spec.HostNetwork = probe.HostNetwork
+	inspectPod.Spec.Containers[0].TerminationMessagePolicy = corev1.TerminationMessageReadFile
+
+	result, err := h.runHelperPod(ctx, inspectPod, timeout)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to run probe: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"node":        nodeName,
+		"probe":       inspectRequest.Probe,
+		"description": probe.Description,
+		"stdout":      result.Output,
+		"stderr":      result.TerminationMessage,
+		"exitCode":    result.ExitCode,
+		"truncated":   result.Truncated,
+	})
+}
@@ -0,0 +1,217 @@
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// kubeletProxyTimeout bounds requests to a kubelet through the node proxy,
+// the kubelet of a NotReady node may never answer
+const kubeletProxyTimeout = 10 * time.Second
+
+// nodeProxyGet issues a GET for path on the kubelet of a node through the
+// apiserver's node proxy
+func (h *NodeHandler) nodeProxyGet(ctx context.Context, nodeName string, path ...string) ([]byte, error) {
+	return h.K8sClient.ClientSet.CoreV1().RESTClient().Get().
+		Resource("nodes").
+		Name(nodeName).
+		SubResource(append([]string{"proxy"}, path...)...).
+		Do(ctx).
+		Raw()
+}
+
+// writeNodeProxyError answers with a 502 or 504 and a hint about the usual
+// causes when the apiserver could not reach the kubelet
+func writeNodeProxyError(c *gin.Context, err error) {
+	switch {
+	case errors.IsForbidden(err):
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": err.Error(),
+			"hint":  "kite needs the get permission on nodes/proxy to reach the kubelet",
+		})
+	case errors.IsTimeout(err), errors.IsServerTimeout(err), err == context.DeadlineExceeded:
+		c.JSON(http.StatusGatewayTimeout, gin.H{
+			"error": err.Error(),
+			"hint":  "the kubelet did not answer in time, check that the node is Ready",
+		})
+	default:
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": err.Error(),
+			"hint":  "the apiserver could not reach the kubelet through the node proxy, check that the node is Ready and that the apiserver can connect to the kubelet port",
+		})
+	}
+}
+
+// GetKubeletConfig returns the effective kubelet configuration of a node as
+// reported by the kubelet's /configz endpoint
+func (h *NodeHandler) GetKubeletConfig(c *gin.Context) {
+	nodeName := c.Param("name")
+	ctx, cancel := context.WithTimeout(c.Request.Context(), kubeletProxyTimeout)
+	defer cancel()
+
+	var node corev1.Node
+	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Name: nodeName}, &node); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	raw, err := h.nodeProxyGet(ctx, nodeName, "configz")
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		writeNodeProxyError(c, err)
+		return
+	}
+
+	var configz struct {
+		KubeletConfig json.RawMessage `json:"kubeletconfig"`
+	}
+	if err := json.Unmarshal(raw, &configz); err != nil || configz.KubeletConfig == nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Unexpected response from the kubelet configz endpoint"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"node":          nodeName,
+		"kubeletConfig": configz.KubeletConfig,
+	})
+}
+
+const (
+	// defaultKubeletRestartTimeout bounds a kubelet restart with ?wait=true,
+	// from creating the restart pod until the kubelet has rejoined
+	defaultKubeletRestartTimeout = 2 * time.Minute
+	// kubeletRejoinPollInterval is how often the node is checked while
+	// waiting for the restarted kubelet
+	kubeletRejoinPollInterval = 2 * time.Second
+	// nodeLeaseNamespace holds the leases the kubelets renew as heartbeat
+	nodeLeaseNamespace = "kube-node-lease"
+)
+
+// Stages at which a kubelet restart with ?wait=true can fail
+const (
+	KubeletRestartStageSchedule = "schedule"
+	KubeletRestartStageCommand  = "command"
+	KubeletRestartStageRejoin   = "rejoin"
+)
+
+// restartKubeletAndWait runs the restart pod to completion and then waits
+// for the kubelet to renew its heartbeat and report the node Ready. The
+// response tells apart a restart pod that never started, a failing restart
+// command and a kubelet that didn't come back.
+func (h *NodeHandler) restartKubeletAndWait(c *gin.Context, nodeName string, restartPod *corev1.Pod, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	result, err := h.runHelperPod(ctx, restartPod, timeout)
+	if result == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create restart pod: " + err.Error()})
+		return
+	}
+	finished := result.Phase == corev1.PodSucceeded || result.Phase == corev1.PodFailed
+	if err != nil && !finished {
+		if result.Phase == corev1.PodRunning {
+			// The kubelet reports the pod's completion, so a pod that is
+			// still running usually means the kubelet didn't come back
+			c.JSON(http.StatusGatewayTimeout, gin.H{
+				"error":            fmt.Sprintf("restart pod on node %s never reported completion, the kubelet may not have come back: %v", nodeName, err),
+				"stage":            KubeletRestartStageRejoin,
+				"pod":              restartPod.Name,
+				"kubeletRestarted": false,
+				"nodeReady":        false,
+			})
+			return
+		}
+		c.JSON(http.StatusGatewayTimeout, gin.H{
+			"error":            fmt.Sprintf("restart pod on node %s did not start: %v", nodeName, err),
+			"stage":            KubeletRestartStageSchedule,
+			"pod":              restartPod.Name,
+			"phase":            result.Phase,
+			"reason":           result.Message,
+			"kubeletRestarted": false,
+			"nodeReady":        false,
+		})
+		return
+	}
+
+	response := gin.H{
+		"pod":      restartPod.Name,
+		"exitCode": result.ExitCode,
+		"logs":     result.Output,
+	}
+	if err != nil {
+		response["logsError"] = err.Error()
+	}
+	if result.Phase == corev1.PodFailed || result.ExitCode != 0 {
+		response["error"] = fmt.Sprintf("kubelet restart command failed on node %s: %s", nodeName, result.Message)
+		response["stage"] = KubeletRestartStageCommand
+		response["kubeletRestarted"] = false
+		response["nodeReady"] = false
+		c.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	restarted, ready := h.waitForKubeletRejoin(ctx, nodeName, time.Now())
+	response["kubeletRestarted"] = restarted
+	response["nodeReady"] = ready
+	if !restarted || !ready {
+		response["error"] = fmt.Sprintf("kubelet on node %s did not rejoin within %s", nodeName, timeout)
+		response["stage"] = KubeletRestartStageRejoin
+		c.JSON(http.StatusGatewayTimeout, response)
+		return
+	}
+	response["message"] = fmt.Sprintf("Kubelet restarted on node %s", nodeName)
+	c.JSON(http.StatusOK, response)
+}
+
+// waitForKubeletRejoin polls until the kubelet of a node has sent a
+// heartbeat after since and the node is Ready, or ctx is done. The node
+// lease is the primary heartbeat, the Ready condition's heartbeat is used
+// when the lease can't be read.
+func (h *NodeHandler) waitForKubeletRejoin(ctx context.Context, nodeName string, since time.Time) (restarted, ready bool) {
+	ticker := time.NewTicker(kubeletRejoinPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return restarted, ready
+		case <-ticker.C:
+		}
+
+		lease, err := h.K8sClient.ClientSet.CoordinationV1().Leases(nodeLeaseNamespace).Get(ctx, nodeName, metav1.GetOptions{})
+		if err == nil && lease.Spec.RenewTime != nil && lease.Spec.RenewTime.After(since) {
+			restarted = true
+		}
+
+		var node corev1.Node
+		if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Name: nodeName}, &node); err != nil {
+			continue
+		}
+		for _, condition := range node.Status.Conditions {
+			if condition.Type != corev1.NodeReady {
+				continue
+			}
+			ready = condition.Status == corev1.ConditionTrue
+			if condition.LastHeartbeatTime.After(since) {
+				restarted = true
+			}
+		}
+		if restarted && ready {
+			return restarted, ready
+		}
+	}
+}
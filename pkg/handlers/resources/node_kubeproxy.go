@@ -0,0 +1,206 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	kubeProxyNamespace = "kube-system"
+	kubeProxyName      = "kube-proxy"
+	// kubeProxyPollInterval is how often the replacement kube-proxy pod is
+	// checked while waiting for it to run
+	kubeProxyPollInterval = 2 * time.Second
+	// noKubeProxyMessage explains a missing kube-proxy, which usually means
+	// the CNI replaces it
+	noKubeProxyMessage = "cluster has no kube-proxy DaemonSet in kube-system, it may use a kube-proxy replacement such as Cilium"
+)
+
+// findKubeProxyDaemonSet returns the kube-proxy DaemonSet, or nil when the
+// cluster doesn't run kube-proxy. Distributions that rename the DaemonSet are
+// found by the usual kube-proxy labels.
+func (h *NodeHandler) findKubeProxyDaemonSet(ctx context.Context) (*appsv1.DaemonSet, error) {
+	var ds appsv1.DaemonSet
+	err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Namespace: kubeProxyNamespace, Name: kubeProxyName}, &ds)
+	if err == nil {
+		return &ds, nil
+	}
+	if !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	for _, labels := range []client.MatchingLabels{{"k8s-app": kubeProxyName}, {"component": kubeProxyName}} {
+		var dsList appsv1.DaemonSetList
+		if err := h.K8sClient.Client.List(ctx, &dsList, client.InNamespace(kubeProxyNamespace), labels); err != nil {
+			return nil, err
+		}
+		if len(dsList.Items) > 0 {
+			return &dsList.Items[0], nil
+		}
+	}
+	return nil, nil
+}
+
+// kubeProxyPodOnNode returns the pod of the kube-proxy DaemonSet running on
+// a node, or nil if there is none
+func (h *NodeHandler) kubeProxyPodOnNode(ctx context.Context, ds *appsv1.DaemonSet, nodeName string) (*corev1.Pod, error) {
+	pods, err := h.listNodePods(ctx, nodeName, ds.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pods {
+		owner := metav1.GetControllerOf(&pods[i])
+		if owner != nil && owner.UID == ds.UID {
+			return &pods[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// waitForKubeProxyPod waits until a kube-proxy pod other than oldUID is
+// running and ready on the node
+func (h *NodeHandler) waitForKubeProxyPod(ctx context.Context, ds *appsv1.DaemonSet, nodeName string, oldUID types.UID) (*corev1.Pod, error) {
+	ticker := time.NewTicker(kubeProxyPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("replacement kube-proxy pod did not become ready in time: %w", ctx.Err())
+		case <-ticker.C:
+			pod, err := h.kubeProxyPodOnNode(ctx, ds, nodeName)
+			if err != nil {
+				return nil, err
+			}
+			if pod != nil && pod.UID != oldUID && pod.DeletionTimestamp == nil && utils.IsPodReady(pod) {
+				return pod, nil
+			}
+		}
+	}
+}
+
+// RestartKubeProxy restarts the kube-proxy on a node by deleting its pod.
+// With ?wait=true the response is sent once the replacement pod is ready,
+// bounded by ?timeout= seconds.
+func (h *NodeHandler) RestartKubeProxy(c *gin.Context) {
+	nodeName := c.Param("name")
+	ctx := c.Request.Context()
+
+	wait := c.Query("wait") == "true"
+	timeout, err := helperPodTimeout(c.Query("timeout"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Verify node exists
+	var node corev1.Node
+	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Name: nodeName}, &node); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ds, err := h.findKubeProxyDaemonSet(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up kube-proxy: " + err.Error()})
+		return
+	}
+	if ds == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": noKubeProxyMessage, "kubeProxyMissing": true})
+		return
+	}
+
+	targetPod, err := h.kubeProxyPodOnNode(ctx, ds, nodeName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list kube-proxy pods: " + err.Error()})
+		return
+	}
+	if targetPod == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("kube-proxy pod not found on node %s", nodeName)})
+		return
+	}
+
+	// Delete the pod to trigger restart
+	if err := h.K8sClient.Client.Delete(ctx, targetPod); err != nil && !errors.IsNotFound(err) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete kube-proxy pod: " + err.Error()})
+		return
+	}
+
+	if !wait {
+		c.JSON(http.StatusOK, gin.H{
+			"message": fmt.Sprintf("kube-proxy restart initiated on node %s", nodeName),
+			"pod":     targetPod.Name,
+		})
+		return
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	newPod, err := h.waitForKubeProxyPod(waitCtx, ds, nodeName, targetPod.UID)
+	if err != nil {
+		c.JSON(http.StatusGatewayTimeout, gin.H{
+			"error": err.Error(),
+			"pod":   targetPod.Name,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("kube-proxy restarted on node %s", nodeName),
+		"pod":     targetPod.Name,
+		"newPod":  newPod.Name,
+	})
+}
+
+// RestartKubeProxyDaemonSet performs a rolling restart of kube-proxy on all
+// nodes by annotating the DaemonSet's pod template. The rollout follows the
+// DaemonSet's updateStrategy, so with OnDelete nothing restarts until the
+// pods are deleted.
+func (h *NodeHandler) RestartKubeProxyDaemonSet(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	ds, err := h.findKubeProxyDaemonSet(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up kube-proxy: " + err.Error()})
+		return
+	}
+	if ds == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": noKubeProxyMessage, "kubeProxyMissing": true})
+		return
+	}
+
+	restartedAt := time.Now().Format(time.RFC3339)
+	patch := client.MergeFrom(ds.DeepCopy())
+	if ds.Spec.Template.Annotations == nil {
+		ds.Spec.Template.Annotations = make(map[string]string)
+	}
+	ds.Spec.Template.Annotations[restartedAtAnnotation] = restartedAt
+	if err := h.K8sClient.Client.Patch(ctx, ds, patch); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restart kube-proxy: " + err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"message":        fmt.Sprintf("Rolling restart of %s/%s initiated", ds.Namespace, ds.Name),
+		"daemonSet":      ds.Name,
+		"updateStrategy": ds.Spec.UpdateStrategy.Type,
+		"restartedAt":    restartedAt,
+	}
+	if ds.Spec.UpdateStrategy.Type == appsv1.OnDeleteDaemonSetStrategyType {
+		response["warning"] = "kube-proxy uses the OnDelete update strategy, pods restart only when they are deleted"
+	}
+	c.JSON(http.StatusOK, response)
+}
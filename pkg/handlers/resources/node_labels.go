@@ -0,0 +1,148 @@
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// isReservedLabelKey reports whether a label key uses a kubernetes.io or
+// k8s.io prefix, which are managed by Kubernetes components
+func isReservedLabelKey(key string) bool {
+	prefix, _, found := strings.Cut(key, "/")
+	if !found {
+		return false
+	}
+	return prefix == "kubernetes.io" || prefix == "k8s.io" ||
+		strings.HasSuffix(prefix, ".kubernetes.io") || strings.HasSuffix(prefix, ".k8s.io")
+}
+
+// isProtectedNodeLabel reports whether removing a label would break
+// scheduling: the hostname and the topology labels
+func isProtectedNodeLabel(key string) bool {
+	if key == corev1.LabelHostname {
+		return true
+	}
+	prefix, _, _ := strings.Cut(key, "/")
+	return prefix == "topology.kubernetes.io" || prefix == "failure-domain.beta.kubernetes.io"
+}
+
+// GetNodeLabels returns the labels of a node split into reserved labels,
+// which use a kubernetes.io or k8s.io prefix, and user labels
+func (h *NodeHandler) GetNodeLabels(c *gin.Context) {
+	nodeName := c.Param("name")
+	ctx := c.Request.Context()
+
+	var node corev1.Node
+	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Name: nodeName}, &node); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	reserved := map[string]string{}
+	user := map[string]string{}
+	for key, value := range node.Labels {
+		if isReservedLabelKey(key) {
+			reserved[key] = value
+		} else {
+			user[key] = value
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"node":     nodeName,
+		"reserved": reserved,
+		"user":     user,
+	})
+}
+
+// UpdateNodeLabels adds and removes node labels with a merge patch, retried
+// on conflicts. Removing the hostname or topology labels requires
+// ?force=true.
+func (h *NodeHandler) UpdateNodeLabels(c *gin.Context) {
+	nodeName := c.Param("name")
+	ctx := c.Request.Context()
+
+	var labelRequest struct {
+		Add    map[string]string `json:"add"`
+		Remove []string          `json:"remove"`
+	}
+	if err := c.ShouldBindJSON(&labelRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if len(labelRequest.Add) == 0 && len(labelRequest.Remove) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one label to add or remove is required"})
+		return
+	}
+
+	force := c.Query("force") == "true"
+	labels := map[string]interface{}{}
+	for key, value := range labelRequest.Add {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid label key %q: %s", key, strings.Join(errs, "; "))})
+			return
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid value for label %q: %s", key, strings.Join(errs, "; "))})
+			return
+		}
+		labels[key] = value
+	}
+	for _, key := range labelRequest.Remove {
+		if _, ok := labelRequest.Add[key]; ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("label %q is both added and removed", key)})
+			return
+		}
+		if isProtectedNodeLabel(key) && !force {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("label %q is used for scheduling, pass force=true to remove it", key)})
+			return
+		}
+		// null removes the key in a JSON merge patch
+		labels[key] = nil
+	}
+
+	// Concurrent label edits conflict on the resourceVersion instead of the
+	// last writer winning
+	node := &corev1.Node{}
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Name: nodeName}, node); err != nil {
+			return err
+		}
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"resourceVersion": node.ResourceVersion,
+				"labels":          labels,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		return h.K8sClient.Client.Patch(ctx, node, client.RawPatch(types.MergePatchType, patch))
+	}); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update node labels: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("Labels of node %s updated successfully", nodeName),
+		"labels":  node.Labels,
+	})
+}
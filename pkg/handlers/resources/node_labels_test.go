@@ -0,0 +1,81 @@
+package resources
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/kube"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestUpdateNodeLabelsRetriesConflicts(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:   "worker-1",
+		Labels: map[string]string{"pool": "blue", "team": "a"},
+	}}
+	var patches []string
+	conflicted := false
+	k8sClient := fake.NewClientBuilder().WithObjects(node).WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			data, _ := patch.Data(obj)
+			patches = append(patches, string(data))
+			if !conflicted {
+				// Another writer labels the node first
+				conflicted = true
+				current := &corev1.Node{}
+				if err := c.Get(ctx, client.ObjectKeyFromObject(obj), current); err != nil {
+					return err
+				}
+				current.Labels["gpu"] = "true"
+				if err := c.Update(ctx, current); err != nil {
+					return err
+				}
+			}
+			return c.Patch(ctx, obj, patch, opts...)
+		},
+	}).Build()
+	handler := &NodeHandler{
+		GenericResourceHandler: &GenericResourceHandler[*corev1.Node, *corev1.NodeList]{
+			K8sClient: &kube.K8sClient{Client: k8sClient},
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/nodes/_all/worker-1/labels", strings.NewReader(`{"add": {"pool": "green"}, "remove": ["team"]}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "name", Value: "worker-1"}}
+	handler.UpdateNodeLabels(c)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body.String())
+	}
+	if len(patches) != 2 {
+		t.Fatalf("%d patches, want a conflict and a retry: %v", len(patches), patches)
+	}
+	for _, patch := range patches {
+		if !strings.Contains(patch, `"resourceVersion"`) {
+			t.Errorf("patch %s has no resourceVersion", patch)
+		}
+	}
+
+	var updated corev1.Node
+	if err := k8sClient.Get(context.Background(), types.NamespacedName{Name: "worker-1"}, &updated); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	want := map[string]string{"pool": "green", "gpu": "true"}
+	if !maps.Equal(updated.Labels, want) {
+		t.Errorf("labels = %v, want %v", updated.Labels, want)
+	}
+}
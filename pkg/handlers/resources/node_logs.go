@@ -0,0 +1,107 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+// GetNodeLogs streams kubelet, container runtime or log file output of a node
+// through the node log query API (/api/v1/nodes/:name/proxy/logs/). The
+// query parameter names a service such as kubelet or containerd, or a file
+// below /var/log. sinceTime, tailLines and pattern are passed on. Nodes
+// without the NodeLogQuery feature get a 501.
+func (h *NodeHandler) GetNodeLogs(c *gin.Context) {
+	nodeName := c.Param("name")
+	ctx := c.Request.Context()
+
+	query := c.DefaultQuery("query", "kubelet")
+	req := h.K8sClient.ClientSet.CoreV1().RESTClient().Get().
+		AbsPath(fmt.Sprintf("/api/v1/nodes/%s/proxy/logs/", nodeName)).
+		Param("query", query)
+
+	if sinceTime := c.Query("sinceTime"); sinceTime != "" {
+		if _, err := time.Parse(time.RFC3339, sinceTime); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sinceTime parameter, expected RFC3339"})
+			return
+		}
+		req = req.Param("sinceTime", sinceTime)
+	}
+	if tailLines := c.Query("tailLines"); tailLines != "" {
+		if n, err := strconv.Atoi(tailLines); err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tailLines parameter"})
+			return
+		}
+		req = req.Param("tailLines", tailLines)
+	}
+	if pattern := c.Query("pattern"); pattern != "" {
+		req = req.Param("pattern", pattern)
+	}
+
+	var node corev1.Node
+	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Name: nodeName}, &node); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Only connecting is bounded, the stream itself lasts as long as the
+	// client stays connected
+	connectCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	timer := time.AfterFunc(kubeletProxyTimeout, cancel)
+	stream, err := req.Stream(connectCtx)
+	timer.Stop()
+	if err != nil {
+		if errors.IsNotFound(err) || errors.IsMethodNotSupported(err) {
+			c.JSON(http.StatusNotImplemented, gin.H{
+				"error": "node log query not enabled",
+				"hint":  "enable the NodeLogQuery feature gate and enableSystemLogQuery in the kubelet configuration",
+			})
+			return
+		}
+		if connectCtx.Err() != nil && ctx.Err() == nil {
+			err = context.DeadlineExceeded
+		}
+		writeNodeProxyError(c, err)
+		return
+	}
+	defer stream.Close()
+
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(http.StatusOK)
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := stream.Read(buf)
+		if n > 0 {
+			if _, writeErr := c.Writer.Write(buf[:n]); writeErr != nil {
+				// Client disconnected
+				return
+			}
+			c.Writer.Flush()
+		}
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				klog.Warningf("Failed to stream logs of node %s: %v", nodeName, err)
+			}
+			return
+		}
+	}
+}
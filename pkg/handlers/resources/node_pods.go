@@ -0,0 +1,122 @@
+package resources
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NodePod summarizes a pod scheduled on a node
+type NodePod struct {
+	Name      string              `json:"name"`
+	Namespace string              `json:"namespace"`
+	Phase     corev1.PodPhase     `json:"phase"`
+	Restarts  int32               `json:"restarts"`
+	Requests  corev1.ResourceList `json:"requests"`
+	Limits    corev1.ResourceList `json:"limits"`
+	QOSClass  corev1.PodQOSClass  `json:"qosClass"`
+	OwnerKind string              `json:"ownerKind,omitempty"`
+	OwnerName string              `json:"ownerName,omitempty"`
+	CreatedAt metav1.Time         `json:"createdAt"`
+}
+
+// listNodePods lists the pods scheduled on a node, optionally restricted to
+// a namespace. It relies on the spec.nodeName field index of the cache.
+func (h *NodeHandler) listNodePods(ctx context.Context, nodeName, namespace string) ([]corev1.Pod, error) {
+	podList := &corev1.PodList{}
+	opts := []client.ListOption{client.MatchingFields{"spec.nodeName": nodeName}}
+	if namespace != "" {
+		opts = append(opts, client.InNamespace(namespace))
+	}
+	if err := h.K8sClient.Client.List(ctx, podList, opts...); err != nil {
+		return nil, err
+	}
+	return podList.Items, nil
+}
+
+// GetNodePods lists the pods scheduled on a node, sorted by CPU request in
+// descending order. ?phase= and ?namespace= filter the list.
+func (h *NodeHandler) GetNodePods(c *gin.Context) {
+	nodeName := c.Param("name")
+	ctx := c.Request.Context()
+
+	var node corev1.Node
+	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Name: nodeName}, &node); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	pods, err := h.listNodePods(ctx, nodeName, c.Query("namespace"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list pods: " + err.Error()})
+		return
+	}
+
+	phase := corev1.PodPhase(c.Query("phase"))
+	nodePods := make([]NodePod, 0, len(pods))
+	phases := map[corev1.PodPhase]int{}
+	totalRequests := corev1.ResourceList{}
+	totalLimits := corev1.ResourceList{}
+	for i := range pods {
+		pod := &pods[i]
+		if phase != "" && pod.Status.Phase != phase {
+			continue
+		}
+
+		requests, limits := utils.GetPodRequestsAndLimits(pod)
+		nodePod := NodePod{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+			Phase:     pod.Status.Phase,
+			Restarts:  utils.GetPodRestartCount(pod),
+			Requests:  requests,
+			Limits:    limits,
+			QOSClass:  pod.Status.QOSClass,
+			CreatedAt: pod.CreationTimestamp,
+		}
+		if owner := metav1.GetControllerOf(pod); owner != nil {
+			nodePod.OwnerKind = owner.Kind
+			nodePod.OwnerName = owner.Name
+		}
+		nodePods = append(nodePods, nodePod)
+
+		phases[pod.Status.Phase]++
+		if !utils.IsPodErrorOrSuccess(pod) {
+			utils.AddResourceList(totalRequests, requests)
+			utils.AddResourceList(totalLimits, limits)
+		}
+	}
+
+	sort.SliceStable(nodePods, func(i, j int) bool {
+		cpuI := nodePods[i].Requests[corev1.ResourceCPU]
+		cpuJ := nodePods[j].Requests[corev1.ResourceCPU]
+		if cmp := cpuI.Cmp(cpuJ); cmp != 0 {
+			return cmp > 0
+		}
+		if nodePods[i].Namespace != nodePods[j].Namespace {
+			return nodePods[i].Namespace < nodePods[j].Namespace
+		}
+		return nodePods[i].Name < nodePods[j].Name
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"node":          nodeName,
+		"pods":          nodePods,
+		"total":         len(nodePods),
+		"phases":        phases,
+		"totalRequests": totalRequests,
+		"totalLimits":   totalLimits,
+	})
+}
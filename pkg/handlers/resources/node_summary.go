@@ -0,0 +1,172 @@
+package resources
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// nodeSummaryConditions are the node conditions included in a summary
+var nodeSummaryConditions = []corev1.NodeConditionType{
+	corev1.NodeReady,
+	corev1.NodeMemoryPressure,
+	corev1.NodeDiskPressure,
+	corev1.NodePIDPressure,
+}
+
+// NodeSummaryCondition is the state of a node condition
+type NodeSummaryCondition struct {
+	Type               corev1.NodeConditionType `json:"type"`
+	Status             corev1.ConditionStatus   `json:"status"`
+	Reason             string                   `json:"reason,omitempty"`
+	Message            string                   `json:"message,omitempty"`
+	LastTransitionTime metav1.Time              `json:"lastTransitionTime"`
+	LastHeartbeatTime  metav1.Time              `json:"lastHeartbeatTime"`
+}
+
+// NodeSummary aggregates what the node list shows for a node
+type NodeSummary struct {
+	Name                    string                 `json:"name"`
+	Conditions              []NodeSummaryCondition `json:"conditions"`
+	KubeletVersion          string                 `json:"kubeletVersion"`
+	ContainerRuntimeVersion string                 `json:"containerRuntimeVersion"`
+	KernelVersion           string                 `json:"kernelVersion"`
+	OSImage                 string                 `json:"osImage"`
+	Architecture            string                 `json:"architecture"`
+	Capacity                corev1.ResourceList    `json:"capacity"`
+	Allocatable             corev1.ResourceList    `json:"allocatable"`
+	Taints                  []corev1.Taint         `json:"taints"`
+	Unschedulable           bool                   `json:"unschedulable"`
+	PodCount                int                    `json:"podCount"`
+	MaxPods                 int64                  `json:"maxPods"`
+	ImageCount              int                    `json:"imageCount"`
+	ImagesSizeBytes         int64                  `json:"imagesSizeBytes"`
+	CreatedAt               metav1.Time            `json:"createdAt"`
+	Age                     string                 `json:"age"`
+}
+
+// summarizeNode builds the summary of a node that runs podCount
+// non-terminated pods
+func summarizeNode(node *corev1.Node, podCount int) NodeSummary {
+	summary := NodeSummary{
+		Name:                    node.Name,
+		Conditions:              []NodeSummaryCondition{},
+		KubeletVersion:          node.Status.NodeInfo.KubeletVersion,
+		ContainerRuntimeVersion: node.Status.NodeInfo.ContainerRuntimeVersion,
+		KernelVersion:           node.Status.NodeInfo.KernelVersion,
+		OSImage:                 node.Status.NodeInfo.OSImage,
+		Architecture:            node.Status.NodeInfo.Architecture,
+		Capacity:                node.Status.Capacity,
+		Allocatable:             node.Status.Allocatable,
+		Taints:                  node.Spec.Taints,
+		Unschedulable:           node.Spec.Unschedulable,
+		PodCount:                podCount,
+		ImageCount:              len(node.Status.Images),
+		CreatedAt:               node.CreationTimestamp,
+		Age:                     time.Since(node.CreationTimestamp.Time).Round(time.Second).String(),
+	}
+	if summary.Taints == nil {
+		summary.Taints = []corev1.Taint{}
+	}
+	if maxPods, ok := node.Status.Allocatable[corev1.ResourcePods]; ok {
+		summary.MaxPods = maxPods.Value()
+	}
+	for _, image := range node.Status.Images {
+		summary.ImagesSizeBytes += image.SizeBytes
+	}
+	for _, conditionType := range nodeSummaryConditions {
+		for _, condition := range node.Status.Conditions {
+			if condition.Type != conditionType {
+				continue
+			}
+			summary.Conditions = append(summary.Conditions, NodeSummaryCondition{
+				Type:               condition.Type,
+				Status:             condition.Status,
+				Reason:             condition.Reason,
+				Message:            condition.Message,
+				LastTransitionTime: condition.LastTransitionTime,
+				LastHeartbeatTime:  condition.LastHeartbeatTime,
+			})
+			break
+		}
+	}
+	return summary
+}
+
+// countActivePods counts the pods that are neither succeeded nor failed
+func countActivePods(pods []corev1.Pod) int {
+	count := 0
+	for i := range pods {
+		if !utils.IsPodErrorOrSuccess(&pods[i]) {
+			count++
+		}
+	}
+	return count
+}
+
+// GetNodeSummary returns the conditions, versions, capacity, taints and pod
+// count of a node in a single document
+func (h *NodeHandler) GetNodeSummary(c *gin.Context) {
+	nodeName := c.Param("name")
+	ctx := c.Request.Context()
+
+	var node corev1.Node
+	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Name: nodeName}, &node); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	pods, err := h.listNodePods(ctx, nodeName, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list pods: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, summarizeNode(&node, countActivePods(pods)))
+}
+
+// ListNodeSummaries returns the summary of every node, sorted by name, with
+// one node and one pod list call
+func (h *NodeHandler) ListNodeSummaries(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var nodeList corev1.NodeList
+	if err := h.K8sClient.Client.List(ctx, &nodeList); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list nodes: " + err.Error()})
+		return
+	}
+	var podList corev1.PodList
+	if err := h.K8sClient.Client.List(ctx, &podList); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list pods: " + err.Error()})
+		return
+	}
+
+	podCounts := make(map[string]int, len(nodeList.Items))
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.Spec.NodeName == "" || utils.IsPodErrorOrSuccess(pod) {
+			continue
+		}
+		podCounts[pod.Spec.NodeName]++
+	}
+
+	summaries := make([]NodeSummary, 0, len(nodeList.Items))
+	for i := range nodeList.Items {
+		node := &nodeList.Items[i]
+		summaries = append(summaries, summarizeNode(node, podCounts[node.Name]))
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Name < summaries[j].Name
+	})
+	c.JSON(http.StatusOK, summaries)
+}
@@ -0,0 +1,184 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/retry"
+)
+
+// TaintDiff describes how a node's taints changed
+type TaintDiff struct {
+	Added   []corev1.Taint `json:"added"`
+	Removed []corev1.Taint `json:"removed"`
+	Kept    []corev1.Taint `json:"kept"`
+}
+
+// validateTaint checks a taint's key, value and effect
+func validateTaint(taint corev1.Taint) error {
+	if errs := validation.IsQualifiedName(taint.Key); len(errs) > 0 {
+		return fmt.Errorf("invalid taint key %q: %s", taint.Key, strings.Join(errs, "; "))
+	}
+	if errs := validation.IsValidLabelValue(taint.Value); len(errs) > 0 {
+		return fmt.Errorf("invalid value for taint %q: %s", taint.Key, strings.Join(errs, "; "))
+	}
+	switch taint.Effect {
+	case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+	default:
+		return fmt.Errorf("invalid effect %q for taint %q", taint.Effect, taint.Key)
+	}
+	return nil
+}
+
+// diffTaints computes the taints to set on a node with current taints so
+// that it ends up with desired. Taints are identified by key and effect like
+// kubectl does. Unchanged taints keep their TimeAdded, new NoExecute taints
+// get the current time.
+func diffTaints(current, desired []corev1.Taint) ([]corev1.Taint, TaintDiff) {
+	diff := TaintDiff{Added: []corev1.Taint{}, Removed: []corev1.Taint{}, Kept: []corev1.Taint{}}
+	result := make([]corev1.Taint, 0, len(desired))
+
+	for _, want := range desired {
+		var existing *corev1.Taint
+		for i := range current {
+			if current[i].MatchTaint(&want) {
+				existing = &current[i]
+				break
+			}
+		}
+		if existing != nil && existing.Value == want.Value {
+			result = append(result, *existing)
+			diff.Kept = append(diff.Kept, *existing)
+			continue
+		}
+		if want.Effect == corev1.TaintEffectNoExecute {
+			now := metav1.Now()
+			want.TimeAdded = &now
+		}
+		result = append(result, want)
+		diff.Added = append(diff.Added, want)
+	}
+
+	for i := range current {
+		kept := false
+		for _, taint := range diff.Kept {
+			if current[i].MatchTaint(&taint) {
+				kept = true
+				break
+			}
+		}
+		if !kept {
+			diff.Removed = append(diff.Removed, current[i])
+		}
+	}
+	return result, diff
+}
+
+// ReplaceNodeTaints replaces all taints of a node with the taints in the
+// request body in a single update
+func (h *NodeHandler) ReplaceNodeTaints(c *gin.Context) {
+	nodeName := c.Param("name")
+	ctx := c.Request.Context()
+
+	var taintRequest struct {
+		Taints []corev1.Taint `json:"taints"`
+	}
+	if err := c.ShouldBindJSON(&taintRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	seen := map[string]bool{}
+	for _, taint := range taintRequest.Taints {
+		if err := validateTaint(taint); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		id := taint.Key + ":" + string(taint.Effect)
+		if seen[id] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("duplicate taint %s", id)})
+			return
+		}
+		seen[id] = true
+	}
+
+	var diff TaintDiff
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var node corev1.Node
+		if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Name: nodeName}, &node); err != nil {
+			return err
+		}
+		node.Spec.Taints, diff = diffTaints(node.Spec.Taints, taintRequest.Taints)
+		return h.K8sClient.Client.Update(ctx, &node)
+	})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update node taints: " + err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"message": fmt.Sprintf("Taints of node %s updated successfully", nodeName),
+		"added":   diff.Added,
+		"removed": diff.Removed,
+		"kept":    diff.Kept,
+	}
+	if evicted := h.podsNotToleratingNoExecute(ctx, nodeName, diff.Added); len(evicted) > 0 {
+		response["warning"] = fmt.Sprintf("%d pods on the node do not tolerate the new NoExecute taints and will be evicted", len(evicted))
+		response["evictedPods"] = evicted
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// podsNotToleratingNoExecute returns the namespace/name of the running pods
+// on a node that don't tolerate one of the given NoExecute taints
+func (h *NodeHandler) podsNotToleratingNoExecute(ctx context.Context, nodeName string, taints []corev1.Taint) []string {
+	var noExecute []corev1.Taint
+	for _, taint := range taints {
+		if taint.Effect == corev1.TaintEffectNoExecute {
+			noExecute = append(noExecute, taint)
+		}
+	}
+	if len(noExecute) == 0 {
+		return nil
+	}
+
+	pods, err := h.listNodePods(ctx, nodeName, "")
+	if err != nil {
+		return nil
+	}
+	var result []string
+	for _, pod := range pods {
+		if utils.IsPodErrorOrSuccess(&pod) {
+			continue
+		}
+		for i := range noExecute {
+			if !toleratesTaint(pod.Spec.Tolerations, &noExecute[i]) {
+				result = append(result, pod.Namespace+"/"+pod.Name)
+				break
+			}
+		}
+	}
+	return result
+}
+
+func toleratesTaint(tolerations []corev1.Toleration, taint *corev1.Taint) bool {
+	for i := range tolerations {
+		if tolerations[i].ToleratesTaint(taint) {
+			return true
+		}
+	}
+	return false
+}
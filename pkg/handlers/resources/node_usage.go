@@ -0,0 +1,141 @@
+package resources
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// nodeUsageTimeout bounds the metrics-server requests of a usage request
+	nodeUsageTimeout = 10 * time.Second
+	// nodeUsageTopPods is how many pods are returned per top list
+	nodeUsageTopPods = 10
+)
+
+// PodUsage is the current CPU and memory usage of a pod
+type PodUsage struct {
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace"`
+	CPU       resource.Quantity `json:"cpu"`
+	Memory    resource.Quantity `json:"memory"`
+}
+
+// GetNodeUsage returns the current CPU and memory usage of a node from
+// metrics-server, together with its capacity and the pods using the most CPU
+// and memory. When metrics-server isn't available the response says so
+// instead of failing.
+func (h *NodeHandler) GetNodeUsage(c *gin.Context) {
+	nodeName := c.Param("name")
+	ctx, cancel := context.WithTimeout(c.Request.Context(), nodeUsageTimeout)
+	defer cancel()
+
+	var node corev1.Node
+	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Name: nodeName}, &node); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Node not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"node":        nodeName,
+		"capacity":    node.Status.Capacity,
+		"allocatable": node.Status.Allocatable,
+	}
+
+	if h.K8sClient.MetricsClient == nil {
+		response["available"] = false
+		response["reason"] = "metrics client not available"
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	nodeMetrics, err := h.K8sClient.MetricsClient.MetricsV1beta1().NodeMetricses().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		// metrics-server missing, not ready yet or not scraping this node
+		klog.V(2).Infof("Failed to get metrics of node %s: %v", nodeName, err)
+		response["available"] = false
+		response["reason"] = "metrics unavailable: " + err.Error()
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	cpu := nodeMetrics.Usage[corev1.ResourceCPU]
+	memory := nodeMetrics.Usage[corev1.ResourceMemory]
+	response["available"] = true
+	response["timestamp"] = nodeMetrics.Timestamp
+	response["window"] = nodeMetrics.Window.Duration.String()
+	response["usage"] = nodeMetrics.Usage
+	response["cpuPercent"] = quantityPercent(cpu, node.Status.Allocatable[corev1.ResourceCPU])
+	response["memoryPercent"] = quantityPercent(memory, node.Status.Allocatable[corev1.ResourceMemory])
+
+	podUsages, err := h.nodePodUsages(ctx, nodeName)
+	if err != nil {
+		klog.Warningf("Failed to get pod metrics on node %s: %v", nodeName, err)
+		response["podMetricsError"] = err.Error()
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	topCPU := append([]PodUsage{}, podUsages...)
+	sort.SliceStable(topCPU, func(i, j int) bool {
+		return topCPU[i].CPU.Cmp(topCPU[j].CPU) > 0
+	})
+	topMemory := append([]PodUsage{}, podUsages...)
+	sort.SliceStable(topMemory, func(i, j int) bool {
+		return topMemory[i].Memory.Cmp(topMemory[j].Memory) > 0
+	})
+	response["topPodsByCPU"] = topCPU[:min(nodeUsageTopPods, len(topCPU))]
+	response["topPodsByMemory"] = topMemory[:min(nodeUsageTopPods, len(topMemory))]
+
+	c.JSON(http.StatusOK, response)
+}
+
+// nodePodUsages returns the usage of the pods on a node. PodMetrics carry no
+// node name, so they are listed for the namespaces of the node's pods and
+// matched by name.
+func (h *NodeHandler) nodePodUsages(ctx context.Context, nodeName string) ([]PodUsage, error) {
+	pods, err := h.listNodePods(ctx, nodeName, "")
+	if err != nil {
+		return nil, err
+	}
+	onNode := make(map[string]map[string]bool)
+	for _, pod := range pods {
+		if onNode[pod.Namespace] == nil {
+			onNode[pod.Namespace] = make(map[string]bool)
+		}
+		onNode[pod.Namespace][pod.Name] = true
+	}
+
+	var usages []PodUsage
+	for namespace, names := range onNode {
+		podMetricsList, err := h.K8sClient.MetricsClient.MetricsV1beta1().PodMetricses(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, podMetrics := range podMetricsList.Items {
+			if !names[podMetrics.Name] {
+				continue
+			}
+			usage := PodUsage{Name: podMetrics.Name, Namespace: podMetrics.Namespace}
+			for _, container := range podMetrics.Containers {
+				usage.CPU.Add(container.Usage[corev1.ResourceCPU])
+				usage.Memory.Add(container.Usage[corev1.ResourceMemory])
+			}
+			usages = append(usages, usage)
+		}
+	}
+	return usages, nil
+}
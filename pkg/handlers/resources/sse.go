@@ -0,0 +1,48 @@
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sseHeartbeatInterval is how often a comment line is written to idle
+// streams so that proxies and load balancers keep the connection open
+const sseHeartbeatInterval = 15 * time.Second
+
+// startSSE writes the headers for a server-sent events stream and sends the
+// initial connected event
+func startSSE(c *gin.Context) error {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	return writeSSEEvent(c, "connected", gin.H{"status": "connected"})
+}
+
+// writeSSEEvent marshals data to JSON and writes it as a single SSE frame
+func writeSSEEvent(c *gin.Context, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return nil
+}
+
+// writeSSEHeartbeat writes an SSE comment line, which clients ignore
+func writeSSEHeartbeat(c *gin.Context) error {
+	if _, err := c.Writer.WriteString(": heartbeat\n\n"); err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return nil
+}
@@ -0,0 +1,341 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/kube"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type StatefulSetHandler struct {
+	*GenericResourceHandler[*appsv1.StatefulSet, *appsv1.StatefulSetList]
+}
+
+func NewStatefulSetHandler(client *kube.K8sClient) *StatefulSetHandler {
+	return &StatefulSetHandler{
+		GenericResourceHandler: NewGenericResourceHandler[*appsv1.StatefulSet, *appsv1.StatefulSetList](
+			client,
+			"statefulsets",
+			false, // StatefulSets are namespaced resources
+			false,
+		),
+	}
+}
+
+// serviceMonitorListGVK is the list kind of the Prometheus operator's
+// ServiceMonitor, which may not be installed in the cluster
+var serviceMonitorListGVK = schema.GroupVersionKind{
+	Group:   "monitoring.coreos.com",
+	Version: "v1",
+	Kind:    "ServiceMonitorList",
+}
+
+// Restart triggers a rolling restart of a statefulset by setting
+// restartedAtAnnotation on its pod template
+func (h *StatefulSetHandler) Restart(ctx context.Context, namespace, name string) error {
+	statefulSet := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	return restartPodTemplate(ctx, h.K8sClient, statefulSet)
+}
+
+func (h *StatefulSetHandler) RestartStatefulSet(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	if err := h.Restart(c.Request.Context(), namespace, name); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "StatefulSet not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restart statefulset: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "StatefulSet restarted successfully",
+	})
+}
+
+// ScaleStatefulSet sets the replicas of a statefulset through the scale
+// subresource
+func (h *StatefulSetHandler) ScaleStatefulSet(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	ctx := c.Request.Context()
+
+	var scaleRequest struct {
+		Replicas *int32 `json:"replicas" binding:"required,min=0"`
+	}
+	if err := c.ShouldBindJSON(&scaleRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if scaleRequest.Replicas == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "replicas field is required"})
+		return
+	}
+
+	statefulSets := h.K8sClient.ClientSet.AppsV1().StatefulSets(namespace)
+	current, err := statefulSets.GetScale(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "StatefulSet not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get statefulset scale: " + err.Error()})
+		return
+	}
+
+	scale := &autoscalingv1.Scale{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       autoscalingv1.ScaleSpec{Replicas: *scaleRequest.Replicas},
+	}
+	scale, err = statefulSets.UpdateScale(ctx, name, scale, metav1.UpdateOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "StatefulSet not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scale statefulset: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "StatefulSet scaled successfully",
+		"scale":            scale,
+		"replicas":         scale.Spec.Replicas,
+		"previousReplicas": current.Spec.Replicas,
+	})
+}
+
+// getStatefulSet fetches the statefulset named by the request, writing the
+// error response when it can't
+func (h *StatefulSetHandler) getStatefulSet(c *gin.Context) (*appsv1.StatefulSet, bool) {
+	var statefulSet appsv1.StatefulSet
+	if err := h.K8sClient.Client.Get(c.Request.Context(), types.NamespacedName{Namespace: c.Param("namespace"), Name: c.Param("name")}, &statefulSet); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "StatefulSet not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return &statefulSet, true
+}
+
+// ListStatefulSetRelatedResources lists resources related to a statefulset:
+// its pods ordered by ordinal, its headless service, the PVCs created from
+// its volumeClaimTemplates, and the PDBs and ServiceMonitors selecting it.
+// Like the deployment variant, a failure for one kind adds a warning.
+func (h *StatefulSetHandler) ListStatefulSetRelatedResources(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	ctx := c.Request.Context()
+
+	var statefulSet appsv1.StatefulSet
+	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &statefulSet); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "StatefulSet not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	warnings := []string{}
+	podLabels := labels.Set(statefulSet.Spec.Template.Labels)
+
+	pods, err := h.listStatefulSetPods(ctx, &statefulSet)
+	if err != nil {
+		warnings = append(warnings, "Failed to list pods: "+err.Error())
+		pods = []corev1.Pod{}
+	}
+
+	// The governing service named by spec.serviceName, and any other
+	// service selecting the pods so that ServiceMonitors can be matched
+	var headlessService *corev1.Service
+	services := []corev1.Service{}
+	var serviceList corev1.ServiceList
+	if err := h.K8sClient.Client.List(ctx, &serviceList, client.InNamespace(namespace)); err != nil {
+		warnings = append(warnings, "Failed to list services: "+err.Error())
+	} else {
+		for i, service := range serviceList.Items {
+			if service.Name == statefulSet.Spec.ServiceName {
+				headlessService = &serviceList.Items[i]
+			}
+			if service.Name == statefulSet.Spec.ServiceName ||
+				(len(service.Spec.Selector) > 0 && labels.SelectorFromSet(service.Spec.Selector).Matches(podLabels)) {
+				services = append(services, service)
+			}
+		}
+	}
+
+	pvcs := []corev1.PersistentVolumeClaim{}
+	if len(statefulSet.Spec.VolumeClaimTemplates) > 0 {
+		var pvcList corev1.PersistentVolumeClaimList
+		if err := h.K8sClient.Client.List(ctx, &pvcList, client.InNamespace(namespace)); err != nil {
+			warnings = append(warnings, "Failed to list PersistentVolumeClaims: "+err.Error())
+		} else {
+			for _, pvc := range pvcList.Items {
+				if _, _, ok := statefulSetPVCOrdinal(&statefulSet, pvc.Name); ok {
+					pvcs = append(pvcs, pvc)
+				}
+			}
+			sort.Slice(pvcs, func(i, j int) bool { return pvcs[i].Name < pvcs[j].Name })
+		}
+	}
+
+	pdbs := []policyv1.PodDisruptionBudget{}
+	var pdbList policyv1.PodDisruptionBudgetList
+	if err := h.K8sClient.Client.List(ctx, &pdbList, client.InNamespace(namespace)); err != nil {
+		warnings = append(warnings, "Failed to list PodDisruptionBudgets: "+err.Error())
+	} else {
+		for _, pdb := range pdbList.Items {
+			if pdb.Spec.Selector == nil {
+				continue
+			}
+			selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+			if err == nil && selector.Matches(podLabels) {
+				pdbs = append(pdbs, pdb)
+			}
+		}
+	}
+
+	serviceMonitors, err := h.listServiceMonitors(ctx, namespace, services)
+	if err != nil {
+		warnings = append(warnings, "Failed to list ServiceMonitors: "+err.Error())
+		serviceMonitors = []unstructured.Unstructured{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pods":                   pods,
+		"headlessService":        headlessService,
+		"services":               services,
+		"persistentVolumeClaims": pvcs,
+		"podDisruptionBudgets":   pdbs,
+		"serviceMonitors":        serviceMonitors,
+		"warnings":               warnings,
+	})
+}
+
+// listStatefulSetPods returns the pods controlled by a statefulset, ordered
+// by ordinal
+func (h *StatefulSetHandler) listStatefulSetPods(ctx context.Context, statefulSet *appsv1.StatefulSet) ([]corev1.Pod, error) {
+	selector, err := metav1.LabelSelectorAsSelector(statefulSet.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid statefulset selector: %w", err)
+	}
+	var podList corev1.PodList
+	if err := h.K8sClient.Client.List(ctx, &podList, client.InNamespace(statefulSet.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+
+	pods := []corev1.Pod{}
+	for _, pod := range podList.Items {
+		if owner := metav1.GetControllerOf(&pod); owner != nil && owner.UID == statefulSet.UID {
+			pods = append(pods, pod)
+		}
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		return statefulSetPodOrdinal(statefulSet.Name, pods[i].Name) < statefulSetPodOrdinal(statefulSet.Name, pods[j].Name)
+	})
+	return pods, nil
+}
+
+// statefulSetPodOrdinal returns the ordinal of a statefulset pod from its
+// <statefulset>-<ordinal> name, or -1
+func statefulSetPodOrdinal(statefulSetName, podName string) int {
+	suffix, ok := strings.CutPrefix(podName, statefulSetName+"-")
+	if !ok {
+		return -1
+	}
+	ordinal, err := strconv.Atoi(suffix)
+	if err != nil || ordinal < 0 {
+		return -1
+	}
+	return ordinal
+}
+
+// statefulSetPVCOrdinal reports whether a PVC name follows the
+// <template>-<statefulset>-<ordinal> naming of one of the statefulset's
+// volumeClaimTemplates, and returns the template and ordinal
+func statefulSetPVCOrdinal(statefulSet *appsv1.StatefulSet, pvcName string) (template string, ordinal int, ok bool) {
+	for _, claim := range statefulSet.Spec.VolumeClaimTemplates {
+		podName, found := strings.CutPrefix(pvcName, claim.Name+"-")
+		if !found {
+			continue
+		}
+		if n := statefulSetPodOrdinal(statefulSet.Name, podName); n >= 0 {
+			return claim.Name, n, true
+		}
+	}
+	return "", -1, false
+}
+
+// listServiceMonitors returns the ServiceMonitors in namespace whose
+// selector matches one of the services. Clusters without the Prometheus
+// operator have no ServiceMonitors rather than an error.
+func (h *StatefulSetHandler) listServiceMonitors(ctx context.Context, namespace string, services []corev1.Service) ([]unstructured.Unstructured, error) {
+	serviceMonitors := []unstructured.Unstructured{}
+	if len(services) == 0 {
+		return serviceMonitors, nil
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(serviceMonitorListGVK)
+	if err := h.K8sClient.Client.List(ctx, list, client.InNamespace(namespace)); err != nil {
+		if meta.IsNoMatchError(err) || errors.IsNotFound(err) {
+			return serviceMonitors, nil
+		}
+		return nil, err
+	}
+
+	for _, serviceMonitor := range list.Items {
+		raw, found, err := unstructured.NestedMap(serviceMonitor.Object, "spec", "selector")
+		if err != nil || !found {
+			continue
+		}
+		var labelSelector metav1.LabelSelector
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &labelSelector); err != nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(&labelSelector)
+		if err != nil {
+			continue
+		}
+		for _, service := range services {
+			if selector.Matches(labels.Set(service.Labels)) {
+				serviceMonitors = append(serviceMonitors, serviceMonitor)
+				break
+			}
+		}
+	}
+	return serviceMonitors, nil
+}
+
+func (h *StatefulSetHandler) registerCustomRoutes(group *gin.RouterGroup) {
+	group.GET("/:namespace/:name/related", h.ListStatefulSetRelatedResources)
+	group.POST("/:namespace/:name/scale", h.ScaleStatefulSet)
+	group.POST("/:namespace/:name/restart", h.RestartStatefulSet)
+	group.POST("/:namespace/:name/partition", h.UpdateStatefulSetPartition)
+	group.GET("/:namespace/:name/rollout-status", h.GetStatefulSetRolloutStatus)
+	group.GET("/:namespace/:name/pvcs", h.ListStatefulSetPVCs)
+	group.POST("/:namespace/:name/pvcs/cleanup", h.CleanupStatefulSetPVCs)
+}
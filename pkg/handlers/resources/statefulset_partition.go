@@ -0,0 +1,297 @@
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zxh326/kite/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// defaultPartitionWaitTimeout bounds the wait for the pods updated by a
+	// partition change
+	defaultPartitionWaitTimeout = 5 * time.Minute
+	// maxPartitionWaitTimeout bounds the timeoutSeconds of a partition change
+	maxPartitionWaitTimeout = 30 * time.Minute
+	// partitionPollInterval is how often the pods are checked while waiting
+	partitionPollInterval = 2 * time.Second
+)
+
+// StatefulSetPartitionRequest sets the rollingUpdate partition of a
+// statefulset, either to Partition or, with Advance, to the current
+// partition minus Advance. Wait waits for the pods below the old partition
+// that the change releases to be updated and Ready.
+type StatefulSetPartitionRequest struct {
+	Partition      *int32 `json:"partition,omitempty" binding:"omitempty,min=0"`
+	Advance        *int32 `json:"advance,omitempty" binding:"omitempty,min=1"`
+	Wait           bool   `json:"wait,omitempty"`
+	TimeoutSeconds int    `json:"timeoutSeconds,omitempty" binding:"min=0"`
+}
+
+// StatefulSetPodRevision is the revision a statefulset pod runs
+type StatefulSetPodRevision struct {
+	Name     string `json:"name"`
+	Ordinal  int    `json:"ordinal"`
+	Revision string `json:"revision"`
+	Updated  bool   `json:"updated"`
+	Ready    bool   `json:"ready"`
+}
+
+// StatefulSetRolloutStatus reports a partitioned rollout. Pods with an
+// ordinal at or above Partition are updated to UpdateRevision,
+// BoundaryPod is the lowest of them.
+type StatefulSetRolloutStatus struct {
+	UpdateStrategy   appsv1.StatefulSetUpdateStrategyType `json:"updateStrategy"`
+	Replicas         int32                                `json:"replicas"`
+	Partition        int32                                `json:"partition"`
+	CurrentRevision  string                               `json:"currentRevision"`
+	UpdateRevision   string                               `json:"updateRevision"`
+	CurrentRevisions int                                  `json:"currentRevisionPods"`
+	UpdatedRevisions int                                  `json:"updateRevisionPods"`
+	OtherRevisions   int                                  `json:"otherRevisionPods"`
+	BoundaryPod      string                               `json:"boundaryPod,omitempty"`
+	Complete         bool                                 `json:"complete"`
+	Pods             []StatefulSetPodRevision             `json:"pods"`
+}
+
+// statefulSetPartition returns the rollingUpdate partition of a statefulset,
+// 0 when it isn't set
+func statefulSetPartition(statefulSet *appsv1.StatefulSet) int32 {
+	if rollingUpdate := statefulSet.Spec.UpdateStrategy.RollingUpdate; rollingUpdate != nil && rollingUpdate.Partition != nil {
+		return *rollingUpdate.Partition
+	}
+	return 0
+}
+
+// statefulSetRolloutStatus counts the pods on each revision of a statefulset
+func statefulSetRolloutStatus(statefulSet *appsv1.StatefulSet, pods []corev1.Pod) StatefulSetRolloutStatus {
+	replicas := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		replicas = *statefulSet.Spec.Replicas
+	}
+	updateStrategy := statefulSet.Spec.UpdateStrategy.Type
+	if updateStrategy == "" {
+		updateStrategy = appsv1.RollingUpdateStatefulSetStrategyType
+	}
+
+	status := StatefulSetRolloutStatus{
+		UpdateStrategy:  updateStrategy,
+		Replicas:        replicas,
+		Partition:       statefulSetPartition(statefulSet),
+		CurrentRevision: statefulSet.Status.CurrentRevision,
+		UpdateRevision:  statefulSet.Status.UpdateRevision,
+		Pods:            []StatefulSetPodRevision{},
+	}
+	if status.Partition < replicas {
+		status.BoundaryPod = fmt.Sprintf("%s-%d", statefulSet.Name, status.Partition)
+	}
+
+	for i := range pods {
+		revision := pods[i].Labels[appsv1.StatefulSetRevisionLabel]
+		pod := StatefulSetPodRevision{
+			Name:     pods[i].Name,
+			Ordinal:  statefulSetPodOrdinal(statefulSet.Name, pods[i].Name),
+			Revision: revision,
+			Updated:  revision == status.UpdateRevision,
+			Ready:    utils.IsPodReady(&pods[i]),
+		}
+		switch {
+		case pod.Updated:
+			status.UpdatedRevisions++
+		case revision == status.CurrentRevision:
+			status.CurrentRevisions++
+		default:
+			status.OtherRevisions++
+		}
+		status.Pods = append(status.Pods, pod)
+	}
+	status.Complete = statefulSet.Status.ObservedGeneration >= statefulSet.Generation &&
+		status.UpdatedRevisions == int(replicas) && status.CurrentRevision == status.UpdateRevision
+	return status
+}
+
+// GetStatefulSetRolloutStatus reports how far a partitioned rollout of a
+// statefulset has progressed
+func (h *StatefulSetHandler) GetStatefulSetRolloutStatus(c *gin.Context) {
+	statefulSet, ok := h.getStatefulSet(c)
+	if !ok {
+		return
+	}
+	pods, err := h.listStatefulSetPods(c.Request.Context(), statefulSet)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list pods: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, statefulSetRolloutStatus(statefulSet, pods))
+}
+
+// UpdateStatefulSetPartition sets the rollingUpdate partition of a
+// statefulset. The patch carries the resourceVersion that was validated, so
+// two concurrent advances can't both decrement from the same partition.
+func (h *StatefulSetHandler) UpdateStatefulSetPartition(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	ctx := c.Request.Context()
+
+	var req StatefulSetPartitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if (req.Partition == nil) == (req.Advance == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "exactly one of partition or advance is required"})
+		return
+	}
+	timeout := defaultPartitionWaitTimeout
+	if req.TimeoutSeconds > 0 {
+		timeout = min(time.Duration(req.TimeoutSeconds)*time.Second, maxPartitionWaitTimeout)
+	}
+
+	var statefulSet appsv1.StatefulSet
+	if err := h.K8sClient.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &statefulSet); err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "StatefulSet not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if statefulSet.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "StatefulSet uses the OnDelete update strategy, partition only applies to RollingUpdate"})
+		return
+	}
+
+	replicas := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		replicas = *statefulSet.Spec.Replicas
+	}
+	previous := statefulSetPartition(&statefulSet)
+	var partition int32
+	if req.Partition != nil {
+		partition = *req.Partition
+	} else {
+		partition = max(previous-*req.Advance, 0)
+	}
+	if partition > replicas {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("partition must be between 0 and replicas (%d)", replicas)})
+		return
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"resourceVersion": statefulSet.ResourceVersion,
+		},
+		"spec": map[string]interface{}{
+			"updateStrategy": map[string]interface{}{
+				"type": appsv1.RollingUpdateStatefulSetStrategyType,
+				"rollingUpdate": map[string]interface{}{
+					"partition": partition,
+				},
+			},
+		},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.K8sClient.Client.Patch(ctx, &statefulSet, client.RawPatch(types.MergePatchType, patch)); err != nil {
+		switch {
+		case errors.IsNotFound(err):
+			c.JSON(http.StatusNotFound, gin.H{"error": "StatefulSet not found"})
+		case errors.IsConflict(err):
+			c.JSON(http.StatusConflict, gin.H{"error": "StatefulSet was modified concurrently, retry with the current partition"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update partition: " + err.Error()})
+		}
+		return
+	}
+
+	response := gin.H{
+		"message":           "Partition updated successfully",
+		"partition":         partition,
+		"previousPartition": previous,
+	}
+	if !req.Wait || partition >= previous {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	status, err := h.waitForPartitionReady(ctx, &statefulSet, partition, previous, timeout)
+	if status != nil {
+		response["rolloutStatus"] = status
+	}
+	if err != nil {
+		response["error"] = err.Error()
+		c.JSON(http.StatusGatewayTimeout, response)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// waitForPartitionReady polls until the pods with ordinals in [from, to)
+// run the update revision and are Ready. On timeout the error names the
+// pods that aren't.
+func (h *StatefulSetHandler) waitForPartitionReady(ctx context.Context, statefulSet *appsv1.StatefulSet, from, to int32, timeout time.Duration) (*StatefulSetRolloutStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(partitionPollInterval)
+	defer ticker.Stop()
+
+	var status *StatefulSetRolloutStatus
+	pending := []string{"statefulset status not observed yet"}
+	for {
+		select {
+		case <-ctx.Done():
+			return status, fmt.Errorf("not ready after %s: %s", timeout, strings.Join(pending, ", "))
+		case <-ticker.C:
+		}
+
+		var current appsv1.StatefulSet
+		if err := h.K8sClient.Client.Get(ctx, client.ObjectKeyFromObject(statefulSet), &current); err != nil {
+			if errors.IsNotFound(err) {
+				return status, fmt.Errorf("statefulset was deleted")
+			}
+			continue
+		}
+		if current.Status.ObservedGeneration < current.Generation {
+			continue
+		}
+		pods, err := h.listStatefulSetPods(ctx, &current)
+		if err != nil {
+			continue
+		}
+		rollout := statefulSetRolloutStatus(&current, pods)
+		status = &rollout
+
+		found := map[int]StatefulSetPodRevision{}
+		for _, pod := range rollout.Pods {
+			found[pod.Ordinal] = pod
+		}
+		pending = pending[:0]
+		for ordinal := int(from); ordinal < int(to) && ordinal < int(rollout.Replicas); ordinal++ {
+			pod, ok := found[ordinal]
+			switch {
+			case !ok:
+				pending = append(pending, fmt.Sprintf("%s-%d missing", current.Name, ordinal))
+			case !pod.Updated:
+				pending = append(pending, pod.Name+" not updated")
+			case !pod.Ready:
+				pending = append(pending, pod.Name+" not ready")
+			}
+		}
+		if len(pending) == 0 {
+			return status, nil
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package resources
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// StatefulSetPVC is a PVC created from a volumeClaimTemplate of a
+// statefulset. Orphaned is set when its ordinal is at or above the current
+// replicas, which is what a scale-down leaves behind.
+type StatefulSetPVC struct {
+	Name         string                              `json:"name"`
+	Template     string                              `json:"template"`
+	Ordinal      int                                 `json:"ordinal"`
+	Orphaned     bool                                `json:"orphaned"`
+	Phase        corev1.PersistentVolumeClaimPhase   `json:"phase"`
+	Size         string                              `json:"size,omitempty"`
+	StorageClass string                              `json:"storageClass,omitempty"`
+	VolumeName   string                              `json:"volumeName,omitempty"`
+	CreatedAt    metav1.Time                         `json:"createdAt"`
+	Age          string                              `json:"age"`
+	AccessModes  []corev1.PersistentVolumeAccessMode `json:"accessModes,omitempty"`
+	Terminating  bool                                `json:"terminating,omitempty"`
+}
+
+// StatefulSetPVCCleanupRequest confirms the deletion of the orphaned PVCs
+// of a statefulset. Names restricts the cleanup to some of them.
+type StatefulSetPVCCleanupRequest struct {
+	Confirm bool     `json:"confirm"`
+	Names   []string `json:"names,omitempty"`
+}
+
+// StatefulSetPVCCleanupResult is the result of deleting one PVC
+type StatefulSetPVCCleanupResult struct {
+	Name    string `json:"name"`
+	Deleted bool   `json:"deleted"`
+	Skipped bool   `json:"skipped,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// listStatefulSetPVCs returns the PVCs following the
+// <template>-<statefulset>-<ordinal> naming of the volumeClaimTemplates of a
+// statefulset, ordered by template and ordinal
+func (h *StatefulSetHandler) listStatefulSetPVCs(ctx context.Context, statefulSet *appsv1.StatefulSet) ([]StatefulSetPVC, error) {
+	pvcs := []StatefulSetPVC{}
+	if len(statefulSet.Spec.VolumeClaimTemplates) == 0 {
+		return pvcs, nil
+	}
+
+	var pvcList corev1.PersistentVolumeClaimList
+	if err := h.K8sClient.Client.List(ctx, &pvcList, client.InNamespace(statefulSet.Namespace)); err != nil {
+		return nil, err
+	}
+
+	replicas := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		replicas = *statefulSet.Spec.Replicas
+	}
+	now := time.Now()
+	for _, pvc := range pvcList.Items {
+		template, ordinal, ok := statefulSetPVCOrdinal(statefulSet, pvc.Name)
+		if !ok {
+			continue
+		}
+		item := StatefulSetPVC{
+			Name:        pvc.Name,
+			Template:    template,
+			Ordinal:     ordinal,
+			Orphaned:    ordinal >= int(replicas),
+			Phase:       pvc.Status.Phase,
+			VolumeName:  pvc.Spec.VolumeName,
+			CreatedAt:   pvc.CreationTimestamp,
+			Age:         now.Sub(pvc.CreationTimestamp.Time).Round(time.Second).String(),
+			AccessModes: pvc.Spec.AccessModes,
+			Terminating: pvc.DeletionTimestamp != nil,
+		}
+		if size, ok := pvc.Status.Capacity[corev1.ResourceStorage]; ok {
+			item.Size = size.String()
+		} else if size, ok := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; ok {
+			item.Size = size.String()
+		}
+		if pvc.Spec.StorageClassName != nil {
+			item.StorageClass = *pvc.Spec.StorageClassName
+		}
+		pvcs = append(pvcs, item)
+	}
+	sort.Slice(pvcs, func(i, j int) bool {
+		if pvcs[i].Template != pvcs[j].Template {
+			return pvcs[i].Template < pvcs[j].Template
+		}
+		return pvcs[i].Ordinal < pvcs[j].Ordinal
+	})
+	return pvcs, nil
+}
+
+// retainsScaledPVCs reports whether the statefulset controller leaves the
+// PVCs of scaled-down ordinals alone. With a WhenScaled policy of Delete the
+// controller deletes them itself.
+func retainsScaledPVCs(statefulSet *appsv1.StatefulSet) bool {
+	policy := statefulSet.Spec.PersistentVolumeClaimRetentionPolicy
+	return policy == nil || policy.WhenScaled != appsv1.DeletePersistentVolumeClaimRetentionPolicyType
+}
+
+// ListStatefulSetPVCs lists the PVCs created from the volumeClaimTemplates
+// of a statefulset and flags the ones of ordinals beyond the current
+// replicas
+func (h *StatefulSetHandler) ListStatefulSetPVCs(c *gin.Context) {
+	statefulSet, ok := h.getStatefulSet(c)
+	if !ok {
+		return
+	}
+	pvcs, err := h.listStatefulSetPVCs(c.Request.Context(), statefulSet)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list PersistentVolumeClaims: " + err.Error()})
+		return
+	}
+
+	orphaned := 0
+	for _, pvc := range pvcs {
+		if pvc.Orphaned {
+			orphaned++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"pvcs":                    pvcs,
+		"orphaned":                orphaned,
+		"retentionPolicy":         statefulSet.Spec.PersistentVolumeClaimRetentionPolicy,
+		"controllerDeletesScaled": !retainsScaledPVCs(statefulSet),
+	})
+}
+
+// CleanupStatefulSetPVCs deletes the orphaned PVCs of a statefulset. PVCs
+// still mounted by a pod of their ordinal, and all of them when the
+// retention policy already has the controller delete them, are skipped.
+func (h *StatefulSetHandler) CleanupStatefulSetPVCs(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req StatefulSetPVCCleanupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if !req.Confirm {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "confirm must be true to delete PersistentVolumeClaims"})
+		return
+	}
+
+	statefulSet, ok := h.getStatefulSet(c)
+	if !ok {
+		return
+	}
+	pvcs, err := h.listStatefulSetPVCs(ctx, statefulSet)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list PersistentVolumeClaims: " + err.Error()})
+		return
+	}
+
+	// A scale-down in progress still has pods above the new replicas
+	pods, err := h.listStatefulSetPods(ctx, statefulSet)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list pods: " + err.Error()})
+		return
+	}
+	running := map[int]bool{}
+	for _, pod := range pods {
+		running[statefulSetPodOrdinal(statefulSet.Name, pod.Name)] = true
+	}
+
+	var selected map[string]bool
+	if len(req.Names) > 0 {
+		selected = make(map[string]bool, len(req.Names))
+		for _, name := range req.Names {
+			selected[name] = true
+		}
+	}
+
+	retained := retainsScaledPVCs(statefulSet)
+	results := []StatefulSetPVCCleanupResult{}
+	failed := false
+	for _, pvc := range pvcs {
+		if !pvc.Orphaned || (selected != nil && !selected[pvc.Name]) {
+			continue
+		}
+		result := StatefulSetPVCCleanupResult{Name: pvc.Name}
+		switch {
+		case !retained:
+			result.Skipped = true
+			result.Reason = "persistentVolumeClaimRetentionPolicy.whenScaled is Delete, the StatefulSet controller deletes it"
+		case pvc.Terminating:
+			result.Skipped = true
+			result.Reason = "already terminating"
+		case running[pvc.Ordinal]:
+			result.Skipped = true
+			result.Reason = "pod of this ordinal still exists"
+		default:
+			claim := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: statefulSet.Namespace, Name: pvc.Name}}
+			if err := h.K8sClient.Client.Delete(ctx, claim); err != nil && !errors.IsNotFound(err) {
+				result.Error = err.Error()
+				failed = true
+			} else {
+				result.Deleted = true
+				klog.Infof("Deleted orphaned PVC %s/%s of statefulset %s", statefulSet.Namespace, pvc.Name, statefulSet.Name)
+			}
+		}
+		results = append(results, result)
+	}
+	for _, name := range req.Names {
+		if !slices.ContainsFunc(results, func(result StatefulSetPVCCleanupResult) bool { return result.Name == name }) {
+			results = append(results, StatefulSetPVCCleanupResult{Name: name, Skipped: true, Reason: "not an orphaned PVC of this StatefulSet"})
+		}
+	}
+
+	status := http.StatusOK
+	if failed {
+		status = http.StatusPartialContent
+	}
+	c.JSON(status, gin.H{"results": results})
+}
@@ -5,12 +5,16 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/rest"
 	toolscache "k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
@@ -26,12 +30,19 @@ import (
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 )
 
+// crdCacheSyncTimeout bounds how long startup waits for the CRD cache, an
+// unreachable apiserver or missing RBAC must not keep kite from serving
+const crdCacheSyncTimeout = 30 * time.Second
+
 // K8sClient holds the Kubernetes client instances
 type K8sClient struct {
-	Client        client.Client
-	ClientSet     *kubernetes.Clientset
-	Configuration *rest.Config
-	MetricsClient *metricsclient.Clientset
+	Client         client.Client
+	ClientSet      kubernetes.Interface
+	DynamicClient  dynamic.Interface
+	MetadataClient metadata.Interface
+	Configuration  *rest.Config
+	MetricsClient  *metricsclient.Clientset
+	CRDCache       *CRDCache
 }
 
 func init() {
@@ -71,6 +82,28 @@ func NewK8sClient() (*K8sClient, error) {
 		return nil, err
 	}
 
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	metadataClient, err := metadata.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	apiextensionsClient, err := apiextensionsclientset.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	crdCache, err := NewCRDCache(apiextensionsClient, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := crdCache.Start(context.Background(), crdCacheSyncTimeout); err != nil {
+		klog.Warningf("Serving CRD lookups from the apiserver until the cache syncs: %v", err)
+	}
+
 	metricsClient, err := metricsclient.NewForConfig(config)
 	if err != nil {
 		klog.Warningf("failed to create metrics client: %v", err)
@@ -129,9 +162,12 @@ func NewK8sClient() (*K8sClient, error) {
 	}
 
 	return &K8sClient{
-		Client:        c,
-		ClientSet:     clientset,
-		Configuration: config,
-		MetricsClient: metricsClient,
+		Client:         c,
+		ClientSet:      clientset,
+		DynamicClient:  dynamicClient,
+		MetadataClient: metadataClient,
+		Configuration:  config,
+		MetricsClient:  metricsClient,
+		CRDCache:       crdCache,
 	}, nil
 }
@@ -0,0 +1,212 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apiextensionsinformers "k8s.io/apiextensions-apiserver/pkg/client/informers/externalversions"
+	apiextensionslisters "k8s.io/apiextensions-apiserver/pkg/client/listers/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// crdNamesIndex indexes CRDs by their lower-cased plural, singular, kind and
+// short names
+const crdNamesIndex = "names"
+
+// AmbiguousCRDError is returned when a short CRD name matches CRDs in more
+// than one group
+type AmbiguousCRDError struct {
+	Name       string
+	Candidates []string
+}
+
+func (e *AmbiguousCRDError) Error() string {
+	return fmt.Sprintf("%q matches multiple CustomResourceDefinitions: %s", e.Name, strings.Join(e.Candidates, ", "))
+}
+
+// CRDCache serves CustomResourceDefinitions from a shared informer so that
+// custom resource requests don't fetch the CRD from the apiserver every time.
+// The informer keeps the cache current when CRDs are updated or deleted.
+// Objects returned by the cache are shared and must be treated as read-only.
+type CRDCache struct {
+	client   apiextensionsclientset.Interface
+	factory  apiextensionsinformers.SharedInformerFactory
+	informer cache.SharedIndexInformer
+	lister   apiextensionslisters.CustomResourceDefinitionLister
+}
+
+// NewCRDCache creates a CRDCache backed by the given clientset. Call Start
+// to begin watching CRDs.
+func NewCRDCache(client apiextensionsclientset.Interface, resync time.Duration) (*CRDCache, error) {
+	factory := apiextensionsinformers.NewSharedInformerFactory(client, resync)
+	crdInformer := factory.Apiextensions().V1().CustomResourceDefinitions()
+	informer := crdInformer.Informer()
+	if err := informer.AddIndexers(cache.Indexers{crdNamesIndex: indexCRDNames}); err != nil {
+		return nil, fmt.Errorf("failed to add CRD name indexer: %w", err)
+	}
+
+	return &CRDCache{
+		client:   client,
+		factory:  factory,
+		informer: informer,
+		lister:   crdInformer.Lister(),
+	}, nil
+}
+
+// Start starts the informer, which runs until ctx is done, and waits up to
+// syncTimeout for the initial sync. When the sync doesn't finish in time an
+// error is returned, the informer keeps syncing in the background and
+// lookups go to the apiserver until it has.
+func (c *CRDCache) Start(ctx context.Context, syncTimeout time.Duration) error {
+	c.factory.Start(ctx.Done())
+	syncCtx, cancel := context.WithTimeout(ctx, syncTimeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(syncCtx.Done(), c.informer.HasSynced) {
+		return fmt.Errorf("CRD cache did not sync within %s", syncTimeout)
+	}
+	return nil
+}
+
+// synced reports whether the informer has completed its initial list
+func (c *CRDCache) synced() bool {
+	return c.informer.HasSynced()
+}
+
+// Get returns the CRD with the given full name. A cache miss falls through to
+// a live Get so that CRDs created moments ago are found too, and so does
+// every lookup until the cache has synced.
+func (c *CRDCache) Get(ctx context.Context, name string) (*apiextensionsv1.CustomResourceDefinition, error) {
+	if c.synced() {
+		crd, err := c.lister.Get(name)
+		if err == nil {
+			return crd, nil
+		}
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+	return c.client.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, name, metav1.GetOptions{})
+}
+
+// List returns all cached CRDs
+func (c *CRDCache) List() ([]*apiextensionsv1.CustomResourceDefinition, error) {
+	return c.lister.List(labels.Everything())
+}
+
+// Lookup returns the sorted full names of the CRDs whose plural, singular,
+// kind or short names match alias case-insensitively
+func (c *CRDCache) Lookup(alias string) ([]string, error) {
+	objs, err := c.informer.GetIndexer().ByIndex(crdNamesIndex, strings.ToLower(alias))
+	if err != nil {
+		return nil, err
+	}
+	return crdNames(objs), nil
+}
+
+// Resolve returns the CRD named by its full name or, failing that, by its
+// plural, singular, kind or short name. An *AmbiguousCRDError is returned
+// when the short form matches CRDs in several groups. Once the cache has
+// synced, only a full name that is in neither index goes to the apiserver.
+func (c *CRDCache) Resolve(ctx context.Context, name string) (*apiextensionsv1.CustomResourceDefinition, error) {
+	if !c.synced() {
+		return c.resolveLive(ctx, name)
+	}
+
+	crd, err := c.lister.Get(name)
+	if err == nil {
+		return crd, nil
+	}
+	if !errors.IsNotFound(err) {
+		return nil, err
+	}
+	candidates, err := c.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	switch len(candidates) {
+	case 0:
+		// Full names are <plural>.<group>, a CRD created since the last
+		// watch event may still be missing from the cache
+		if strings.Contains(name, ".") {
+			return c.client.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, name, metav1.GetOptions{})
+		}
+		return nil, errors.NewNotFound(apiextensionsv1.Resource("customresourcedefinitions"), name)
+	case 1:
+		return c.lister.Get(candidates[0])
+	default:
+		return nil, &AmbiguousCRDError{Name: name, Candidates: candidates}
+	}
+}
+
+// resolveLive resolves name like Resolve against the apiserver, for when the
+// cache hasn't synced
+func (c *CRDCache) resolveLive(ctx context.Context, name string) (*apiextensionsv1.CustomResourceDefinition, error) {
+	crds := c.client.ApiextensionsV1().CustomResourceDefinitions()
+	crd, err := crds.Get(ctx, name, metav1.GetOptions{})
+	if err == nil || !errors.IsNotFound(err) {
+		return crd, err
+	}
+
+	list, listErr := crds.List(ctx, metav1.ListOptions{})
+	if listErr != nil {
+		return nil, listErr
+	}
+	alias := strings.ToLower(name)
+	var matches []interface{}
+	for i := range list.Items {
+		keys, _ := indexCRDNames(&list.Items[i])
+		if slices.Contains(keys, alias) {
+			matches = append(matches, &list.Items[i])
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, err
+	case 1:
+		return matches[0].(*apiextensionsv1.CustomResourceDefinition), nil
+	default:
+		return nil, &AmbiguousCRDError{Name: name, Candidates: crdNames(matches)}
+	}
+}
+
+// crdNames returns the sorted names of the CRDs in objs
+func crdNames(objs []interface{}) []string {
+	names := make([]string, 0, len(objs))
+	for _, obj := range objs {
+		if crd, ok := obj.(*apiextensionsv1.CustomResourceDefinition); ok {
+			names = append(names, crd.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func indexCRDNames(obj interface{}) ([]string, error) {
+	crd, ok := obj.(*apiextensionsv1.CustomResourceDefinition)
+	if !ok {
+		return nil, nil
+	}
+	names := []string{crd.Spec.Names.Plural, crd.Spec.Names.Singular, crd.Spec.Names.Kind}
+	names = append(names, crd.Spec.Names.ShortNames...)
+
+	keys := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.ToLower(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		keys = append(keys, name)
+	}
+	return keys, nil
+}
@@ -0,0 +1,174 @@
+package kube
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func testCRD(plural, group, kind string, shortNames ...string) *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: plural + "." + group},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: group,
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Plural:     plural,
+				Singular:   strings.ToLower(kind),
+				Kind:       kind,
+				ShortNames: shortNames,
+			},
+		},
+	}
+}
+
+func testCRDs() []runtime.Object {
+	return []runtime.Object{
+		testCRD("workflows", "argoproj.io", "Workflow", "wf"),
+		testCRD("certificates", "cert-manager.io", "Certificate", "cert", "certs"),
+		testCRD("certificates", "networking.internal.io", "Certificate", "certs"),
+	}
+}
+
+// startedCRDCache returns a synced cache over a fake clientset, the
+// clientset's recorded actions are cleared after the sync
+func startedCRDCache(t *testing.T) (*CRDCache, *fake.Clientset) {
+	t.Helper()
+	client := fake.NewSimpleClientset(testCRDs()...)
+	crdCache, err := NewCRDCache(client, 0)
+	if err != nil {
+		t.Fatalf("NewCRDCache: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := crdCache.Start(ctx, 5*time.Second); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	client.ClearActions()
+	return crdCache, client
+}
+
+func liveGets(client *fake.Clientset) int {
+	gets := 0
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "get" {
+			gets++
+		}
+	}
+	return gets
+}
+
+func TestCRDCacheGet(t *testing.T) {
+	crdCache, client := startedCRDCache(t)
+
+	tests := []struct {
+		name     string
+		lookup   string
+		found    bool
+		liveGets int
+	}{
+		{name: "cached full name", lookup: "workflows.argoproj.io", found: true},
+		{name: "unknown full name goes to the apiserver", lookup: "rollouts.argoproj.io", liveGets: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client.ClearActions()
+			crd, err := crdCache.Get(context.Background(), tt.lookup)
+			if tt.found {
+				if err != nil || crd.Name != tt.lookup {
+					t.Fatalf("Get(%q) = %v, %v", tt.lookup, crd, err)
+				}
+			} else if !errors.IsNotFound(err) {
+				t.Fatalf("Get(%q) error = %v, want NotFound", tt.lookup, err)
+			}
+			if got := liveGets(client); got != tt.liveGets {
+				t.Errorf("Get(%q) made %d live gets, want %d", tt.lookup, got, tt.liveGets)
+			}
+		})
+	}
+}
+
+func TestCRDCacheResolve(t *testing.T) {
+	crdCache, client := startedCRDCache(t)
+
+	tests := []struct {
+		name       string
+		lookup     string
+		want       string
+		candidates []string
+		notFound   bool
+	}{
+		{name: "full name", lookup: "workflows.argoproj.io", want: "workflows.argoproj.io"},
+		{name: "plural", lookup: "workflows", want: "workflows.argoproj.io"},
+		{name: "singular", lookup: "workflow", want: "workflows.argoproj.io"},
+		{name: "short name", lookup: "wf", want: "workflows.argoproj.io"},
+		{name: "kind", lookup: "Workflow", want: "workflows.argoproj.io"},
+		{name: "unique short name", lookup: "cert", want: "certificates.cert-manager.io"},
+		{
+			name:       "ambiguous short name",
+			lookup:     "certs",
+			candidates: []string{"certificates.cert-manager.io", "certificates.networking.internal.io"},
+		},
+		{
+			name:       "ambiguous kind",
+			lookup:     "certificate",
+			candidates: []string{"certificates.cert-manager.io", "certificates.networking.internal.io"},
+		},
+		{name: "unknown alias", lookup: "rollout", notFound: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client.ClearActions()
+			crd, err := crdCache.Resolve(context.Background(), tt.lookup)
+			switch {
+			case tt.candidates != nil:
+				ambiguous, ok := err.(*AmbiguousCRDError)
+				if !ok {
+					t.Fatalf("Resolve(%q) error = %v, want *AmbiguousCRDError", tt.lookup, err)
+				}
+				if !slices.Equal(ambiguous.Candidates, tt.candidates) {
+					t.Errorf("candidates = %v, want %v", ambiguous.Candidates, tt.candidates)
+				}
+			case tt.notFound:
+				if !errors.IsNotFound(err) {
+					t.Fatalf("Resolve(%q) error = %v, want NotFound", tt.lookup, err)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("Resolve(%q): %v", tt.lookup, err)
+				}
+				if crd.Name != tt.want {
+					t.Errorf("Resolve(%q) = %s, want %s", tt.lookup, crd.Name, tt.want)
+				}
+			}
+			if got := liveGets(client); got != 0 {
+				t.Errorf("Resolve(%q) made %d live gets, want 0", tt.lookup, got)
+			}
+		})
+	}
+}
+
+func TestCRDCacheResolveBeforeSync(t *testing.T) {
+	// Never started, every lookup goes to the apiserver
+	crdCache, err := NewCRDCache(fake.NewSimpleClientset(testCRDs()...), 0)
+	if err != nil {
+		t.Fatalf("NewCRDCache: %v", err)
+	}
+
+	crd, err := crdCache.Resolve(context.Background(), "wf")
+	if err != nil || crd.Name != "workflows.argoproj.io" {
+		t.Fatalf("Resolve(wf) = %v, %v", crd, err)
+	}
+	if _, err := crdCache.Resolve(context.Background(), "certs"); err == nil {
+		t.Fatal("Resolve(certs) succeeded, want *AmbiguousCRDError")
+	} else if _, ok := err.(*AmbiguousCRDError); !ok {
+		t.Fatalf("Resolve(certs) error = %v, want *AmbiguousCRDError", err)
+	}
+}
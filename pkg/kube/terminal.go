@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/net/websocket"
@@ -33,6 +34,11 @@ type TerminalSession struct {
 	container string
 
 	lastHeartbeat time.Time // Track last heartbeat for ping/pong
+
+	// idleTimeout closes the session when no stdin or resize message arrives
+	// for that long, zero disables it
+	idleTimeout  time.Duration
+	lastActivity atomic.Int64
 }
 
 func NewTerminalSession(client *K8sClient, conn *websocket.Conn, namespace, podName, container string) *TerminalSession {
@@ -46,6 +52,11 @@ func NewTerminalSession(client *K8sClient, conn *websocket.Conn, namespace, podN
 	}
 }
 
+// SetIdleTimeout closes the session after d without user input
+func (session *TerminalSession) SetIdleTimeout(d time.Duration) {
+	session.idleTimeout = d
+}
+
 func (session *TerminalSession) Start(ctx context.Context, subResource string) error {
 	req := session.k8sClient.ClientSet.CoreV1().RESTClient().Post().
 		Resource("pods").
@@ -109,9 +120,11 @@ func (session *TerminalSession) Read(p []byte) (int, error) {
 
 	switch msg.Type {
 	case "stdin":
+		session.lastActivity.Store(time.Now().UnixNano())
 		data := []byte(msg.Data)
 		return copy(p, data), nil
 	case "resize":
+		session.lastActivity.Store(time.Now().UnixNano())
 		if msg.Rows > 0 && msg.Cols > 0 {
 			select {
 			case session.sizeChan <- &remotecommand.TerminalSize{
@@ -164,6 +177,7 @@ func (session *TerminalSession) SendErrorMessage(errMsg string) {
 
 func (session *TerminalSession) checkHeartbeat(ctx context.Context) {
 	session.lastHeartbeat = time.Now()
+	session.lastActivity.Store(time.Now().UnixNano())
 	ticker := time.NewTicker(10 * time.Second)
 	defer ticker.Stop()
 
@@ -178,6 +192,14 @@ func (session *TerminalSession) checkHeartbeat(ctx context.Context) {
 				}
 				return
 			}
+			idle := time.Since(time.Unix(0, session.lastActivity.Load()))
+			if session.idleTimeout > 0 && idle > session.idleTimeout {
+				session.SendErrorMessage(fmt.Sprintf("Session closed after %s without input", session.idleTimeout))
+				if err := session.conn.Close(); err != nil {
+					klog.Errorf("WebSocket close error: %v", err)
+				}
+				return
+			}
 		}
 	}
 }
@@ -9,6 +9,7 @@ import (
 
 var unlogPath = []string{
 	"/healthz",
+	"/metrics",
 	"/assets/",
 	"/favicon.ico",
 }
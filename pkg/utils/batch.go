@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	// DefaultBatchConcurrency is how many items of a batch operation are
+	// processed at the same time unless the request asks otherwise
+	DefaultBatchConcurrency = 10
+	// MaxBatchConcurrency caps the concurrency a request may ask for
+	MaxBatchConcurrency = 50
+)
+
+// BatchConcurrency returns the requested concurrency capped at
+// MaxBatchConcurrency, or DefaultBatchConcurrency when none was requested
+func BatchConcurrency(requested int) int {
+	if requested <= 0 {
+		return DefaultBatchConcurrency
+	}
+	return min(requested, MaxBatchConcurrency)
+}
+
+// BatchItem is the result of item Index of a batch
+type BatchItem[T any] struct {
+	Index  int
+	Result T
+}
+
+// RunBatch calls fn for the items 0 to n-1 from a pool of concurrency
+// workers and returns the results in item order. Once ctx is done the
+// remaining items are not started, cancelled provides their results instead.
+func RunBatch[T any](ctx context.Context, n, concurrency int, fn func(ctx context.Context, i int) T, cancelled func(i int, err error) T) []T {
+	results := make([]T, n)
+	for item := range StreamBatch(ctx, n, concurrency, fn, cancelled) {
+		results[item.Index] = item.Result
+	}
+	return results
+}
+
+// StreamBatch works like RunBatch but publishes each result on the returned
+// channel as soon as it is available, in completion order. The channel is
+// closed once all items are done. It is buffered for the whole batch, so
+// workers don't block when the consumer stops reading.
+func StreamBatch[T any](ctx context.Context, n, concurrency int, fn func(ctx context.Context, i int) T, cancelled func(i int, err error) T) <-chan BatchItem[T] {
+	out := make(chan BatchItem[T], n)
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(max(concurrency, 1), n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := ctx.Err(); err != nil {
+					out <- BatchItem[T]{Index: i, Result: cancelled(i, err)}
+					continue
+				}
+				out <- BatchItem[T]{Index: i, Result: fn(ctx, i)}
+			}
+		}()
+	}
+	go func() {
+		for i := 0; i < n; i++ {
+			indexes <- i
+		}
+		close(indexes)
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBatchConcurrency(t *testing.T) {
+	tests := map[int]int{
+		-1:                      DefaultBatchConcurrency,
+		0:                       DefaultBatchConcurrency,
+		1:                       1,
+		25:                      25,
+		MaxBatchConcurrency:     MaxBatchConcurrency,
+		MaxBatchConcurrency + 1: MaxBatchConcurrency,
+	}
+	for requested, want := range tests {
+		if got := BatchConcurrency(requested); got != want {
+			t.Errorf("BatchConcurrency(%d) = %d, want %d", requested, got, want)
+		}
+	}
+}
+
+func TestRunBatch(t *testing.T) {
+	results := RunBatch(context.Background(), 20, 3, func(ctx context.Context, i int) int {
+		return i * i
+	}, func(i int, err error) int {
+		t.Errorf("item %d cancelled: %v", i, err)
+		return -1
+	})
+
+	for i, result := range results {
+		if result != i*i {
+			t.Errorf("results[%d] = %d, want %d", i, result, i*i)
+		}
+	}
+}
+
+func TestRunBatchCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	results := RunBatch(ctx, 10, 1, func(ctx context.Context, i int) string {
+		if i == 2 {
+			cancel()
+		}
+		return "done"
+	}, func(i int, err error) string {
+		return "cancelled: " + err.Error()
+	})
+
+	for i, result := range results {
+		want := "done"
+		if i > 2 {
+			want = "cancelled: " + context.Canceled.Error()
+		}
+		if result != want {
+			t.Errorf("results[%d] = %q, want %q", i, result, want)
+		}
+	}
+}
+
+func TestStreamBatch(t *testing.T) {
+	seen := map[int]bool{}
+	for item := range StreamBatch(context.Background(), 5, 0, func(ctx context.Context, i int) int {
+		return i + 1
+	}, nil) {
+		if item.Result != item.Index+1 || seen[item.Index] {
+			t.Errorf("unexpected item %+v", item)
+		}
+		seen[item.Index] = true
+	}
+	if len(seen) != 5 {
+		t.Errorf("got %d items, want 5", len(seen))
+	}
+
+	// An empty batch closes the channel without results
+	for item := range StreamBatch(context.Background(), 0, 3, func(ctx context.Context, i int) int { return i }, nil) {
+		t.Errorf("unexpected item %+v", item)
+	}
+}
@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDiffCells bounds the size of the table used to compare two texts.
+// Larger inputs are diffed as a whole replacement.
+const maxDiffCells = 4 << 20
+
+type diffLine struct {
+	kind byte // ' ', '-' or '+'
+	text string
+}
+
+// UnifiedDiff returns a unified diff of two texts with three lines of
+// context, or an empty string when they are equal
+func UnifiedDiff(fromName, toName, from, to string) string {
+	if from == to {
+		return ""
+	}
+	ops := diffLines(splitLines(from), splitLines(to))
+
+	const context = 3
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+
+	// Line numbers of the old and new text before each op
+	fromLine := make([]int, len(ops)+1)
+	toLine := make([]int, len(ops)+1)
+	for k, op := range ops {
+		fromLine[k+1], toLine[k+1] = fromLine[k], toLine[k]
+		if op.kind != '+' {
+			fromLine[k+1]++
+		}
+		if op.kind != '-' {
+			toLine[k+1]++
+		}
+	}
+
+	for k := 0; k < len(ops); {
+		if ops[k].kind == ' ' {
+			k++
+			continue
+		}
+		start := max(0, k-context)
+		end := min(len(ops), k+1+context)
+		for j := k + 1; j < len(ops) && j < end+context; j++ {
+			if ops[j].kind != ' ' {
+				end = min(len(ops), j+1+context)
+			}
+		}
+
+		fromStart, fromLen := fromLine[start]+1, fromLine[end]-fromLine[start]
+		toStart, toLen := toLine[start]+1, toLine[end]-toLine[start]
+		if fromLen == 0 {
+			fromStart--
+		}
+		if toLen == 0 {
+			toStart--
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", fromStart, fromLen, toStart, toLen)
+		for _, op := range ops[start:end] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.text)
+			out.WriteByte('\n')
+		}
+		k = end
+	}
+	return out.String()
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines computes a line diff from the longest common subsequence
+func diffLines(a, b []string) []diffLine {
+	if len(a)*len(b) > maxDiffCells {
+		ops := make([]diffLine, 0, len(a)+len(b))
+		for _, line := range a {
+			ops = append(ops, diffLine{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffLine{'+', line})
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the length of the common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := make([]diffLine, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffLine{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffLine{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffLine{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffLine{'+', b[j]})
+	}
+	return ops
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+)
+
+// CoreEventFromEventsV1 converts an events.k8s.io/v1 event into the core/v1
+// shape returned by the event endpoints
+func CoreEventFromEventsV1(event *eventsv1.Event) corev1.Event {
+	coreEvent := corev1.Event{
+		ObjectMeta:          event.ObjectMeta,
+		InvolvedObject:      event.Regarding,
+		Reason:              event.Reason,
+		Message:             event.Note,
+		Type:                event.Type,
+		Source:              event.DeprecatedSource,
+		FirstTimestamp:      event.DeprecatedFirstTimestamp,
+		LastTimestamp:       event.DeprecatedLastTimestamp,
+		Count:               event.DeprecatedCount,
+		EventTime:           event.EventTime,
+		Action:              event.Action,
+		Related:             event.Related,
+		ReportingController: event.ReportingController,
+		ReportingInstance:   event.ReportingInstance,
+	}
+	if coreEvent.Source.Component == "" {
+		coreEvent.Source.Component = event.ReportingController
+	}
+	if event.Series != nil {
+		coreEvent.Series = &corev1.EventSeries{
+			Count:            event.Series.Count,
+			LastObservedTime: event.Series.LastObservedTime,
+		}
+		coreEvent.Count = event.Series.Count
+	}
+	return coreEvent
+}
+
+// EventContainerName returns the container an event refers to from its
+// field path, e.g. spec.containers{api}
+func EventContainerName(event *corev1.Event) string {
+	path := event.InvolvedObject.FieldPath
+	start := strings.Index(path, "{")
+	if start < 0 || !strings.HasSuffix(path, "}") {
+		return ""
+	}
+	return path[start+1 : len(path)-1]
+}
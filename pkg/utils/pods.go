@@ -43,3 +43,88 @@ func IsPodErrorOrSuccess(pod *corev1.Pod) bool {
 	}
 	return false
 }
+
+// GetPodRequestsAndLimits returns the effective resource requests and limits
+// of a pod the way the scheduler computes them: the sum over containers and
+// restartable (sidecar) init containers, at least the largest regular init
+// container, plus the pod overhead
+func GetPodRequestsAndLimits(pod *corev1.Pod) (corev1.ResourceList, corev1.ResourceList) {
+	requests := corev1.ResourceList{}
+	limits := corev1.ResourceList{}
+	if pod == nil {
+		return requests, limits
+	}
+
+	for _, container := range pod.Spec.Containers {
+		AddResourceList(requests, container.Resources.Requests)
+		AddResourceList(limits, container.Resources.Limits)
+	}
+
+	// Sidecars keep running next to the regular containers, while regular
+	// init containers run one at a time before them
+	sidecarRequests := corev1.ResourceList{}
+	sidecarLimits := corev1.ResourceList{}
+	initRequests := corev1.ResourceList{}
+	initLimits := corev1.ResourceList{}
+	for _, container := range pod.Spec.InitContainers {
+		if container.RestartPolicy != nil && *container.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			AddResourceList(requests, container.Resources.Requests)
+			AddResourceList(limits, container.Resources.Limits)
+			AddResourceList(sidecarRequests, container.Resources.Requests)
+			AddResourceList(sidecarLimits, container.Resources.Limits)
+			continue
+		}
+		// A regular init container runs next to the sidecars started before it
+		stepRequests := corev1.ResourceList{}
+		stepLimits := corev1.ResourceList{}
+		AddResourceList(stepRequests, sidecarRequests)
+		AddResourceList(stepRequests, container.Resources.Requests)
+		AddResourceList(stepLimits, sidecarLimits)
+		AddResourceList(stepLimits, container.Resources.Limits)
+		maxResourceList(initRequests, stepRequests)
+		maxResourceList(initLimits, stepLimits)
+	}
+	maxResourceList(requests, initRequests)
+	maxResourceList(limits, initLimits)
+
+	AddResourceList(requests, pod.Spec.Overhead)
+	if len(limits) > 0 {
+		AddResourceList(limits, pod.Spec.Overhead)
+	}
+	return requests, limits
+}
+
+// AddResourceList adds the quantities in add to list
+func AddResourceList(list, add corev1.ResourceList) {
+	for name, quantity := range add {
+		if value, ok := list[name]; ok {
+			value.Add(quantity)
+			list[name] = value
+		} else {
+			list[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+func maxResourceList(list, other corev1.ResourceList) {
+	for name, quantity := range other {
+		if value, ok := list[name]; !ok || quantity.Cmp(value) > 0 {
+			list[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+// GetPodRestartCount returns the total restart count of a pod's containers
+func GetPodRestartCount(pod *corev1.Pod) int32 {
+	var restarts int32
+	if pod == nil {
+		return restarts
+	}
+	for _, status := range pod.Status.InitContainerStatuses {
+		restarts += status.RestartCount
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		restarts += status.RestartCount
+	}
+	return restarts
+}
@@ -0,0 +1,16 @@
+package utils
+
+import "github.com/gin-gonic/gin"
+
+// ActingUser returns the username stored in the request context by the auth
+// middleware
+func ActingUser(c *gin.Context) string {
+	if user, exists := c.Get("user"); exists {
+		if info, ok := user.(gin.H); ok {
+			if username, ok := info["username"].(string); ok && username != "" {
+				return username
+			}
+		}
+	}
+	return "unknown"
+}